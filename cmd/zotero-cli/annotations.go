@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var annotationsCommand = &command{
+	name:    "annotations",
+	usage:   "(-attachment KEY | -collection KEY)",
+	summary: "List or export PDF/EPUB annotations",
+	examples: []string{
+		"zotero-cli annotations -attachment ATT123",
+		"zotero-cli annotations -collection ABC123 -out highlights.md",
+	},
+	output:   true,
+	complete: map[string]completer{"attachment": completeItems, "collection": completeCollections, "out": completeFiles},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		attachment := fs.String("attachment", "", "List annotations of this attachment item")
+		collection := fs.String("collection", "", "Export annotations of all items in this collection as Markdown")
+		outFile := fs.String("out", "", "Output file for -collection export (empty for stdout)")
+		return func(c *invocation) {
+			if c.libraryID == "" || (*attachment == "" && *collection == "") {
+				c.usageError("-library and one of -attachment or -collection are required")
+			}
+			if *collection != "" {
+				exportAnnotations(c.libraryID, c.libraryType, c.apiKey, c.verbose, *collection, *outFile)
+			} else {
+				listAnnotations(c.libraryID, c.libraryType, c.apiKey, c.verbose, *attachment, c.out)
+			}
+		}
+	},
+}
+
+// listAnnotations prints the annotations of a single attachment
+func listAnnotations(libraryID, libraryType, apiKey string, verbose bool, attachmentKey string, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	annotations, err := client.Annotations(ctx, attachmentKey, nil)
+	if err != nil {
+		fatal("fetching annotations", err)
+	}
+
+	annotationPrinter.print(out, annotations)
+}
+
+// exportAnnotations writes the annotations of a collection as Markdown grouped by source
+func exportAnnotations(libraryID, libraryType, apiKey string, verbose bool, collectionKey, out string) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	groups, err := client.CollectionAnnotations(ctx, collectionKey)
+	if err != nil {
+		fatal("fetching annotations", err)
+	}
+
+	markdown := zotero.AnnotationsMarkdown(groups)
+	if out == "" {
+		fmt.Print(markdown)
+		return
+	}
+
+	if err := os.WriteFile(out, []byte(markdown), 0o644); err != nil {
+		fatal("writing annotations", err)
+	}
+	fmt.Printf("Exported annotations from %d sources to %s\n", len(groups), out)
+}
+
+// annotationPrinter prints annotations in the format selected by -o
+var annotationPrinter = recordPrinter[zotero.Annotation]{
+	fields: []string{"key", "type", "pageLabel", "color", "text", "comment"},
+	key:    func(annotation zotero.Annotation) string { return annotation.Key },
+	table: func(annotations []zotero.Annotation) {
+		fmt.Printf("Retrieved %d annotations:\n\n", len(annotations))
+		printAnnotationsTable(annotations)
+	},
+}
+
+// printAnnotationsTable displays annotations in a formatted table
+func printAnnotationsTable(annotations []zotero.Annotation) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tPAGE\tCOLOR\tTEXT")
+	fmt.Fprintln(w, "---\t----\t----\t-----\t----")
+
+	for _, annotation := range annotations {
+		text := annotation.Text
+		if text == "" {
+			text = annotation.Comment
+		}
+		page := annotation.PageLabel
+		if page == "" {
+			page = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", annotation.Key, annotation.Type, page, annotation.Color, truncate(text, 50))
+	}
+	w.Flush()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// attach creates a linked URL, linked file or web snapshot attachment
+func attach(libraryID, libraryType, apiKey string, verbose bool, parentItem, url, link, snapshot, title, contentType string, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	var item *zotero.Item
+	var err error
+	switch {
+	case url != "" && snapshot != "":
+		out.status("Uploading snapshot of %s: %s\n", url, snapshot)
+		item, err = client.CreateImportedURL(ctx, parentItem, url, title, snapshot, contentType)
+	case url != "":
+		out.status("Linking URL: %s\n", url)
+		item, err = client.CreateLinkedURLAttachment(ctx, parentItem, url, title)
+	default:
+		out.status("Linking file: %s\n", link)
+		item, err = client.CreateLinkedFileAttachment(ctx, parentItem, link, contentType)
+	}
+	if err != nil {
+		fatal("creating attachment", err)
+	}
+
+	printCreatedItem(out, item, func() {
+		fmt.Printf("\nSuccessfully created attachment!\n")
+		fmt.Printf("Key: %s\n", item.Key)
+		fmt.Printf("Title: %s\n", item.Data.Title)
+		fmt.Printf("Link Mode: %s\n", item.Data.LinkMode)
+		if item.Data.URL != "" {
+			fmt.Printf("URL: %s\n", item.Data.URL)
+		}
+		if item.Data.Path != "" {
+			fmt.Printf("Path: %s\n", item.Data.Path)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// command is a zotero-cli command. A command either runs (setup is set) or dispatches to
+// its subcommands.
+type command struct {
+	name     string
+	usage    string // arguments and required flags, e.g. "-item KEY -set field=value [-set ...]"
+	summary  string // one line, shown in command lists
+	help     string // further details, shown after the flags in the command's help
+	examples []string
+	globals  globalFlagSet
+	output   bool // the command accepts the output flags -o, -fields and -format
+	hidden   bool // not listed in help
+
+	// complete maps flag names to the completion of their values; "" completes the
+	// positional arguments
+	complete map[string]completer
+
+	// setup defines the command's flags and returns the function that runs it
+	setup       func(fs *flag.FlagSet) func(c *invocation)
+	subcommands []*command
+
+	parent *command
+}
+
+// invocation is a parsed command line, passed to the function that runs a command
+type invocation struct {
+	cmd  *command
+	args []string // positional arguments
+
+	global      *globalFlags
+	apiKey      string
+	libraryID   string
+	libraryType string
+	verbose     bool
+	out         *outputFlags // nil unless the command accepts the output flags
+}
+
+// link sets the parent of every command below cmd
+func (cmd *command) link() *command {
+	for _, sub := range cmd.subcommands {
+		sub.parent = cmd
+		sub.link()
+	}
+	return cmd
+}
+
+// path returns the full name of the command, e.g. "zotero-cli tag add"
+func (cmd *command) path() string {
+	if cmd.parent == nil {
+		return cmd.name
+	}
+	return cmd.parent.path() + " " + cmd.name
+}
+
+// lookup returns the subcommand with the given name, or nil
+func (cmd *command) lookup(name string) *command {
+	for _, sub := range cmd.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// flagSet creates the command's flag set, with its own flags followed by the output and
+// global flags. It returns the flag set, the function that runs the command and the
+// output flags (nil if the command does not print records).
+func (cmd *command) flagSet(g *globalFlags) (*flag.FlagSet, func(*invocation), *outputFlags) {
+	fs := flag.NewFlagSet(cmd.path(), flag.ExitOnError)
+	fs.Usage = func() { cmd.printHelp(fs.Output()) }
+	run := cmd.setup(fs)
+	var out *outputFlags
+	if cmd.output {
+		out = addOutputFlags(fs)
+	}
+	g.register(fs, cmd.globals)
+	return fs, run, out
+}
+
+// execute runs the command with args, the command line after the command's name
+func (cmd *command) execute(g *globalFlags, args []string) {
+	if cmd.setup == nil {
+		if len(args) == 0 {
+			cmd.printHelp(os.Stderr)
+			os.Exit(exitUsage)
+		}
+		if isHelpFlag(args[0]) {
+			cmd.printHelp(os.Stdout)
+			return
+		}
+		sub := cmd.lookup(args[0])
+		if sub == nil {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", strings.TrimPrefix(cmd.path()+" "+args[0], "zotero-cli "))
+			cmd.printHelp(os.Stderr)
+			os.Exit(exitUsage)
+		}
+		sub.execute(g, args[1:])
+		return
+	}
+
+	fs, run, out := cmd.flagSet(g)
+	c := &invocation{cmd: cmd, args: parseArgs(fs, args), global: g, out: out}
+	if cmd.globals != profileFlags {
+		c.apiKey, c.libraryID, c.libraryType, c.verbose = g.resolve()
+	}
+	if out != nil {
+		out.resolve()
+	}
+	run(c)
+}
+
+// isHelpFlag reports whether arg asks for help
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// parseArgs parses flags anywhere on the command line and returns the positional arguments,
+// so that "edit KEY -yes" and "edit -yes KEY" are equivalent. Arguments after "--" are
+// never parsed as flags.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...)
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// usageError prints an error and the command's help, and exits with exitUsage
+func (c *invocation) usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	c.cmd.printHelp(os.Stderr)
+	os.Exit(exitUsage)
+}
+
+// requireLibrary exits with a usage error if no library is configured
+func (c *invocation) requireLibrary() {
+	if c.libraryID == "" {
+		c.usageError("-library is required")
+	}
+}
+
+// requireAPIKey exits if no API key is configured, which write operations need
+func (c *invocation) requireAPIKey() {
+	if c.apiKey == "" {
+		fmt.Fprintln(os.Stderr, "Error: API key required for write operations (use -key, set ZOTERO_API_KEY or run zotero-cli login)")
+		os.Exit(exitAuth)
+	}
+}
+
+// client creates a client for the invocation's library
+func (c *invocation) client() *zotero.Client {
+	return createClient(c.libraryID, c.libraryType, c.apiKey, c.verbose)
+}
+
+// printHelp prints the command's description, usage, subcommands or flags, and examples
+func (cmd *command) printHelp(w io.Writer) {
+	if cmd.summary != "" {
+		fmt.Fprintf(w, "%s\n\n", cmd.summary)
+	}
+
+	fmt.Fprintln(w, "Usage:")
+	if cmd.setup == nil {
+		fmt.Fprintf(w, "  %s <command> [flags]\n", cmd.path())
+		fmt.Fprintln(w, "\nCommands:")
+		width := 0
+		for _, sub := range cmd.subcommands {
+			width = max(width, len(sub.name))
+		}
+		for _, sub := range cmd.subcommands {
+			if !sub.hidden {
+				fmt.Fprintf(w, "  %-*s  %s\n", width, sub.name, sub.summary)
+			}
+		}
+	} else {
+		fmt.Fprintf(w, "  %s\n", strings.Join(slices.DeleteFunc([]string{cmd.path(), cmd.usage, "[flags]"}, func(s string) bool { return s == "" }), " "))
+		cmd.printFlags(w)
+	}
+
+	if cmd.help != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(cmd.help, "\n"))
+	}
+	if len(cmd.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range cmd.examples {
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+	if cmd.setup == nil {
+		fmt.Fprintf(w, "\nRun \"zotero-cli help %s<command>\" for the flags and examples of a command.\n",
+			strings.TrimPrefix(cmd.path()+" ", "zotero-cli "))
+	}
+}
+
+// printFlags prints the command's flags, the output flags and the global flags as separate groups
+func (cmd *command) printFlags(w io.Writer) {
+	fs, _, out := cmd.flagSet(&globalFlags{})
+	isGlobal := func(name string) bool { return slices.Contains(globalFlagNames, name) }
+	isOutput := func(name string) bool { return out != nil && slices.Contains(out.names, name) }
+	groups := []struct {
+		title string
+		match func(name string) bool
+	}{
+		{"Flags", func(name string) bool { return !isOutput(name) && !isGlobal(name) }},
+		{"Output Flags", isOutput},
+		{"Global Flags", isGlobal},
+	}
+	for _, group := range groups {
+		// Copy the group's flags into a flag set of their own to print them with PrintDefaults
+		groupFlags := flag.NewFlagSet(cmd.path(), flag.ContinueOnError)
+		fs.VisitAll(func(f *flag.Flag) {
+			if group.match(f.Name) {
+				groupFlags.Var(f.Value, f.Name, f.Usage)
+				groupFlags.Lookup(f.Name).DefValue = f.DefValue
+			}
+		})
+		if !hasFlags(groupFlags) {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", group.title)
+		groupFlags.SetOutput(w)
+		groupFlags.PrintDefaults()
+	}
+}
+
+// hasFlags reports whether any flag is defined in fs
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var collectionCommand = &command{
+	name:    "collection",
+	summary: "Rename, move or delete a collection",
+	subcommands: []*command{
+		{
+			name:     "rename",
+			usage:    "-collection KEY -name NAME",
+			summary:  "Rename a collection",
+			examples: []string{"zotero-cli collection rename -collection COLL123 -name 'Thesis'"},
+			complete: map[string]completer{"collection": completeCollections},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				collectionKey, version := addCollectionFlags(fs)
+				name := fs.String("name", "", "New collection name (required)")
+				return func(c *invocation) {
+					if *name == "" {
+						c.usageError("-name is required")
+					}
+					client, ctx, collection := fetchCollectionForUpdate(c, *collectionKey, *version)
+					old := collection.Data.Name
+					collection.Data.Name = *name
+					if err := client.UpdateCollection(ctx, collection); err != nil {
+						fatal("renaming collection", err)
+					}
+					fmt.Printf("Renamed collection %s from '%s' to '%s'\n", collection.Key, old, *name)
+				}
+			},
+		},
+		{
+			name:    "move",
+			usage:   "-collection KEY (-parent KEY | -top)",
+			summary: "Move a collection into another collection or to the top level",
+			examples: []string{
+				"zotero-cli collection move -collection COLL123 -parent COLL456",
+				"zotero-cli collection move -collection COLL123 -top",
+			},
+			complete: map[string]completer{"collection": completeCollections, "parent": completeCollections},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				collectionKey, version := addCollectionFlags(fs)
+				parent := fs.String("parent", "", "New parent collection key")
+				top := fs.Bool("top", false, "Make the collection a top-level collection")
+				return func(c *invocation) {
+					if (*parent == "") == !*top {
+						c.usageError("one of -parent or -top is required")
+					}
+					client, ctx, collection := fetchCollectionForUpdate(c, *collectionKey, *version)
+					if *parent != "" {
+						requireNotDescendant(ctx, client, collection.Key, *parent)
+					}
+					collection.Data.ParentCollection = zotero.ParentCollectionRef(*parent)
+					if *top {
+						collection.Data.ParentCollection = zotero.TopLevelCollection
+					}
+					if err := client.UpdateCollection(ctx, collection); err != nil {
+						fatal("moving collection", err)
+					}
+					if *parent == "" {
+						fmt.Printf("Moved collection '%s' to the top level\n", collection.Data.Name)
+					} else {
+						fmt.Printf("Moved collection '%s' into %s\n", collection.Data.Name, *parent)
+					}
+				}
+			},
+		},
+		{
+			name:     "delete",
+			usage:    "-collection KEY",
+			summary:  "Delete a collection and its subcollections, keeping their items",
+			examples: []string{"zotero-cli collection delete -collection COLL123 -yes"},
+			complete: map[string]completer{"collection": completeCollections},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				collectionKey, version := addCollectionFlags(fs)
+				yes := fs.Bool("yes", false, "Do not ask for confirmation")
+				return func(c *invocation) {
+					client, ctx, collection := fetchCollectionForUpdate(c, *collectionKey, *version)
+					if !confirm(*yes, "Delete collection '%s' (%d items) and its subcollections? Items are kept in the library.", collection.Data.Name, collection.Meta.NumItems) {
+						fmt.Fprintln(os.Stderr, "Aborted")
+						os.Exit(exitError)
+					}
+					if err := client.DeleteCollection(ctx, collection.Key, collection.Version); err != nil {
+						fatal("deleting collection", err)
+					}
+					fmt.Printf("Deleted collection '%s'\n", collection.Data.Name)
+				}
+			},
+		},
+	},
+}
+
+// addCollectionFlags registers the flags shared by the collection subcommands
+func addCollectionFlags(fs *flag.FlagSet) (collectionKey *string, version *int) {
+	collectionKey = fs.String("collection", "", "Collection key (required)")
+	version = fs.Int("version", 0, "Only modify the collection if it is still at this version")
+	return collectionKey, version
+}
+
+// fetchCollectionForUpdate checks the flags and write access, and fetches the collection
+// at the expected version
+func fetchCollectionForUpdate(c *invocation, collectionKey string, version int) (*zotero.Client, context.Context, *zotero.Collection) {
+	if c.libraryID == "" || collectionKey == "" {
+		c.usageError("-library and -collection are required")
+	}
+	c.requireAPIKey()
+
+	client := c.client()
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	collection, err := client.Collection(ctx, collectionKey, nil)
+	if err != nil {
+		fatal("fetching collection", err)
+	}
+	checkVersion("collection", collection.Key, collection.Version, version)
+	return client, ctx, collection
+}
+
+// requireNotDescendant exits if parentKey is the collection itself or one of its
+// subcollections, which would create a cycle
+func requireNotDescendant(ctx context.Context, client *zotero.Client, collectionKey, parentKey string) {
+	for key := parentKey; key != ""; {
+		if key == collectionKey {
+			fmt.Fprintln(os.Stderr, "Error: cannot move a collection into itself or one of its subcollections")
+			os.Exit(exitUsage)
+		}
+		ancestor, err := client.Collection(ctx, key, nil)
+		if err != nil {
+			fatal("fetching parent collection", err)
+		}
+		key = string(ancestor.Data.ParentCollection)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// completer returns completion candidates as "value" or "value\tdescription" lines.
+// The cache is nil if the library has not been cached yet.
+type completer func(cache *completionCache) []string
+
+// completeFilesDirective tells the shell scripts to complete file names instead
+const completeFilesDirective = "__files__"
+
+func completeItems(cache *completionCache) []string       { return cache.candidates(cacheItems) }
+func completeCollections(cache *completionCache) []string { return cache.candidates(cacheCollections) }
+func completeSearches(cache *completionCache) []string    { return cache.candidates(cacheSearches) }
+func completeTags(cache *completionCache) []string        { return cache.candidates(cacheTags) }
+func completeFiles(*completionCache) []string             { return []string{completeFilesDirective} }
+
+// completeProfiles completes the names of the profiles in the config file
+func completeProfiles(*completionCache) []string {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.profileNames()
+}
+
+// completeValues completes a fixed list of values
+func completeValues(values ...string) completer {
+	return func(*completionCache) []string { return values }
+}
+
+// globalCompletions complete the values of the global and output flags of every command
+var globalCompletions = map[string]completer{
+	"profile": completeProfiles,
+	"type":    completeValues("user", "group"),
+	"o":       completeValues(outputFormats...),
+}
+
+// completionCache is a local copy of the keys and names of a library's items, collections,
+// saved searches and tags, so that completion does not wait for the API
+type completionCache struct {
+	Library     string      `json:"library"` // e.g. "users/12345"
+	Updated     time.Time   `json:"updated"`
+	Items       []cacheName `json:"items"`
+	Collections []cacheName `json:"collections"`
+	Searches    []cacheName `json:"searches"`
+	Tags        []string    `json:"tags"`
+}
+
+// cacheName is a key and the name it is shown with
+type cacheName struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// cacheKind selects a list in the completion cache
+type cacheKind int
+
+const (
+	cacheItems cacheKind = iota
+	cacheCollections
+	cacheSearches
+	cacheTags
+)
+
+// candidates returns the cached keys of a kind with their names as descriptions
+func (cache *completionCache) candidates(kind cacheKind) []string {
+	if cache == nil {
+		return nil
+	}
+	var names []cacheName
+	switch kind {
+	case cacheItems:
+		names = cache.Items
+	case cacheCollections:
+		names = cache.Collections
+	case cacheSearches:
+		names = cache.Searches
+	case cacheTags:
+		return cache.Tags
+	}
+	candidates := make([]string, len(names))
+	for i, name := range names {
+		// Tabs and newlines would break the line format
+		description := strings.Join(strings.Fields(name.Name), " ")
+		candidates[i] = name.Key + "\t" + truncate(description, 60)
+	}
+	return candidates
+}
+
+// completionCacheDir returns the directory of the completion caches
+func completionCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "zotero-cli", "completion"), nil
+}
+
+// completionCachePath returns the cache file of a library
+func completionCachePath(libraryType, libraryID string) (string, error) {
+	dir, err := completionCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, libraryType+"-"+libraryID+".json"), nil
+}
+
+// loadCompletionCache reads the cache of a library. If libraryID is empty (it would take an
+// API request to find the API key's user), the most recently refreshed cache is used.
+func loadCompletionCache(libraryType, libraryID string) *completionCache {
+	var path string
+	if libraryID != "" {
+		path, _ = completionCachePath(libraryType, libraryID)
+	} else if dir, err := completionCacheDir(); err == nil {
+		var newest time.Time
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && info.ModTime().After(newest) {
+				newest, path = info.ModTime(), filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cache completionCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+	return &cache
+}
+
+// refreshCompletionCache fetches the library's collections, saved searches, tags and up to
+// itemLimit of the most recently modified top-level items, and saves them in the cache
+func refreshCompletionCache(c *invocation, itemLimit int) (string, *completionCache, error) {
+	client := c.client()
+	ctx := context.Background()
+	cache := &completionCache{Library: c.libraryType + "/" + c.libraryID, Updated: time.Now()}
+
+	items, err := fetchPages(ctx, itemLimit, func(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+		params.Sort, params.Direction = "dateModified", "desc"
+		return client.Top(ctx, params)
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching items: %w", err)
+	}
+	for _, item := range items {
+		cache.Items = append(cache.Items, cacheName{Key: item.Key, Name: itemTitle(item)})
+	}
+
+	collections, err := fetchPages(ctx, 0, client.Collections)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching collections: %w", err)
+	}
+	for _, collection := range collections {
+		cache.Collections = append(cache.Collections, cacheName{Key: collection.Key, Name: collection.Data.Name})
+	}
+
+	searches, err := fetchPages(ctx, 0, client.Searches)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching saved searches: %w", err)
+	}
+	for _, search := range searches {
+		cache.Searches = append(cache.Searches, cacheName{Key: search.Key, Name: search.Data.Name})
+	}
+
+	tags, err := fetchPages(ctx, 0, client.Tags)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching tags: %w", err)
+	}
+	for _, tag := range tags {
+		cache.Tags = append(cache.Tags, tag.Tag)
+	}
+	slices.Sort(cache.Tags)
+	cache.Tags = slices.Compact(cache.Tags)
+
+	path, err := completionCachePath(c.libraryType, c.libraryID)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", nil, err
+	}
+	return path, cache, os.WriteFile(path, data, 0o600)
+}
+
+// fetchPages pages through a list endpoint 100 results at a time, up to limit results
+// (0 for all)
+func fetchPages[T any](ctx context.Context, limit int, fetch func(context.Context, *zotero.QueryParams) ([]T, error)) ([]T, error) {
+	var all []T
+	for {
+		page, err := fetch(ctx, &zotero.QueryParams{Limit: 100, Start: len(all)})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if limit > 0 && len(all) >= limit {
+			return all[:limit], nil
+		}
+		if len(page) < 100 {
+			return all, nil
+		}
+	}
+}
+
+// completeCommandLine returns the completion candidates for the last word of args, the
+// command line after the program name
+func completeCommandLine(root *command, args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, current := args[:len(args)-1], args[len(args)-1]
+
+	// Walk the command tree, keeping the values of the flags that select the library
+	cmd := root
+	var fs *flag.FlagSet
+	var pending *flag.Flag // a flag whose value is the next word
+	values := make(map[string]string)
+	for _, word := range words {
+		if pending != nil {
+			values[pending.Name] = word
+			pending = nil
+			continue
+		}
+		if fs == nil && cmd.setup != nil {
+			fs, _, _ = cmd.flagSet(&globalFlags{})
+		}
+		if name, ok := strings.CutPrefix(word, "-"); ok && word != "-" && word != "--" {
+			name, value, hasValue := strings.Cut(strings.TrimPrefix(name, "-"), "=")
+			f := lookupFlag(cmd, fs, name)
+			switch {
+			case hasValue:
+				values[name] = value
+			case f != nil && !isBoolFlag(f):
+				pending = f
+			}
+			continue
+		}
+		if sub := cmd.lookup(word); sub != nil && cmd.setup == nil {
+			cmd, fs = sub, nil
+		}
+	}
+	if fs == nil && cmd.setup != nil {
+		fs, _, _ = cmd.flagSet(&globalFlags{})
+	}
+
+	var complete completer
+	switch {
+	case pending != nil:
+		complete = cmd.complete[pending.Name]
+		if complete == nil {
+			complete = globalCompletions[pending.Name]
+		}
+		if complete == nil {
+			return nil
+		}
+	case strings.HasPrefix(current, "-"):
+		var flags []string
+		if fs == nil {
+			fs = flag.NewFlagSet(cmd.path(), flag.ContinueOnError)
+			(&globalFlags{}).register(fs, libraryFlags)
+		}
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name+"\t"+f.Usage)
+		})
+		return filterCandidates(flags, current)
+	case cmd.setup == nil:
+		var names []string
+		for _, sub := range cmd.subcommands {
+			if !sub.hidden {
+				names = append(names, sub.name+"\t"+sub.summary)
+			}
+		}
+		return filterCandidates(names, current)
+	default:
+		complete = cmd.complete[""]
+		if complete == nil {
+			return nil
+		}
+	}
+
+	// Resolve the library without API requests: flags, then environment, then profile
+	var profile map[string]string
+	if cfg, err := loadConfig(); err == nil {
+		profile = cfg.Profiles[cfg.activeProfileName(values["profile"])]
+	}
+	libraryID := cmp.Or(values["library"], os.Getenv("ZOTERO_LIBRARY_ID"), profile["library_id"])
+	libraryType := cmp.Or(values["type"], os.Getenv("ZOTERO_LIBRARY_TYPE"), profile["library_type"], "user")
+
+	candidates := complete(loadCompletionCache(libraryType, libraryID))
+	if slices.Equal(candidates, []string{completeFilesDirective}) {
+		return candidates
+	}
+	return filterCandidates(candidates, current)
+}
+
+// lookupFlag finds a flag of cmd, or a global flag for commands with subcommands (which
+// accept the global flags before the subcommand name)
+func lookupFlag(cmd *command, fs *flag.FlagSet, name string) *flag.Flag {
+	if fs == nil {
+		fs = flag.NewFlagSet(cmd.path(), flag.ContinueOnError)
+		(&globalFlags{}).register(fs, libraryFlags)
+	}
+	return fs.Lookup(name)
+}
+
+// isBoolFlag reports whether f takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// filterCandidates returns the candidates whose value starts with prefix
+func filterCandidates(candidates []string, prefix string) []string {
+	return slices.DeleteFunc(candidates, func(candidate string) bool {
+		value, _, _ := strings.Cut(candidate, "\t")
+		return !strings.HasPrefix(value, prefix)
+	})
+}
+
+// completionScripts are the shell completion scripts. They call "zotero-cli __complete" with
+// the words of the command line and complete file names when it prints completeFilesDirective.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for zotero-cli
+_zotero_cli() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" out
+    out=$("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    if [[ "$out" == "` + completeFilesDirective + `" ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(cut -f1 <<<"$out")" -- "$cur"))
+}
+complete -o default -F _zotero_cli zotero-cli
+`,
+	"zsh": `#compdef zotero-cli
+# zsh completion for zotero-cli
+_zotero_cli() {
+    local -a lines candidates
+    local line
+    lines=("${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ "${lines[1]}" == "` + completeFilesDirective + `" ]]; then
+        _files
+        return
+    fi
+    for line in "${lines[@]}"; do
+        [[ -z "$line" ]] && continue
+        if [[ "$line" == *$'\t'* ]]; then
+            candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+        else
+            candidates+=("${line//:/\\:}")
+        fi
+    done
+    _describe 'zotero-cli' candidates
+}
+compdef _zotero_cli zotero-cli
+`,
+	"fish": `# fish completion for zotero-cli
+function __zotero_cli_complete
+    set -l tokens (commandline -opc)
+    set -l current (commandline -ct)
+    set -l out ($tokens[1] __complete $tokens[2..-1] $current 2>/dev/null)
+    if test "$out[1]" = "` + completeFilesDirective + `"
+        __fish_complete_path $current
+        return
+    end
+    printf '%s\n' $out
+end
+complete -c zotero-cli -f -a '(__zotero_cli_complete)'
+`,
+}
+
+// completionCommand prints the shell completion scripts and refreshes the completion cache
+var completionCommand = &command{
+	name:    "completion",
+	summary: "Generate shell completion scripts and refresh the completion cache",
+	subcommands: []*command{
+		completionScriptCommand("bash", "source <(zotero-cli completion bash)   # in ~/.bashrc"),
+		completionScriptCommand("zsh", "source <(zotero-cli completion zsh)    # in ~/.zshrc, after compinit"),
+		completionScriptCommand("fish", "zotero-cli completion fish > ~/.config/fish/completions/zotero-cli.fish"),
+		{
+			name:    "refresh",
+			summary: "Cache item keys, collections, saved searches and tags for completion",
+			help: "Completion reads item keys, collection keys, saved searches and tags from a local cache,\n" +
+				"so that it does not wait for the API. Run refresh when the library has changed.",
+			examples: []string{"zotero-cli completion refresh", "zotero-cli completion refresh -profile lab -items 5000"},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				itemLimit := fs.Int("items", 1000, "Number of recently modified items to cache (0 for all)")
+				return func(c *invocation) {
+					c.requireLibrary()
+					path, cache, err := refreshCompletionCache(c, *itemLimit)
+					if err != nil {
+						fatal("refreshing completion cache", err)
+					}
+					fmt.Printf("Cached %d items, %d collections, %d saved searches and %d tags in %s\n",
+						len(cache.Items), len(cache.Collections), len(cache.Searches), len(cache.Tags), path)
+				}
+			},
+		},
+	},
+}
+
+// completionScriptCommand creates the command that prints the completion script of a shell
+func completionScriptCommand(shell, install string) *command {
+	return &command{
+		name:     shell,
+		summary:  "Print the " + shell + " completion script",
+		help:     "Item keys, collections, saved searches and tags are completed from a cache;\nrun \"zotero-cli completion refresh\" to fill it.",
+		examples: []string{install},
+		globals:  profileFlags,
+		setup: func(*flag.FlagSet) func(*invocation) {
+			return func(*invocation) {
+				fmt.Print(completionScripts[shell])
+			}
+		},
+	}
+}
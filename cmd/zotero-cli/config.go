@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// profileKeys lists the settings a profile can hold, in file order
+var profileKeys = []string{"key", "library_id", "library_type", "base_url", "rate_limit", "output"}
+
+// profileKeyHelp describes each profile setting for "config" usage
+var profileKeyHelp = map[string]string{
+	"key":          "Zotero API key",
+	"library_id":   "Library ID",
+	"library_type": "Library type: user or group",
+	"base_url":     "API base URL (default https://api.zotero.org)",
+	"rate_limit":   "Minimum time between requests, e.g. 1s or 0 to disable",
+	"output":       "Default output format: table, json, jsonl, csv, tsv or keys",
+}
+
+// cliConfig is the contents of the config file: named profiles and the one used by default.
+//
+//	default = "personal"
+//
+//	[personal]
+//	key = "..."
+//	library_id = "12345"
+//
+//	[lab]
+//	library_id = "777"
+//	library_type = "group"
+type cliConfig struct {
+	Default  string
+	Profiles map[string]map[string]string
+}
+
+// configPath returns the config file location: $ZOTERO_CONFIG, or config.toml in
+// $XDG_CONFIG_HOME/zotero-cli (default ~/.config/zotero-cli)
+func configPath() (string, error) {
+	if path := os.Getenv("ZOTERO_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "zotero-cli", "config.toml"), nil
+}
+
+// loadConfig reads the config file, returning an empty config if it does not exist
+func loadConfig() (*cliConfig, error) {
+	cfg := &cliConfig{Profiles: make(map[string]map[string]string)}
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.parse(data); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parse reads the TOML subset written by encode: comments, [table] headers, and
+// key = value pairs with string, number or boolean values
+func (cfg *cliConfig) parse(data []byte) error {
+	var current map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line, "]")
+			if !ok {
+				return fmt.Errorf("line %d: unterminated table header", n)
+			}
+			name = strings.TrimSpace(name[1:])
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if name == "" {
+				return fmt.Errorf("line %d: empty profile name", n)
+			}
+			current = make(map[string]string)
+			cfg.Profiles[name] = current
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected key = value", n)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if current == nil {
+			if key != "default" {
+				return fmt.Errorf("line %d: unknown setting %q outside a profile", n, key)
+			}
+			cfg.Default = value
+			continue
+		}
+		current[key] = value
+	}
+	return scanner.Err()
+}
+
+// parseTOMLValue parses a quoted string, number or boolean, dropping any trailing comment
+func parseTOMLValue(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"`) {
+		// Find the closing quote, skipping escaped characters
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '\\':
+				i++
+			case '"':
+				rest := strings.TrimSpace(raw[i+1:])
+				if rest != "" && !strings.HasPrefix(rest, "#") {
+					return "", fmt.Errorf("unexpected text after string: %s", rest)
+				}
+				return strconv.Unquote(raw[:i+1])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+	}
+	if strings.HasPrefix(raw, "'") {
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : end+1], nil
+	}
+
+	value, _, _ := strings.Cut(raw, "#")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return value, nil
+}
+
+// encode writes the config with profiles sorted by name and settings in a fixed order
+func (cfg *cliConfig) encode() []byte {
+	var b bytes.Buffer
+	b.WriteString("# zotero-cli configuration\n")
+	if cfg.Default != "" {
+		fmt.Fprintf(&b, "default = %s\n", strconv.Quote(cfg.Default))
+	}
+
+	for _, name := range cfg.profileNames() {
+		fmt.Fprintf(&b, "\n[%s]\n", tomlKey(name))
+		settings := cfg.Profiles[name]
+		keys := make([]string, 0, len(settings))
+		for key := range settings {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			ia, ib := slices.Index(profileKeys, a), slices.Index(profileKeys, b)
+			if ia != ib {
+				return ia - ib
+			}
+			return strings.Compare(a, b)
+		})
+		for _, key := range keys {
+			fmt.Fprintf(&b, "%s = %s\n", key, strconv.Quote(settings[key]))
+		}
+	}
+	return b.Bytes()
+}
+
+// tomlKey quotes a table name unless it is a bare key
+func tomlKey(name string) string {
+	for _, r := range name {
+		if !(r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')) {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
+// save writes the config file, readable only by the current user since it holds API keys
+func (cfg *cliConfig) save() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, cfg.encode(), 0o600)
+}
+
+func (cfg *cliConfig) profileNames() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// activeProfileName returns the profile to use: the -profile flag, $ZOTERO_PROFILE, the
+// config's default, or "default"
+func (cfg *cliConfig) activeProfileName(flagValue string) string {
+	return cmp.Or(flagValue, os.Getenv("ZOTERO_PROFILE"), cfg.Default, "default")
+}
+
+// validateProfileSetting checks a value before it is saved
+func validateProfileSetting(key, value string) error {
+	if _, ok := profileKeyHelp[key]; !ok {
+		return fmt.Errorf("unknown setting %q (expected one of %s)", key, strings.Join(profileKeys, ", "))
+	}
+	switch key {
+	case "library_type":
+		if value != "user" && value != "group" {
+			return fmt.Errorf("library_type must be user or group")
+		}
+	case "rate_limit":
+		if value != "0" {
+			if _, err := time.ParseDuration(value); err != nil {
+				return fmt.Errorf("invalid rate_limit: %w", err)
+			}
+		}
+	case "output":
+		if !slices.Contains(outputFormats, value) {
+			return fmt.Errorf("output must be one of %s", strings.Join(outputFormats, ", "))
+		}
+	case "library_id":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("library_id must be numeric")
+		}
+	}
+	return nil
+}
+
+var configCommand = &command{
+	name:    "config",
+	summary: "Manage connection profiles",
+	help:    configSettingsHelp(),
+	subcommands: []*command{
+		{
+			name:    "set",
+			usage:   "SETTING VALUE",
+			summary: "Set a setting of a profile, or the default profile with 'set default NAME'",
+			help:    configSettingsHelp(),
+			examples: []string{
+				"zotero-cli config set -profile lab library_id 777",
+				"zotero-cli config set -profile lab library_type group",
+				"zotero-cli config set default lab",
+			},
+			globals:  profileFlags,
+			complete: map[string]completer{"": completeValues(append([]string{"default"}, profileKeys...)...)},
+			setup: func(*flag.FlagSet) func(*invocation) {
+				return func(c *invocation) {
+					if len(c.args) != 2 {
+						c.usageError("a setting and a value are required")
+					}
+					cfg := loadConfigOrExit()
+					name := cfg.activeProfileName(c.global.profile)
+					key, value := c.args[0], c.args[1]
+
+					if key == "default" {
+						cfg.Default = value
+					} else {
+						if err := validateProfileSetting(key, value); err != nil {
+							fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+							os.Exit(exitUsage)
+						}
+						if cfg.Profiles[name] == nil {
+							cfg.Profiles[name] = make(map[string]string)
+						}
+						cfg.Profiles[name][key] = value
+						if cfg.Default == "" {
+							cfg.Default = name
+						}
+					}
+
+					path, err := cfg.save()
+					if err != nil {
+						fatal("saving config", err)
+					}
+					if key == "default" {
+						fmt.Printf("Default profile set to %q in %s\n", value, path)
+					} else {
+						fmt.Printf("Set %s in profile %q (%s)\n", key, name, path)
+					}
+				}
+			},
+		},
+		{
+			name:     "get",
+			usage:    "SETTING",
+			summary:  "Print a setting of a profile, or the default profile with 'get default'",
+			examples: []string{"zotero-cli config get library_id", "zotero-cli config get -profile lab base_url"},
+			globals:  profileFlags,
+			complete: map[string]completer{"": completeValues(append([]string{"default"}, profileKeys...)...)},
+			setup: func(*flag.FlagSet) func(*invocation) {
+				return func(c *invocation) {
+					if len(c.args) != 1 {
+						c.usageError("a setting is required")
+					}
+					cfg := loadConfigOrExit()
+					key := c.args[0]
+					if key == "default" {
+						fmt.Println(cfg.activeProfileName(""))
+						return
+					}
+					name := cfg.activeProfileName(c.global.profile)
+					value, ok := cfg.Profiles[name][key]
+					if !ok {
+						fmt.Fprintf(os.Stderr, "Error: %s is not set in profile %q\n", key, name)
+						os.Exit(exitNotFound)
+					}
+					fmt.Println(value)
+				}
+			},
+		},
+		{
+			name:     "list",
+			summary:  "List the profiles and their settings",
+			examples: []string{"zotero-cli config list", "zotero-cli config list -show-keys"},
+			globals:  profileFlags,
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				showKeys := fs.Bool("show-keys", false, "Show API keys in full")
+				return func(c *invocation) {
+					cfg := loadConfigOrExit()
+					if len(cfg.Profiles) == 0 {
+						path, _ := configPath()
+						fmt.Printf("No profiles configured in %s\n", path)
+						return
+					}
+					for i, profileName := range cfg.profileNames() {
+						if i > 0 {
+							fmt.Println()
+						}
+						marker := ""
+						if profileName == cfg.activeProfileName("") {
+							marker = " (active)"
+						}
+						fmt.Printf("[%s]%s\n", profileName, marker)
+
+						w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+						for _, key := range profileKeys {
+							value, ok := cfg.Profiles[profileName][key]
+							if !ok {
+								continue
+							}
+							if key == "key" && !*showKeys {
+								value = maskKey(value)
+							}
+							fmt.Fprintf(w, "  %s\t%s\n", key, value)
+						}
+						w.Flush()
+					}
+				}
+			},
+		},
+	},
+}
+
+// loadConfigOrExit reads the config file, exiting if it cannot be parsed
+func loadConfigOrExit() *cliConfig {
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal("reading config", err)
+	}
+	return cfg
+}
+
+// configSettingsHelp describes the profile settings
+func configSettingsHelp() string {
+	var b strings.Builder
+	b.WriteString("Settings:\n")
+	for _, key := range profileKeys {
+		fmt.Fprintf(&b, "  %-13s %s\n", key, profileKeyHelp[key])
+	}
+	b.WriteString("\nValues are resolved in the order: flags, environment variables, profile.")
+	return b.String()
+}
+
+// maskKey hides all but the last four characters of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// readOnlyFields are item fields the server maintains, which are not shown in the editor
+var readOnlyFields = []string{"key", "version", "dateAdded", "dateModified"}
+
+// structuralFields are item fields that are valid for every item type but are not in the
+// item type schema (and not plain string fields of ItemData)
+var structuralFields = []string{"creators", "tags", "collections", "relations", "deleted", "inPublications"}
+
+var editCommand = &command{
+	name:    "edit",
+	usage:   "KEY",
+	summary: "Edit an item as JSON in $EDITOR",
+	help: "The item is opened in $VISUAL or $EDITOR (default vi) with every field of its item type.\n" +
+		"The changes are checked against the item type's fields and creator types and shown as a\n" +
+		"diff before saving. If the item was modified elsewhere in the meantime, the editor is\n" +
+		"re-opened with your changes applied to the latest version.",
+	examples: []string{"zotero-cli edit ABC123", "EDITOR='code --wait' zotero-cli edit ABC123"},
+	complete: map[string]completer{"": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		yes := fs.Bool("yes", false, "Save the changes without asking for confirmation")
+		return func(c *invocation) {
+			if c.libraryID == "" || len(c.args) != 1 {
+				c.usageError("-library and an item key are required")
+			}
+			c.requireAPIKey()
+
+			client := c.client()
+			requireWriteAccess(client)
+			ctx := context.Background()
+
+			item, err := client.Item(ctx, c.args[0], nil)
+			if err != nil {
+				fatal("fetching item", err)
+			}
+			editItem(ctx, client, item, *yes)
+		}
+	},
+}
+
+// editItem runs the edit loop: open the editor, validate, show the changes and save them.
+// The editor is re-opened with the user's changes when validation fails or the item was
+// modified on the server in the meantime.
+func editItem(ctx context.Context, client *zotero.Client, item *zotero.Item, yes bool) {
+	schemas := &schemaCache{client: client, schemas: make(map[string]*itemSchema)}
+	original := editableFields(item.Data)
+
+	file, err := os.CreateTemp("", "zotero-"+item.Key+"-*.json")
+	if err != nil {
+		fatal("creating temporary file", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	if err := writeEditable(ctx, schemas, file.Name(), original); err != nil {
+		fatal("preparing item for editing", err)
+	}
+
+	for {
+		if err := runEditor(file.Name()); err != nil {
+			fatal("running editor", err)
+		}
+
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			fatal("reading edited item", err)
+		}
+		edited, problems := parseEditable(content, original)
+		if problems == nil {
+			problems = schemas.validate(ctx, edited, original)
+		}
+		if len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: the edited item is invalid:\n  %s\n", strings.Join(problems, "\n  "))
+			if confirm(false, "Re-open the editor?") {
+				continue
+			}
+			os.Exit(exitUsage)
+		}
+
+		changes := diffFields(original, edited)
+		if len(changes) == 0 {
+			fmt.Println("No changes")
+			return
+		}
+		fmt.Printf("Changes to item %s:\n", item.Key)
+		printChanges(changes)
+
+		if !yes {
+			switch prompt("Save changes? [y]es, [e]dit, [n]o") {
+			case "y", "yes":
+			case "e", "edit":
+				continue
+			default:
+				fmt.Fprintln(os.Stderr, "Aborted")
+				os.Exit(exitError)
+			}
+		}
+
+		err = saveEdit(ctx, client, item, original, edited)
+		var apiErr *zotero.APIError
+		switch {
+		case err == nil:
+			fmt.Printf("Updated item %s\n", item.Key)
+			return
+
+		case errors.Is(err, zotero.ErrPreconditionFailed):
+			latest, err := client.Item(ctx, item.Key, nil)
+			if err != nil {
+				fatal("fetching item", err)
+			}
+			fmt.Fprintf(os.Stderr, "Item %s was modified on the server (version %d -> %d):\n", item.Key, item.Version, latest.Version)
+			latestFields := editableFields(latest.Data)
+			printChanges(diffFields(original, latestFields))
+
+			// Re-apply the user's changes on top of the latest version
+			merged, conflicts := rebaseEdits(original, edited, latestFields)
+			if len(conflicts) > 0 {
+				fmt.Fprintf(os.Stderr, "Your changes to %s replace the server's\n", strings.Join(conflicts, ", "))
+			}
+			item, original = latest, latestFields
+			if err := writeEditable(ctx, schemas, file.Name(), merged); err != nil {
+				fatal("preparing item for editing", err)
+			}
+			if !confirm(false, "Re-open the editor with your changes applied to the latest version?") {
+				os.Exit(exitConflict)
+			}
+
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest:
+			fmt.Fprintf(os.Stderr, "Error: the server rejected the item: %s\n", apiErr.Message)
+			if !confirm(false, "Re-open the editor?") {
+				os.Exit(exitError)
+			}
+
+		default:
+			fatal("updating item", err)
+		}
+	}
+}
+
+// runEditor opens path in $VISUAL or $EDITOR (default vi). The variable may include
+// arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	editor := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"))
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// editableFields returns the item data as a JSON object without the read-only fields.
+// Numbers are kept as json.Number so that values compare equal after a round trip.
+func editableFields(data zotero.ItemData) map[string]any {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		fatal("encoding item", err)
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		fatal("encoding item", err)
+	}
+	for _, name := range readOnlyFields {
+		delete(fields, name)
+	}
+	return fields
+}
+
+// writeEditable writes the fields to path as indented JSON, with every field of the item
+// type present (empty if unset) and in schema order, so that they can be filled in
+func writeEditable(ctx context.Context, schemas *schemaCache, path string, fields map[string]any) error {
+	itemType, _ := fields["itemType"].(string)
+	schema, err := schemas.get(ctx, itemType)
+	if err != nil {
+		return err
+	}
+
+	fields = maps.Clone(fields)
+	order := []string{"itemType"}
+	for _, name := range schema.fields {
+		order = append(order, name)
+		if _, ok := fields[name]; !ok {
+			fields[name] = ""
+		}
+		// Creators follow the title, as in the Zotero item pane
+		if len(order) == 2 && len(schema.creatorTypes) > 0 {
+			order = append(order, "creators")
+		}
+	}
+	if len(schema.creatorTypes) > 0 && fields["creators"] == nil {
+		fields["creators"] = []any{}
+	}
+	if fields["tags"] == nil {
+		fields["tags"] = []any{}
+	}
+	trailing := []string{"tags", "collections", "relations"}
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !slices.Contains(order, name) && !slices.Contains(trailing, name) {
+			order = append(order, name)
+		}
+	}
+	order = append(order, trailing...)
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for _, name := range order {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteString(",")
+		}
+		buf.Write(marshalValue(name))
+		buf.WriteString(":")
+		buf.Write(marshalValue(value))
+	}
+	buf.WriteString("}")
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	return os.WriteFile(path, out.Bytes(), 0o600)
+}
+
+// marshalValue encodes a value as JSON without escaping HTML, which notes are made of
+func marshalValue(value any) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		fatal("encoding item", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// parseEditable decodes the edited JSON. Empty fields that the original item did not have
+// (the placeholders added by writeEditable) are dropped.
+func parseEditable(content []byte, original map[string]any) (map[string]any, []string) {
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, []string{"invalid JSON: " + err.Error()}
+	}
+	if fields == nil {
+		return nil, []string{"expected a JSON object"}
+	}
+	for name, value := range fields {
+		if _, ok := original[name]; !ok && isEmptyValue(value) {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// isEmptyValue reports whether a decoded JSON value is null, an empty string, array or object
+func isEmptyValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// itemSchema holds the fields and creator types of an item type
+type itemSchema struct {
+	fields       []string
+	creatorTypes []string
+}
+
+// schemaCache fetches item type schemas once per item type
+type schemaCache struct {
+	client  *zotero.Client
+	schemas map[string]*itemSchema
+}
+
+// get returns the schema of an item type. Item types without type-specific fields (notes,
+// attachments and annotations) have an empty schema.
+func (c *schemaCache) get(ctx context.Context, itemType string) (*itemSchema, error) {
+	if schema, ok := c.schemas[itemType]; ok {
+		return schema, nil
+	}
+	schema := &itemSchema{}
+	switch itemType {
+	case "note", "attachment", "annotation":
+	default:
+		fields, err := c.client.ItemTypeFields(ctx, itemType, "")
+		if err != nil {
+			return nil, fmt.Errorf("error fetching fields of item type %s: %w", itemType, err)
+		}
+		for _, field := range fields {
+			schema.fields = append(schema.fields, field.Field)
+		}
+		creatorTypes, err := c.client.ItemTypeCreatorTypes(ctx, itemType, "")
+		if err != nil {
+			return nil, fmt.Errorf("error fetching creator types of item type %s: %w", itemType, err)
+		}
+		for _, creatorType := range creatorTypes {
+			schema.creatorTypes = append(schema.creatorTypes, creatorType.CreatorType)
+		}
+	}
+	c.schemas[itemType] = schema
+	return schema, nil
+}
+
+// validate checks the edited fields against the schema of the item type and returns the
+// problems found. Fields the original item already had are accepted if the type is unchanged.
+func (c *schemaCache) validate(ctx context.Context, edited, original map[string]any) []string {
+	itemType, ok := edited["itemType"].(string)
+	if !ok || itemType == "" {
+		return []string{"itemType is required"}
+	}
+	schema, err := c.get(ctx, itemType)
+	var apiErr *zotero.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		return []string{fmt.Sprintf("%s is not a valid item type", itemType)}
+	} else if err != nil {
+		fatal("validating item", err)
+	}
+	sameType := itemType == original["itemType"]
+
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(edited)) {
+		_, inOriginal := original[name]
+		switch {
+		case slices.Contains(readOnlyFields, name):
+			problems = append(problems, fmt.Sprintf("%s is read-only", name))
+		case slices.Contains(schema.fields, name):
+			if _, ok := edited[name].(string); !ok {
+				problems = append(problems, fmt.Sprintf("%s must be a string", name))
+			}
+		case name == "itemType" || slices.Contains(structuralFields, name) || isItemDataField(name) || (sameType && inOriginal):
+		default:
+			problems = append(problems, fmt.Sprintf("%s is not a field of item type %s", name, itemType))
+		}
+	}
+
+	var data zotero.ItemData
+	if err := json.Unmarshal(marshalValue(edited), &data); err != nil {
+		return append(problems, err.Error())
+	}
+	for i, creator := range data.Creators {
+		if !slices.Contains(schema.creatorTypes, creator.CreatorType) {
+			problems = append(problems, fmt.Sprintf("creator %d: %q is not a creator type of item type %s (valid: %s)",
+				i+1, creator.CreatorType, itemType, strings.Join(schema.creatorTypes, ", ")))
+		}
+		if creator.Name == "" && creator.LastName == "" && creator.FirstName == "" {
+			problems = append(problems, fmt.Sprintf("creator %d: a name, or a lastName and firstName, is required", i+1))
+		}
+		if creator.Name != "" && (creator.LastName != "" || creator.FirstName != "") {
+			problems = append(problems, fmt.Sprintf("creator %d: use either name or lastName and firstName", i+1))
+		}
+	}
+	for i, tag := range data.Tags {
+		if strings.TrimSpace(tag.Tag) == "" {
+			problems = append(problems, fmt.Sprintf("tag %d: the tag is empty", i+1))
+		}
+	}
+	return problems
+}
+
+// fieldChange is a changed field. Old or new is nil if the field was added or removed.
+type fieldChange struct {
+	field    string
+	old, new any
+}
+
+// diffFields returns the fields that differ between two versions of an item
+func diffFields(old, new map[string]any) []fieldChange {
+	var changes []fieldChange
+	names := slices.Sorted(maps.Keys(old))
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !reflect.DeepEqual(old[name], new[name]) {
+			changes = append(changes, fieldChange{field: name, old: old[name], new: new[name]})
+		}
+	}
+	return changes
+}
+
+// printChanges prints changes as a diff of "field: value" lines
+func printChanges(changes []fieldChange) {
+	for _, change := range changes {
+		if change.old != nil {
+			fmt.Printf("- %s: %s\n", change.field, marshalValue(change.old))
+		}
+		if change.new != nil {
+			fmt.Printf("+ %s: %s\n", change.field, marshalValue(change.new))
+		}
+	}
+}
+
+// rebaseEdits applies the changes between base and edited to latest. It also returns the
+// fields that were changed both by the user and on the server, where the user's change wins.
+func rebaseEdits(base, edited, latest map[string]any) (map[string]any, []string) {
+	merged := maps.Clone(latest)
+	var conflicts []string
+	for _, change := range diffFields(base, edited) {
+		if !reflect.DeepEqual(base[change.field], latest[change.field]) &&
+			!reflect.DeepEqual(latest[change.field], change.new) {
+			conflicts = append(conflicts, change.field)
+		}
+		if change.new == nil {
+			delete(merged, change.field)
+		} else {
+			merged[change.field] = change.new
+		}
+	}
+	return merged, conflicts
+}
+
+// saveEdit writes the edited fields back, conditional on the version the item was fetched at.
+// A partial update cannot clear fields that are omitted when encoded (such as the title or
+// the tags), so edits that clear a field replace the whole item instead.
+func saveEdit(ctx context.Context, client *zotero.Client, item *zotero.Item, original, edited map[string]any) error {
+	var data zotero.ItemData
+	if err := json.Unmarshal(marshalValue(edited), &data); err != nil {
+		return fmt.Errorf("error decoding item: %w", err)
+	}
+	data.Key = item.Key
+	data.Version = item.Version
+	data.DateAdded = item.Data.DateAdded
+	data.DateModified = item.Data.DateModified
+	updated := &zotero.Item{Key: item.Key, Version: item.Version, Data: data}
+
+	for name, old := range original {
+		if value, ok := edited[name]; !isEmptyValue(old) && (!ok || isEmptyValue(value)) {
+			return client.ReplaceItem(ctx, updated)
+		}
+	}
+	return client.UpdateItem(ctx, updated)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// Exit codes. These are stable so scripts can tell failures apart.
+const (
+	exitError    = 1 // Any other error
+	exitUsage    = 2 // Invalid or missing flags or arguments
+	exitNotFound = 3 // The item, collection, search or library does not exist
+	exitAuth     = 4 // Missing or invalid API key, or insufficient permissions
+	exitConflict = 5 // The object was modified on the server (version conflict)
+	exitNetwork  = 6 // The server could not be reached or the request timed out
+)
+
+// exitCode returns the exit code for an error
+func exitCode(err error) int {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, zotero.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, zotero.ErrUnauthorized), errors.Is(err, zotero.ErrForbidden), errors.Is(err, zotero.ErrNoAPIKey):
+		return exitAuth
+	case errors.Is(err, zotero.ErrConflict), errors.Is(err, zotero.ErrPreconditionFailed):
+		return exitConflict
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return exitNetwork
+	}
+	return exitError
+}
+
+// fatal prints an error to stderr and exits with the matching exit code
+func fatal(action string, err error) {
+	fmt.Fprintf(os.Stderr, "Error %s: %v\n", action, err)
+	os.Exit(exitCode(err))
+}
+
+// writeFailedCode returns the exit code for the failures of a write request
+func writeFailedCode(failed map[string]zotero.FailedWrite) int {
+	code := exitError
+	for _, failure := range failed {
+		code = exitCode(&zotero.APIError{StatusCode: failure.Code, Message: failure.Message})
+		if code != exitError {
+			break
+		}
+	}
+	return code
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// exportCollection downloads all stored attachments of a collection and writes a manifest
+func exportCollection(libraryID, libraryType, apiKey string, verbose bool, collectionKey string, recursive bool, opts *zotero.ExportOptions, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	out.status("Exporting attachments of collection: %s\n\n", collectionKey)
+
+	opts.Progress = func(entry zotero.ExportEntry) { printExportProgress(out, entry) }
+	manifest, err := client.ExportCollectionAttachments(ctx, collectionKey, recursive, opts)
+	if err != nil {
+		fatal("exporting attachments", err)
+	}
+
+	printExportSummary(out, manifest, opts)
+}
+
+// exportSearch downloads all stored attachments of the items matching a saved search
+func exportSearch(libraryID, libraryType, apiKey string, verbose bool, searchKey string, opts *zotero.ExportOptions, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	out.status("Exporting attachments of saved search: %s\n\n", searchKey)
+
+	items, err := searchItems(ctx, client, searchKey, 0)
+	if err != nil {
+		fatal("running search", err)
+	}
+
+	opts.Progress = func(entry zotero.ExportEntry) { printExportProgress(out, entry) }
+	manifest, err := client.ExportAttachments(ctx, items, opts)
+	if err != nil {
+		fatal("exporting attachments", err)
+	}
+
+	printExportSummary(out, manifest, opts)
+}
+
+// printExportProgress prints one line per exported attachment
+func printExportProgress(out *outputFlags, entry zotero.ExportEntry) {
+	switch entry.Status {
+	case zotero.ExportFailed:
+		out.status("  failed      %s (%s): %s\n", entry.Path, entry.AttachmentKey, entry.Error)
+	default:
+		out.status("  %-11s %s\n", entry.Status, entry.Path)
+	}
+}
+
+// exportEntryPrinter prints the files of an export manifest for -o
+var exportEntryPrinter = recordPrinter[zotero.ExportEntry]{
+	fields: []string{"attachmentKey", "path", "status", "error"},
+	key:    func(entry zotero.ExportEntry) string { return entry.AttachmentKey },
+	table:  func([]zotero.ExportEntry) {},
+}
+
+// printExportSummary prints the export totals, or the manifest entries with -o, and exits
+// with an error if any download failed
+func printExportSummary(out *outputFlags, manifest *zotero.ExportManifest, opts *zotero.ExportOptions) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	out.status("\nDownloaded %d, unchanged %d, failed %d\n",
+		manifest.Count(zotero.ExportDownloaded),
+		manifest.Count(zotero.ExportUnchanged),
+		manifest.Count(zotero.ExportFailed))
+	out.status("Manifest: %s\n", filepath.Join(dir, zotero.ManifestFilename))
+	exportEntryPrinter.print(out, manifest.Files)
+
+	if manifest.Count(zotero.ExportFailed) > 0 {
+		os.Exit(exitError)
+	}
+}
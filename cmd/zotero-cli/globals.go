@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// globalFlags are the connection flags shared by every subcommand. Values are resolved with
+// the precedence flags > environment > profile.
+type globalFlags struct {
+	apiKey      string
+	libraryID   string
+	libraryType string
+	verbose     bool
+	profile     string
+	withLibrary bool
+}
+
+// clientSettings holds the connection settings that only come from the active profile
+var clientSettings struct {
+	baseURL   string
+	rateLimit *time.Duration
+	output    string
+}
+
+// globalFlagSet selects which of the shared flags a command accepts
+type globalFlagSet int
+
+const (
+	libraryFlags globalFlagSet = iota // -key, -library, -type, -v and -profile
+	keyFlags                          // -key, -v and -profile, for commands not tied to a library
+	profileFlags                      // only -profile, for commands that manage the config
+)
+
+// globalFlagNames are the names of the shared flags, which help lists separately
+var globalFlagNames = []string{"key", "library", "type", "v", "profile"}
+
+// register adds the shared flags in set to fs. Values given before the command name
+// (zotero-cli -profile lab items) are kept.
+func (g *globalFlags) register(fs *flag.FlagSet, set globalFlagSet) {
+	given := *g
+	if set != profileFlags {
+		fs.StringVar(&g.apiKey, "key", "", "Zotero API key (or set ZOTERO_API_KEY)")
+	}
+	if set == libraryFlags {
+		fs.StringVar(&g.libraryID, "library", "", "Library ID (or set ZOTERO_LIBRARY_ID)")
+		fs.StringVar(&g.libraryType, "type", "", "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
+	}
+	if set != profileFlags {
+		fs.BoolVar(&g.verbose, "v", false, "Enable verbose logging")
+	}
+	fs.StringVar(&g.profile, "profile", "", "Config profile to use (or set ZOTERO_PROFILE)")
+	*g = given
+	g.withLibrary = set == libraryFlags
+}
+
+// resolve fills settings not given as flags from the environment and then the active profile,
+// and returns the API key, library ID, library type and verbosity. When no library is
+// configured, the API key owner's user library is used.
+func (g *globalFlags) resolve() (apiKey, libraryID, libraryType string, verbose bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal("reading config", err)
+	}
+
+	name := cfg.activeProfileName(g.profile)
+	profile := cfg.Profiles[name]
+	// A profile named explicitly, by flag or environment, must exist
+	if profile == nil && (g.profile != "" || os.Getenv("ZOTERO_PROFILE") != "") {
+		fmt.Fprintf(os.Stderr, "Error: profile %q not found\n", name)
+		os.Exit(exitUsage)
+	}
+
+	g.apiKey = cmp.Or(g.apiKey, os.Getenv("ZOTERO_API_KEY"), profile["key"])
+	g.libraryID = cmp.Or(g.libraryID, os.Getenv("ZOTERO_LIBRARY_ID"), profile["library_id"])
+	g.libraryType = cmp.Or(g.libraryType, os.Getenv("ZOTERO_LIBRARY_TYPE"), profile["library_type"], "user")
+
+	clientSettings.baseURL = profile["base_url"]
+	clientSettings.output = profile["output"]
+	if value := profile["rate_limit"]; value != "" {
+		rateLimit, err := time.ParseDuration(value)
+		if value != "0" && err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid rate_limit %q in profile %q\n", value, name)
+			os.Exit(exitUsage)
+		}
+		clientSettings.rateLimit = &rateLimit
+	}
+
+	// Default to the API key owner's library when no library is configured
+	if g.withLibrary && g.libraryID == "" && g.apiKey != "" && g.libraryType == "user" {
+		g.libraryID = defaultLibraryID(g.apiKey, g.verbose)
+	}
+
+	return g.apiKey, g.libraryID, g.libraryType, g.verbose
+}
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitKeys splits a comma-separated list of keys, dropping empty entries
+func splitKeys(s string) []string {
+	var keys []string
+	for key := range strings.SplitSeq(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// confirm asks before a destructive operation unless yes is set. Anything but "y" or "yes",
+// including a closed stdin, declines.
+func confirm(yes bool, format string, args ...any) bool {
+	if yes {
+		return true
+	}
+	answer := prompt(format+" [y/N]", args...)
+	return answer == "y" || answer == "yes"
+}
+
+// stdin is shared by every prompt, so that buffered input is not lost between prompts
+var stdin = bufio.NewReader(os.Stdin)
+
+// prompt prints a question on stderr and returns the answer, trimmed and lowercased
+func prompt(format string, args ...any) string {
+	fmt.Fprintf(os.Stderr, format+" ", args...)
+	line, _ := stdin.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(line))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/oauth"
+)
+
+var loginCommand = &command{
+	name:    "login",
+	summary: "Authorize zotero-cli in the browser and save an API key",
+	help:    "Register an application at https://www.zotero.org/oauth/apps to get a client key and secret.",
+	examples: []string{
+		"zotero-cli login -consumer-key KEY -consumer-secret SECRET",
+		"zotero-cli login -profile lab -groups write -no-browser",
+	},
+	globals:  profileFlags,
+	complete: map[string]completer{"groups": completeValues("none", "read", "write")},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		consumerKey := fs.String("consumer-key", os.Getenv("ZOTERO_OAUTH_CONSUMER_KEY"), "OAuth client key of a registered application (or set ZOTERO_OAUTH_CONSUMER_KEY)")
+		consumerSecret := fs.String("consumer-secret", os.Getenv("ZOTERO_OAUTH_CONSUMER_SECRET"), "OAuth client secret (or set ZOTERO_OAUTH_CONSUMER_SECRET)")
+		name := fs.String("name", "zotero-cli", "Key description shown on zotero.org")
+		write := fs.Bool("write", true, "Request write access to your library")
+		notes := fs.Bool("notes", true, "Request access to notes")
+		groups := fs.String("groups", "read", "Access to all groups: none, read or write")
+		noBrowser := fs.Bool("no-browser", false, "Print the authorization URL instead of opening a browser")
+		timeout := fs.Duration("timeout", 5*time.Minute, "How long to wait for authorization")
+		return func(c *invocation) {
+			if *consumerKey == "" || *consumerSecret == "" {
+				c.usageError("-consumer-key and -consumer-secret are required (register an application at https://www.zotero.org/oauth/apps)")
+			}
+			groupAccess := oauth.GroupAccess(*groups)
+			if groupAccess != oauth.GroupAccessNone && groupAccess != oauth.GroupAccessRead && groupAccess != oauth.GroupAccessWrite {
+				fmt.Fprintf(os.Stderr, "Error: invalid -groups %q (expected none, read or write)\n", *groups)
+				os.Exit(exitUsage)
+			}
+
+			cfg := &oauth.Config{ConsumerKey: *consumerKey, ConsumerSecret: *consumerSecret}
+			perms := oauth.Permissions{Name: *name, Library: true, Notes: *notes, Write: *write, AllGroups: groupAccess}
+			login(cfg, perms, *noBrowser, c.global.profile, *timeout)
+		}
+	},
+}
+
+// login authorizes the CLI through Zotero's OAuth flow and saves the resulting API key
+func login(cfg *oauth.Config, perms oauth.Permissions, noBrowser bool, profile string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	creds, err := cfg.AuthorizeLoopback(ctx, perms, func(authorizeURL string) error {
+		fmt.Printf("Open this URL to authorize zotero-cli:\n\n  %s\n\nWaiting for authorization...\n", authorizeURL)
+		if !noBrowser {
+			if err := openBrowser(authorizeURL); err != nil {
+				fmt.Printf("Could not open a browser (%v); open the URL manually.\n", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fatal("logging in", err)
+	}
+
+	profileName, path, err := saveLogin(profile, creds)
+	if err != nil {
+		fatal("saving credentials", err)
+	}
+
+	fmt.Printf("\nLogged in as %s (user ID %s)\n", creds.Username, creds.UserID)
+	fmt.Printf("Credentials saved to profile %q in %s\n", profileName, path)
+}
+
+// saveLogin stores the credentials in a profile, making it the default if none is set
+func saveLogin(profileName string, creds *oauth.Credentials) (string, string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", "", err
+	}
+
+	name := cfg.activeProfileName(profileName)
+	profile := cfg.Profiles[name]
+	if profile == nil {
+		profile = make(map[string]string)
+		cfg.Profiles[name] = profile
+	}
+	profile["key"] = creds.APIKey
+	profile["library_id"] = creds.UserID
+	profile["library_type"] = "user"
+	if cfg.Default == "" {
+		cfg.Default = name
+	}
+
+	path, err := cfg.save()
+	return name, path, err
+}
+
+// openBrowser opens url in the user's default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
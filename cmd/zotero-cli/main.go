@@ -1,12 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
@@ -14,229 +16,356 @@ import (
 )
 
 func main() {
-	// Define subcommands
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
-	}
-
-	// Common flags
-	var (
-		apiKey      string
-		libraryID   string
-		libraryType string
-		verbose     bool
-	)
-
-	// Get values from environment if not provided
-	envAPIKey := os.Getenv("ZOTERO_API_KEY")
-	envLibraryID := os.Getenv("ZOTERO_LIBRARY_ID")
-	envLibraryType := os.Getenv("ZOTERO_LIBRARY_TYPE")
-	if envLibraryType == "" {
-		envLibraryType = "user"
-	}
-
-	switch os.Args[1] {
-	case "items":
-		itemsCmd := flag.NewFlagSet("items", flag.ExitOnError)
-		itemsCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		itemsCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		itemsCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		itemsCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		limit := itemsCmd.Int("limit", 25, "Number of items to retrieve")
-		start := itemsCmd.Int("start", 0, "Starting index")
-		itemType := itemsCmd.String("itemtype", "", "Filter by item type(s), comma-separated; prefix with '-' to exclude (e.g., 'journalArticle' or '-annotation')")
-		itemsCmd.Parse(os.Args[2:])
-
-		if libraryID == "" {
-			fmt.Println("Error: -library is required")
-			itemsCmd.PrintDefaults()
-			os.Exit(1)
-		}
+	root := rootCommand()
 
-		listItems(libraryID, libraryType, apiKey, verbose, *limit, *start, *itemType)
-
-	case "item":
-		itemCmd := flag.NewFlagSet("item", flag.ExitOnError)
-		itemCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		itemCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		itemCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		itemCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		itemKey := itemCmd.String("item", "", "Item key (required)")
-		itemCmd.Parse(os.Args[2:])
-
-		if libraryID == "" || *itemKey == "" {
-			fmt.Println("Error: -library and -item are required")
-			itemCmd.PrintDefaults()
-			os.Exit(1)
+	// The completion scripts pass the words of the command line, which are not parsed as flags
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		for _, candidate := range completeCommandLine(root, os.Args[2:]) {
+			fmt.Println(candidate)
 		}
+		return
+	}
 
-		getItem(libraryID, libraryType, apiKey, verbose, *itemKey)
+	// Global flags may also be given before the command name
+	g := &globalFlags{}
+	fs := flag.NewFlagSet("zotero-cli", flag.ExitOnError)
+	fs.Usage = func() { root.printHelp(fs.Output()) }
+	g.register(fs, libraryFlags)
+	fs.Parse(os.Args[1:])
 
-	case "collections":
-		collectionsCmd := flag.NewFlagSet("collections", flag.ExitOnError)
-		collectionsCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		collectionsCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		collectionsCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		collectionsCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		collectionsCmd.Parse(os.Args[2:])
+	root.execute(g, fs.Args())
+}
 
-		if libraryID == "" {
-			fmt.Println("Error: -library is required")
-			collectionsCmd.PrintDefaults()
-			os.Exit(1)
-		}
+// rootCommand returns the command tree, in the order commands are listed in the help
+func rootCommand() *command {
+	return (&command{
+		name:    "zotero-cli",
+		summary: "Zotero CLI - Interact with the Zotero Web API",
+		subcommands: []*command{
+			itemsCommand,
+			itemCommand,
+			childrenCommand,
+			trashCommand,
+			updateCommand,
+			editCommand,
+			deleteCommand,
+			tagCommand,
+			tagsCommand,
+			collectionsCommand,
+			collectionCommand,
+			createCollectionCommand,
+			groupsCommand,
+			configCommand,
+			loginCommand,
+			whoamiCommand,
+			createCommand,
+			uploadCommand,
+			attachCommand,
+			downloadCommand,
+			noteCommand,
+			searchCommand,
+			searchesCommand,
+			annotationsCommand,
+			tuiCommand,
+			completionCommand,
+			helpCommand,
+		},
+		help: `Environment Variables:
+  ZOTERO_API_KEY       API key for authentication
+  ZOTERO_LIBRARY_ID    Library ID (default for commands; defaults to the API key's user)
+  ZOTERO_LIBRARY_TYPE  Library type: user or group (default: user)
+  ZOTERO_PROFILE       Config profile (default: the config's default profile)
+  ZOTERO_CONFIG        Config file (default: ~/.config/zotero-cli/config.toml)
+  ZOTERO_OAUTH_CONSUMER_KEY, ZOTERO_OAUTH_CONSUMER_SECRET  OAuth application for login
+
+Global flags (-key, -library, -type, -profile, -v) are accepted before or after the command name.
+
+Output (items, item, children, trash, tags, collections, groups, whoami, search run, searches list, annotations):
+  -o FORMAT          table, json, jsonl, csv, tsv or keys
+  -fields LIST       Columns to show, e.g. key,title,date,DOI
+  -format TEMPLATE   Go template for each record, e.g. '{{.Key}} {{.Data.Title}}'
+
+Exit Codes:
+  1 error, 2 usage, 3 not found, 4 authentication or permission, 5 version conflict, 6 network`,
+		examples: []string{
+			"zotero-cli login -consumer-key KEY -consumer-secret SECRET",
+			"zotero-cli items -limit 10",
+			"zotero-cli -profile lab collections -o json",
+			"zotero-cli help download",
+		},
+	}).link()
+}
 
-		listCollections(libraryID, libraryType, apiKey, verbose)
+// helpCommand prints the help of the root command or of the command named by the arguments
+var helpCommand = &command{
+	name:     "help",
+	usage:    "[COMMAND [SUBCOMMAND]]",
+	summary:  "Show help for a command",
+	examples: []string{"zotero-cli help", "zotero-cli help tag add"},
+	globals:  profileFlags,
+	setup: func(*flag.FlagSet) func(*invocation) {
+		return func(c *invocation) {
+			cmd := c.cmd
+			for cmd.parent != nil {
+				cmd = cmd.parent
+			}
+			for _, name := range c.args {
+				sub := cmd.lookup(name)
+				if sub == nil {
+					fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", strings.Join(c.args, " "))
+					cmd.printHelp(os.Stderr)
+					os.Exit(exitUsage)
+				}
+				cmd = sub
+			}
+			cmd.printHelp(os.Stdout)
+		}
+	},
+}
 
-	case "groups":
-		groupsCmd := flag.NewFlagSet("groups", flag.ExitOnError)
-		groupsCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		groupsCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		userID := groupsCmd.String("user", "", "User ID (required for groups)")
-		groupsCmd.Parse(os.Args[2:])
+var itemsCommand = &command{
+	name:    "items",
+	summary: "List items in a library",
+	examples: []string{
+		"zotero-cli items -library 12345 -type user -limit 10",
+		"zotero-cli items -itemtype journalArticle,book",
+		"zotero-cli items -o csv -fields key,title,date,DOI",
+		"zotero-cli items -format '{{.Key}} {{.Data.Title}}'",
+	},
+	output: true,
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		limit := fs.Int("limit", 25, "Number of items to retrieve")
+		start := fs.Int("start", 0, "Starting index")
+		itemType := fs.String("itemtype", "", "Filter by item type(s), comma-separated; prefix with '-' to exclude (e.g., 'journalArticle' or '-annotation')")
+		return func(c *invocation) {
+			c.requireLibrary()
+			listItems(c.libraryID, c.libraryType, c.apiKey, c.verbose, *limit, *start, *itemType, c.out)
+		}
+	},
+}
 
-		if *userID == "" {
-			fmt.Println("Error: -user is required")
-			groupsCmd.PrintDefaults()
-			os.Exit(1)
+var itemCommand = &command{
+	name:     "item",
+	usage:    "-item KEY",
+	summary:  "Get a specific item",
+	examples: []string{"zotero-cli item -library 12345 -item ABC123", "zotero-cli item -item ABC123 -o json"},
+	output:   true,
+	complete: map[string]completer{"item": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKey := fs.String("item", "", "Item key (required)")
+		return func(c *invocation) {
+			if c.libraryID == "" || *itemKey == "" {
+				c.usageError("-library and -item are required")
+			}
+			getItem(c.libraryID, c.libraryType, c.apiKey, c.verbose, *itemKey, c.out)
 		}
+	},
+}
 
-		listGroups(*userID, apiKey, verbose)
-
-	case "create":
-		createCmd := flag.NewFlagSet("create", flag.ExitOnError)
-		createCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		createCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		createCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		createCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		itemType := createCmd.String("itemtype", zotero.ItemTypeJournalArticle, "Item type (e.g., book, journalArticle, webpage)")
-		title := createCmd.String("title", "", "Item title (required)")
-		authors := createCmd.String("authors", "", "Authors (comma-separated, format: 'First Last, First Last')")
-		file := createCmd.String("file", "", "Optional: Path to file to attach to the item")
-		contentType := createCmd.String("contenttype", "application/pdf", "MIME type of the file (used with -file)")
-		createCmd.Parse(os.Args[2:])
-
-		if libraryID == "" || *title == "" {
-			fmt.Println("Error: -library and -title are required")
-			createCmd.PrintDefaults()
-			os.Exit(1)
+var collectionsCommand = &command{
+	name:     "collections",
+	summary:  "List collections in a library",
+	examples: []string{"zotero-cli collections -library 12345"},
+	output:   true,
+	setup: func(*flag.FlagSet) func(*invocation) {
+		return func(c *invocation) {
+			c.requireLibrary()
+			listCollections(c.libraryID, c.libraryType, c.apiKey, c.verbose, c.out)
 		}
+	},
+}
 
-		if apiKey == "" {
-			fmt.Println("Error: API key required for write operations")
-			createCmd.PrintDefaults()
-			os.Exit(1)
+var groupsCommand = &command{
+	name:     "groups",
+	usage:    "-user ID",
+	summary:  "List groups for a user",
+	examples: []string{"zotero-cli groups -user 12345"},
+	globals:  keyFlags,
+	output:   true,
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		userID := fs.String("user", "", "User ID (required for groups)")
+		return func(c *invocation) {
+			if *userID == "" {
+				c.usageError("-user is required")
+			}
+			listGroups(*userID, c.apiKey, c.verbose, c.out)
 		}
+	},
+}
 
-		createItem(libraryID, libraryType, apiKey, verbose, *itemType, *title, *authors, *file, *contentType)
-
-	case "upload":
-		uploadCmd := flag.NewFlagSet("upload", flag.ExitOnError)
-		uploadCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		uploadCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		uploadCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		uploadCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		file := uploadCmd.String("file", "", "Path to file to upload (required)")
-		parentItem := uploadCmd.String("parent", "", "Parent item key (empty for standalone attachment)")
-		contentType := uploadCmd.String("contenttype", "application/pdf", "MIME type of the file")
-		uploadCmd.Parse(os.Args[2:])
-
-		if libraryID == "" || *file == "" {
-			fmt.Println("Error: -library and -file are required")
-			uploadCmd.PrintDefaults()
-			os.Exit(1)
+var createCommand = &command{
+	name:    "create",
+	usage:   "-title TITLE",
+	summary: "Create a new item",
+	examples: []string{
+		"zotero-cli create -title 'My Paper' -authors 'John Doe, Jane Smith'",
+		"zotero-cli create -title 'Research Article' -file paper.pdf",
+		"zotero-cli create -title 'My Paper' -o keys",
+	},
+	output:   true,
+	complete: map[string]completer{"file": completeFiles},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemType := fs.String("itemtype", zotero.ItemTypeJournalArticle, "Item type (e.g., book, journalArticle, webpage)")
+		title := fs.String("title", "", "Item title (required)")
+		authors := fs.String("authors", "", "Authors (comma-separated, format: 'First Last, First Last')")
+		file := fs.String("file", "", "Optional: Path to file to attach to the item")
+		contentType := fs.String("contenttype", "application/pdf", "MIME type of the file (used with -file)")
+		return func(c *invocation) {
+			if c.libraryID == "" || *title == "" {
+				c.usageError("-library and -title are required")
+			}
+			c.requireAPIKey()
+			createItem(c.libraryID, c.libraryType, c.apiKey, c.verbose, *itemType, *title, *authors, *file, *contentType, c.out)
 		}
+	},
+}
 
-		if apiKey == "" {
-			fmt.Println("Error: API key required for write operations")
-			uploadCmd.PrintDefaults()
-			os.Exit(1)
+var uploadCommand = &command{
+	name:     "upload",
+	usage:    "-file PATH",
+	summary:  "Upload a file attachment",
+	examples: []string{"zotero-cli upload -file paper.pdf -parent ABC123"},
+	output:   true,
+	complete: map[string]completer{"file": completeFiles, "parent": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		file := fs.String("file", "", "Path to file to upload (required)")
+		parentItem := fs.String("parent", "", "Parent item key (empty for standalone attachment)")
+		contentType := fs.String("contenttype", "application/pdf", "MIME type of the file")
+		return func(c *invocation) {
+			if c.libraryID == "" || *file == "" {
+				c.usageError("-library and -file are required")
+			}
+			c.requireAPIKey()
+			uploadFile(c.libraryID, c.libraryType, c.apiKey, c.verbose, *file, *parentItem, *contentType, c.out)
 		}
+	},
+}
 
-		uploadFile(libraryID, libraryType, apiKey, verbose, *file, *parentItem, *contentType)
-
-	case "download":
-		downloadCmd := flag.NewFlagSet("download", flag.ExitOnError)
-		downloadCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		downloadCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		downloadCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		downloadCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		itemKey := downloadCmd.String("item", "", "Item key of the attachment (required)")
-		filename := downloadCmd.String("filename", "", "Output filename (empty to auto-detect from item)")
-		path := downloadCmd.String("path", "", "Output directory (empty for current directory)")
-		downloadCmd.Parse(os.Args[2:])
-
-		if libraryID == "" || *itemKey == "" {
-			fmt.Println("Error: -library and -item are required")
-			downloadCmd.PrintDefaults()
-			os.Exit(1)
+var attachCommand = &command{
+	name:    "attach",
+	usage:   "(-url URL | -link PATH)",
+	summary: "Attach a linked URL, linked file or web snapshot",
+	examples: []string{
+		"zotero-cli attach -parent ABC123 -url https://example.com/article",
+		"zotero-cli attach -parent ABC123 -link /data/scans/paper.pdf -contenttype application/pdf",
+	},
+	output:   true,
+	complete: map[string]completer{"parent": completeItems, "link": completeFiles, "file": completeFiles},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		parentItem := fs.String("parent", "", "Parent item key (empty for standalone attachment)")
+		url := fs.String("url", "", "URL to link (with -file, uploads the file as a snapshot of the URL)")
+		link := fs.String("link", "", "Path to a local file to link without uploading")
+		file := fs.String("file", "", "Saved snapshot to upload for -url")
+		title := fs.String("title", "", "Attachment title (defaults to the URL or filename)")
+		contentType := fs.String("contenttype", "", "MIME type of the linked file or snapshot")
+		return func(c *invocation) {
+			if c.libraryID == "" || (*url == "") == (*link == "") {
+				c.usageError("-library and exactly one of -url or -link are required")
+			}
+			if *file != "" && *url == "" {
+				c.usageError("-file requires -url")
+			}
+			c.requireAPIKey()
+			attach(c.libraryID, c.libraryType, c.apiKey, c.verbose, *parentItem, *url, *link, *file, *title, *contentType, c.out)
 		}
+	},
+}
+
+var downloadCommand = &command{
+	name:    "download",
+	usage:   "(-item KEY | -collection KEY | -search KEY)",
+	summary: "Download a file attachment, or all attachments of a collection or saved search",
+	examples: []string{
+		"zotero-cli download -item ABC123 -path ./downloads",
+		"zotero-cli download -collection ABC123 -recursive -dir out/",
+		"zotero-cli download -search SRCH123 -dir out/",
+		"zotero-cli download -item ABC123 -path ./papers -template \"{creator}-{year}-{title}.{ext}\" -conflict suffix",
+		"zotero-cli download -collection ABC123 -dir out/ -o csv",
+	},
+	output: true,
+	complete: map[string]completer{
+		"item":       completeItems,
+		"collection": completeCollections,
+		"search":     completeSearches,
+		"path":       completeFiles,
+		"dir":        completeFiles,
+		"conflict":   completeValues("overwrite", "skip", "suffix", "skip-if-md5-matches"),
+	},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKey := fs.String("item", "", "Item key of the attachment")
+		collectionKey := fs.String("collection", "", "Download all stored attachments of a collection")
+		searchKey := fs.String("search", "", "Download all stored attachments of items matching a saved search")
+		recursive := fs.Bool("recursive", false, "Include subcollections (with -collection)")
+		workers := fs.Int("workers", zotero.DefaultExportWorkers, "Concurrent downloads (with -collection or -search)")
+		filename := fs.String("filename", "", "Output filename (empty to auto-detect from item)")
+		var path string
+		fs.StringVar(&path, "path", "", "Output directory (empty for current directory)")
+		fs.StringVar(&path, "dir", "", "Output directory (alias for -path)")
+		template := fs.String("template", "", "Filename template, e.g. {creator}-{year}-{title}.{ext}")
+		conflict := fs.String("conflict", "overwrite", "If the file exists: overwrite, skip, suffix or skip-if-md5-matches")
+		return func(c *invocation) {
+			sources := 0
+			for _, key := range []string{*itemKey, *collectionKey, *searchKey} {
+				if key != "" {
+					sources++
+				}
+			}
+			if c.libraryID == "" || sources != 1 {
+				c.usageError("-library and one of -item, -collection or -search are required")
+			}
+
+			if *searchKey != "" {
+				exportSearch(c.libraryID, c.libraryType, c.apiKey, c.verbose, *searchKey, &zotero.ExportOptions{
+					Dir:      path,
+					Template: *template,
+					Workers:  *workers,
+				}, c.out)
+				return
+			}
 
-		downloadFile(libraryID, libraryType, apiKey, verbose, *itemKey, *filename, *path)
-
-	case "create-collection":
-		createCollCmd := flag.NewFlagSet("create-collection", flag.ExitOnError)
-		createCollCmd.StringVar(&apiKey, "key", envAPIKey, "Zotero API key (or set ZOTERO_API_KEY)")
-		createCollCmd.StringVar(&libraryID, "library", envLibraryID, "Library ID (or set ZOTERO_LIBRARY_ID)")
-		createCollCmd.StringVar(&libraryType, "type", envLibraryType, "Library type: user or group (or set ZOTERO_LIBRARY_TYPE)")
-		createCollCmd.BoolVar(&verbose, "v", false, "Enable verbose logging")
-		name := createCollCmd.String("name", "", "Collection name (required)")
-		parent := createCollCmd.String("parent", "", "Parent collection key (empty for top-level collection)")
-		createCollCmd.Parse(os.Args[2:])
-
-		if libraryID == "" || *name == "" {
-			fmt.Println("Error: -library and -name are required")
-			createCollCmd.PrintDefaults()
-			os.Exit(1)
+			if *collectionKey != "" {
+				exportCollection(c.libraryID, c.libraryType, c.apiKey, c.verbose, *collectionKey, *recursive, &zotero.ExportOptions{
+					Dir:      path,
+					Template: *template,
+					Workers:  *workers,
+				}, c.out)
+				return
+			}
+
+			downloadFile(c.libraryID, c.libraryType, c.apiKey, c.verbose, *itemKey, &zotero.DumpOptions{
+				Dir:      path,
+				Filename: *filename,
+				Template: *template,
+				Conflict: zotero.ConflictPolicy(*conflict),
+			}, c.out)
 		}
+	},
+}
 
-		if apiKey == "" {
-			fmt.Println("Error: API key required for write operations")
-			createCollCmd.PrintDefaults()
-			os.Exit(1)
+var createCollectionCommand = &command{
+	name:    "create-collection",
+	usage:   "-name NAME",
+	summary: "Create a new collection",
+	examples: []string{
+		"zotero-cli create-collection -name 'My Research'",
+		"zotero-cli create-collection -name 'Subproject' -parent ABC123",
+	},
+	output:   true,
+	complete: map[string]completer{"parent": completeCollections},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		name := fs.String("name", "", "Collection name (required)")
+		parent := fs.String("parent", "", "Parent collection key (empty for top-level collection)")
+		return func(c *invocation) {
+			if c.libraryID == "" || *name == "" {
+				c.usageError("-library and -name are required")
+			}
+			c.requireAPIKey()
+			createCollection(c.libraryID, c.libraryType, c.apiKey, c.verbose, *name, *parent, c.out)
 		}
+	},
+}
 
-		createCollection(libraryID, libraryType, apiKey, verbose, *name, *parent)
-
-	default:
-		fmt.Printf("Unknown command: %s\n\n", os.Args[1])
-		printUsage()
-		os.Exit(1)
-	}
-}
-
-func printUsage() {
-	fmt.Println("Zotero CLI - Interact with the Zotero Web API")
-	fmt.Println("\nUsage:")
-	fmt.Println("  zotero-cli <command> [options]")
-	fmt.Println("\nCommands:")
-	fmt.Println("  items              List items in a library")
-	fmt.Println("  item               Get a specific item")
-	fmt.Println("  collections        List collections in a library")
-	fmt.Println("  create-collection  Create a new collection")
-	fmt.Println("  groups             List groups for a user")
-	fmt.Println("  create             Create a new item")
-	fmt.Println("  upload             Upload a file attachment")
-	fmt.Println("  download           Download a file attachment")
-	fmt.Println("\nEnvironment Variables:")
-	fmt.Println("  ZOTERO_API_KEY       API key for authentication")
-	fmt.Println("  ZOTERO_LIBRARY_ID    Library ID (default for commands)")
-	fmt.Println("  ZOTERO_LIBRARY_TYPE  Library type: user or group (default: user)")
-	fmt.Println("\nExamples:")
-	fmt.Println("  zotero-cli items -library 12345 -type user -limit 10")
-	fmt.Println("  zotero-cli item -library 12345 -item ABC123")
-	fmt.Println("  zotero-cli collections -library 12345")
-	fmt.Println("  zotero-cli create-collection -name 'My Research'")
-	fmt.Println("  zotero-cli create-collection -name 'Subproject' -parent ABC123")
-	fmt.Println("  zotero-cli groups -user 12345")
-	fmt.Println("  zotero-cli create -title 'My Paper' -authors 'John Doe, Jane Smith'")
-	fmt.Println("  zotero-cli create -title 'Research Article' -file paper.pdf")
-	fmt.Println("  zotero-cli upload -file paper.pdf -parent ABC123")
-	fmt.Println("  zotero-cli download -item ABC123 -path ./downloads")
-}
-
-func listItems(libraryID, libraryType, apiKey string, verbose bool, limit, start int, itemType string) {
+func listItems(libraryID, libraryType, apiKey string, verbose bool, limit, start int, itemType string, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
 	ctx := context.Background()
 
@@ -256,53 +385,48 @@ func listItems(libraryID, libraryType, apiKey string, verbose bool, limit, start
 
 	items, err := client.Items(ctx, params)
 	if err != nil {
-		fmt.Printf("Error fetching items: %v\n", err)
-		os.Exit(1)
+		fatal("fetching items", err)
 	}
 
-	fmt.Printf("Retrieved %d items:\n\n", len(items))
-	printItemsTable(items)
+	itemPrinter.print(out, items)
 }
 
-func getItem(libraryID, libraryType, apiKey string, verbose bool, itemKey string) {
+func getItem(libraryID, libraryType, apiKey string, verbose bool, itemKey string, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
 	ctx := context.Background()
 
 	item, err := client.Item(ctx, itemKey, nil)
 	if err != nil {
-		fmt.Printf("Error fetching item: %v\n", err)
-		os.Exit(1)
+		fatal("fetching item", err)
 	}
 
-	printItemDetails(item)
+	printer := itemPrinter
+	printer.table = func(items []zotero.Item) { printItemDetails(&items[0]) }
+	printer.print(out, []zotero.Item{*item})
 }
 
-func listCollections(libraryID, libraryType, apiKey string, verbose bool) {
+func listCollections(libraryID, libraryType, apiKey string, verbose bool, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
 	ctx := context.Background()
 
 	collections, err := client.Collections(ctx, nil)
 	if err != nil {
-		fmt.Printf("Error fetching collections: %v\n", err)
-		os.Exit(1)
+		fatal("fetching collections", err)
 	}
 
-	fmt.Printf("Retrieved %d collections:\n\n", len(collections))
-	printCollectionsTable(collections)
+	collectionPrinter.print(out, collections)
 }
 
-func listGroups(userID, apiKey string, verbose bool) {
+func listGroups(userID, apiKey string, verbose bool, out *outputFlags) {
 	client := createClient(userID, string(zotero.LibraryTypeUser), apiKey, verbose)
 	ctx := context.Background()
 
 	groups, err := client.Groups(ctx, nil)
 	if err != nil {
-		fmt.Printf("Error fetching groups: %v\n", err)
-		os.Exit(1)
+		fatal("fetching groups", err)
 	}
 
-	fmt.Printf("Retrieved %d groups:\n\n", len(groups))
-	printGroupsTable(groups)
+	groupPrinter.print(out, groups)
 }
 
 func createClient(libraryID, libraryType, apiKey string, verbose bool) *zotero.Client {
@@ -315,6 +439,12 @@ func createClient(libraryID, libraryType, apiKey string, verbose bool) *zotero.C
 	if apiKey != "" {
 		opts = append(opts, zotero.WithAPIKey(apiKey))
 	}
+	if clientSettings.baseURL != "" {
+		opts = append(opts, zotero.WithBaseURL(clientSettings.baseURL))
+	}
+	if clientSettings.rateLimit != nil {
+		opts = append(opts, zotero.WithRateLimit(*clientSettings.rateLimit))
+	}
 
 	if verbose {
 		logger := log.New(os.Stderr, "[zotero] ", log.LstdFlags)
@@ -327,12 +457,22 @@ func createClient(libraryID, libraryType, apiKey string, verbose bool) *zotero.C
 func printJSON(v any) {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		fmt.Printf("Error formatting JSON: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error formatting JSON: %v\n", err)
 		return
 	}
 	fmt.Println(string(data))
 }
 
+// itemPrinter prints items in the format selected by -o
+var itemPrinter = recordPrinter[zotero.Item]{
+	fields: []string{"key", "itemType", "title", "creators", "date", "dateAdded"},
+	key:    func(item zotero.Item) string { return item.Key },
+	table: func(items []zotero.Item) {
+		fmt.Printf("Retrieved %d items:\n\n", len(items))
+		printItemsTable(items)
+	},
+}
+
 // printItemsTable displays items in a formatted table
 func printItemsTable(items []zotero.Item) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -343,7 +483,7 @@ func printItemsTable(items []zotero.Item) {
 		key := item.Key
 		itemType := item.Data.ItemType
 		title := truncate(item.Data.Title, 40)
-		creators := formatCreators(item.Data.Creators)
+		creators := cmp.Or(truncate(item.Data.CreatorSummary(), 30), "-")
 		date := item.Data.DateAdded
 		if len(date) > 10 {
 			date = date[:10] // Show only date part (YYYY-MM-DD)
@@ -407,6 +547,16 @@ func printItemDetails(item *zotero.Item) {
 	fmt.Printf("Version:  %d\n", item.Version)
 }
 
+// collectionPrinter prints collections in the format selected by -o
+var collectionPrinter = recordPrinter[zotero.Collection]{
+	fields: []string{"key", "name", "parentCollection", "numItems"},
+	key:    func(coll zotero.Collection) string { return coll.Key },
+	table: func(collections []zotero.Collection) {
+		fmt.Printf("Retrieved %d collections:\n\n", len(collections))
+		printCollectionsTable(collections)
+	},
+}
+
 // printCollectionsTable displays collections in a formatted table
 func printCollectionsTable(collections []zotero.Collection) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -429,6 +579,16 @@ func printCollectionsTable(collections []zotero.Collection) {
 	w.Flush()
 }
 
+// groupPrinter prints groups in the format selected by -o
+var groupPrinter = recordPrinter[zotero.Group]{
+	fields: []string{"id", "name", "type", "numItems"},
+	key:    func(group zotero.Group) string { return strconv.Itoa(group.ID) },
+	table: func(groups []zotero.Group) {
+		fmt.Printf("Retrieved %d groups:\n\n", len(groups))
+		printGroupsTable(groups)
+	},
+}
+
 // printGroupsTable displays groups in a formatted table
 func printGroupsTable(groups []zotero.Group) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -447,33 +607,6 @@ func printGroupsTable(groups []zotero.Group) {
 	w.Flush()
 }
 
-// formatCreators formats a list of creators for display
-func formatCreators(creators []zotero.Creator) string {
-	if len(creators) == 0 {
-		return "-"
-	}
-
-	names := make([]string, 0, len(creators))
-	for i, creator := range creators {
-		if i >= 2 {
-			names = append(names, fmt.Sprintf("(+%d more)", len(creators)-2))
-			break
-		}
-
-		name := creator.Name
-		if name == "" {
-			if creator.LastName != "" {
-				name = creator.LastName
-			} else {
-				name = creator.FirstName
-			}
-		}
-		names = append(names, name)
-	}
-
-	return truncate(strings.Join(names, ", "), 30)
-}
-
 // truncate truncates a string to a maximum length with ellipsis
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -485,9 +618,10 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
-// createItem creates a new item in the library
-func createItem(libraryID, libraryType, apiKey string, verbose bool, itemType, title, authors, file, contentType string) {
+// createItem creates a new item in the library, and uploads a file attachment to it if given
+func createItem(libraryID, libraryType, apiKey string, verbose bool, itemType, title, authors, file, contentType string, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
 	ctx := context.Background()
 
 	// Parse authors
@@ -523,8 +657,7 @@ func createItem(libraryID, libraryType, apiKey string, verbose bool, itemType, t
 
 	resp, err := client.CreateItems(ctx, []zotero.Item{item})
 	if err != nil {
-		fmt.Printf("Error creating item: %v\n", err)
-		os.Exit(1)
+		fatal("creating item", err)
 	}
 
 	var itemKey string
@@ -532,79 +665,121 @@ func createItem(libraryID, libraryType, apiKey string, verbose bool, itemType, t
 		for idx, key := range resp.Success {
 			if keyStr, ok := key.(string); ok {
 				itemKey = keyStr
-				fmt.Printf("Successfully created item with key: %s (index: %s)\n", keyStr, idx)
+				out.status("Successfully created item with key: %s (index: %s)\n", keyStr, idx)
 			}
 		}
 	}
 
 	if len(resp.Failed) > 0 {
-		fmt.Println("\nFailed items:")
+		fmt.Fprintln(os.Stderr, "\nFailed items:")
 		for idx, failure := range resp.Failed {
-			fmt.Printf("  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
+			fmt.Fprintf(os.Stderr, "  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
 		}
-		os.Exit(1)
+		os.Exit(writeFailedCode(resp.Failed))
+	}
+
+	var records []zotero.Item
+	if !out.human() {
+		created, err := client.Item(ctx, itemKey, nil)
+		if err != nil {
+			fatal("fetching created item", err)
+		}
+		records = append(records, *created)
 	}
 
 	// Upload file attachment if specified
 	if file != "" && itemKey != "" {
-		fmt.Printf("\nUploading attachment: %s\n", file)
+		out.status("\nUploading attachment: %s\n", file)
 		attachment, err := client.UploadAttachment(ctx, itemKey, file, "", contentType)
 		if err != nil {
-			fmt.Printf("Error uploading attachment: %v\n", err)
-			fmt.Println("Note: Item was created successfully, but attachment upload failed")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error uploading attachment: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Note: Item was created successfully, but attachment upload failed")
+			os.Exit(exitCode(err))
 		}
-		fmt.Printf("Successfully attached file!\n")
-		fmt.Printf("Attachment Key: %s\n", attachment.Key)
-		fmt.Printf("Filename: %s\n", attachment.Data.Filename)
+		out.status("Successfully attached file!\n")
+		out.status("Attachment Key: %s\n", attachment.Key)
+		out.status("Filename: %s\n", attachment.Data.Filename)
+		records = append(records, *attachment)
+	}
+
+	if !out.human() {
+		itemPrinter.print(out, records)
 	}
 }
 
 // uploadFile uploads a file as an attachment
-func uploadFile(libraryID, libraryType, apiKey string, verbose bool, filepath, parentItem, contentType string) {
+func uploadFile(libraryID, libraryType, apiKey string, verbose bool, filepath, parentItem, contentType string, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
 	ctx := context.Background()
 
-	fmt.Printf("Uploading file: %s\n", filepath)
+	out.status("Uploading file: %s\n", filepath)
 	if parentItem != "" {
-		fmt.Printf("Parent item: %s\n", parentItem)
+		out.status("Parent item: %s\n", parentItem)
 	} else {
-		fmt.Println("Creating standalone attachment")
+		out.status("Creating standalone attachment\n")
 	}
 
 	item, err := client.UploadAttachment(ctx, parentItem, filepath, "", contentType)
 	if err != nil {
-		fmt.Printf("Error uploading file: %v\n", err)
-		os.Exit(1)
+		fatal("uploading file", err)
 	}
 
-	fmt.Printf("\nSuccessfully uploaded attachment!\n")
-	fmt.Printf("Key: %s\n", item.Key)
-	fmt.Printf("Title: %s\n", item.Data.Title)
-	fmt.Printf("Content Type: %s\n", item.Data.ContentType)
-	fmt.Printf("Filename: %s\n", item.Data.Filename)
+	printCreatedItem(out, item, func() {
+		fmt.Printf("\nSuccessfully uploaded attachment!\n")
+		fmt.Printf("Key: %s\n", item.Key)
+		fmt.Printf("Title: %s\n", item.Data.Title)
+		fmt.Printf("Content Type: %s\n", item.Data.ContentType)
+		fmt.Printf("Filename: %s\n", item.Data.Filename)
+	})
+}
+
+// printCreatedItem prints an item created by a command, with the confirmation printed by
+// message for human-readable output
+func printCreatedItem(out *outputFlags, item *zotero.Item, message func()) {
+	printer := itemPrinter
+	printer.table = func([]zotero.Item) { message() }
+	printer.print(out, []zotero.Item{*item})
+}
+
+// downloadRecord describes a downloaded attachment file for -o
+type downloadRecord struct {
+	Key     string `json:"key"`
+	Path    string `json:"path"`
+	Skipped bool   `json:"skipped"`
 }
 
 // downloadFile downloads a file attachment from the library
-func downloadFile(libraryID, libraryType, apiKey string, verbose bool, itemKey, filename, path string) {
+func downloadFile(libraryID, libraryType, apiKey string, verbose bool, itemKey string, opts *zotero.DumpOptions, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
 	ctx := context.Background()
 
-	fmt.Printf("Downloading attachment: %s\n", itemKey)
+	out.status("Downloading attachment: %s\n", itemKey)
 
-	fullPath, err := client.Dump(ctx, itemKey, filename, path)
+	result, err := client.DumpWithOptions(ctx, itemKey, opts)
 	if err != nil {
-		fmt.Printf("Error downloading file: %v\n", err)
-		os.Exit(1)
+		fatal("downloading file", err)
 	}
 
-	fmt.Printf("\nSuccessfully downloaded attachment!\n")
-	fmt.Printf("Saved to: %s\n", fullPath)
+	printer := recordPrinter[downloadRecord]{
+		fields: []string{"key", "path", "skipped"},
+		key:    func(r downloadRecord) string { return r.Key },
+		table: func([]downloadRecord) {
+			if result.Skipped {
+				fmt.Printf("\nSkipped: %s already exists\n", result.Path)
+				return
+			}
+			fmt.Printf("\nSuccessfully downloaded attachment!\n")
+			fmt.Printf("Saved to: %s\n", result.Path)
+		},
+	}
+	printer.print(out, []downloadRecord{{Key: result.Item.Key, Path: result.Path, Skipped: result.Skipped}})
 }
 
 // createCollection creates a new collection in the library
-func createCollection(libraryID, libraryType, apiKey string, verbose bool, name, parent string) {
+func createCollection(libraryID, libraryType, apiKey string, verbose bool, name, parent string, out *outputFlags) {
 	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
 	ctx := context.Background()
 
 	collection := zotero.Collection{
@@ -616,29 +791,41 @@ func createCollection(libraryID, libraryType, apiKey string, verbose bool, name,
 
 	resp, err := client.CreateCollections(ctx, []zotero.Collection{collection})
 	if err != nil {
-		fmt.Printf("Error creating collection: %v\n", err)
-		os.Exit(1)
+		fatal("creating collection", err)
 	}
 
-	if len(resp.Success) > 0 {
-		for idx, key := range resp.Success {
-			if keyStr, ok := key.(string); ok {
-				fmt.Printf("Successfully created collection '%s'\n", name)
-				fmt.Printf("Key: %s (index: %s)\n", keyStr, idx)
-				if parent != "" {
-					fmt.Printf("Parent: %s\n", parent)
-				} else {
-					fmt.Println("Type: Top-level collection")
-				}
-			}
+	if len(resp.Failed) > 0 {
+		fmt.Fprintln(os.Stderr, "Failed to create collection:")
+		for idx, failure := range resp.Failed {
+			fmt.Fprintf(os.Stderr, "  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
 		}
+		os.Exit(writeFailedCode(resp.Failed))
 	}
 
-	if len(resp.Failed) > 0 {
-		fmt.Println("\nFailed to create collection:")
-		for idx, failure := range resp.Failed {
-			fmt.Printf("  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
+	var records []zotero.Collection
+	for idx, key := range resp.Success {
+		keyStr, ok := key.(string)
+		if !ok {
+			continue
+		}
+		if out.human() {
+			fmt.Printf("Successfully created collection '%s'\n", name)
+			fmt.Printf("Key: %s (index: %s)\n", keyStr, idx)
+			if parent != "" {
+				fmt.Printf("Parent: %s\n", parent)
+			} else {
+				fmt.Println("Type: Top-level collection")
+			}
+			continue
+		}
+		created, err := client.Collection(ctx, keyStr, nil)
+		if err != nil {
+			fatal("fetching created collection", err)
 		}
-		os.Exit(1)
+		records = append(records, *created)
+	}
+
+	if !out.human() {
+		collectionPrinter.print(out, records)
 	}
 }
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var noteCommand = &command{
+	name:    "note",
+	summary: "Add or export notes",
+	subcommands: []*command{
+		{
+			name:    "add",
+			usage:   "-file PATH",
+			summary: "Add a note from a Markdown or HTML file",
+			examples: []string{
+				"zotero-cli note add -parent ABC123 -file note.md",
+				"echo '# Summary' | zotero-cli note add -parent ABC123 -file - -format markdown",
+				"zotero-cli note add -parent ABC123 -file note.md -o keys",
+			},
+			output: true,
+			complete: map[string]completer{
+				"parent": completeItems,
+				"file":   completeFiles,
+				"format": completeValues("auto", "markdown", "html"),
+			},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				parent := fs.String("parent", "", "Parent item key (empty for standalone note)")
+				file := fs.String("file", "", "Path to note file, or '-' for stdin (required)")
+				format := fs.String("format", "auto", "Input format: auto, markdown or html (auto uses the file extension)")
+				return func(c *invocation) {
+					if c.libraryID == "" || *file == "" {
+						c.usageError("-library and -file are required")
+					}
+					c.requireAPIKey()
+					addNote(c.libraryID, c.libraryType, c.apiKey, c.verbose, *parent, *file, *format, c.out)
+				}
+			},
+		},
+		{
+			name:    "export",
+			usage:   "(-parent KEY | -note KEY)",
+			summary: "Export notes as Markdown or HTML",
+			examples: []string{
+				"zotero-cli note export -parent ABC123 -out notes.md",
+				"zotero-cli note export -note NOTE123 -format html",
+			},
+			complete: map[string]completer{
+				"parent": completeItems,
+				"out":    completeFiles,
+				"format": completeValues("markdown", "html"),
+			},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				parent := fs.String("parent", "", "Export all child notes of this item")
+				noteKey := fs.String("note", "", "Export a single note by key")
+				format := fs.String("format", "markdown", "Output format: markdown or html")
+				out := fs.String("out", "", "Output file (empty for stdout)")
+				return func(c *invocation) {
+					if c.libraryID == "" || (*parent == "" && *noteKey == "") {
+						c.usageError("-library and one of -parent or -note are required")
+					}
+					exportNotes(c.libraryID, c.libraryType, c.apiKey, c.verbose, *parent, *noteKey, *format, *out)
+				}
+			},
+		},
+	},
+}
+
+// addNote creates a note from a Markdown or HTML file
+func addNote(libraryID, libraryType, apiKey string, verbose bool, parent, file, format string, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		fatal("reading note file", err)
+	}
+
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".html", ".htm":
+			format = "html"
+		default:
+			format = "markdown"
+		}
+	}
+
+	var noteHTML string
+	switch format {
+	case "markdown", "md":
+		noteHTML = zotero.MarkdownToNoteHTML(string(content))
+	case "html":
+		noteHTML = zotero.SanitizeNoteHTML(string(content))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (use markdown or html)\n", format)
+		os.Exit(exitUsage)
+	}
+
+	note, err := client.CreateNote(ctx, parent, noteHTML)
+	if err != nil {
+		fatal("creating note", err)
+	}
+
+	item := note.Item()
+	printCreatedItem(out, &item, func() {
+		fmt.Printf("Successfully created note!\n")
+		fmt.Printf("Key: %s\n", note.Key)
+		if note.ParentItem != "" {
+			fmt.Printf("Parent: %s\n", note.ParentItem)
+		}
+		fmt.Printf("Title: %s\n", note.Title())
+	})
+}
+
+// exportNotes writes one note, or all child notes of an item, as Markdown or HTML
+func exportNotes(libraryID, libraryType, apiKey string, verbose bool, parent, noteKey, format, out string) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	var notes []zotero.Note
+	if noteKey != "" {
+		item, err := client.Item(ctx, noteKey, nil)
+		if err != nil {
+			fatal("fetching note", err)
+		}
+		note, err := zotero.NoteFromItem(item)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		notes = append(notes, *note)
+	} else {
+		var err error
+		notes, err = client.Notes(ctx, parent, nil)
+		if err != nil {
+			fatal("fetching notes", err)
+		}
+	}
+
+	parts := make([]string, 0, len(notes))
+	for _, note := range notes {
+		switch format {
+		case "markdown", "md":
+			parts = append(parts, note.Markdown())
+		case "html":
+			parts = append(parts, note.HTML)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q (use markdown or html)\n", format)
+			os.Exit(exitUsage)
+		}
+	}
+
+	separator := "\n\n---\n\n"
+	if format == "html" {
+		separator = "\n\n"
+	}
+	output := strings.Join(parts, separator) + "\n"
+
+	if out == "" {
+		fmt.Print(output)
+		return
+	}
+
+	if err := os.WriteFile(out, []byte(output), 0o644); err != nil {
+		fatal("writing notes", err)
+	}
+	fmt.Printf("Exported %d notes to %s\n", len(notes), out)
+}
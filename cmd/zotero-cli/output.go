@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// outputFormats are the values accepted by -o
+var outputFormats = []string{"table", "json", "jsonl", "csv", "tsv", "keys"}
+
+// outputFlags select how commands that list records print them
+type outputFlags struct {
+	format   string   // -o
+	fields   string   // -fields
+	template string   // -format
+	names    []string // Flags registered by addOutputFlags
+}
+
+// addOutputFlags registers -o, -fields and -format on fs. A flag the command already defines
+// keeps its meaning (e.g. note add's input -format), and its output flag is left out.
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{}
+	add := func(p *string, name, usage string) {
+		if fs.Lookup(name) == nil {
+			fs.StringVar(p, name, "", usage)
+			o.names = append(o.names, name)
+		}
+	}
+	add(&o.format, "o", "Output format: "+strings.Join(outputFormats, ", ")+" (default: the profile's output setting or table)")
+	add(&o.fields, "fields", "Comma-separated fields for table, csv and tsv output, e.g. key,title,date,DOI")
+	add(&o.template, "format", "Go template applied to each record, e.g. '{{.Key}} {{.Data.Title}}'")
+	return o
+}
+
+// resolve applies the profile's default output format and checks the flags. Call it after
+// globalFlags.resolve.
+func (o *outputFlags) resolve() {
+	o.format = cmp.Or(o.format, clientSettings.output, "table")
+	if !slices.Contains(outputFormats, o.format) {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (expected one of %s)\n", o.format, strings.Join(outputFormats, ", "))
+		os.Exit(exitUsage)
+	}
+	if o.template != "" {
+		if _, err := o.parseTemplate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing -format: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+func (o *outputFlags) parseTemplate() (*template.Template, error) {
+	return template.New("format").Funcs(template.FuncMap{
+		"field": func(record any, name string) string {
+			return formatValue(lookupField(recordMap(record), name))
+		},
+		"join": strings.Join,
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(o.template)
+}
+
+// fieldList returns the -fields columns, or defaults if none were given
+func (o *outputFlags) fieldList(defaults []string) []string {
+	if o.fields == "" {
+		return defaults
+	}
+	var fields []string
+	for field := range strings.SplitSeq(o.fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// human reports whether the default human-readable output is selected. Commands that
+// change the library then print messages rather than records.
+func (o *outputFlags) human() bool {
+	return o.format == "table" && o.fields == "" && o.template == ""
+}
+
+// status prints a progress or confirmation message: to stdout for human-readable output,
+// and to stderr otherwise so that stdout only holds records
+func (o *outputFlags) status(format string, args ...any) {
+	w := os.Stdout
+	if !o.human() {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// recordPrinter describes how to print one kind of record
+type recordPrinter[T any] struct {
+	fields []string       // Default columns for csv and tsv
+	key    func(T) string // Identifier printed by -o keys
+	table  func([]T)      // Human-readable output when -o is table and -fields is not set
+}
+
+// print writes records in the selected output format. Fields are looked up by their JSON
+// name in the record, then in its data and meta objects, so both API fields ("title",
+// "DOI") and paths ("meta.numChildren") work.
+func (p recordPrinter[T]) print(o *outputFlags, records []T) {
+	if records == nil {
+		records = []T{}
+	}
+
+	if o.template != "" {
+		tmpl, err := o.parseTemplate()
+		if err != nil {
+			fatal("parsing -format", err)
+		}
+		for _, record := range records {
+			var b bytes.Buffer
+			if err := tmpl.Execute(&b, record); err != nil {
+				fatal("formatting output", err)
+			}
+			fmt.Println(strings.TrimSuffix(b.String(), "\n"))
+		}
+		return
+	}
+
+	switch o.format {
+	case "json":
+		printJSON(records)
+
+	case "jsonl":
+		for _, record := range records {
+			data, err := json.Marshal(record)
+			if err != nil {
+				fatal("formatting JSON", err)
+			}
+			fmt.Println(string(data))
+		}
+
+	case "keys":
+		for _, record := range records {
+			fmt.Println(p.key(record))
+		}
+
+	case "csv", "tsv":
+		w := csv.NewWriter(os.Stdout)
+		if o.format == "tsv" {
+			w.Comma = '\t'
+		}
+		fields := o.fieldList(p.fields)
+		w.Write(fields)
+		for _, record := range records {
+			w.Write(recordRow(record, fields))
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fatal("writing output", err)
+		}
+
+	default:
+		if o.fields == "" {
+			p.table(records)
+			return
+		}
+		fields := o.fieldList(nil)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		headers := make([]string, len(fields))
+		for i, field := range fields {
+			headers[i] = strings.ToUpper(field)
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		for _, record := range records {
+			fmt.Fprintln(w, strings.Join(recordRow(record, fields), "\t"))
+		}
+		w.Flush()
+	}
+}
+
+// recordRow returns the values of fields in a record
+func recordRow(record any, fields []string) []string {
+	m := recordMap(record)
+	row := make([]string, len(fields))
+	for i, field := range fields {
+		row[i] = formatValue(lookupField(m, field))
+	}
+	return row
+}
+
+// recordMap converts a record to its JSON object form, which includes the extra fields of
+// item data
+func recordMap(record any) map[string]any {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]any
+	decoder.Decode(&m)
+	return m
+}
+
+// lookupField finds a dotted field path in a record, falling back to its data and meta
+// objects. Names are matched case-insensitively.
+func lookupField(m map[string]any, name string) any {
+	if v, ok := lookupPath(m, name); ok {
+		return v
+	}
+	for _, nested := range []string{"data", "meta"} {
+		if sub, ok := m[nested].(map[string]any); ok {
+			if v, ok := lookupPath(sub, name); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func lookupPath(m map[string]any, path string) (any, bool) {
+	var current any = m
+	for part := range strings.SplitSeq(path, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok := obj[part]
+		if !ok {
+			for k, v := range obj {
+				if strings.EqualFold(k, part) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+		current = value
+	}
+	return current, true
+}
+
+// formatValue formats a field value for a table or CSV cell. Lists are joined with "; ",
+// and tags and creators are shown by name.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, elem := range v {
+			parts[i] = formatValue(elem)
+		}
+		return strings.Join(parts, "; ")
+	case map[string]any:
+		if tag, ok := v["tag"].(string); ok {
+			return tag
+		}
+		if name, ok := v["name"].(string); ok && name != "" {
+			return name
+		}
+		if last, ok := v["lastName"].(string); ok {
+			first, _ := v["firstName"].(string)
+			return strings.TrimSpace(first + " " + last)
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var searchCommand = &command{
+	name:    "search",
+	summary: "Run a saved search",
+	subcommands: []*command{
+		{
+			name:     "run",
+			usage:    "KEY",
+			summary:  "List the items matching a saved search",
+			examples: []string{"zotero-cli search run SRCH123 -limit 20"},
+			output:   true,
+			complete: map[string]completer{"": completeSearches},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				limit := fs.Int("limit", 0, "Maximum number of items (0 for all)")
+				return func(c *invocation) {
+					if c.libraryID == "" || len(c.args) != 1 {
+						c.usageError("-library and a search key are required")
+					}
+					runSearch(c.libraryID, c.libraryType, c.apiKey, c.verbose, c.args[0], *limit, c.out)
+				}
+			},
+		},
+	},
+}
+
+// runSearch prints the items matching a saved search
+func runSearch(libraryID, libraryType, apiKey string, verbose bool, searchKey string, limit int, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	items, err := searchItems(ctx, client, searchKey, limit)
+	if err != nil {
+		fatal("running search", err)
+	}
+
+	itemPrinter.print(out, items)
+}
+
+// searchItems fetches up to limit items matching a saved search, or all of them if limit is 0
+func searchItems(ctx context.Context, client *zotero.Client, searchKey string, limit int) ([]zotero.Item, error) {
+	if limit > 0 {
+		return client.SearchItems(ctx, searchKey, &zotero.QueryParams{Limit: limit})
+	}
+	return client.SearchItemsAll(ctx, searchKey, nil)
+}
+
+var searchesCommand = &command{
+	name:    "searches",
+	summary: "List, create or delete saved searches",
+	subcommands: []*command{
+		{
+			name:     "list",
+			summary:  "List the saved searches of the library",
+			examples: []string{"zotero-cli searches list"},
+			output:   true,
+			setup: func(*flag.FlagSet) func(*invocation) {
+				return func(c *invocation) {
+					c.requireLibrary()
+					listSearches(c.libraryID, c.libraryType, c.apiKey, c.verbose, c.out)
+				}
+			},
+		},
+		{
+			name:    "create",
+			usage:   "-name NAME -where 'condition operator value' [-where ...]",
+			summary: "Create a saved search",
+			examples: []string{
+				"zotero-cli searches create -name 'To read' -where 'tag is to-read'",
+				"zotero-cli searches create -name 'Recent' -where 'dateAdded isInTheLast 7 days' -where 'itemType isNot note'",
+			},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				name := fs.String("name", "", "Search name (required)")
+				var conditions stringList
+				fs.Var(&conditions, "where", "Condition as 'condition operator value', e.g. 'tag is to-read' (repeatable)")
+				matchAny := fs.Bool("any", false, "Match any condition instead of all")
+				return func(c *invocation) {
+					if c.libraryID == "" || *name == "" || len(conditions) == 0 {
+						c.usageError("-library, -name and at least one -where are required")
+					}
+					c.requireAPIKey()
+					createSearch(c.libraryID, c.libraryType, c.apiKey, c.verbose, *name, conditions, *matchAny)
+				}
+			},
+		},
+		{
+			name:     "delete",
+			usage:    "KEY",
+			summary:  "Delete a saved search",
+			examples: []string{"zotero-cli searches delete SRCH123"},
+			complete: map[string]completer{"": completeSearches},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				version := fs.Int("version", 0, "Only delete if the search is still at this version")
+				yes := fs.Bool("yes", false, "Do not ask for confirmation")
+				return func(c *invocation) {
+					if c.libraryID == "" || len(c.args) != 1 {
+						c.usageError("-library and a search key are required")
+					}
+					c.requireAPIKey()
+					deleteSearch(c.libraryID, c.libraryType, c.apiKey, c.verbose, c.args[0], *version, *yes)
+				}
+			},
+		},
+	},
+}
+
+// searchPrinter prints saved searches in the format selected by -o
+var searchPrinter = recordPrinter[zotero.Search]{
+	fields: []string{"key", "name", "version"},
+	key:    func(search zotero.Search) string { return search.Key },
+	table:  printSearchesTable,
+}
+
+// listSearches prints the saved searches of the library
+func listSearches(libraryID, libraryType, apiKey string, verbose bool, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	searches, err := client.SearchesAll(ctx, nil)
+	if err != nil {
+		fatal("fetching searches", err)
+	}
+
+	searchPrinter.print(out, searches)
+}
+
+// printSearchesTable displays saved searches and their conditions in a formatted table
+func printSearchesTable(searches []zotero.Search) {
+	fmt.Printf("Retrieved %d searches:\n\n", len(searches))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tNAME\tCONDITIONS")
+	fmt.Fprintln(w, "---\t----\t----------")
+
+	for _, search := range searches {
+		conditions := make([]string, len(search.Data.Conditions))
+		for i, c := range search.Data.Conditions {
+			conditions[i] = strings.TrimSpace(fmt.Sprintf("%s %s %s", c.Condition, c.Operator, c.Value))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", search.Key, truncate(search.Data.Name, 30), truncate(strings.Join(conditions, "; "), 60))
+	}
+	w.Flush()
+}
+
+// createSearch creates a saved search from conditions written as "condition operator value"
+func createSearch(libraryID, libraryType, apiKey string, verbose bool, name string, conditions []string, matchAny bool) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	builder := zotero.NewSearch(name)
+	if matchAny {
+		builder.MatchAny()
+	}
+	for _, condition := range conditions {
+		fields := strings.Fields(condition)
+		if len(fields) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: invalid -where %q (expected 'condition operator value')\n", condition)
+			os.Exit(exitUsage)
+		}
+		condition, op := zotero.Condition(fields[0]), zotero.Operator(fields[1])
+		if !zotero.ValidOperator(condition, op) {
+			fmt.Fprintf(os.Stderr, "Error: operator %q is not valid for condition %q\n", op, condition)
+			os.Exit(exitUsage)
+		}
+		builder.Where(condition, op, strings.Join(fields[2:], " "))
+	}
+	data, err := builder.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	resp, err := client.CreateSearches(ctx, []zotero.Search{{Data: data}})
+	if err != nil {
+		fatal("creating search", err)
+	}
+	for _, key := range resp.Success {
+		if keyStr, ok := key.(string); ok {
+			fmt.Printf("Successfully created search '%s'\n", name)
+			fmt.Printf("Key: %s\n", keyStr)
+		}
+	}
+	if len(resp.Failed) > 0 {
+		fmt.Println("\nFailed to create search:")
+		for idx, failure := range resp.Failed {
+			fmt.Printf("  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
+		}
+		os.Exit(writeFailedCode(resp.Failed))
+	}
+}
+
+// deleteSearch deletes a saved search after confirmation. Matching items are not affected.
+func deleteSearch(libraryID, libraryType, apiKey string, verbose bool, searchKey string, version int, yes bool) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	search, err := client.Search(ctx, searchKey, nil)
+	if err != nil {
+		fatal("fetching search", err)
+	}
+	checkVersion("search", search.Key, search.Version, version)
+
+	if !confirm(yes, "Delete saved search '%s'?", search.Data.Name) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(exitError)
+	}
+	if err := client.DeleteSearch(ctx, search.Key, search.Version); err != nil {
+		fatal("deleting search", err)
+	}
+	fmt.Printf("Deleted saved search '%s'\n", search.Data.Name)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var tagCommand = &command{
+	name:    "tag",
+	summary: "Add or remove tags",
+	subcommands: []*command{
+		{
+			name:     "add",
+			usage:    "-item KEY[,KEY...] TAG [TAG...]",
+			summary:  "Add tags to items",
+			examples: []string{"zotero-cli tag add -item ABC123 to-read important"},
+			complete: map[string]completer{"item": completeItems, "": completeTags},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				itemKeys := fs.String("item", "", "Item key(s), comma-separated (required)")
+				return func(c *invocation) {
+					if c.libraryID == "" || len(c.args) == 0 || *itemKeys == "" {
+						c.usageError("-library, -item and at least one tag are required")
+					}
+					c.requireAPIKey()
+					client := c.client()
+					requireWriteAccess(client)
+					tagItems(client, splitKeys(*itemKeys), c.args, true)
+				}
+			},
+		},
+		{
+			name:    "remove",
+			usage:   "[-item KEY[,KEY...]] TAG [TAG...]",
+			summary: "Remove tags from items, or from every item in the library",
+			help:    "Without -item, the tags are removed from every item in the library, after confirmation.",
+			examples: []string{
+				"zotero-cli tag remove -item ABC123 to-read",
+				"zotero-cli tag remove -yes obsolete-tag",
+			},
+			complete: map[string]completer{"item": completeItems, "": completeTags},
+			setup: func(fs *flag.FlagSet) func(*invocation) {
+				itemKeys := fs.String("item", "", "Item key(s), comma-separated")
+				yes := fs.Bool("yes", false, "Do not ask for confirmation (without -item)")
+				return func(c *invocation) {
+					if c.libraryID == "" || len(c.args) == 0 {
+						c.usageError("-library and at least one tag are required")
+					}
+					c.requireAPIKey()
+					client := c.client()
+					requireWriteAccess(client)
+					if *itemKeys != "" {
+						tagItems(client, splitKeys(*itemKeys), c.args, false)
+					} else {
+						deleteLibraryTags(client, c.args, *yes)
+					}
+				}
+			},
+		},
+	},
+}
+
+var tagsCommand = &command{
+	name:     "tags",
+	summary:  "List tags of the library, a collection or an item",
+	examples: []string{"zotero-cli tags", "zotero-cli tags -collection COLL123 -o keys"},
+	output:   true,
+	complete: map[string]completer{"item": completeItems, "collection": completeCollections},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKey := fs.String("item", "", "List the tags of this item")
+		collectionKey := fs.String("collection", "", "List the tags of items in this collection")
+		limit := fs.Int("limit", 100, "Number of tags to retrieve")
+		start := fs.Int("start", 0, "Starting index")
+		return func(c *invocation) {
+			if c.libraryID == "" || (*itemKey != "" && *collectionKey != "") {
+				c.usageError("-library is required, with at most one of -item or -collection")
+			}
+			listTags(c.libraryID, c.libraryType, c.apiKey, c.verbose, *itemKey, *collectionKey, *limit, *start, c.out)
+		}
+	},
+}
+
+// tagItems adds or removes tags on each item. Each update is conditional on the version
+// of the item it was fetched at.
+func tagItems(client *zotero.Client, itemKeys, tags []string, add bool) {
+	ctx := context.Background()
+	for _, key := range itemKeys {
+		var err error
+		if add {
+			err = client.AddTags(ctx, key, tags...)
+		} else {
+			err = client.RemoveTags(ctx, key, tags...)
+		}
+		if err != nil {
+			fatal("tagging item "+key, err)
+		}
+	}
+
+	verb := "Added"
+	if !add {
+		verb = "Removed"
+	}
+	fmt.Printf("%s %s on %d item(s)\n", verb, strings.Join(tags, ", "), len(itemKeys))
+}
+
+// deleteLibraryTags removes tags from every item in the library
+func deleteLibraryTags(client *zotero.Client, tags []string, yes bool) {
+	ctx := context.Background()
+	if !confirm(yes, "Remove %s from every item in the library?", strings.Join(tags, ", ")) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(exitError)
+	}
+
+	for chunk := range slices.Chunk(tags, 50) {
+		version, err := client.LastModifiedVersion(ctx)
+		if err != nil {
+			fatal("fetching library version", err)
+		}
+		if err := client.DeleteTags(ctx, version, chunk...); err != nil {
+			fatal("deleting tags", err)
+		}
+	}
+	fmt.Printf("Deleted %d tag(s) from the library\n", len(tags))
+}
+
+// tagPrinter prints library tags in the format selected by -o
+var tagPrinter = recordPrinter[zotero.TagsResponse]{
+	fields: []string{"tag", "type", "numItems"},
+	key:    func(tag zotero.TagsResponse) string { return tag.Tag },
+	table:  printTagsTable,
+}
+
+// listTags prints the tags of the library, a collection or an item
+func listTags(libraryID, libraryType, apiKey string, verbose bool, itemKey, collectionKey string, limit, start int, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+	params := &zotero.QueryParams{Limit: limit, Start: start}
+
+	var tags []zotero.TagsResponse
+	var err error
+	switch {
+	case itemKey != "":
+		var itemTags []zotero.Tag
+		itemTags, err = client.ItemTags(ctx, itemKey, params)
+		for _, tag := range itemTags {
+			tags = append(tags, zotero.TagsResponse{Tag: tag.Tag, Type: tag.Type})
+		}
+	case collectionKey != "":
+		tags, err = client.CollectionTags(ctx, collectionKey, params)
+	default:
+		tags, err = client.Tags(ctx, params)
+	}
+	if err != nil {
+		fatal("fetching tags", err)
+	}
+
+	tagPrinter.print(out, tags)
+}
+
+// printTagsTable displays tags in a formatted table
+func printTagsTable(tags []zotero.TagsResponse) {
+	fmt.Printf("Retrieved %d tags:\n\n", len(tags))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tTYPE\tITEMS")
+	fmt.Fprintln(w, "---\t----\t-----")
+
+	for _, tag := range tags {
+		tagType := "manual"
+		if tag.Type == 1 {
+			tagType = "automatic"
+		}
+		items := "-"
+		if n := max(tag.NumItems, tag.Meta.NumItems); n > 0 {
+			items = fmt.Sprintf("%d", n)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", truncate(tag.Tag, 40), tagType, items)
+	}
+	w.Flush()
+}
@@ -0,0 +1,956 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// tuiPageSize is the number of items the terminal UI fetches per request
+const tuiPageSize = 50
+
+// trashView is the view of the trash. The other views are "" for the whole library and
+// collection keys.
+const trashView = "trash"
+
+// tuiKeys lists the keys of the terminal UI, for its help screen and the command's help
+var tuiKeys = [][2]string{
+	{"Tab, Shift-Tab", "Switch between the collections, items and details panes"},
+	{"↑ ↓, j k", "Move the selection"},
+	{"PgUp PgDn, Home End", "Move by a page, or to the first or last entry"},
+	{"Enter, → l", "Expand a collection, or move to the next pane"},
+	{"← h", "Collapse a collection, or move to the previous pane"},
+	{"Space", "Expand or collapse a collection"},
+	{"/, Q", "Quick search the items (Enter keeps the search, Esc clears it)"},
+	{"o", "Open the selected attachment, or the item's first attachment"},
+	{"d", "Download the attachment"},
+	{"t", "Add a tag to the item"},
+	{"c", "Copy the item's citation key (or its item key)"},
+	{"r", "Reload everything from the server"},
+	{"?", "Show or hide the keys"},
+	{"q, Ctrl-C", "Quit"},
+}
+
+var tuiCommand = &command{
+	name:    "tui",
+	summary: "Browse the library in a full-screen terminal interface",
+	help:    tuiHelp(),
+	examples: []string{
+		"zotero-cli tui",
+		"zotero-cli -profile lab tui -dir ~/papers",
+		"zotero-cli tui -qmode everything",
+	},
+	complete: map[string]completer{"dir": completeFiles, "qmode": completeValues("titleCreatorYear", "everything")},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		dir := fs.String("dir", "", "Directory for downloaded attachments (empty for the current directory)")
+		qmode := fs.String("qmode", "titleCreatorYear", "Quick search mode: titleCreatorYear or everything")
+		noCache := fs.Bool("no-cache", false, "Neither read nor write the local cache")
+		return func(c *invocation) {
+			c.requireLibrary()
+			if *qmode != "titleCreatorYear" && *qmode != "everything" {
+				c.usageError("-qmode must be titleCreatorYear or everything")
+			}
+			runTUI(c, *dir, *qmode, *noCache)
+		}
+	},
+}
+
+// tuiHelp returns the help text of the tui command
+func tuiHelp() string {
+	var b strings.Builder
+	b.WriteString("Keys:\n")
+	for _, k := range tuiKeys {
+		fmt.Fprintf(&b, "  %-20s %s\n", k[0], k[1])
+	}
+	b.WriteString("\nCollections, item lists and children are cached in the user cache directory and reused\n")
+	b.WriteString("until the library changes on the server. Opened attachments are downloaded to the cache.")
+	return b.String()
+}
+
+// tuiPane is one of the three panes of the terminal UI
+type tuiPane int
+
+const (
+	paneCollections tuiPane = iota
+	paneItems
+	paneDetails
+)
+
+// treeNode is a row of the collection tree
+type treeNode struct {
+	view   string
+	name   string
+	depth  int
+	parent bool // the collection has subcollections
+}
+
+// listKey identifies an item list: a view, optionally narrowed by a quick search
+type listKey struct {
+	view, query string
+}
+
+// itemList is the items of a view, fetched a page at a time
+type itemList struct {
+	items   []zotero.Item
+	done    bool // every item has been fetched
+	loading bool
+	err     error
+}
+
+// childList is the attachments, notes and annotations of an item
+type childList struct {
+	items   []zotero.Item
+	loading bool
+	err     error
+}
+
+// tuiPrompt is a line of text being entered in the status line
+type tuiPrompt struct {
+	label, value string
+	submit       func(value string)
+}
+
+// tui is the state of the terminal UI. It is only accessed from the event loop; background
+// requests hand their results to the loop as functions on events.
+type tui struct {
+	ctx         context.Context
+	client      *zotero.Client
+	apiKey      string
+	libraryName string
+	downloadDir string
+	qmode       string
+	cachePath   string // empty if the cache is disabled
+	filesDir    string // where opened attachments are downloaded
+
+	screen        *screen
+	width, height int
+	events        chan func()
+	quit          bool
+
+	version            int // library version of the cached data, 0 if unknown
+	collections        []zotero.Collection
+	collectionNames    map[string]string
+	collectionsLoading bool
+	expanded           map[string]bool
+	tree               []treeNode
+	treeCursor         int
+	treeTop            int
+	treeGen            int
+
+	view       string
+	query      string
+	searching  bool
+	queryGen   int
+	lists      map[listKey]*itemList
+	itemCursor int
+	itemTop    int
+	itemGen    int
+
+	children    map[string]*childList
+	childCursor int
+	detailTop   int
+	detailLen   int // lines of details at the last draw
+
+	focus       tuiPane
+	prompt      *tuiPrompt
+	showHelp    bool
+	status      string
+	statusError bool
+}
+
+// runTUI runs the terminal UI until the user quits
+func runTUI(c *invocation, downloadDir, qmode string, noCache bool) {
+	t := &tui{
+		ctx: context.Background(),
+		// Verbose logging would write over the screen
+		client:      createClient(c.libraryID, c.libraryType, c.apiKey, false),
+		apiKey:      c.apiKey,
+		libraryName: "My Library",
+		downloadDir: downloadDir,
+		qmode:       qmode,
+		events:      make(chan func(), 16),
+		expanded:    make(map[string]bool),
+		lists:       make(map[listKey]*itemList),
+		children:    make(map[string]*childList),
+	}
+	if c.libraryType == "group" {
+		t.libraryName = "Group " + c.libraryID
+	}
+
+	library := c.libraryType + "-" + c.libraryID
+	dir, err := tuiCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "zotero-cli-tui")
+	}
+	t.filesDir = filepath.Join(dir, "files", library)
+	if !noCache {
+		t.cachePath = filepath.Join(dir, library+".json")
+		t.loadCache()
+	}
+
+	t.screen, err = openScreen()
+	if err != nil {
+		fatal("starting the terminal interface", err)
+	}
+	t.run()
+	t.screen.close()
+
+	if err := t.saveCache(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save the cache: %v\n", err)
+	}
+}
+
+// tuiCacheDir returns the directory of the terminal UI's caches
+func tuiCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "zotero-cli", "tui"), nil
+}
+
+// run is the event loop: it handles key presses, results of background requests and
+// terminal resizes, redrawing the screen after each
+func (t *tui) run() {
+	keys := make(chan key)
+	go readKeys(t.screen.in, keys)
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	t.width, t.height = t.screen.size()
+	if t.collections == nil {
+		t.loadCollections()
+	}
+	t.buildTree()
+	t.loadMore()
+	t.loadChildren()
+	t.checkVersion()
+
+	t.draw()
+	for !t.quit {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return
+			}
+			t.handleKey(k)
+		case f := <-t.events:
+			f()
+		case <-resize.C:
+			width, height := t.screen.size()
+			if width == t.width && height == t.height {
+				continue
+			}
+			t.width, t.height = width, height
+		}
+		t.draw()
+	}
+}
+
+// async runs fetch in the background and passes its result to done on the event loop
+func async[T any](t *tui, fetch func(context.Context) (T, error), done func(T, error)) {
+	go func() {
+		result, err := fetch(t.ctx)
+		t.events <- func() { done(result, err) }
+	}()
+}
+
+// debounce runs f on the event loop after d, unless debounce is called again with the same
+// counter in the meantime
+func (t *tui) debounce(counter *int, d time.Duration, f func()) {
+	*counter++
+	gen := *counter
+	time.AfterFunc(d, func() {
+		t.events <- func() {
+			if *counter == gen {
+				f()
+			}
+		}
+	})
+}
+
+// setError shows an error in the status line
+func (t *tui) setError(action string, err error) {
+	t.status = fmt.Sprintf("Error %s: %v", action, err)
+	t.statusError = true
+}
+
+// setStatus shows a message in the status line
+func (t *tui) setStatus(format string, args ...any) {
+	t.status = fmt.Sprintf(format, args...)
+	t.statusError = false
+}
+
+// checkVersion fetches the library version, and reloads everything if the cached data is
+// from another version
+func (t *tui) checkVersion() {
+	async(t, t.client.LastModifiedVersion, func(version int, err error) {
+		if err != nil {
+			t.setError("fetching the library version", err)
+			return
+		}
+		if t.version != 0 && version != t.version {
+			t.reload()
+			t.setStatus("The library has changed; reloading")
+		}
+		t.version = version
+	})
+}
+
+// reload discards the fetched collections, items and children and fetches them again
+func (t *tui) reload() {
+	t.collections = nil
+	clear(t.lists)
+	clear(t.children)
+	t.loadCollections()
+	t.buildTree()
+	t.loadMore()
+	t.loadChildren()
+}
+
+// loadCollections fetches every collection of the library
+func (t *tui) loadCollections() {
+	t.collectionsLoading = true
+	async(t, func(ctx context.Context) ([]zotero.Collection, error) {
+		return fetchPages(ctx, 0, t.client.Collections)
+	}, func(collections []zotero.Collection, err error) {
+		t.collectionsLoading = false
+		if err != nil {
+			t.setError("fetching collections", err)
+			return
+		}
+		t.collections = collections
+		t.buildTree()
+	})
+}
+
+// buildTree lays out the collection tree, with the subcollections of expanded collections,
+// keeping the cursor on the same view
+func (t *tui) buildTree() {
+	cursorView := t.view
+	if t.treeCursor < len(t.tree) {
+		cursorView = t.tree[t.treeCursor].view
+	}
+
+	byParent := make(map[string][]zotero.Collection)
+	t.collectionNames = make(map[string]string)
+	for _, coll := range t.collections {
+		parent := string(coll.Data.ParentCollection)
+		byParent[parent] = append(byParent[parent], coll)
+		t.collectionNames[coll.Key] = coll.Data.Name
+	}
+
+	t.tree = []treeNode{{view: "", name: t.libraryName}}
+	var add func(parent string, depth int)
+	add = func(parent string, depth int) {
+		colls := byParent[parent]
+		slices.SortFunc(colls, func(a, b zotero.Collection) int {
+			return cmp.Compare(strings.ToLower(a.Data.Name), strings.ToLower(b.Data.Name))
+		})
+		for _, coll := range colls {
+			t.tree = append(t.tree, treeNode{view: coll.Key, name: coll.Data.Name, depth: depth, parent: len(byParent[coll.Key]) > 0})
+			if t.expanded[coll.Key] {
+				add(coll.Key, depth+1)
+			}
+		}
+	}
+	add("", 1)
+	t.tree = append(t.tree, treeNode{view: trashView, name: "Trash"})
+
+	t.treeCursor = max(slices.IndexFunc(t.tree, func(node treeNode) bool { return node.view == cursorView }), 0)
+}
+
+// viewName returns the name of a view for display
+func (t *tui) viewName(view string) string {
+	switch view {
+	case "":
+		return t.libraryName
+	case trashView:
+		return "Trash"
+	}
+	return cmp.Or(t.collectionNames[view], view)
+}
+
+// list returns the item list shown in the items pane
+func (t *tui) list() *itemList {
+	key := listKey{view: t.view, query: t.query}
+	list := t.lists[key]
+	if list == nil {
+		list = &itemList{}
+		t.lists[key] = list
+	}
+	return list
+}
+
+// selectedItem returns the item under the cursor, or nil
+func (t *tui) selectedItem() *zotero.Item {
+	list := t.list()
+	if t.itemCursor < len(list.items) {
+		return &list.items[t.itemCursor]
+	}
+	return nil
+}
+
+// loadMore fetches the next page of the item list once the cursor nears the end of the
+// items fetched so far
+func (t *tui) loadMore() {
+	list := t.list()
+	if list.loading || list.done || list.err != nil || t.itemCursor < len(list.items)-10 {
+		return
+	}
+
+	view := t.view
+	params := &zotero.QueryParams{
+		Limit:     tuiPageSize,
+		Start:     len(list.items),
+		Sort:      "dateModified",
+		Direction: "desc",
+		Q:         t.query,
+	}
+	if t.query != "" {
+		params.QMode = t.qmode
+	}
+	list.loading = true
+	async(t, func(ctx context.Context) ([]zotero.Item, error) {
+		switch view {
+		case "":
+			return t.client.Top(ctx, params)
+		case trashView:
+			return t.client.Trash(ctx, params)
+		}
+		return t.client.CollectionItemsTop(ctx, view, params)
+	}, func(items []zotero.Item, err error) {
+		list.loading = false
+		if err != nil {
+			list.err = err
+			t.setError("fetching items", err)
+			return
+		}
+		list.items = append(list.items, items...)
+		list.done = len(items) < tuiPageSize
+		t.loadChildren()
+	})
+}
+
+// loadChildren fetches the children of the selected item, if it has any that are not cached
+func (t *tui) loadChildren() {
+	item := t.selectedItem()
+	if item == nil || item.Meta.NumChildren == 0 || t.children[item.Key] != nil {
+		return
+	}
+
+	itemKey := item.Key
+	children := &childList{loading: true}
+	t.children[itemKey] = children
+	async(t, func(ctx context.Context) ([]zotero.Item, error) {
+		return fetchPages(ctx, 0, func(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+			return t.client.Children(ctx, itemKey, params)
+		})
+	}, func(items []zotero.Item, err error) {
+		children.loading = false
+		children.items, children.err = items, err
+	})
+}
+
+// selectView shows the items of a view
+func (t *tui) selectView(view string) {
+	if view == t.view {
+		return
+	}
+	t.view = view
+	t.itemCursor, t.itemTop = 0, 0
+	t.childCursor, t.detailTop = 0, 0
+	t.loadMore()
+	t.loadChildren()
+}
+
+// setQuery changes the quick search, fetching the matching items once typing pauses
+func (t *tui) setQuery(query string) {
+	if query == t.query {
+		return
+	}
+	t.query = query
+	t.itemCursor, t.itemTop = 0, 0
+	t.childCursor, t.detailTop = 0, 0
+	t.debounce(&t.queryGen, 250*time.Millisecond, func() {
+		t.loadMore()
+		t.loadChildren()
+	})
+}
+
+// replaceItem replaces every copy of an item in the item lists
+func (t *tui) replaceItem(item zotero.Item) {
+	for _, list := range t.lists {
+		for i := range list.items {
+			if list.items[i].Key == item.Key {
+				list.items[i] = item
+			}
+		}
+	}
+}
+
+// handleKey handles a key press
+func (t *tui) handleKey(k key) {
+	switch {
+	case k.code == keyCtrlC:
+		t.quit = true
+		return
+	case t.showHelp:
+		t.showHelp = false
+		return
+	case t.prompt != nil:
+		t.promptKey(k)
+		return
+	case t.searching && t.searchKey(k):
+		return
+	}
+
+	if k.code == keyRune {
+		switch k.r {
+		case 'q':
+			t.quit = true
+		case '?':
+			t.showHelp = true
+		case '/', 'Q':
+			t.searching = true
+			t.focus = paneItems
+		case 'o':
+			t.openAttachment()
+		case 'd':
+			t.downloadAttachment()
+		case 't':
+			t.addTag()
+		case 'c':
+			t.copyCitationKey()
+		case 'r':
+			t.reload()
+			t.version = 0
+			t.checkVersion()
+			t.setStatus("Reloading")
+		case ' ':
+			if t.focus == paneCollections {
+				t.toggleCollection(!t.expanded[t.tree[t.treeCursor].view])
+			}
+		}
+		// Vi-style movement
+		if code, ok := map[rune]keyCode{'k': keyUp, 'j': keyDown, 'h': keyLeft, 'l': keyRight, 'g': keyHome, 'G': keyEnd}[k.r]; ok {
+			t.navigate(code)
+		}
+		return
+	}
+
+	switch k.code {
+	case keyTab:
+		t.focus = (t.focus + 1) % 3
+	case keyBackTab:
+		t.focus = (t.focus + 2) % 3
+	case keyEscape:
+		t.setQuery("")
+	default:
+		t.navigate(k.code)
+	}
+}
+
+// promptKey edits the line being entered in the status line
+func (t *tui) promptKey(k key) {
+	p := t.prompt
+	switch k.code {
+	case keyRune:
+		p.value += string(k.r)
+	case keyBackspace:
+		p.value = trimLastRune(p.value)
+	case keyCtrlU:
+		p.value = ""
+	case keyEscape:
+		t.prompt = nil
+	case keyEnter:
+		t.prompt = nil
+		p.submit(p.value)
+	}
+}
+
+// searchKey edits the quick search and reports whether it used the key; other keys still
+// move the selection while searching
+func (t *tui) searchKey(k key) bool {
+	switch k.code {
+	case keyRune:
+		t.setQuery(t.query + string(k.r))
+	case keyBackspace:
+		t.setQuery(trimLastRune(t.query))
+	case keyCtrlU:
+		t.setQuery("")
+	case keyEnter:
+		t.searching = false
+	case keyEscape:
+		t.searching = false
+		t.setQuery("")
+	default:
+		return false
+	}
+	return true
+}
+
+// trimLastRune removes the last character of s
+func trimLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+// navigate handles a movement key in the focused pane
+func (t *tui) navigate(code keyCode) {
+	page := max(t.height-4, 1)
+	switch t.focus {
+	case paneCollections:
+		node := t.tree[t.treeCursor]
+		switch code {
+		case keyRight:
+			if node.parent && !t.expanded[node.view] {
+				t.toggleCollection(true)
+				return
+			}
+			fallthrough
+		case keyEnter:
+			t.selectView(node.view)
+			t.focus = paneItems
+		case keyLeft:
+			if t.expanded[node.view] {
+				t.toggleCollection(false)
+				return
+			}
+			// Move to the parent collection
+			for i := t.treeCursor - 1; i >= 0 && node.depth > 1; i-- {
+				if t.tree[i].depth < node.depth {
+					t.treeCursor = i
+					break
+				}
+			}
+			t.selectView(t.tree[t.treeCursor].view)
+		default:
+			if moveCursor(&t.treeCursor, len(t.tree), code, page) {
+				view := t.tree[t.treeCursor].view
+				t.debounce(&t.treeGen, 200*time.Millisecond, func() { t.selectView(view) })
+			}
+		}
+
+	case paneItems:
+		switch code {
+		case keyLeft:
+			t.focus = paneCollections
+		case keyRight, keyEnter:
+			if t.selectedItem() != nil {
+				t.focus = paneDetails
+			}
+		default:
+			if moveCursor(&t.itemCursor, len(t.list().items), code, page) {
+				t.childCursor, t.detailTop = 0, 0
+				t.loadMore()
+				t.debounce(&t.itemGen, 150*time.Millisecond, t.loadChildren)
+			}
+		}
+
+	case paneDetails:
+		if code == keyLeft {
+			t.focus = paneItems
+			return
+		}
+		// Move through the children if there are any, and otherwise scroll
+		if item := t.selectedItem(); item != nil && t.children[item.Key] != nil && len(t.children[item.Key].items) > 0 {
+			moveCursor(&t.childCursor, len(t.children[item.Key].items), code, page)
+			return
+		}
+		moveCursor(&t.detailTop, t.detailLen-page+1, code, page)
+	}
+}
+
+// moveCursor moves a cursor over n entries by a movement key, and reports whether it moved
+func moveCursor(cursor *int, n int, code keyCode, page int) bool {
+	old := *cursor
+	switch code {
+	case keyUp:
+		*cursor--
+	case keyDown:
+		*cursor++
+	case keyPageUp:
+		*cursor -= page
+	case keyPageDown:
+		*cursor += page
+	case keyHome:
+		*cursor = 0
+	case keyEnd:
+		*cursor = n - 1
+	}
+	*cursor = max(min(*cursor, n-1), 0)
+	return *cursor != old
+}
+
+// toggleCollection expands or collapses the collection under the tree cursor
+func (t *tui) toggleCollection(expand bool) {
+	node := t.tree[t.treeCursor]
+	if !node.parent {
+		return
+	}
+	if expand {
+		t.expanded[node.view] = true
+	} else {
+		delete(t.expanded, node.view)
+	}
+	t.buildTree()
+}
+
+// attachment returns the attachment that the open and download keys act on: the selected
+// child in the details pane, the item itself if it is an attachment, or else the item's
+// first attachment, preferring PDFs. If there is none it sets the status and returns nil.
+func (t *tui) attachment() *zotero.Item {
+	item := t.selectedItem()
+	if item == nil {
+		return nil
+	}
+	if item.Data.ItemType == zotero.ItemTypeAttachment {
+		return item
+	}
+
+	children := t.children[item.Key]
+	switch {
+	case item.Meta.NumChildren == 0:
+		t.setStatus("The item has no attachments")
+		return nil
+	case children == nil || children.loading:
+		t.loadChildren()
+		t.setStatus("Loading attachments...")
+		return nil
+	case children.err != nil:
+		t.setError("fetching attachments", children.err)
+		return nil
+	}
+
+	if t.focus == paneDetails && t.childCursor < len(children.items) {
+		child := &children.items[t.childCursor]
+		if child.Data.ItemType != zotero.ItemTypeAttachment {
+			t.setStatus("The selected child is not an attachment")
+			return nil
+		}
+		return child
+	}
+
+	var first *zotero.Item
+	for i := range children.items {
+		child := &children.items[i]
+		if child.Data.ItemType != zotero.ItemTypeAttachment {
+			continue
+		}
+		if child.Data.ContentType == "application/pdf" {
+			return child
+		}
+		if first == nil {
+			first = child
+		}
+	}
+	if first == nil {
+		t.setStatus("The item has no attachments")
+	}
+	return first
+}
+
+// openAttachment opens an attachment with the system's default application, downloading
+// stored files to the cache first
+func (t *tui) openAttachment() {
+	att := t.attachment()
+	if att == nil {
+		return
+	}
+	switch att.Data.LinkMode {
+	case zotero.LinkModeLinkedURL:
+		t.open(att.Data.URL)
+		return
+	case zotero.LinkModeLinkedFile:
+		t.open(att.Data.Path)
+		return
+	}
+
+	itemKey := att.Key
+	dir := filepath.Join(t.filesDir, itemKey)
+	t.setStatus("Downloading %s...", itemTitle(*att))
+	async(t, func(ctx context.Context) (*zotero.DumpResult, error) {
+		return t.client.DumpWithOptions(ctx, itemKey, &zotero.DumpOptions{Dir: dir, Conflict: zotero.ConflictSkipIfMD5Matches})
+	}, func(result *zotero.DumpResult, err error) {
+		if err != nil {
+			t.setError("downloading attachment", err)
+			return
+		}
+		t.open(result.Path)
+	})
+}
+
+// open opens a file or URL with the system's default application
+func (t *tui) open(target string) {
+	if target == "" {
+		t.setStatus("The attachment has no file or URL")
+		return
+	}
+	if err := openBrowser(target); err != nil {
+		t.setError("opening "+target, err)
+		return
+	}
+	t.setStatus("Opened %s", target)
+}
+
+// downloadAttachment saves an attachment's file to the download directory
+func (t *tui) downloadAttachment() {
+	att := t.attachment()
+	if att == nil {
+		return
+	}
+	if att.Data.LinkMode == zotero.LinkModeLinkedURL || att.Data.LinkMode == zotero.LinkModeLinkedFile {
+		t.setStatus("Linked attachments have no stored file to download")
+		return
+	}
+
+	itemKey := att.Key
+	t.setStatus("Downloading %s...", itemTitle(*att))
+	async(t, func(ctx context.Context) (*zotero.DumpResult, error) {
+		return t.client.DumpWithOptions(ctx, itemKey, &zotero.DumpOptions{Dir: t.downloadDir, Conflict: zotero.ConflictSuffix})
+	}, func(result *zotero.DumpResult, err error) {
+		if err != nil {
+			t.setError("downloading attachment", err)
+			return
+		}
+		t.setStatus("Saved %s", result.Path)
+	})
+}
+
+// addTag asks for a tag and adds it to the selected item
+func (t *tui) addTag() {
+	item := t.selectedItem()
+	if item == nil {
+		return
+	}
+	if t.apiKey == "" {
+		t.setStatus("Adding tags needs an API key (use -key, set ZOTERO_API_KEY or run zotero-cli login)")
+		return
+	}
+
+	itemKey := item.Key
+	t.prompt = &tuiPrompt{label: "Add tag: ", submit: func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return
+		}
+		t.setStatus("Adding tag %q...", tag)
+		async(t, func(ctx context.Context) (*zotero.Item, error) {
+			if err := t.client.AddTags(ctx, itemKey, tag); err != nil {
+				return nil, err
+			}
+			return t.client.Item(ctx, itemKey, nil)
+		}, func(item *zotero.Item, err error) {
+			if err != nil {
+				t.setError("adding tag", err)
+				return
+			}
+			// The cache keeps the old library version, so it is revalidated on the next start
+			t.replaceItem(*item)
+			t.setStatus("Added tag %q to %s", tag, itemKey)
+		})
+	}}
+}
+
+// copyCitationKey copies the selected item's citation key, or its item key if it has none
+func (t *tui) copyCitationKey() {
+	item := t.selectedItem()
+	if item == nil {
+		return
+	}
+	if citationKey := item.Data.CitationKey(); citationKey != "" {
+		copyToClipboard(t.screen.out, citationKey)
+		t.setStatus("Copied citation key %s", citationKey)
+		return
+	}
+	copyToClipboard(t.screen.out, item.Key)
+	t.setStatus("The item has no citation key; copied item key %s", item.Key)
+}
+
+// tuiCache is the terminal UI's local copy of a library, valid while the library version is
+// unchanged
+type tuiCache struct {
+	Version     int                      `json:"version"`
+	Collections []zotero.Collection      `json:"collections"`
+	Lists       map[string][]zotero.Item `json:"lists"`    // Items fetched so far of each view, without a search
+	Complete    []string                 `json:"complete"` // Views whose lists hold every item
+	Children    map[string][]zotero.Item `json:"children"`
+	Expanded    []string                 `json:"expanded"` // Expanded collections
+}
+
+// loadCache restores the fetched data of an earlier session
+func (t *tui) loadCache() {
+	data, err := os.ReadFile(t.cachePath)
+	if err != nil {
+		return
+	}
+	var cache tuiCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Version == 0 {
+		return
+	}
+
+	t.version = cache.Version
+	t.collections = cache.Collections
+	for view, items := range cache.Lists {
+		t.lists[listKey{view: view}] = &itemList{items: items, done: slices.Contains(cache.Complete, view)}
+	}
+	for itemKey, items := range cache.Children {
+		t.children[itemKey] = &childList{items: items}
+	}
+	for _, key := range cache.Expanded {
+		t.expanded[key] = true
+	}
+}
+
+// saveCache writes the fetched data for the next session
+func (t *tui) saveCache() error {
+	if t.cachePath == "" || t.version == 0 {
+		return nil
+	}
+
+	cache := tuiCache{
+		Version:     t.version,
+		Collections: t.collections,
+		Lists:       make(map[string][]zotero.Item),
+		Children:    make(map[string][]zotero.Item),
+	}
+	for key, list := range t.lists {
+		if key.query != "" || list.err != nil || len(list.items) == 0 {
+			continue
+		}
+		cache.Lists[key.view] = list.items
+		if list.done {
+			cache.Complete = append(cache.Complete, key.view)
+		}
+	}
+	for itemKey, children := range t.children {
+		if !children.loading && children.err == nil {
+			cache.Children[itemKey] = children.items
+		}
+	}
+	for key := range t.expanded {
+		cache.Expanded = append(cache.Expanded, key)
+	}
+	slices.Sort(cache.Complete)
+	slices.Sort(cache.Expanded)
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.cachePath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(t.cachePath, data, 0o600)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// screen is the terminal in raw mode, showing the alternate screen
+type screen struct {
+	in, out *os.File
+	state   *term.State
+}
+
+// openScreen switches the terminal to raw mode and the alternate screen
+func openScreen() (*screen, error) {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, errors.New("standard input and output must be a terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	out.WriteString("\x1b[?1049h\x1b[?25l")
+	return &screen{in: in, out: out, state: state}, nil
+}
+
+// close restores the terminal to the state openScreen found it in
+func (s *screen) close() {
+	s.out.WriteString("\x1b[?25h\x1b[?1049l")
+	term.Restore(int(s.in.Fd()), s.state)
+}
+
+// size returns the terminal's width and height
+func (s *screen) size() (width, height int) {
+	width, height, err := term.GetSize(int(s.out.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return width, height
+}
+
+// copyToClipboard copies text to the clipboard with an OSC 52 escape sequence, which
+// terminals support over SSH too, and with the first clipboard tool that works
+func copyToClipboard(out io.Writer, text string) {
+	fmt.Fprintf(out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	for _, tool := range [][]string{{"pbcopy"}, {"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}} {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if cmd.Run() == nil {
+			return
+		}
+	}
+}
+
+// keyCode identifies a key; printable characters are keyRune
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyTab
+	keyBackTab
+	keyBackspace
+	keyEscape
+	keyCtrlC
+	keyCtrlU
+	keyUnknown
+)
+
+// key is a key press
+type key struct {
+	code keyCode
+	r    rune // the character, for keyRune
+}
+
+// escapeKeys maps the escape sequences of special keys, without the leading ESC, to their codes
+var escapeKeys = map[string]keyCode{
+	"[A": keyUp, "OA": keyUp,
+	"[B": keyDown, "OB": keyDown,
+	"[C": keyRight, "OC": keyRight,
+	"[D": keyLeft, "OD": keyLeft,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+	"[H": keyHome, "OH": keyHome, "[1~": keyHome, "[7~": keyHome,
+	"[F": keyEnd, "OF": keyEnd, "[4~": keyEnd, "[8~": keyEnd,
+	"[Z": keyBackTab,
+}
+
+// readKeys reads key presses from r until it fails, and then closes keys
+func readKeys(r io.Reader, keys chan<- key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// parseKeys decodes the key presses in a read from a raw-mode terminal
+func parseKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		code, size := keyUnknown, 1
+		switch b := data[0]; {
+		case b == 0x1b && len(data) > 1 && (data[1] == '[' || data[1] == 'O'):
+			// CSI or SS3 sequence: parameters followed by a final byte
+			size = 2
+			for size < len(data) && (data[size] >= '0' && data[size] <= '9' || data[size] == ';') {
+				size++
+			}
+			size = min(size+1, len(data))
+			if c, ok := escapeKeys[string(data[1:size])]; ok {
+				code = c
+			}
+		case b == 0x1b:
+			code = keyEscape
+		case b == '\r' || b == '\n':
+			code = keyEnter
+		case b == '\t':
+			code = keyTab
+		case b == 0x7f || b == 0x08:
+			code = keyBackspace
+		case b == 0x03:
+			code = keyCtrlC
+		case b == 0x15:
+			code = keyCtrlU
+		case b >= 0x20:
+			r, n := utf8.DecodeRune(data)
+			keys = append(keys, key{code: keyRune, r: r})
+			data = data[n:]
+			continue
+		}
+		keys = append(keys, key{code: code})
+		data = data[size:]
+	}
+	return keys
+}
+
+// cleanText replaces control characters, which would move the cursor or change the
+// terminal's state, with spaces
+func cleanText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// runeWidth returns the number of terminal columns r takes up
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && (r <= 0x115f ||
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f ||
+		r >= 0xac00 && r <= 0xd7a3 ||
+		r >= 0xf900 && r <= 0xfaff ||
+		r >= 0xfe30 && r <= 0xfe4f ||
+		r >= 0xff00 && r <= 0xff60 ||
+		r >= 0xffe0 && r <= 0xffe6 ||
+		r >= 0x1f300 && r <= 0x1f64f ||
+		r >= 0x1f900 && r <= 0x1f9ff ||
+		r >= 0x20000 && r <= 0x3fffd):
+		return 2
+	}
+	return 1
+}
+
+// textWidth returns the number of terminal columns s takes up
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// fit clips s to width columns, ending it with "…" if it is too long, and pads it with spaces
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = cleanText(s)
+	used := textWidth(s)
+	if used > width {
+		var b strings.Builder
+		used = 0
+		for _, r := range s {
+			if used+runeWidth(r) > width-1 {
+				break
+			}
+			b.WriteRune(r)
+			used += runeWidth(r)
+		}
+		b.WriteString("…")
+		s, used = b.String(), used+1
+	}
+	return s + strings.Repeat(" ", width-used)
+}
+
+// wrap breaks s into lines of at most width columns at spaces, splitting words that do not
+// fit on a line of their own
+func wrap(s string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for paragraph := range strings.SplitSeq(s, "\n") {
+		var line []rune
+		lineWidth := 0
+		for _, word := range strings.Fields(cleanText(paragraph)) {
+			wordWidth := textWidth(word)
+			if lineWidth > 0 && lineWidth+1+wordWidth > width {
+				lines = append(lines, string(line))
+				line, lineWidth = nil, 0
+			}
+			if lineWidth > 0 {
+				line = append(line, ' ')
+				lineWidth++
+			}
+			for _, r := range word {
+				if lineWidth+runeWidth(r) > width {
+					lines = append(lines, string(line))
+					line, lineWidth = nil, 0
+				}
+				line = append(line, r)
+				lineWidth += runeWidth(r)
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
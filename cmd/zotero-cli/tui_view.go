@@ -0,0 +1,342 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// SGR styles of the terminal UI
+const (
+	styleReset    = "\x1b[0m"
+	styleSelected = "\x1b[7m"
+	styleBold     = "\x1b[1m"
+	styleDim      = "\x1b[2m"
+	styleError    = "\x1b[31m"
+)
+
+// line is a row of a pane
+type line struct {
+	text  string
+	style string // SGR style, "" for the default
+}
+
+// draw redraws the whole screen
+func (t *tui) draw() {
+	var b strings.Builder
+	width, height := t.width, t.height
+	row := func(y int, style, text string, textWidth int) {
+		fmt.Fprintf(&b, "\x1b[%d;1H%s%s%s", y+1, style, fit(text, textWidth), styleReset)
+	}
+
+	b.WriteString("\x1b[?25l")
+	if width < 40 || height < 8 {
+		b.WriteString("\x1b[2J")
+		row(0, "", "The terminal is too small", width)
+		t.screen.out.WriteString(b.String())
+		return
+	}
+
+	header := " zotero-cli │ " + t.viewName(t.view)
+	if t.query != "" {
+		header += fmt.Sprintf(" │ search: %s", t.query)
+	}
+	row(0, styleSelected, header, width)
+
+	// Below the header, each pane has a title row and rows of content; the status line is last
+	rows := height - 3
+	treeWidth := min(max(width/5, 16), 32)
+	detailWidth := (width - treeWidth - 2) * 2 / 5
+	itemWidth := width - treeWidth - detailWidth - 2
+	widths := []int{treeWidth, itemWidth, detailWidth}
+	titles := []string{"Collections", t.itemsTitle(), "Details"}
+	panes := [][]line{t.treeLines(rows, treeWidth), t.itemLines(rows, itemWidth), t.detailLines(rows, detailWidth)}
+
+	for y := range rows + 1 {
+		fmt.Fprintf(&b, "\x1b[%d;1H", y+2)
+		for i, pane := range panes {
+			if i > 0 {
+				b.WriteString(styleDim + "│" + styleReset)
+			}
+			switch {
+			case y == 0 && tuiPane(i) == t.focus:
+				b.WriteString(styleSelected + styleBold + fit(" "+titles[i], widths[i]) + styleReset)
+			case y == 0:
+				b.WriteString(styleBold + fit(" "+titles[i], widths[i]) + styleReset)
+			case y-1 < len(pane):
+				b.WriteString(pane[y-1].style + fit(pane[y-1].text, widths[i]) + styleReset)
+			default:
+				b.WriteString(strings.Repeat(" ", widths[i]))
+			}
+		}
+	}
+
+	// Leave the last column free, so the terminal does not scroll
+	status, style := t.status, ""
+	switch {
+	case t.prompt != nil:
+		status = t.prompt.label + t.prompt.value
+	case t.searching:
+		status = "Search: " + t.query
+	case t.statusError:
+		style = styleError
+	case status == "":
+		status, style = "? keys  / search  o open  d download  t tag  c copy key  r reload  q quit", styleDim
+	}
+	row(height-1, style, " "+status, width-1)
+
+	if t.showHelp {
+		t.drawHelp(&b)
+	}
+	if t.prompt != nil || t.searching {
+		// Show the cursor at the end of the text being entered
+		fmt.Fprintf(&b, "\x1b[%d;%dH\x1b[?25h", height, min(textWidth(" "+cleanText(status))+1, width-1))
+	}
+	t.screen.out.WriteString(b.String())
+}
+
+// drawHelp draws the keys over the middle of the screen
+func (t *tui) drawHelp(b *strings.Builder) {
+	lines := []string{"Keys", ""}
+	for _, k := range tuiKeys {
+		lines = append(lines, fmt.Sprintf("%-20s %s", k[0], k[1]))
+	}
+	lines = append(lines, "", "Press any key to close")
+
+	width := 0
+	for _, text := range lines {
+		width = max(width, textWidth(text)+4)
+	}
+	width = min(width, t.width-2)
+	top := max((t.height-len(lines)-2)/2, 0)
+	left := (t.width-width)/2 + 1
+	border := strings.Repeat("─", width-2)
+	fmt.Fprintf(b, "\x1b[%d;%dH┌%s┐", top+1, left, border)
+	for i, text := range lines {
+		if top+i+2 >= t.height {
+			break
+		}
+		fmt.Fprintf(b, "\x1b[%d;%dH│ %s │", top+i+2, left, fit(text, width-4))
+	}
+	fmt.Fprintf(b, "\x1b[%d;%dH└%s┘", min(top+len(lines)+2, t.height), left, border)
+}
+
+// itemsTitle returns the title of the items pane, with the number of items fetched
+func (t *tui) itemsTitle() string {
+	list := t.list()
+	more := ""
+	if !list.done {
+		more = "+"
+	}
+	return fmt.Sprintf("Items (%d%s)", len(list.items), more)
+}
+
+// selectionStyle returns the style of a row, depending on whether it is under the cursor and
+// its pane has the focus
+func (t *tui) selectionStyle(pane tuiPane, selected bool) string {
+	switch {
+	case selected && t.focus == pane:
+		return styleSelected
+	case selected:
+		return styleBold
+	}
+	return ""
+}
+
+// scrollTo returns the first row to show of a list of n rows, moved from top as little as
+// possible to show the cursor within height rows
+func scrollTo(top, cursor, height, n int) int {
+	if cursor < top {
+		top = cursor
+	}
+	if cursor >= top+height {
+		top = cursor - height + 1
+	}
+	return max(min(top, n-height), 0)
+}
+
+// treeLines renders the collection tree
+func (t *tui) treeLines(rows, width int) []line {
+	t.treeTop = scrollTo(t.treeTop, t.treeCursor, rows, len(t.tree))
+	var lines []line
+	for i := t.treeTop; i < len(t.tree) && len(lines) < rows; i++ {
+		node := t.tree[i]
+		marker := "  "
+		if node.parent && t.expanded[node.view] {
+			marker = "▾ "
+		} else if node.parent {
+			marker = "▸ "
+		}
+		lines = append(lines, line{
+			text:  strings.Repeat("  ", node.depth) + marker + node.name,
+			style: t.selectionStyle(paneCollections, i == t.treeCursor),
+		})
+	}
+	if t.collectionsLoading && len(t.collections) == 0 && len(lines) < rows {
+		lines = append(lines, line{text: "  Loading...", style: styleDim})
+	}
+	return lines
+}
+
+// itemLines renders the item list
+func (t *tui) itemLines(rows, width int) []line {
+	list := t.list()
+	t.itemTop = scrollTo(t.itemTop, t.itemCursor, rows, len(list.items))
+	var lines []line
+	for i := t.itemTop; i < len(list.items) && len(lines) < rows; i++ {
+		lines = append(lines, line{
+			text:  itemRow(list.items[i], width),
+			style: t.selectionStyle(paneItems, i == t.itemCursor),
+		})
+	}
+	if len(lines) < rows {
+		switch {
+		case list.err != nil:
+			lines = append(lines, line{text: " Error: " + list.err.Error(), style: styleError})
+		case !list.done:
+			lines = append(lines, line{text: " Loading...", style: styleDim})
+		case len(list.items) == 0:
+			lines = append(lines, line{text: " No items", style: styleDim})
+		}
+	}
+	return lines
+}
+
+// itemRow renders an item as a row of the item list: title, creators and year
+func itemRow(item zotero.Item, width int) string {
+	title := itemTitle(item)
+	if width < 40 {
+		return " " + title
+	}
+	year := item.Meta.ParsedDate
+	if len(year) > 4 {
+		year = year[:4]
+	}
+	creatorWidth := min(20, width/4)
+	return " " + fit(title, width-creatorWidth-8) + " " + fit(cmp.Or(item.Meta.CreatorSummary, item.Data.CreatorSummary()), creatorWidth) + " " + fit(year, 4)
+}
+
+// detailLines renders the details of the selected item, scrolled to show the selected child
+func (t *tui) detailLines(rows, width int) []line {
+	item := t.selectedItem()
+	if item == nil {
+		t.detailLen = 0
+		return nil
+	}
+
+	var lines []line
+	wrapWidth := width - 2
+	add := func(text, style string) {
+		lines = append(lines, line{text: " " + text, style: style})
+	}
+	field := func(label, value string) {
+		if value == "" {
+			return
+		}
+		for i, text := range wrap(value, wrapWidth-13) {
+			if i == 0 {
+				add(fmt.Sprintf("%-12s %s", label, text), "")
+			} else {
+				add(strings.Repeat(" ", 13)+text, "")
+			}
+		}
+	}
+
+	for _, text := range wrap(itemTitle(*item), wrapWidth) {
+		add(text, styleBold)
+	}
+	add("", "")
+	field("Type", item.Data.ItemType)
+	for i, creator := range item.Data.Creators {
+		label := ""
+		if i == 0 {
+			label = "Creators"
+		}
+		field(label, creatorName(creator)+" ("+creator.CreatorType+")")
+	}
+	field("Date", item.Data.Field("date"))
+	field("Publication", item.Data.Field("publicationTitle"))
+	field("DOI", item.Data.Field("DOI"))
+	field("URL", item.Data.URL)
+	field("Citation key", item.Data.CitationKey())
+	field("Item key", item.Key)
+
+	tags := make([]string, len(item.Data.Tags))
+	for i, tag := range item.Data.Tags {
+		tags[i] = tag.Tag
+	}
+	field("Tags", strings.Join(tags, "; "))
+	collections := make([]string, len(item.Data.Collections))
+	for i, key := range item.Data.Collections {
+		collections[i] = t.viewName(key)
+	}
+	field("Collections", strings.Join(collections, "; "))
+
+	if item.Data.AbstractNote != "" {
+		add("", "")
+		add("Abstract", styleBold)
+		for _, text := range wrap(item.Data.AbstractNote, wrapWidth) {
+			add(text, "")
+		}
+	}
+
+	cursorLine := -1
+	if item.Meta.NumChildren > 0 {
+		add("", "")
+		add("Attachments and notes", styleBold)
+		switch children := t.children[item.Key]; {
+		case children == nil || children.loading:
+			add("Loading...", styleDim)
+		case children.err != nil:
+			add("Error: "+children.err.Error(), styleError)
+		default:
+			t.childCursor = min(t.childCursor, max(len(children.items)-1, 0))
+			for i, child := range children.items {
+				if i == t.childCursor && t.focus == paneDetails {
+					cursorLine = len(lines)
+				}
+				add(childLabel(child), t.selectionStyle(paneDetails, i == t.childCursor && t.focus == paneDetails))
+			}
+		}
+	}
+
+	t.detailLen = len(lines)
+	if cursorLine >= 0 {
+		t.detailTop = scrollTo(t.detailTop, cursorLine, rows, len(lines))
+	}
+	t.detailTop = max(min(t.detailTop, len(lines)-rows), 0)
+	return lines[t.detailTop:]
+}
+
+// creatorName returns a creator's name as "Last, First", or their single-field name
+func creatorName(creator zotero.Creator) string {
+	switch {
+	case creator.Name != "":
+		return creator.Name
+	case creator.FirstName == "":
+		return creator.LastName
+	}
+	return creator.LastName + ", " + creator.FirstName
+}
+
+// childLabel describes a child item in the details pane, e.g. "[PDF] Full Text"
+func childLabel(child zotero.Item) string {
+	kind := "File"
+	switch {
+	case child.Data.ItemType == zotero.ItemTypeNote:
+		kind = "Note"
+	case child.Data.ItemType == zotero.ItemTypeAnnotation:
+		kind = "Annotation"
+	case child.Data.LinkMode == zotero.LinkModeLinkedURL:
+		kind = "Link"
+	case child.Data.ContentType == "application/pdf":
+		kind = "PDF"
+	case child.Data.ContentType == "application/epub+zip":
+		kind = "EPUB"
+	case child.Data.ContentType == "text/html":
+		kind = "Snapshot"
+	}
+	return fmt.Sprintf("[%s] %s", kind, itemTitle(child))
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var updateCommand = &command{
+	name:     "update",
+	usage:    "-item KEY -set field=value [-set ...]",
+	summary:  "Set fields of an item",
+	examples: []string{"zotero-cli update -item ABC123 -set title='New Title' -set date=2024", "zotero-cli update -item ABC123 -set extra="},
+	complete: map[string]completer{"item": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKey := fs.String("item", "", "Item key (required)")
+		var sets stringList
+		fs.Var(&sets, "set", "Field to set as field=value, e.g. title='New Title' (repeatable; an empty value clears the field)")
+		version := fs.Int("version", 0, "Only update if the item is still at this version")
+		return func(c *invocation) {
+			if c.libraryID == "" || *itemKey == "" || len(sets) == 0 {
+				c.usageError("-library, -item and at least one -set are required")
+			}
+			c.requireAPIKey()
+			updateItem(c.client(), *itemKey, sets, *version)
+		}
+	},
+}
+
+// updateItem sets fields of an item, given as field=value
+func updateItem(client *zotero.Client, itemKey string, sets []string, version int) {
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	item, err := client.Item(ctx, itemKey, nil)
+	if err != nil {
+		fatal("fetching item", err)
+	}
+	checkVersion("item", item.Key, item.Version, version)
+
+	// Fields the item type allows, for catching typos in fields without a struct field
+	var validFields []string
+	if fields, err := client.ItemTypeFields(ctx, item.Data.ItemType, ""); err == nil {
+		for _, field := range fields {
+			validFields = append(validFields, field.Field)
+		}
+	}
+
+	for _, set := range sets {
+		name, value, ok := strings.Cut(set, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "Error: invalid -set %q (expected field=value)\n", set)
+			os.Exit(exitUsage)
+		}
+		if validFields != nil && item.Data.Field(name) == "" && !slices.Contains(validFields, name) && !isItemDataField(name) {
+			fmt.Fprintf(os.Stderr, "Error: %s is not a field of item type %s\n", name, item.Data.ItemType)
+			os.Exit(exitUsage)
+		}
+
+		old := item.Data.Field(name)
+		if err := item.Data.SetField(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		fmt.Printf("%s: %q -> %q\n", name, old, value)
+	}
+
+	// Replace rather than patch, so that cleared fields are removed on the server
+	if err := client.ReplaceItem(ctx, item); err != nil {
+		fatal("updating item", err)
+	}
+	fmt.Printf("Updated item %s\n", item.Key)
+}
+
+// isItemDataField reports whether name is a field of ItemData, such as the attachment and
+// note fields, which the item type schema does not list
+func isItemDataField(name string) bool {
+	var data zotero.ItemData
+	return data.SetField(name, "") == nil && data.Extra == nil
+}
+
+// checkVersion exits with a conflict if an expected version was given and the object has changed
+func checkVersion(kind, key string, current, expected int) {
+	if expected != 0 && current != expected {
+		fmt.Fprintf(os.Stderr, "Error: %s %s is at version %d, not %d (it was modified on the server)\n", kind, key, current, expected)
+		os.Exit(exitConflict)
+	}
+}
+
+var deleteCommand = &command{
+	name:    "delete",
+	usage:   "KEY [KEY...]",
+	summary: "Delete items, or move them to the trash with -trash",
+	help:    "Deleting asks for confirmation unless -yes is given.",
+	examples: []string{
+		"zotero-cli delete ABC123",
+		"zotero-cli delete -trash ABC123 DEF456",
+		"zotero-cli delete -item ABC123,DEF456 -yes",
+	},
+	complete: map[string]completer{"item": completeItems, "": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKeys := fs.String("item", "", "Item key(s), comma-separated (or pass keys as arguments)")
+		trash := fs.Bool("trash", false, "Move the items to the trash instead of deleting them permanently")
+		version := fs.Int("version", 0, "Only delete if the library (or a single item) is still at this version")
+		yes := fs.Bool("yes", false, "Do not ask for confirmation")
+		return func(c *invocation) {
+			keys := append(splitKeys(*itemKeys), c.args...)
+			if c.libraryID == "" || len(keys) == 0 {
+				c.usageError("-library and at least one item key are required")
+			}
+			c.requireAPIKey()
+			deleteItems(c.client(), keys, *trash, *version, *yes)
+		}
+	},
+}
+
+// deleteItems permanently deletes items, or moves them to the trash
+func deleteItems(client *zotero.Client, keys []string, trash bool, version int, yes bool) {
+	requireWriteAccess(client)
+	ctx := context.Background()
+
+	// Fetch the items first, to confirm they exist and show what will be deleted
+	libraryVersion, err := client.LastModifiedVersion(ctx)
+	if err != nil {
+		fatal("fetching library version", err)
+	}
+	items, err := client.QueryItems(ctx, zotero.NewQuery().ItemKeys(keys...).IncludeTrashed())
+	if err != nil {
+		fatal("fetching items", err)
+	}
+	requireAllFound(keys, items)
+
+	action := fmt.Sprintf("Permanently delete %d item(s)", len(items))
+	if trash {
+		action = fmt.Sprintf("Move %d item(s) to the trash", len(items))
+	}
+	for _, item := range items {
+		fmt.Printf("  %s  %s\n", item.Key, truncate(itemTitle(item), 60))
+	}
+	if !confirm(yes, "%s?", action) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(exitError)
+	}
+
+	if trash {
+		trashItems(ctx, client, items, version)
+		return
+	}
+
+	if len(items) == 1 {
+		checkVersion("item", items[0].Key, items[0].Version, version)
+		if err := client.DeleteItem(ctx, items[0].Key, items[0].Version); err != nil {
+			fatal("deleting item", err)
+		}
+		fmt.Printf("Deleted item %s\n", items[0].Key)
+		return
+	}
+
+	checkVersion("library", client.LibraryID, libraryVersion, version)
+	deleted := 0
+	for chunk := range slices.Chunk(keys, 50) {
+		if err := client.DeleteItems(ctx, chunk, libraryVersion); err != nil {
+			fatal("deleting items", err)
+		}
+		deleted += len(chunk)
+		// Each delete advances the library version
+		if deleted < len(keys) {
+			if libraryVersion, err = client.LastModifiedVersion(ctx); err != nil {
+				fatal("fetching library version", err)
+			}
+		}
+	}
+	fmt.Printf("Deleted %d items\n", deleted)
+}
+
+// trashItems marks items as deleted, each conditional on its own version
+func trashItems(ctx context.Context, client *zotero.Client, items []zotero.Item, version int) {
+	if len(items) == 1 {
+		checkVersion("item", items[0].Key, items[0].Version, version)
+	}
+	for chunk := range slices.Chunk(items, 50) {
+		updates := make([]zotero.Item, len(chunk))
+		for i, item := range chunk {
+			updates[i] = zotero.Item{
+				Key:     item.Key,
+				Version: item.Version,
+				Data:    zotero.ItemData{ItemType: item.Data.ItemType, Extra: map[string]any{"deleted": 1}},
+			}
+		}
+		resp, err := client.UpdateItems(ctx, updates)
+		if err != nil {
+			fatal("moving items to the trash", err)
+		}
+		if len(resp.Failed) > 0 {
+			fmt.Fprintln(os.Stderr, "Failed items:")
+			for idx, failure := range resp.Failed {
+				fmt.Fprintf(os.Stderr, "  Index %s: %d - %s\n", idx, failure.Code, failure.Message)
+			}
+			os.Exit(writeFailedCode(resp.Failed))
+		}
+	}
+	fmt.Printf("Moved %d item(s) to the trash\n", len(items))
+}
+
+// requireAllFound exits with not found if any key is missing from items
+func requireAllFound(keys []string, items []zotero.Item) {
+	var missing []string
+	for _, key := range keys {
+		if !slices.ContainsFunc(items, func(item zotero.Item) bool { return item.Key == key }) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: items not found: %s\n", strings.Join(missing, ", "))
+		os.Exit(exitNotFound)
+	}
+}
+
+// itemTitle returns an item's title, or its note title for notes
+func itemTitle(item zotero.Item) string {
+	if item.Data.Title != "" {
+		return item.Data.Title
+	}
+	if note, err := zotero.NoteFromItem(&item); err == nil {
+		return note.Title()
+	}
+	return "(" + item.Data.ItemType + ")"
+}
+
+var childrenCommand = &command{
+	name:     "children",
+	usage:    "-item KEY",
+	summary:  "List the attachments, notes and annotations of an item",
+	examples: []string{"zotero-cli children -item ABC123", "zotero-cli children -item ABC123 -o keys"},
+	output:   true,
+	complete: map[string]completer{"item": completeItems},
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		itemKey := fs.String("item", "", "Parent item key (required)")
+		return func(c *invocation) {
+			if c.libraryID == "" || *itemKey == "" {
+				c.usageError("-library and -item are required")
+			}
+			listChildren(c.libraryID, c.libraryType, c.apiKey, c.verbose, *itemKey, c.out)
+		}
+	},
+}
+
+var trashCommand = &command{
+	name:     "trash",
+	summary:  "List items in the trash",
+	examples: []string{"zotero-cli trash -limit 50"},
+	output:   true,
+	setup: func(fs *flag.FlagSet) func(*invocation) {
+		limit := fs.Int("limit", 25, "Number of items to retrieve")
+		start := fs.Int("start", 0, "Starting index")
+		return func(c *invocation) {
+			c.requireLibrary()
+			listTrash(c.libraryID, c.libraryType, c.apiKey, c.verbose, *limit, *start, c.out)
+		}
+	},
+}
+
+// listChildren prints the child items (attachments, notes and annotations) of an item
+func listChildren(libraryID, libraryType, apiKey string, verbose bool, itemKey string, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	items, err := client.Children(ctx, itemKey, nil)
+	if err != nil {
+		fatal("fetching children", err)
+	}
+
+	itemPrinter.print(out, items)
+}
+
+// listTrash prints the items in the trash
+func listTrash(libraryID, libraryType, apiKey string, verbose bool, limit, start int, out *outputFlags) {
+	client := createClient(libraryID, libraryType, apiKey, verbose)
+	ctx := context.Background()
+
+	items, err := client.Trash(ctx, &zotero.QueryParams{Limit: limit, Start: start})
+	if err != nil {
+		fatal("fetching trash", err)
+	}
+
+	itemPrinter.print(out, items)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var whoamiCommand = &command{
+	name:     "whoami",
+	summary:  "Show the API key's user and permissions",
+	examples: []string{"zotero-cli whoami", "zotero-cli whoami -profile lab -o json"},
+	globals:  keyFlags,
+	output:   true,
+	setup: func(*flag.FlagSet) func(*invocation) {
+		return func(c *invocation) {
+			if c.apiKey == "" {
+				fmt.Fprintln(os.Stderr, "Error: API key required (use -key or set ZOTERO_API_KEY)")
+				os.Exit(exitAuth)
+			}
+			whoami(c.apiKey, c.verbose, c.out)
+		}
+	},
+}
+
+// whoami prints the user and permissions of the API key
+func whoami(apiKey string, verbose bool, out *outputFlags) {
+	client := createClient("", "user", apiKey, verbose)
+
+	info, err := client.KeyInfo(context.Background())
+	if err != nil {
+		fatal("fetching API key info", err)
+	}
+
+	// Never print the secret itself, e.g. with -o json
+	info.Key = maskKey(info.Key)
+
+	printer := recordPrinter[zotero.KeyInfo]{
+		fields: []string{"userID", "username", "displayName"},
+		key:    func(info zotero.KeyInfo) string { return strconv.Itoa(info.UserID) },
+		table:  func(infos []zotero.KeyInfo) { printKeyInfo(&infos[0]) },
+	}
+	printer.print(out, []zotero.KeyInfo{*info})
+}
+
+// printKeyInfo displays the key's user and a table of its library permissions
+func printKeyInfo(info *zotero.KeyInfo) {
+	fmt.Printf("User ID:   %d\n", info.UserID)
+	fmt.Printf("Username:  %s\n", info.Username)
+	if info.DisplayName != "" {
+		fmt.Printf("Name:      %s\n", info.DisplayName)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIBRARY\tACCESS")
+	fmt.Fprintln(w, "-------\t------")
+	if info.Access.User != nil {
+		fmt.Fprintf(w, "user %d\t%s\n", info.UserID, formatAccess(*info.Access.User))
+	}
+	groups := make([]string, 0, len(info.Access.Groups))
+	for id := range info.Access.Groups {
+		groups = append(groups, id)
+	}
+	slices.Sort(groups)
+	for _, id := range groups {
+		name := "group " + id
+		if id == "all" {
+			name = "all groups"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, formatAccess(info.Access.Groups[id]))
+	}
+	w.Flush()
+
+	if os.Getenv("ZOTERO_LIBRARY_ID") == "" {
+		fmt.Printf("\nTo use this library by default:\n  export ZOTERO_LIBRARY_ID=%d\n", info.UserID)
+	}
+}
+
+// formatAccess lists the permissions granted on a library
+func formatAccess(access zotero.LibraryAccess) string {
+	var perms []string
+	if access.Library {
+		perms = append(perms, "read")
+	}
+	if access.Notes {
+		perms = append(perms, "notes")
+	}
+	if access.Files {
+		perms = append(perms, "files")
+	}
+	if access.Write {
+		perms = append(perms, "write")
+	}
+	if len(perms) == 0 {
+		return "none"
+	}
+	return strings.Join(perms, ", ")
+}
+
+// keyInfos holds the API key info fetched during this invocation, by API key, so that
+// finding the default library and checking write access look the key up once
+var keyInfos = map[string]*zotero.KeyInfo{}
+
+// keyInfo returns the info of the client's API key, fetching it on first use
+func keyInfo(client *zotero.Client) (*zotero.KeyInfo, error) {
+	if info, ok := keyInfos[client.APIKey]; ok {
+		return info, nil
+	}
+	info, err := client.KeyInfo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	keyInfos[client.APIKey] = info
+	return info, nil
+}
+
+// defaultLibraryID returns the user ID of the API key, so ZOTERO_LIBRARY_ID can be left unset
+// for the key owner's library. Exits if the key cannot be looked up.
+func defaultLibraryID(apiKey string, verbose bool) string {
+	client := createClient("", "user", apiKey, verbose)
+	info, err := keyInfo(client)
+	if err != nil {
+		fatal("looking up the API key's library (set -library or ZOTERO_LIBRARY_ID to skip)", err)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Using the API key owner's library %d\n", info.UserID)
+	}
+	return strconv.Itoa(info.UserID)
+}
+
+// requireWriteAccess exits before a write operation if the API key cannot write to the
+// library. The local API is read-only.
+func requireWriteAccess(client *zotero.Client) {
+	canWrite := false
+	if !client.IsLocal() {
+		info, err := keyInfo(client)
+		if err != nil {
+			fatal("checking API key permissions", err)
+		}
+		canWrite = info.LibraryAccess(client.LibraryType, client.LibraryID).Write
+	}
+	if !canWrite {
+		fmt.Fprintf(os.Stderr, "Error: API key does not have write access to library %s\n", client.LibraryID)
+		os.Exit(exitAuth)
+	}
+}
@@ -0,0 +1,396 @@
+// Package connector saves items into the running Zotero desktop app through its connector
+// server, the protocol the browser extensions use.
+//
+// The local API of the desktop app is read-only; the connector server on the same port is
+// how new items get in. Items are saved into the collection currently selected in the app,
+// unless a session is moved to another target afterwards.
+//
+//	c := &connector.Client{}
+//	if _, err := c.Ping(ctx); err != nil { ... } // errors.Is(err, connector.ErrNotRunning)
+//	session, err := c.SaveItems(ctx, []connector.Item{{
+//		Data:        zotero.ItemData{ItemType: "book", Title: "Title"},
+//		Attachments: []connector.Attachment{{Title: "Full Text", ContentType: "application/pdf", Content: file}},
+//	}}, nil)
+package connector
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// DefaultURL is the address of the connector server of the Zotero desktop app
+const DefaultURL = "http://127.0.0.1:23119"
+
+// APIVersion is the version of the connector protocol spoken by the client
+const APIVersion = 3
+
+var (
+	// ErrNotRunning is returned when the connector server cannot be reached, usually because the
+	// desktop app is not running
+	ErrNotRunning = errors.New("the Zotero desktop app is not running")
+
+	// ErrLibraryNotEditable is matched by an Error when the selected library is read-only
+	ErrLibraryNotEditable = errors.New("library is not editable")
+)
+
+// Error is returned when the connector server responds with an error status
+type Error struct {
+	StatusCode int
+	Message    string // Response body
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("connector error: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is reports whether the response matches target, so callers can write
+// errors.Is(err, connector.ErrLibraryNotEditable)
+func (e *Error) Is(target error) bool {
+	if target != ErrLibraryNotEditable {
+		return false
+	}
+	var body struct {
+		LibraryEditable *bool `json:"libraryEditable"`
+	}
+	return json.Unmarshal([]byte(e.Message), &body) == nil && body.LibraryEditable != nil && !*body.LibraryEditable
+}
+
+// Client talks to the connector server. The zero value uses DefaultURL and http.DefaultClient.
+type Client struct {
+	BaseURL    string       // Defaults to DefaultURL
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+}
+
+// Status describes the running desktop app
+type Status struct {
+	Version string // Zotero version, e.g. "7.0.11"
+	Prefs   Prefs
+}
+
+// Prefs are the desktop app's preferences that matter to connectors
+type Prefs struct {
+	AutomaticSnapshots       bool `json:"automaticSnapshots"`
+	DownloadAssociatedFiles  bool `json:"downloadAssociatedFiles"`
+	SupportsAttachmentUpload bool `json:"supportsAttachmentUpload"`
+	SupportsTagsAutocomplete bool `json:"supportsTagsAutocomplete"`
+}
+
+// Selection is the library or collection selected in the desktop app, where items are saved
+type Selection struct {
+	LibraryID       int      `json:"libraryID"`
+	LibraryName     string   `json:"libraryName"`
+	LibraryEditable bool     `json:"libraryEditable"`
+	FilesEditable   bool     `json:"filesEditable"`
+	ID              int      `json:"id,omitempty"`   // Collection ID, 0 if a library is selected
+	Name            string   `json:"name,omitempty"` // Collection name
+	Targets         []Target `json:"targets"`
+}
+
+// Target returns the ID of the selected library or collection as a Target, e.g. "C12"
+func (s *Selection) Target() string {
+	if s.ID != 0 {
+		return "C" + strconv.Itoa(s.ID)
+	}
+	return "L" + strconv.Itoa(s.LibraryID)
+}
+
+// Target is a library ("L1") or collection ("C12") that saved items can be moved to
+type Target struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Level         int    `json:"level"` // Depth in the tree; libraries are 0
+	FilesEditable bool   `json:"filesEditable"`
+	Recent        bool   `json:"recent,omitempty"`
+}
+
+// Item is an item to save with its child notes and attachments
+type Item struct {
+	Data        zotero.ItemData
+	Notes       []string // HTML of child notes
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a saved item. The desktop app downloads URL itself, unless
+// Content is set, in which case the content is uploaded.
+type Attachment struct {
+	Title       string
+	URL         string    // Source of the file
+	ContentType string    // MIME type, e.g. application/pdf
+	Content     io.Reader // File content to upload instead of downloading URL
+}
+
+// SaveOptions configures a save
+type SaveOptions struct {
+	SessionID string   // Identifies the save for later updates; generated if empty
+	URI       string   // Page the items were found on
+	Target    string   // Library or collection to save into (see Selection.Targets); defaults to the selected one
+	Tags      []string // Tags added to every saved item
+}
+
+// Snapshot is a web page to save as a webpage item with a snapshot, or a PDF or EPUB URL to
+// save as a standalone attachment
+type Snapshot struct {
+	URL          string
+	Title        string
+	HTML         string // Page content; the desktop app fetches URL if empty
+	SkipSnapshot bool   // Save the webpage item without a snapshot
+}
+
+// Ping checks that the desktop app is running and returns its version and preferences
+func (c *Client) Ping(ctx context.Context) (*Status, error) {
+	var prefs struct {
+		Prefs Prefs `json:"prefs"`
+	}
+	header, err := c.post(ctx, "ping", struct{}{}, &prefs)
+	if err != nil {
+		return nil, fmt.Errorf("error pinging Zotero: %w", err)
+	}
+	return &Status{Version: header.Get("X-Zotero-Version"), Prefs: prefs.Prefs}, nil
+}
+
+// SelectedCollection returns the library or collection selected in the desktop app and the
+// targets items can be saved to
+func (c *Client) SelectedCollection(ctx context.Context) (*Selection, error) {
+	var selection Selection
+	if _, err := c.post(ctx, "getSelectedCollection", struct{}{}, &selection); err != nil {
+		return nil, fmt.Errorf("error getting selected collection: %w", err)
+	}
+	return &selection, nil
+}
+
+// SaveItems saves items with their notes and attachments into the selected collection, or into
+// opts.Target, and returns the session ID. Attachments with Content are uploaded after the
+// items are saved.
+func (c *Client) SaveItems(ctx context.Context, items []Item, opts *SaveOptions) (string, error) {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+	sessionID := opts.SessionID
+	if sessionID == "" {
+		sessionID = newID()
+	}
+
+	type upload struct {
+		parentID   string
+		attachment Attachment
+	}
+	var uploads []upload
+	payload := make([]map[string]any, len(items))
+	for i, item := range items {
+		data, err := itemJSON(item.Data)
+		if err != nil {
+			return "", fmt.Errorf("error converting item: %w", err)
+		}
+		id := newID()
+		data["id"] = id
+
+		notes := make([]map[string]any, len(item.Notes))
+		for j, note := range item.Notes {
+			notes[j] = map[string]any{"note": note}
+		}
+		data["notes"] = notes
+
+		attachments := []map[string]any{}
+		for _, attachment := range item.Attachments {
+			if attachment.Content != nil {
+				uploads = append(uploads, upload{parentID: id, attachment: attachment})
+				continue
+			}
+			attachments = append(attachments, map[string]any{
+				"title":    attachment.Title,
+				"url":      attachment.URL,
+				"mimeType": attachment.ContentType,
+			})
+		}
+		data["attachments"] = attachments
+		payload[i] = data
+	}
+
+	body := map[string]any{"sessionID": sessionID, "items": payload}
+	if opts.URI != "" {
+		body["uri"] = opts.URI
+	}
+	if _, err := c.post(ctx, "saveItems", body, nil); err != nil {
+		return "", fmt.Errorf("error saving items: %w", err)
+	}
+
+	for _, u := range uploads {
+		if err := c.saveAttachment(ctx, sessionID, u.parentID, u.attachment); err != nil {
+			return sessionID, err
+		}
+	}
+
+	if err := c.updateSession(ctx, sessionID, opts.Target, opts.Tags); err != nil {
+		return sessionID, err
+	}
+	return sessionID, nil
+}
+
+// SaveSnapshot saves a web page into the selected collection, or into opts.Target, and returns
+// the session ID. opts.URI is ignored.
+func (c *Client) SaveSnapshot(ctx context.Context, snapshot Snapshot, opts *SaveOptions) (string, error) {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+	sessionID := opts.SessionID
+	if sessionID == "" {
+		sessionID = newID()
+	}
+
+	body := map[string]any{"sessionID": sessionID, "url": snapshot.URL}
+	if snapshot.Title != "" {
+		body["title"] = snapshot.Title
+	}
+	if snapshot.HTML != "" {
+		body["html"] = snapshot.HTML
+	}
+	if snapshot.SkipSnapshot {
+		body["skipSnapshot"] = true
+	}
+	if _, err := c.post(ctx, "saveSnapshot", body, nil); err != nil {
+		return "", fmt.Errorf("error saving snapshot: %w", err)
+	}
+
+	if err := c.updateSession(ctx, sessionID, opts.Target, opts.Tags); err != nil {
+		return sessionID, err
+	}
+	return sessionID, nil
+}
+
+// UpdateSession moves the items of a save to target and replaces the tags added to them
+func (c *Client) UpdateSession(ctx context.Context, sessionID, target string, tags []string) error {
+	body := map[string]any{"sessionID": sessionID, "target": target, "tags": strings.Join(tags, ", ")}
+	if _, err := c.post(ctx, "updateSession", body, nil); err != nil {
+		return fmt.Errorf("error updating session: %w", err)
+	}
+	return nil
+}
+
+// updateSession applies the target and tags of SaveOptions to a save, if any are set
+func (c *Client) updateSession(ctx context.Context, sessionID, target string, tags []string) error {
+	if target == "" && len(tags) == 0 {
+		return nil
+	}
+	if target == "" {
+		selection, err := c.SelectedCollection(ctx)
+		if err != nil {
+			return err
+		}
+		target = selection.Target()
+	}
+	return c.UpdateSession(ctx, sessionID, target, tags)
+}
+
+// saveAttachment uploads the content of an attachment of the item with the connector ID parentID
+func (c *Client) saveAttachment(ctx context.Context, sessionID, parentID string, attachment Attachment) error {
+	metadata, err := json.Marshal(map[string]any{
+		"id":           newID(),
+		"parentItemID": parentID,
+		"sessionID":    sessionID,
+		"title":        attachment.Title,
+		"url":          attachment.URL,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding attachment metadata: %w", err)
+	}
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := http.Header{"Content-Type": {contentType}, "X-Metadata": {string(metadata)}}
+	if _, err := c.do(ctx, "saveAttachment", header, attachment.Content, nil); err != nil {
+		return fmt.Errorf("error saving attachment %q: %w", attachment.Title, err)
+	}
+	return nil
+}
+
+// post sends body as JSON to a connector endpoint and decodes a JSON response into result
+func (c *Client) post(ctx context.Context, endpoint string, body, result any) (http.Header, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+	return c.do(ctx, endpoint, http.Header{"Content-Type": {"application/json"}}, bytes.NewReader(data), result)
+}
+
+// do sends a POST request to a connector endpoint and decodes a JSON response into result, if
+// it is not nil. It returns the response headers.
+func (c *Client) do(ctx context.Context, endpoint string, header http.Header, body io.Reader, result any) (http.Header, error) {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/connector/"+endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header = header
+	req.Header.Set("X-Zotero-Connector-API-Version", strconv.Itoa(APIVersion))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("%w: %w", ErrNotRunning, err)
+		}
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+
+	// Some endpoints answer with plain text or nothing at all
+	if result != nil && bytes.HasPrefix(bytes.TrimSpace(respBody), []byte("{")) {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return nil, fmt.Errorf("error parsing response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// itemJSON converts item data to the connector's item format, which has the fields of the Web
+// API without the ones that belong to an existing item
+func itemJSON(data zotero.ItemData) (map[string]any, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var item map[string]any
+	if err := json.Unmarshal(encoded, &item); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"key", "version", "collections", "relations", "dateAdded", "dateModified", "parentItem", "md5", "mtime"} {
+		delete(item, field)
+	}
+	return item, nil
+}
+
+// newID returns a random ID for sessions and items
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,348 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// standInServer imitates the connector server of the desktop app. It keeps the saved items of
+// each session and refuses to save into a read-only library.
+type standInServer struct {
+	t        *testing.T
+	readOnly bool
+
+	mu          sync.Mutex
+	sessions    map[string][]map[string]any // Saved items by session ID
+	uploads     map[string]string           // Uploaded content by parent item ID
+	uploadTypes map[string]string           // Content types of uploads by parent item ID
+	snapshots   []map[string]any
+	updates     []map[string]any
+}
+
+func newStandIn(t *testing.T) (*httptest.Server, *standInServer, *Client) {
+	standIn := &standInServer{
+		t:           t,
+		sessions:    make(map[string][]map[string]any),
+		uploads:     make(map[string]string),
+		uploadTypes: make(map[string]string),
+	}
+	server := httptest.NewServer(standIn)
+	return server, standIn, &Client{BaseURL: server.URL}
+}
+
+func (s *standInServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if v := r.Header.Get("X-Zotero-Connector-API-Version"); v != "3" {
+		s.t.Errorf("%s: X-Zotero-Connector-API-Version = %q, want 3", r.URL.Path, v)
+	}
+
+	// Every endpoint except saveAttachment takes JSON
+	var data map[string]any
+	if r.URL.Path != "/connector/saveAttachment" {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			s.t.Errorf("%s: Content-Type = %q, want application/json", r.URL.Path, ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/connector/ping":
+		w.Header().Set("X-Zotero-Version", "7.0.11")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"prefs":{"automaticSnapshots":true,"downloadAssociatedFiles":true,"supportsAttachmentUpload":true}}`))
+
+	case "/connector/getSelectedCollection":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"libraryID":1,"libraryName":"My Library","libraryEditable":true,"filesEditable":true,"id":12,"name":"Reading",` +
+			`"targets":[{"id":"L1","name":"My Library","level":0,"filesEditable":true},{"id":"C12","name":"Reading","level":1,"filesEditable":true}]}`))
+
+	case "/connector/saveItems":
+		if s.readOnly {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"libraryEditable":false}`))
+			return
+		}
+		sessionID, _ := data["sessionID"].(string)
+		if _, ok := s.sessions[sessionID]; ok {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"SESSION_EXISTS"}`))
+			return
+		}
+		var items []map[string]any
+		for _, item := range data["items"].([]any) {
+			items = append(items, item.(map[string]any))
+		}
+		s.sessions[sessionID] = items
+		w.WriteHeader(http.StatusCreated)
+
+	case "/connector/saveAttachment":
+		var metadata map[string]string
+		if err := json.Unmarshal([]byte(r.Header.Get("X-Metadata")), &metadata); err != nil {
+			http.Error(w, "invalid metadata", http.StatusBadRequest)
+			return
+		}
+		items, ok := s.sessions[metadata["sessionID"]]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"SESSION_NOT_FOUND"}`))
+			return
+		}
+		found := false
+		for _, item := range items {
+			found = found || item["id"] == metadata["parentItemID"]
+		}
+		if !found {
+			http.Error(w, "parent item not found", http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(r.Body)
+		s.uploads[metadata["parentItemID"]] = string(content)
+		s.uploadTypes[metadata["parentItemID"]] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusCreated)
+
+	case "/connector/saveSnapshot":
+		s.snapshots = append(s.snapshots, data)
+		s.sessions[data["sessionID"].(string)] = nil
+		w.WriteHeader(http.StatusCreated)
+
+	case "/connector/updateSession":
+		if _, ok := s.sessions[data["sessionID"].(string)]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"SESSION_NOT_FOUND"}`))
+			return
+		}
+		s.updates = append(s.updates, data)
+		w.Write([]byte(`{}`))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server, _, client := newStandIn(t)
+	defer server.Close()
+
+	status, err := client.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if status.Version != "7.0.11" || !status.Prefs.SupportsAttachmentUpload || status.Prefs.SupportsTagsAutocomplete {
+		t.Errorf("Ping() = %+v", status)
+	}
+}
+
+func TestPingNotRunning(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	client := &Client{BaseURL: server.URL}
+	server.Close()
+
+	if _, err := client.Ping(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Ping() error = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestSelectedCollection(t *testing.T) {
+	server, _, client := newStandIn(t)
+	defer server.Close()
+
+	selection, err := client.SelectedCollection(context.Background())
+	if err != nil {
+		t.Fatalf("SelectedCollection() error = %v", err)
+	}
+	if selection.Name != "Reading" || selection.Target() != "C12" || len(selection.Targets) != 2 || selection.Targets[1].Level != 1 {
+		t.Errorf("SelectedCollection() = %+v", selection)
+	}
+
+	library := &Selection{LibraryID: 1}
+	if library.Target() != "L1" {
+		t.Errorf("Target() = %s, want L1", library.Target())
+	}
+}
+
+func TestSaveItems(t *testing.T) {
+	server, standIn, client := newStandIn(t)
+	defer server.Close()
+
+	data := zotero.ItemData{
+		Key:         "ABCD1234",
+		Version:     5,
+		ItemType:    "journalArticle",
+		Title:       "On Connectors",
+		Creators:    []zotero.Creator{{CreatorType: "author", FirstName: "Ada", LastName: "Lovelace"}},
+		Tags:        []zotero.Tag{{Tag: "protocols"}},
+		Collections: []string{"COLL1234"},
+		Extra:       map[string]any{"DOI": "10.1000/xyz", "date": "2024"},
+	}
+	sessionID, err := client.SaveItems(context.Background(), []Item{{
+		Data:  data,
+		Notes: []string{"<p>Read this</p>"},
+		Attachments: []Attachment{
+			{Title: "Full Text PDF", URL: "https://example.com/paper.pdf", ContentType: "application/pdf"},
+			{Title: "Dataset", ContentType: "text/csv", Content: strings.NewReader("a,b\n1,2\n")},
+		},
+	}}, &SaveOptions{URI: "https://example.com/paper"})
+	if err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+
+	items := standIn.sessions[sessionID]
+	if len(items) != 1 {
+		t.Fatalf("saved %d items in session %q, want 1", len(items), sessionID)
+	}
+	item := items[0]
+	if item["title"] != "On Connectors" || item["DOI"] != "10.1000/xyz" || item["date"] != "2024" {
+		t.Errorf("saved item = %v", item)
+	}
+	for _, field := range []string{"key", "version", "collections"} {
+		if _, ok := item[field]; ok {
+			t.Errorf("saved item has %s", field)
+		}
+	}
+	if notes := item["notes"].([]any); len(notes) != 1 || notes[0].(map[string]any)["note"] != "<p>Read this</p>" {
+		t.Errorf("notes = %v", item["notes"])
+	}
+	attachments := item["attachments"].([]any)
+	if len(attachments) != 1 || attachments[0].(map[string]any)["url"] != "https://example.com/paper.pdf" {
+		t.Errorf("attachments = %v", attachments)
+	}
+
+	id := item["id"].(string)
+	if standIn.uploads[id] != "a,b\n1,2\n" || standIn.uploadTypes[id] != "text/csv" {
+		t.Errorf("upload = %q (%s)", standIn.uploads[id], standIn.uploadTypes[id])
+	}
+	if len(standIn.updates) != 0 {
+		t.Errorf("updated session %d times, want none", len(standIn.updates))
+	}
+}
+
+func TestSaveItemsTargetAndTags(t *testing.T) {
+	server, standIn, client := newStandIn(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	items := []Item{{Data: zotero.ItemData{ItemType: "book", Title: "Tagged"}}}
+	if _, err := client.SaveItems(ctx, items, &SaveOptions{Target: "L1"}); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+	// Tags without a target keep the items in the selected collection
+	if _, err := client.SaveItems(ctx, items, &SaveOptions{Tags: []string{"to read", "cli"}}); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+
+	if len(standIn.updates) != 2 {
+		t.Fatalf("updated session %d times, want 2", len(standIn.updates))
+	}
+	if standIn.updates[0]["target"] != "L1" || standIn.updates[0]["tags"] != "" {
+		t.Errorf("first update = %v", standIn.updates[0])
+	}
+	if standIn.updates[1]["target"] != "C12" || standIn.updates[1]["tags"] != "to read, cli" {
+		t.Errorf("second update = %v", standIn.updates[1])
+	}
+}
+
+func TestSaveItemsErrors(t *testing.T) {
+	server, standIn, client := newStandIn(t)
+	defer server.Close()
+	ctx := context.Background()
+	items := []Item{{Data: zotero.ItemData{ItemType: "book", Title: "Twice"}}}
+
+	if _, err := client.SaveItems(ctx, items, &SaveOptions{SessionID: "session-1"}); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+	_, err := client.SaveItems(ctx, items, &SaveOptions{SessionID: "session-1"})
+	var connErr *Error
+	if !errors.As(err, &connErr) || connErr.StatusCode != http.StatusConflict {
+		t.Errorf("SaveItems() with a used session ID error = %v, want status 409", err)
+	}
+	if errors.Is(err, ErrLibraryNotEditable) {
+		t.Errorf("SaveItems() error = %v, want no ErrLibraryNotEditable", err)
+	}
+
+	standIn.readOnly = true
+	if _, err := client.SaveItems(ctx, items, nil); !errors.Is(err, ErrLibraryNotEditable) {
+		t.Errorf("SaveItems() error = %v, want ErrLibraryNotEditable", err)
+	}
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	server, standIn, client := newStandIn(t)
+	defer server.Close()
+
+	sessionID, err := client.SaveSnapshot(context.Background(), Snapshot{
+		URL:   "https://example.com/post",
+		Title: "A Post",
+		HTML:  "<html><body>Post</body></html>",
+	}, &SaveOptions{Target: "C12"})
+	if err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+
+	if len(standIn.snapshots) != 1 {
+		t.Fatalf("saved %d snapshots, want 1", len(standIn.snapshots))
+	}
+	snapshot := standIn.snapshots[0]
+	if snapshot["sessionID"] != sessionID || snapshot["url"] != "https://example.com/post" || snapshot["html"] != "<html><body>Post</body></html>" {
+		t.Errorf("snapshot = %v", snapshot)
+	}
+	if _, ok := snapshot["skipSnapshot"]; ok {
+		t.Error("snapshot has skipSnapshot")
+	}
+	if len(standIn.updates) != 1 || standIn.updates[0]["target"] != "C12" {
+		t.Errorf("updates = %v", standIn.updates)
+	}
+}
+
+func TestUpdateSessionUnknown(t *testing.T) {
+	server, _, client := newStandIn(t)
+	defer server.Close()
+
+	err := client.UpdateSession(context.Background(), "missing", "L1", nil)
+	var connErr *Error
+	if !errors.As(err, &connErr) || connErr.StatusCode != http.StatusBadRequest || !strings.Contains(connErr.Message, "SESSION_NOT_FOUND") {
+		t.Errorf("UpdateSession() error = %v, want SESSION_NOT_FOUND", err)
+	}
+}
+
+func TestItemJSON(t *testing.T) {
+	item, err := itemJSON(zotero.ItemData{
+		Key:          "ABCD1234",
+		Version:      3,
+		ItemType:     "book",
+		Title:        "Book",
+		DateAdded:    "2024-01-01T00:00:00Z",
+		DateModified: "2024-01-02T00:00:00Z",
+		Relations:    zotero.Relations{DCRelation: "http://zotero.org/users/1/items/XYZ"},
+		Extra:        map[string]any{"publisher": "Press"},
+	})
+	if err != nil {
+		t.Fatalf("itemJSON() error = %v", err)
+	}
+	want := map[string]any{"itemType": "book", "title": "Book", "publisher": "Press"}
+	if len(item) != len(want) {
+		t.Errorf("itemJSON() = %v, want %v", item, want)
+	}
+	for k, v := range want {
+		if item[k] != v {
+			t.Errorf("itemJSON()[%s] = %v, want %v", k, item[k], v)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+package localdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// linkModes maps the linkMode column of itemAttachments to link modes
+var linkModes = map[int]zotero.LinkMode{
+	0: zotero.LinkModeImportedFile,
+	1: zotero.LinkModeImportedURL,
+	2: zotero.LinkModeLinkedFile,
+	3: zotero.LinkModeLinkedURL,
+	4: zotero.LinkModeEmbeddedImage,
+}
+
+// annotationTypes maps the type column of itemAnnotations to annotation types
+var annotationTypes = map[int]string{
+	1: "highlight",
+	2: "note",
+	3: "image",
+	4: "ink",
+	5: "underline",
+	6: "text",
+}
+
+// dateFields hold multipart dates, which the database stores as "2021-05-00 May 2021"
+var dateFields = map[string]bool{"date": true, "dateDecided": true, "dateEnacted": true, "issueDate": true, "filingDate": true}
+
+var multipartDatePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}) (.*)$`)
+
+// load reads a library from the database at path
+func (db *DB) load(ctx context.Context, path string, groupID int) error {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer conn.Close()
+
+	l := &loader{ctx: ctx, conn: conn, db: db, itemKeys: make(map[int64]string), items: make(map[int64]*zotero.Item)}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"library", func() error { return l.loadLibrary(groupID) }},
+		{"items", l.loadItems},
+		{"item fields", l.loadFields},
+		{"creators", l.loadCreators},
+		{"tags", l.loadTags},
+		{"relations", l.loadRelations},
+		{"notes", l.loadNotes},
+		{"attachments", l.loadAttachments},
+		{"annotations", l.loadAnnotations},
+		{"trash", l.loadTrash},
+		{"collections", l.loadCollections},
+		{"searches", l.loadSearches},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return fmt.Errorf("error reading %s: %w", step.name, err)
+		}
+	}
+
+	l.finish()
+	return nil
+}
+
+// loader holds the state of a load: the library's ID and its items by itemID
+type loader struct {
+	ctx       context.Context
+	conn      *sql.DB
+	db        *DB
+	libraryID int64
+	order     []int64 // itemIDs in the order they were read
+	itemKeys  map[int64]string
+	items     map[int64]*zotero.Item
+}
+
+// each runs a query for the library and calls scan for every row
+func (l *loader) each(query string, scan func(*sql.Rows) error) error {
+	rows, err := l.conn.QueryContext(l.ctx, query, l.libraryID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (l *loader) loadLibrary(groupID int) error {
+	if groupID != 0 {
+		var name sql.NullString
+		err := l.conn.QueryRowContext(l.ctx,
+			`SELECT g.libraryID, g.name, l.version FROM groups g JOIN libraries l USING (libraryID) WHERE g.groupID = ?`, groupID,
+		).Scan(&l.libraryID, &name, &l.db.version)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("group %d: %w", groupID, zotero.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		l.db.library = zotero.Library{Type: "group", ID: groupID, Name: name.String}
+		return nil
+	}
+
+	err := l.conn.QueryRowContext(l.ctx, `SELECT libraryID, version FROM libraries WHERE type = 'user'`).Scan(&l.libraryID, &l.db.version)
+	if err != nil {
+		return err
+	}
+	l.db.library = zotero.Library{Type: "user"}
+
+	// The account settings are only present once the app has been linked to a zotero.org account
+	rows, err := l.conn.QueryContext(l.ctx, `SELECT key, value FROM settings WHERE setting = 'account'`)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var value any
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		switch key {
+		case "userID":
+			fmt.Sscan(valueText(value), &l.db.library.ID)
+		case "username":
+			l.db.library.Name = valueText(value)
+		}
+	}
+	return rows.Err()
+}
+
+// loadItems reads the items of the library, except for external annotations, which the app
+// reads from PDF files and which are not part of the library
+func (l *loader) loadItems() error {
+	return l.each(`SELECT i.itemID, i.key, i.version, t.typeName, i.dateAdded, i.dateModified
+		FROM items i JOIN itemTypes t USING (itemTypeID)
+		WHERE i.libraryID = ? AND i.itemID NOT IN (SELECT itemID FROM itemAnnotations WHERE isExternal)
+		ORDER BY i.itemID`, func(rows *sql.Rows) error {
+		var id int64
+		var item zotero.Item
+		var dateAdded, dateModified string
+		if err := rows.Scan(&id, &item.Key, &item.Version, &item.Data.ItemType, &dateAdded, &dateModified); err != nil {
+			return err
+		}
+		item.Library = l.db.library
+		item.Data.Key = item.Key
+		item.Data.Version = item.Version
+		item.Data.DateAdded = isoTimestamp(dateAdded)
+		item.Data.DateModified = isoTimestamp(dateModified)
+		l.order = append(l.order, id)
+		l.itemKeys[id] = item.Key
+		l.items[id] = &item
+		return nil
+	})
+}
+
+func (l *loader) loadFields() error {
+	return l.each(`SELECT d.itemID, f.fieldName, v.value
+		FROM itemData d JOIN fields f USING (fieldID) JOIN itemDataValues v USING (valueID) JOIN items i USING (itemID)
+		WHERE i.libraryID = ?`, func(rows *sql.Rows) error {
+		var id int64
+		var name string
+		var value any
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		text := valueText(value)
+		switch {
+		case name == "accessDate":
+			text = isoTimestamp(text)
+		case dateFields[name]:
+			if m := multipartDatePattern.FindStringSubmatch(text); m != nil {
+				text = m[2]
+				if name == "date" {
+					item.Meta.ParsedDate = parsedDate(m[1])
+				}
+			}
+		}
+		if err := item.Data.SetField(name, text); err != nil {
+			if item.Data.Extra == nil {
+				item.Data.Extra = make(map[string]any)
+			}
+			item.Data.Extra[name] = text
+		}
+		return nil
+	})
+}
+
+func (l *loader) loadCreators() error {
+	return l.each(`SELECT ic.itemID, t.creatorType, c.firstName, c.lastName, c.fieldMode
+		FROM itemCreators ic JOIN creators c USING (creatorID) JOIN creatorTypes t USING (creatorTypeID) JOIN items i USING (itemID)
+		WHERE i.libraryID = ? ORDER BY ic.itemID, ic.orderIndex`, func(rows *sql.Rows) error {
+		var id int64
+		var creator zotero.Creator
+		var firstName, lastName sql.NullString
+		var fieldMode sql.NullInt64
+		if err := rows.Scan(&id, &creator.CreatorType, &firstName, &lastName, &fieldMode); err != nil {
+			return err
+		}
+		if fieldMode.Int64 == 1 {
+			creator.Name = lastName.String
+		} else {
+			creator.FirstName, creator.LastName = firstName.String, lastName.String
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		item.Data.Creators = append(item.Data.Creators, creator)
+		return nil
+	})
+}
+
+func (l *loader) loadTags() error {
+	return l.each(`SELECT it.itemID, t.name, it.type
+		FROM itemTags it JOIN tags t USING (tagID) JOIN items i USING (itemID)
+		WHERE i.libraryID = ? ORDER BY t.name`, func(rows *sql.Rows) error {
+		var id int64
+		var tag zotero.Tag
+		if err := rows.Scan(&id, &tag.Tag, &tag.Type); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		item.Data.Tags = append(item.Data.Tags, tag)
+		return nil
+	})
+}
+
+func (l *loader) loadRelations() error {
+	return l.each(`SELECT r.itemID, p.predicate, r.object
+		FROM itemRelations r JOIN relationPredicates p USING (predicateID) JOIN items i USING (itemID)
+		WHERE i.libraryID = ? ORDER BY r.itemID, r.object`, func(rows *sql.Rows) error {
+		var id int64
+		var predicate, object string
+		if err := rows.Scan(&id, &predicate, &object); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		relations := &item.Data.Relations
+		switch predicate {
+		case "owl:sameAs":
+			relations.OwlSameAs = addRelation(relations.OwlSameAs, object)
+		case "dc:relation":
+			relations.DCRelation = addRelation(relations.DCRelation, object)
+		case "dc:replaces":
+			relations.DCReplaces = addRelation(relations.DCReplaces, object)
+		case "dc:isReplacedBy":
+			relations.DCIsReplacedBy = addRelation(relations.DCIsReplacedBy, object)
+		}
+		return nil
+	})
+}
+
+// addRelation adds an object to a relation, which the API encodes as a string for one object
+// and an array for several
+func addRelation(relation any, object string) any {
+	switch v := relation.(type) {
+	case string:
+		return []string{v, object}
+	case []string:
+		return append(v, object)
+	}
+	return object
+}
+
+func (l *loader) loadNotes() error {
+	return l.each(`SELECT n.itemID, n.parentItemID, n.note
+		FROM itemNotes n JOIN items i USING (itemID) WHERE i.libraryID = ?`, func(rows *sql.Rows) error {
+		var id int64
+		var parentID sql.NullInt64
+		var note sql.NullString
+		if err := rows.Scan(&id, &parentID, &note); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		// Attachments keep their notes here too, but their parents in itemAttachments
+		data := &item.Data
+		if data.ItemType == zotero.ItemTypeNote {
+			data.ParentItem = l.itemKeys[parentID.Int64]
+		}
+		data.Note = note.String
+		return nil
+	})
+}
+
+func (l *loader) loadAttachments() error {
+	return l.each(`SELECT a.itemID, a.parentItemID, a.linkMode, a.contentType, c.charset, a.path, a.storageModTime, a.storageHash
+		FROM itemAttachments a LEFT JOIN charsets c USING (charsetID) JOIN items i USING (itemID)
+		WHERE i.libraryID = ?`, func(rows *sql.Rows) error {
+		var id int64
+		var parentID, linkMode, mtime sql.NullInt64
+		var contentType, charset, path, md5 sql.NullString
+		if err := rows.Scan(&id, &parentID, &linkMode, &contentType, &charset, &path, &mtime, &md5); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		data := &item.Data
+		data.ParentItem = l.itemKeys[parentID.Int64]
+		data.LinkMode = linkModes[int(linkMode.Int64)]
+		data.ContentType = contentType.String
+		data.Charset = charset.String
+		data.MD5 = md5.String
+		data.MTime = mtime.Int64
+		if filename, ok := strings.CutPrefix(path.String, "storage:"); ok {
+			data.Filename = filename
+		} else if data.LinkMode == zotero.LinkModeLinkedFile {
+			data.Path = path.String
+		}
+		return nil
+	})
+}
+
+func (l *loader) loadAnnotations() error {
+	return l.each(`SELECT a.itemID, a.parentItemID, a.type, a.authorName, a.text, a.comment, a.color, a.pageLabel, a.sortIndex, a.position
+		FROM itemAnnotations a JOIN items i USING (itemID)
+		WHERE i.libraryID = ?`, func(rows *sql.Rows) error {
+		var id, parentID int64
+		var annotationType int
+		var authorName, text, comment, color, pageLabel, sortIndex, position sql.NullString
+		if err := rows.Scan(&id, &parentID, &annotationType, &authorName, &text, &comment, &color, &pageLabel, &sortIndex, &position); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		data := &item.Data
+		data.ParentItem = l.itemKeys[parentID]
+		data.AnnotationType = annotationTypes[annotationType]
+		data.AnnotationText = text.String
+		data.AnnotationComment = comment.String
+		data.AnnotationColor = color.String
+		data.AnnotationPageLabel = pageLabel.String
+		data.AnnotationSortIndex = sortIndex.String
+		data.AnnotationPosition = position.String
+		if authorName.String != "" {
+			if data.Extra == nil {
+				data.Extra = make(map[string]any)
+			}
+			data.Extra["annotationAuthorName"] = authorName.String
+		}
+		return nil
+	})
+}
+
+func (l *loader) loadTrash() error {
+	return l.each(`SELECT d.itemID FROM deletedItems d JOIN items i USING (itemID) WHERE i.libraryID = ?`, func(rows *sql.Rows) error {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		item, ok := l.items[id]
+		if !ok {
+			return nil
+		}
+		if item.Data.Extra == nil {
+			item.Data.Extra = make(map[string]any)
+		}
+		item.Data.Extra["deleted"] = true
+		return nil
+	})
+}
+
+func (l *loader) loadCollections() error {
+	collectionKeys := make(map[int64]string)
+	parents := make(map[int64]int64)
+	var order []int64
+	collections := make(map[int64]*zotero.Collection)
+	err := l.each(`SELECT collectionID, collectionName, parentCollectionID, key, version
+		FROM collections WHERE libraryID = ? ORDER BY collectionID`, func(rows *sql.Rows) error {
+		var id int64
+		var parentID sql.NullInt64
+		collection := &zotero.Collection{Library: l.db.library}
+		if err := rows.Scan(&id, &collection.Data.Name, &parentID, &collection.Key, &collection.Version); err != nil {
+			return err
+		}
+		collection.Data.Key = collection.Key
+		collection.Data.Version = collection.Version
+		collectionKeys[id] = collection.Key
+		parents[id] = parentID.Int64
+		order = append(order, id)
+		collections[id] = collection
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range order {
+		if parent, ok := collections[parents[id]]; ok {
+			collections[id].Data.ParentCollection = zotero.ParentCollectionRef(parent.Key)
+			parent.Meta.NumCollections++
+		}
+	}
+
+	err = l.each(`SELECT ci.collectionID, ci.itemID FROM collectionItems ci JOIN collections c USING (collectionID)
+		WHERE c.libraryID = ? ORDER BY ci.collectionID, ci.orderIndex`, func(rows *sql.Rows) error {
+		var collectionID, itemID int64
+		if err := rows.Scan(&collectionID, &itemID); err != nil {
+			return err
+		}
+		item, ok := l.items[itemID]
+		if !ok {
+			return nil
+		}
+		item.Data.Collections = append(item.Data.Collections, collectionKeys[collectionID])
+		if !item.Data.InTrash() {
+			collections[collectionID].Meta.NumItems++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		l.db.collections = append(l.db.collections, *collections[id])
+	}
+	return nil
+}
+
+func (l *loader) loadSearches() error {
+	var order []int64
+	searches := make(map[int64]*zotero.Search)
+	err := l.each(`SELECT savedSearchID, savedSearchName, key, version
+		FROM savedSearches WHERE libraryID = ? ORDER BY savedSearchID`, func(rows *sql.Rows) error {
+		var id int64
+		search := &zotero.Search{Library: l.db.library}
+		if err := rows.Scan(&id, &search.Data.Name, &search.Key, &search.Version); err != nil {
+			return err
+		}
+		search.Data.Key = search.Key
+		search.Data.Version = search.Version
+		search.Data.Conditions = []zotero.SearchCondition{}
+		order = append(order, id)
+		searches[id] = search
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = l.each(`SELECT c.savedSearchID, c.condition, c.operator, c.value
+		FROM savedSearchConditions c JOIN savedSearches s USING (savedSearchID)
+		WHERE s.libraryID = ? ORDER BY c.savedSearchID, c.searchConditionID`, func(rows *sql.Rows) error {
+		var id int64
+		var condition, operator string
+		var value sql.NullString
+		if err := rows.Scan(&id, &condition, &operator, &value); err != nil {
+			return err
+		}
+		search := searches[id]
+		search.Data.Conditions = append(search.Data.Conditions, zotero.SearchCondition{
+			Condition: zotero.Condition(condition),
+			Operator:  zotero.Operator(operator),
+			Value:     value.String,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		l.db.searches = append(l.db.searches, *searches[id])
+	}
+	return nil
+}
+
+// finish fills in the metadata that depends on other items and stores the items in the DB
+func (l *loader) finish() {
+	l.db.itemIndex = make(map[string]int, len(l.order))
+	for i, id := range l.order {
+		l.db.itemIndex[l.itemKeys[id]] = i
+	}
+
+	l.db.items = make([]zotero.Item, len(l.order))
+	for i, id := range l.order {
+		item := l.items[id]
+		item.Meta.CreatorSummary = item.Data.CreatorSummary()
+		l.db.items[i] = *item
+	}
+
+	// The API counts the notes and attachments outside the trash as children
+	for _, item := range l.db.items {
+		parent, ok := l.db.itemIndex[item.Data.ParentItem]
+		if !ok || item.Data.ItemType == zotero.ItemTypeAnnotation || item.Data.InTrash() {
+			continue
+		}
+		l.db.items[parent].Meta.NumChildren++
+	}
+}
+
+// parsedDate converts the SQL part of a multipart date ("2021-05-00") to the API's parsedDate
+// ("2021-05")
+func parsedDate(sqlDate string) string {
+	date := strings.TrimSuffix(strings.TrimSuffix(sqlDate, "-00"), "-00")
+	if date == "0000" {
+		return ""
+	}
+	return date
+}
+
+// isoTimestamp converts a timestamp stored in UTC as "2006-01-02 15:04:05" to ISO 8601. The
+// driver may already have parsed it into RFC 3339.
+func isoTimestamp(value string) string {
+	for _, layout := range []string{time.DateTime, time.RFC3339Nano} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+// valueText formats a value of itemDataValues, which holds numbers as well as text
+func valueText(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,384 @@
+// Package localdb reads a library straight from the database of the Zotero desktop app,
+// zotero.sqlite, for offline analysis without the Web API.
+//
+// Open copies the database (the running app keeps it locked), loads a library into memory and
+// deletes the copy. The DB answers the same read calls as zotero.Client, returning the same
+// Item, Collection and Search values, and resolves attachment files in the storage directory.
+//
+//	db, err := localdb.Open(ctx, "/home/me/Zotero/zotero.sqlite", nil)
+//	items, err := db.Top(ctx, &zotero.QueryParams{Sort: "title"})
+//	path, err := db.LocalFilePath(ctx, "ATTACH01")
+package localdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var (
+	_ zotero.ItemReader       = (*DB)(nil)
+	_ zotero.CollectionReader = (*DB)(nil)
+	_ zotero.SearchReader     = (*DB)(nil)
+)
+
+// Options configures which library Open reads
+type Options struct {
+	// GroupID selects a group library; 0 reads the user's library
+	GroupID int
+
+	// BaseDir resolves linked files stored relative to the "Linked Attachment Base Directory"
+	// set in the app's preferences, which the database does not record
+	BaseDir string
+}
+
+// DB is a library loaded from zotero.sqlite. It is read-only and safe for concurrent use.
+type DB struct {
+	dataDir string
+	baseDir string
+	library zotero.Library
+	version int
+
+	items       []zotero.Item // In the order of the database
+	itemIndex   map[string]int
+	collections []zotero.Collection
+	searches    []zotero.Search
+}
+
+// Open loads a library from a snapshot of the database at path, which is either zotero.sqlite
+// or the Zotero data directory containing it
+func Open(ctx context.Context, path string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "zotero.sqlite")
+	}
+
+	snapshot, err := snapshotDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(snapshot))
+
+	db := &DB{dataDir: filepath.Dir(path), baseDir: opts.BaseDir}
+	if err := db.load(ctx, snapshot, opts.GroupID); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// snapshotDatabase copies the database and its write-ahead log into a temporary directory and
+// returns the path of the copy
+func snapshotDatabase(path string) (string, error) {
+	dir, err := os.MkdirTemp("", "zotero-localdb-")
+	if err != nil {
+		return "", fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+	snapshot := filepath.Join(dir, "zotero.sqlite")
+	for _, suffix := range []string{"", "-wal"} {
+		err := copyFile(path+suffix, snapshot+suffix)
+		if suffix != "" && errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("error copying database: %w", err)
+		}
+	}
+	return snapshot, nil
+}
+
+// copyFile streams src to a new file dst, so large databases are not held in memory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Library returns the library that was loaded
+func (db *DB) Library() zotero.Library {
+	return db.library
+}
+
+// LastModifiedVersion returns the library's version as of its last sync
+func (db *DB) LastModifiedVersion(ctx context.Context) (int, error) {
+	return db.version, nil
+}
+
+// NumItems returns the number of items outside the trash
+func (db *DB) NumItems(ctx context.Context) (int, error) {
+	return len(zotero.FilterItems(db.items, nil)), nil
+}
+
+// Items returns the library's items. Unlike the API, Limit 0 returns every item.
+func (db *DB) Items(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	return zotero.FilterItems(db.items, params), nil
+}
+
+// Top returns the library's top-level items
+func (db *DB) Top(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	return zotero.FilterItems(db.selectItems(isTop), params), nil
+}
+
+// Item returns an item by key
+func (db *DB) Item(ctx context.Context, itemKey string, params *zotero.QueryParams) (*zotero.Item, error) {
+	i, ok := db.itemIndex[itemKey]
+	if !ok {
+		return nil, fmt.Errorf("item %s: %w", itemKey, zotero.ErrNotFound)
+	}
+	item := db.items[i]
+	return &item, nil
+}
+
+// Children returns the child items of an item
+func (db *DB) Children(ctx context.Context, itemKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	if _, ok := db.itemIndex[itemKey]; !ok {
+		return nil, fmt.Errorf("item %s: %w", itemKey, zotero.ErrNotFound)
+	}
+	return zotero.FilterItems(db.selectItems(func(item *zotero.Item) bool {
+		return item.Data.ParentItem == itemKey
+	}), params), nil
+}
+
+// Trash returns the items in the trash
+func (db *DB) Trash(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	trashParams := zotero.QueryParams{}
+	if params != nil {
+		trashParams = *params
+	}
+	trashParams.IncludeTrashed = true
+	return zotero.FilterItems(db.selectItems(func(item *zotero.Item) bool { return item.Data.InTrash() }), &trashParams), nil
+}
+
+// Notes returns the child notes of an item
+func (db *DB) Notes(ctx context.Context, parentKey string, params *zotero.QueryParams) ([]zotero.Note, error) {
+	items, err := db.Children(ctx, parentKey, withItemType(params, zotero.ItemTypeNote))
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]zotero.Note, 0, len(items))
+	for i := range items {
+		if note, err := zotero.NoteFromItem(&items[i]); err == nil {
+			notes = append(notes, *note)
+		}
+	}
+	return notes, nil
+}
+
+// Annotations returns the annotations of an attachment
+func (db *DB) Annotations(ctx context.Context, attachmentKey string, params *zotero.QueryParams) ([]zotero.Annotation, error) {
+	items, err := db.Children(ctx, attachmentKey, withItemType(params, zotero.ItemTypeAnnotation))
+	if err != nil {
+		return nil, err
+	}
+	annotations := make([]zotero.Annotation, 0, len(items))
+	for i := range items {
+		annotation, err := zotero.AnnotationFromItem(&items[i])
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, *annotation)
+	}
+	return annotations, nil
+}
+
+// Collections returns the library's collections
+func (db *DB) Collections(ctx context.Context, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	return zotero.FilterCollections(db.collections, params), nil
+}
+
+// CollectionsTop returns the library's top-level collections
+func (db *DB) CollectionsTop(ctx context.Context, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	return zotero.FilterCollections(db.selectCollections(""), params), nil
+}
+
+// Collection returns a collection by key
+func (db *DB) Collection(ctx context.Context, collectionKey string, params *zotero.QueryParams) (*zotero.Collection, error) {
+	for _, collection := range db.collections {
+		if collection.Key == collectionKey {
+			return &collection, nil
+		}
+	}
+	return nil, fmt.Errorf("collection %s: %w", collectionKey, zotero.ErrNotFound)
+}
+
+// CollectionsSub returns the subcollections of a collection
+func (db *DB) CollectionsSub(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	if _, err := db.Collection(ctx, collectionKey, nil); err != nil {
+		return nil, err
+	}
+	return zotero.FilterCollections(db.selectCollections(collectionKey), params), nil
+}
+
+// CollectionItems returns the items in a collection
+func (db *DB) CollectionItems(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	if _, err := db.Collection(ctx, collectionKey, nil); err != nil {
+		return nil, err
+	}
+	return zotero.FilterItems(db.selectItems(inCollection(collectionKey)), params), nil
+}
+
+// CollectionItemsTop returns the top-level items in a collection
+func (db *DB) CollectionItemsTop(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	if _, err := db.Collection(ctx, collectionKey, nil); err != nil {
+		return nil, err
+	}
+	inCollection := inCollection(collectionKey)
+	return zotero.FilterItems(db.selectItems(func(item *zotero.Item) bool {
+		return isTop(item) && inCollection(item)
+	}), params), nil
+}
+
+// Searches returns the library's saved searches
+func (db *DB) Searches(ctx context.Context, params *zotero.QueryParams) ([]zotero.Search, error) {
+	return zotero.FilterSearches(db.searches, params), nil
+}
+
+// Search returns a saved search by key
+func (db *DB) Search(ctx context.Context, searchKey string, params *zotero.QueryParams) (*zotero.Search, error) {
+	for _, search := range db.searches {
+		if search.Key == searchKey {
+			return &search, nil
+		}
+	}
+	return nil, fmt.Errorf("search %s: %w", searchKey, zotero.ErrNotFound)
+}
+
+// SearchItems runs a saved search with zotero.SearchEvaluator. Conditions on full-text content
+// return zotero.ErrUnsupportedCondition.
+func (db *DB) SearchItems(ctx context.Context, searchKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	search, err := db.Search(ctx, searchKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	eval := &zotero.SearchEvaluator{Items: db.items, Collections: db.collections, Searches: db.searches}
+	matches, err := eval.MatchSearch(search.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error running search %s: %w", searchKey, err)
+	}
+	return zotero.FilterItems(matches, params), nil
+}
+
+// Tags returns the tags of the items outside the trash, with their number of items
+func (db *DB) Tags(ctx context.Context, params *zotero.QueryParams) ([]zotero.TagsResponse, error) {
+	return zotero.CountTags(zotero.FilterItems(db.items, nil), params), nil
+}
+
+// ItemTags returns the tags of an item
+func (db *DB) ItemTags(ctx context.Context, itemKey string, params *zotero.QueryParams) ([]zotero.Tag, error) {
+	item, err := db.Item(ctx, itemKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return item.Data.Tags, nil
+}
+
+// CollectionTags returns the tags of the items in a collection
+func (db *DB) CollectionTags(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.TagsResponse, error) {
+	items, err := db.CollectionItems(ctx, collectionKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return zotero.CountTags(items, params), nil
+}
+
+// LocalFilePath returns the path of an attachment's file: in the storage directory for stored
+// files, or the linked path for linked files
+func (db *DB) LocalFilePath(ctx context.Context, itemKey string) (string, error) {
+	item, err := db.Item(ctx, itemKey, nil)
+	if err != nil {
+		return "", err
+	}
+	data := &item.Data
+	switch data.LinkMode {
+	case zotero.LinkModeImportedFile, zotero.LinkModeImportedURL, zotero.LinkModeEmbeddedImage:
+		if data.Filename == "" {
+			return "", fmt.Errorf("attachment %s has no stored file", itemKey)
+		}
+		return filepath.Join(db.dataDir, "storage", item.Key, data.Filename), nil
+	case zotero.LinkModeLinkedFile:
+		if relative, ok := strings.CutPrefix(data.Path, "attachments:"); ok {
+			if db.baseDir == "" {
+				return "", fmt.Errorf("attachment %s is relative to the linked attachment base directory, which is not set", itemKey)
+			}
+			return filepath.Join(db.baseDir, filepath.FromSlash(relative)), nil
+		}
+		return data.Path, nil
+	}
+	return "", fmt.Errorf("item %s is not a file attachment", itemKey)
+}
+
+// File returns the content of an attachment's file
+func (db *DB) File(ctx context.Context, itemKey string) ([]byte, error) {
+	path, err := db.LocalFilePath(ctx, itemKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return data, nil
+}
+
+// selectItems returns the items for which keep returns true
+func (db *DB) selectItems(keep func(*zotero.Item) bool) []zotero.Item {
+	var items []zotero.Item
+	for i := range db.items {
+		if keep(&db.items[i]) {
+			items = append(items, db.items[i])
+		}
+	}
+	return items
+}
+
+// selectCollections returns the collections whose parent is parentKey ("" for top-level)
+func (db *DB) selectCollections(parentKey string) []zotero.Collection {
+	var collections []zotero.Collection
+	for _, collection := range db.collections {
+		if string(collection.Data.ParentCollection) == parentKey {
+			collections = append(collections, collection)
+		}
+	}
+	return collections
+}
+
+func isTop(item *zotero.Item) bool {
+	return item.Data.ParentItem == ""
+}
+
+func inCollection(collectionKey string) func(*zotero.Item) bool {
+	return func(item *zotero.Item) bool {
+		return slices.Contains(item.Data.Collections, collectionKey)
+	}
+}
+
+// withItemType returns a copy of params restricted to an item type
+func withItemType(params *zotero.QueryParams, itemType string) *zotero.QueryParams {
+	typed := zotero.QueryParams{}
+	if params != nil {
+		typed = *params
+	}
+	typed.ItemType = []string{itemType}
+	return &typed
+}
@@ -0,0 +1,364 @@
+package localdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// newFixture generates a Zotero data directory with a database built from testdata/fixture.sql
+// and the file of the stored attachment ATTACH01
+func newFixture(t *testing.T) string {
+	t.Helper()
+	dataDir := t.TempDir()
+
+	script, err := os.ReadFile(filepath.Join("testdata", "fixture.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sql.Open("sqlite", filepath.Join(dataDir, "zotero.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(string(script)); err != nil {
+		t.Fatalf("error creating fixture database: %v", err)
+	}
+
+	storage := filepath.Join(dataDir, "storage", "ATTACH01")
+	if err := os.MkdirAll(storage, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(storage, "smith2021.pdf"), []byte("%PDF-1.4 fixture"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dataDir
+}
+
+func openFixture(t *testing.T, opts *Options) (*DB, string) {
+	t.Helper()
+	dataDir := newFixture(t)
+	db, err := Open(context.Background(), filepath.Join(dataDir, "zotero.sqlite"), opts)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return db, dataDir
+}
+
+func itemKeys(items []zotero.Item) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	return keys
+}
+
+func TestOpen(t *testing.T) {
+	db, _ := openFixture(t, nil)
+	ctx := context.Background()
+
+	if library := db.Library(); library.Type != "user" || library.ID != 12345 || library.Name != "testuser" {
+		t.Errorf("Library() = %+v", library)
+	}
+	if version, _ := db.LastModifiedVersion(ctx); version != 120 {
+		t.Errorf("LastModifiedVersion() = %d, want 120", version)
+	}
+	// The external annotation and the trashed page are not counted
+	if n, _ := db.NumItems(ctx); n != 7 {
+		t.Errorf("NumItems() = %d, want 7", n)
+	}
+}
+
+func TestOpenDataDirectory(t *testing.T) {
+	dataDir := newFixture(t)
+	db, err := Open(context.Background(), dataDir, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := db.Item(context.Background(), "ARTICLE1", nil); err != nil {
+		t.Errorf("Item() error = %v", err)
+	}
+}
+
+func TestOpenMissing(t *testing.T) {
+	if _, err := Open(context.Background(), filepath.Join(t.TempDir(), "zotero.sqlite"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open() error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestItem(t *testing.T) {
+	db, _ := openFixture(t, nil)
+
+	item, err := db.Item(context.Background(), "ARTICLE1", nil)
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	data := item.Data
+	if item.Version != 100 || data.ItemType != zotero.ItemTypeJournalArticle || data.Title != "Deep Learning for Citations" {
+		t.Errorf("item = %+v", item)
+	}
+	fields := map[string]string{
+		"date":             "May 2021",
+		"volume":           "12",
+		"DOI":              "10.1000/test",
+		"publicationTitle": "Journal of Tests",
+		"accessDate":       "2024-01-10T09:00:00Z",
+		"dateAdded":        "2024-01-10T09:00:00Z",
+		"dateModified":     "2024-03-01T12:30:00Z",
+		"url":              "https://example.com/article",
+		"abstractNote":     "We study citations.",
+	}
+	for name, want := range fields {
+		if got := data.Field(name); got != want {
+			t.Errorf("Field(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if data.CitationKey() != "smith2021" {
+		t.Errorf("CitationKey() = %q", data.CitationKey())
+	}
+
+	wantCreators := []zotero.Creator{
+		{CreatorType: "author", FirstName: "Jane", LastName: "Smith"},
+		{CreatorType: "author", FirstName: "Ann", LastName: "Jones"},
+		{CreatorType: "editor", FirstName: "Ed", LastName: "Itor"},
+	}
+	if !slices.Equal(data.Creators, wantCreators) {
+		t.Errorf("Creators = %+v", data.Creators)
+	}
+	if want := []zotero.Tag{{Tag: "machine learning"}, {Tag: "to read", Type: 1}}; !slices.Equal(data.Tags, want) {
+		t.Errorf("Tags = %+v, want %+v", data.Tags, want)
+	}
+	if !slices.Equal(data.Collections, []string{"COLLML01"}) {
+		t.Errorf("Collections = %v", data.Collections)
+	}
+	if data.Relations.DCRelation != "http://zotero.org/users/12345/items/BOOK0001" {
+		t.Errorf("Relations = %+v", data.Relations)
+	}
+	if item.Meta.CreatorSummary != "Smith and Jones" || item.Meta.ParsedDate != "2021-05" || item.Meta.NumChildren != 2 {
+		t.Errorf("Meta = %+v", item.Meta)
+	}
+
+	book, _ := db.Item(context.Background(), "BOOK0001", nil)
+	if book.Data.Creators[0].Name != "Go Team" || book.Meta.ParsedDate != "2019" || book.Data.Field("date") != "2019" {
+		t.Errorf("book = %+v", book)
+	}
+
+	if _, err := db.Item(context.Background(), "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("Item() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestItems(t *testing.T) {
+	db, _ := openFixture(t, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		list func() ([]zotero.Item, error)
+		want []string
+	}{
+		{"top", func() ([]zotero.Item, error) { return db.Top(ctx, nil) }, []string{"STANDNOT", "ARTICLE1", "BOOK0001"}},
+		{"top by title", func() ([]zotero.Item, error) {
+			return db.Top(ctx, &zotero.QueryParams{Sort: "title", ItemType: []string{"-note"}})
+		}, []string{"ARTICLE1", "BOOK0001"}},
+		{"tag", func() ([]zotero.Item, error) { return db.Items(ctx, &zotero.QueryParams{Tag: []string{"go"}}) }, []string{"BOOK0001"}},
+		{"quick search", func() ([]zotero.Item, error) { return db.Items(ctx, &zotero.QueryParams{Q: "jones 2021"}) }, []string{"ARTICLE1"}},
+		{"since", func() ([]zotero.Item, error) { return db.Items(ctx, &zotero.QueryParams{Since: 102, Sort: "title"}) }, []string{"ANNOT001", "STANDNOT"}},
+		{"children", func() ([]zotero.Item, error) {
+			return db.Children(ctx, "ARTICLE1", &zotero.QueryParams{Sort: "dateAdded", Direction: "asc"})
+		}, []string{"ATTACH01", "NOTE0001"}},
+		{"trash", func() ([]zotero.Item, error) { return db.Trash(ctx, nil) }, []string{"TRASHED1"}},
+		{"trashed included", func() ([]zotero.Item, error) {
+			return db.Items(ctx, &zotero.QueryParams{IncludeTrashed: true, ItemType: []string{"webpage"}})
+		}, []string{"TRASHED1"}},
+		{"collection", func() ([]zotero.Item, error) { return db.CollectionItems(ctx, "COLLML01", nil) }, []string{"ARTICLE1"}},
+		{"collection top", func() ([]zotero.Item, error) { return db.CollectionItemsTop(ctx, "COLLRES1", nil) }, []string{"BOOK0001"}},
+		{"saved search", func() ([]zotero.Item, error) {
+			return db.SearchItems(ctx, "SRCHREAD", &zotero.QueryParams{Sort: "title"})
+		}, []string{"ARTICLE1", "BOOK0001"}},
+		{"paged", func() ([]zotero.Item, error) { return db.Top(ctx, &zotero.QueryParams{Start: 1, Limit: 1}) }, []string{"ARTICLE1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := tt.list()
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := itemKeys(items); !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.Children(ctx, "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("Children() error = %v, want ErrNotFound", err)
+	}
+	if _, err := db.CollectionItems(ctx, "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("CollectionItems() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotesAndAnnotations(t *testing.T) {
+	db, _ := openFixture(t, nil)
+	ctx := context.Background()
+
+	notes, err := db.Notes(ctx, "ARTICLE1", nil)
+	if err != nil {
+		t.Fatalf("Notes() error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].Key != "NOTE0001" || notes[0].Markdown() != "Important **finding**" {
+		t.Errorf("Notes() = %+v", notes)
+	}
+
+	standalone, _ := db.Item(ctx, "STANDNOT", nil)
+	if standalone.Data.ParentItem != "" || standalone.Data.Note != "<p>Standalone thoughts</p>" {
+		t.Errorf("standalone note = %+v", standalone.Data)
+	}
+
+	annotations, err := db.Annotations(ctx, "ATTACH01", nil)
+	if err != nil {
+		t.Fatalf("Annotations() error = %v", err)
+	}
+	if len(annotations) != 1 {
+		t.Fatalf("Annotations() = %+v, want 1 annotation", annotations)
+	}
+	a := annotations[0]
+	if a.Type != "highlight" || a.Text != "highlighted text" || a.Comment != "a comment" || a.PageLabel != "12" || a.Position.PageIndex != 11 {
+		t.Errorf("annotation = %+v", a)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	baseDir := t.TempDir()
+	db, dataDir := openFixture(t, &Options{BaseDir: baseDir})
+	ctx := context.Background()
+
+	attachment, _ := db.Item(ctx, "ATTACH01", nil)
+	data := attachment.Data
+	if data.LinkMode != zotero.LinkModeImportedFile || data.Filename != "smith2021.pdf" || data.ParentItem != "ARTICLE1" ||
+		data.MD5 != "0123456789abcdef0123456789abcdef" || data.MTime != 1704877260000 || data.Note != "<p>Note on the PDF</p>" {
+		t.Errorf("attachment = %+v", data)
+	}
+
+	path, err := db.LocalFilePath(ctx, "ATTACH01")
+	if want := filepath.Join(dataDir, "storage", "ATTACH01", "smith2021.pdf"); err != nil || path != want {
+		t.Errorf("LocalFilePath() = %q, %v, want %q", path, err, want)
+	}
+	content, err := db.File(ctx, "ATTACH01")
+	if err != nil || string(content) != "%PDF-1.4 fixture" {
+		t.Errorf("File() = %q, %v", content, err)
+	}
+
+	linked, _ := db.Item(ctx, "LINKED01", nil)
+	if linked.Data.LinkMode != zotero.LinkModeLinkedFile || linked.Data.Path != "attachments:notes/linked.txt" || linked.Data.Charset != "utf-8" {
+		t.Errorf("linked attachment = %+v", linked.Data)
+	}
+	path, err = db.LocalFilePath(ctx, "LINKED01")
+	if want := filepath.Join(baseDir, "notes", "linked.txt"); err != nil || path != want {
+		t.Errorf("LocalFilePath() = %q, %v, want %q", path, err, want)
+	}
+
+	if _, err := db.LocalFilePath(ctx, "ARTICLE1"); err == nil {
+		t.Error("LocalFilePath() of a regular item error = nil, want error")
+	}
+
+	// Without a base directory, relative linked files cannot be resolved
+	noBase, _ := openFixture(t, nil)
+	if _, err := noBase.LocalFilePath(ctx, "LINKED01"); err == nil {
+		t.Error("LocalFilePath() without a base directory error = nil, want error")
+	}
+}
+
+func TestCollectionsAndSearches(t *testing.T) {
+	db, _ := openFixture(t, nil)
+	ctx := context.Background()
+
+	collections, _ := db.Collections(ctx, nil)
+	var names []string
+	for _, c := range collections {
+		names = append(names, c.Data.Name)
+	}
+	if want := []string{"Archive", "Machine Learning", "Research"}; !slices.Equal(names, want) {
+		t.Errorf("Collections() = %v, want %v", names, want)
+	}
+
+	top, _ := db.CollectionsTop(ctx, nil)
+	sub, _ := db.CollectionsSub(ctx, "COLLRES1", nil)
+	if len(top) != 2 || len(sub) != 1 || sub[0].Key != "COLLML01" || sub[0].Data.ParentCollection != "COLLRES1" {
+		t.Errorf("CollectionsTop() = %+v, CollectionsSub() = %+v", top, sub)
+	}
+
+	research, _ := db.Collection(ctx, "COLLRES1", nil)
+	archive, _ := db.Collection(ctx, "COLLARC1", nil)
+	if research.Meta.NumCollections != 1 || research.Meta.NumItems != 1 || archive.Meta.NumItems != 0 {
+		t.Errorf("Meta = %+v, %+v", research.Meta, archive.Meta)
+	}
+	if _, err := db.Collection(ctx, "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("Collection() error = %v, want ErrNotFound", err)
+	}
+
+	search, err := db.Search(ctx, "SRCHREAD", nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := []zotero.SearchCondition{
+		{Condition: zotero.ConditionTag, Operator: zotero.OperatorIs, Value: "to read"},
+		{Condition: zotero.ConditionItemType, Operator: zotero.OperatorIsNot, Value: "attachment"},
+	}
+	if search.Data.Name != "To Read" || !slices.Equal(search.Data.Conditions, want) {
+		t.Errorf("Search() = %+v", search)
+	}
+}
+
+func TestTags(t *testing.T) {
+	db, _ := openFixture(t, nil)
+	ctx := context.Background()
+
+	tags, _ := db.Tags(ctx, nil)
+	want := []zotero.TagsResponse{
+		{Tag: "go", NumItems: 1, Meta: zotero.Meta{NumItems: 1}},
+		{Tag: "machine learning", NumItems: 1, Meta: zotero.Meta{NumItems: 1}},
+		{Tag: "to read", NumItems: 1, Meta: zotero.Meta{NumItems: 1}},
+		{Tag: "to read", Type: 1, NumItems: 1, Meta: zotero.Meta{NumItems: 1}},
+	}
+	if !slices.EqualFunc(tags, want, func(a, b zotero.TagsResponse) bool {
+		return a.Tag == b.Tag && a.Type == b.Type && a.NumItems == b.NumItems
+	}) {
+		t.Errorf("Tags() = %+v, want %+v", tags, want)
+	}
+
+	if tags, _ := db.Tags(ctx, &zotero.QueryParams{Start: 1, Limit: 1}); len(tags) != 1 || tags[0].Tag != "machine learning" {
+		t.Errorf("Tags() page = %+v", tags)
+	}
+	if tags, _ := db.CollectionTags(ctx, "COLLRES1", nil); len(tags) != 2 {
+		t.Errorf("CollectionTags() = %+v", tags)
+	}
+	if tags, _ := db.ItemTags(ctx, "BOOK0001", nil); len(tags) != 2 {
+		t.Errorf("ItemTags() = %+v", tags)
+	}
+}
+
+func TestGroupLibrary(t *testing.T) {
+	db, _ := openFixture(t, &Options{GroupID: 4242})
+	ctx := context.Background()
+
+	if library := db.Library(); library.Type != "group" || library.ID != 4242 || library.Name != "Reading Group" {
+		t.Errorf("Library() = %+v", library)
+	}
+	items, _ := db.Items(ctx, nil)
+	collections, _ := db.Collections(ctx, nil)
+	if !slices.Equal(itemKeys(items), []string{"GROUPBK1"}) || len(collections) != 1 || items[0].Library.ID != 4242 {
+		t.Errorf("Items() = %v, Collections() = %+v", itemKeys(items), collections)
+	}
+
+	dataDir := newFixture(t)
+	if _, err := Open(ctx, dataDir, &Options{GroupID: 1}); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("Open() for an unknown group error = %v, want ErrNotFound", err)
+	}
+}
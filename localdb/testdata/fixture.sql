@@ -0,0 +1,93 @@
+-- A small library in the tables of Zotero 7's zotero.sqlite that the reader uses
+
+CREATE TABLE libraries (libraryID INTEGER PRIMARY KEY, type TEXT NOT NULL, editable INT NOT NULL, filesEditable INT NOT NULL, version INT NOT NULL DEFAULT 0, storageVersion INT NOT NULL DEFAULT 0, lastSync INT NOT NULL DEFAULT 0, archived INT NOT NULL DEFAULT 0);
+CREATE TABLE groups (groupID INTEGER PRIMARY KEY, libraryID INT NOT NULL UNIQUE, name TEXT NOT NULL, description TEXT NOT NULL, version INT NOT NULL);
+CREATE TABLE settings (setting TEXT, key TEXT, value, PRIMARY KEY (setting, key));
+CREATE TABLE itemTypes (itemTypeID INTEGER PRIMARY KEY, typeName TEXT, templateItemTypeID INT, display INT DEFAULT 1);
+CREATE TABLE fields (fieldID INTEGER PRIMARY KEY, fieldName TEXT, fieldFormatID INT);
+CREATE TABLE creatorTypes (creatorTypeID INTEGER PRIMARY KEY, creatorType TEXT);
+CREATE TABLE charsets (charsetID INTEGER PRIMARY KEY, charset TEXT UNIQUE);
+CREATE TABLE items (itemID INTEGER PRIMARY KEY, itemTypeID INT NOT NULL, dateAdded TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, dateModified TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, clientDateModified TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, libraryID INT NOT NULL, key TEXT NOT NULL, version INT NOT NULL DEFAULT 0, synced INT NOT NULL DEFAULT 0, UNIQUE (libraryID, key));
+CREATE TABLE itemDataValues (valueID INTEGER PRIMARY KEY, value UNIQUE);
+CREATE TABLE itemData (itemID INT, fieldID INT, valueID, PRIMARY KEY (itemID, fieldID));
+CREATE TABLE itemNotes (itemID INTEGER PRIMARY KEY, parentItemID INT, note TEXT, title TEXT);
+CREATE TABLE itemAttachments (itemID INTEGER PRIMARY KEY, parentItemID INT, linkMode INT, contentType TEXT, charsetID INT, path TEXT, syncState INT DEFAULT 0, storageModTime INT, storageHash TEXT, lastProcessedModificationTime INT);
+CREATE TABLE itemAnnotations (itemID INTEGER PRIMARY KEY, parentItemID INT NOT NULL, type INTEGER NOT NULL, authorName TEXT, text TEXT, comment TEXT, color TEXT, pageLabel TEXT, sortIndex TEXT NOT NULL, position TEXT NOT NULL, isExternal INT NOT NULL);
+CREATE TABLE creators (creatorID INTEGER PRIMARY KEY, firstName TEXT NOT NULL, lastName TEXT NOT NULL, fieldMode INT, UNIQUE (lastName, firstName, fieldMode));
+CREATE TABLE itemCreators (itemID INT NOT NULL, creatorID INT NOT NULL, creatorTypeID INT NOT NULL DEFAULT 1, orderIndex INT NOT NULL DEFAULT 0, PRIMARY KEY (itemID, creatorID, creatorTypeID, orderIndex));
+CREATE TABLE tags (tagID INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE);
+CREATE TABLE itemTags (itemID INT NOT NULL, tagID INT NOT NULL, type INT NOT NULL, PRIMARY KEY (itemID, tagID));
+CREATE TABLE relationPredicates (predicateID INTEGER PRIMARY KEY, predicate TEXT UNIQUE);
+CREATE TABLE itemRelations (itemID INT NOT NULL, predicateID INT NOT NULL, object TEXT NOT NULL, PRIMARY KEY (itemID, predicateID, object));
+CREATE TABLE collections (collectionID INTEGER PRIMARY KEY, collectionName TEXT NOT NULL, parentCollectionID INT DEFAULT NULL, clientDateModified TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, libraryID INT NOT NULL, key TEXT NOT NULL, version INT NOT NULL DEFAULT 0, synced INT NOT NULL DEFAULT 0, UNIQUE (libraryID, key));
+CREATE TABLE collectionItems (collectionID INT NOT NULL, itemID INT NOT NULL, orderIndex INT NOT NULL DEFAULT 0, PRIMARY KEY (collectionID, itemID));
+CREATE TABLE savedSearches (savedSearchID INTEGER PRIMARY KEY, savedSearchName TEXT NOT NULL, clientDateModified TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, libraryID INT NOT NULL, key TEXT NOT NULL, version INT NOT NULL DEFAULT 0, synced INT NOT NULL DEFAULT 0, UNIQUE (libraryID, key));
+CREATE TABLE savedSearchConditions (savedSearchID INT NOT NULL, searchConditionID INT NOT NULL, condition TEXT NOT NULL, operator TEXT, value TEXT, required NONE, PRIMARY KEY (savedSearchID, searchConditionID));
+CREATE TABLE deletedItems (itemID INTEGER PRIMARY KEY, dateDeleted DEFAULT CURRENT_TIMESTAMP NOT NULL);
+
+INSERT INTO libraries VALUES (1, 'user', 1, 1, 120, 0, 0, 0), (2, 'group', 1, 1, 7, 0, 0, 0);
+INSERT INTO groups VALUES (4242, 2, 'Reading Group', '', 7);
+INSERT INTO settings VALUES ('account', 'userID', 12345), ('account', 'username', 'testuser');
+
+INSERT INTO itemTypes (itemTypeID, typeName) VALUES (1, 'annotation'), (3, 'attachment'), (7, 'book'), (22, 'journalArticle'), (28, 'note'), (34, 'webpage');
+INSERT INTO fields (fieldID, fieldName) VALUES (1, 'title'), (2, 'abstractNote'), (6, 'date'), (7, 'language'), (13, 'url'), (14, 'accessDate'), (16, 'extra'), (19, 'volume'), (38, 'publicationTitle'), (59, 'DOI'), (110, 'publisher');
+INSERT INTO creatorTypes VALUES (1, 'author'), (2, 'contributor'), (3, 'editor');
+INSERT INTO charsets VALUES (1, 'utf-8');
+
+-- Items of the user library
+INSERT INTO items (itemID, itemTypeID, dateAdded, dateModified, libraryID, key, version) VALUES
+	(1, 22, '2024-01-10 09:00:00', '2024-03-01 12:30:00', 1, 'ARTICLE1', 100),
+	(2, 7, '2024-02-01 10:00:00', '2024-02-01 10:00:00', 1, 'BOOK0001', 90),
+	(3, 3, '2024-01-10 09:01:00', '2024-01-10 09:01:00', 1, 'ATTACH01', 101),
+	(4, 28, '2024-01-11 08:00:00', '2024-01-11 08:00:00', 1, 'NOTE0001', 102),
+	(5, 1, '2024-01-12 08:00:00', '2024-01-12 08:00:00', 1, 'ANNOT001', 103),
+	(6, 1, '2024-01-12 08:05:00', '2024-01-12 08:05:00', 1, 'ANNOTEXT', 0),
+	(7, 34, '2024-03-05 11:00:00', '2024-03-05 11:00:00', 1, 'TRASHED1', 110),
+	(8, 3, '2024-02-01 10:05:00', '2024-02-01 10:05:00', 1, 'LINKED01', 91),
+	(9, 28, '2024-04-01 10:00:00', '2024-04-01 10:00:00', 1, 'STANDNOT', 120),
+	(20, 7, '2024-05-01 10:00:00', '2024-05-01 10:00:00', 2, 'GROUPBK1', 7);
+
+INSERT INTO itemDataValues VALUES
+	(1, 'Deep Learning for Citations'), (2, 'We study citations.'), (3, '2021-05-00 May 2021'), (4, 'en'),
+	(5, 'https://example.com/article'), (6, '2024-01-10 09:00:00'), (7, 'Citation Key: smith2021'), (8, 12),
+	(9, 'Journal of Tests'), (10, '10.1000/test'), (11, 'The Book of Go'), (12, '2019-00-00 2019'), (13, 'Go Press'),
+	(14, 'Full Text PDF'), (15, 'A Trashed Page'), (16, 'Linked Notes'), (17, 'Group Book');
+INSERT INTO itemData VALUES
+	(1, 1, 1), (1, 2, 2), (1, 6, 3), (1, 7, 4), (1, 13, 5), (1, 14, 6), (1, 16, 7), (1, 19, 8), (1, 38, 9), (1, 59, 10),
+	(2, 1, 11), (2, 6, 12), (2, 110, 13),
+	(3, 1, 14),
+	(7, 1, 15),
+	(8, 1, 16),
+	(20, 1, 17);
+
+INSERT INTO creators VALUES (1, 'Jane', 'Smith', 0), (2, 'Ann', 'Jones', 0), (3, '', 'Go Team', 1), (4, 'Ed', 'Itor', 0);
+INSERT INTO itemCreators VALUES (1, 1, 1, 0), (1, 2, 1, 1), (1, 4, 3, 2), (2, 3, 1, 0);
+
+INSERT INTO tags VALUES (1, 'machine learning'), (2, 'to read'), (3, 'go'), (4, 'old');
+INSERT INTO itemTags VALUES (1, 1, 0), (1, 2, 1), (2, 3, 0), (2, 2, 0), (7, 4, 0);
+
+INSERT INTO relationPredicates VALUES (1, 'dc:relation'), (2, 'owl:sameAs');
+INSERT INTO itemRelations VALUES (1, 1, 'http://zotero.org/users/12345/items/BOOK0001'), (2, 1, 'http://zotero.org/users/12345/items/ARTICLE1');
+
+INSERT INTO itemNotes VALUES
+	(4, 1, '<div data-schema-version="9"><p>Important <b>finding</b></p></div>', 'Important finding'),
+	(9, NULL, '<p>Standalone thoughts</p>', 'Standalone thoughts'),
+	(3, NULL, '<p>Note on the PDF</p>', 'Note on the PDF');
+INSERT INTO itemAttachments VALUES
+	(3, 1, 0, 'application/pdf', NULL, 'storage:smith2021.pdf', 0, 1704877260000, '0123456789abcdef0123456789abcdef', NULL),
+	(8, 2, 2, 'text/plain', 1, 'attachments:notes/linked.txt', 0, NULL, NULL, NULL);
+INSERT INTO itemAnnotations VALUES
+	(5, 3, 1, NULL, 'highlighted text', 'a comment', '#ffd400', '12', '00011|001234|00100', '{"pageIndex":11,"rects":[[1,2,3,4]]}', 0),
+	(6, 3, 2, 'Someone', NULL, 'from the PDF file', '#ff6666', '13', '00012|000000|00050', '{"pageIndex":12,"rects":[[1,2,3,4]]}', 1);
+
+INSERT INTO deletedItems VALUES (7, '2024-03-06 00:00:00');
+
+INSERT INTO collections (collectionID, collectionName, parentCollectionID, libraryID, key, version) VALUES
+	(1, 'Research', NULL, 1, 'COLLRES1', 80),
+	(2, 'Machine Learning', 1, 1, 'COLLML01', 85),
+	(3, 'Archive', NULL, 1, 'COLLARC1', 60),
+	(10, 'Group Collection', NULL, 2, 'GROUPCL1', 5);
+INSERT INTO collectionItems VALUES (1, 2, 0), (2, 1, 0), (3, 7, 0), (10, 20, 0);
+
+INSERT INTO savedSearches (savedSearchID, savedSearchName, libraryID, key, version) VALUES (1, 'To Read', 1, 'SRCHREAD', 95);
+INSERT INTO savedSearchConditions VALUES (1, 0, 'tag', 'is', 'to read', 0), (1, 1, 'itemType', 'isNot', 'attachment', 0);
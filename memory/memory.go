@@ -0,0 +1,435 @@
+// Package memory holds a Zotero library in memory, for testing code written against the
+// interfaces of the zotero package (zotero.ItemReader, zotero.LibraryStore, ...) without the
+// Web API.
+//
+// A Library follows the API's versioning: each write that changes something increments the
+// library version and gives it to the objects it changed. Writes made with a stale version
+// fail with a 412 *zotero.APIError (errors.Is(err, zotero.ErrPreconditionFailed)), reads apply
+// the filters, sorting and paging of zotero.QueryParams, including Since, and Deleted reports
+// the objects deleted after a version.
+//
+//	lib := memory.New(zotero.Library{Type: "user", ID: 1})
+//	resp, err := lib.CreateItems(ctx, []zotero.Item{{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: "A Book"}}})
+//	items, err := lib.Top(ctx, &zotero.QueryParams{Since: 0})
+package memory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+var _ zotero.LibraryStore = (*Library)(nil)
+
+// Library is a library held in memory. It is safe for concurrent use.
+type Library struct {
+	mu          sync.RWMutex
+	info        zotero.Library
+	version     int
+	items       *objects
+	collections *objects
+	searches    *objects
+	files       map[string][]byte // Attachment key to file content
+	deletedTags map[string]int    // Deleted tag names and the library version that deleted them
+}
+
+// New returns an empty library at version 0. The library is reported in the Library field of
+// the objects read from it.
+func New(library zotero.Library) *Library {
+	l := &Library{
+		info:        library,
+		items:       newObjects("Item"),
+		collections: newObjects("Collection"),
+		searches:    newObjects("Search"),
+		files:       make(map[string][]byte),
+		deletedTags: make(map[string]int),
+	}
+	l.items.validate = l.validateItem
+	l.items.touch = touchItem
+	l.collections.validate = l.validateCollection
+	l.searches.validate = validateSearch
+	return l
+}
+
+// LastModifiedVersion returns the library version
+func (l *Library) LastModifiedVersion(ctx context.Context) (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version, nil
+}
+
+// NumItems returns the number of items outside the trash
+func (l *Library) NumItems(ctx context.Context) (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	items, err := l.readItems(nil)
+	if err != nil {
+		return 0, err
+	}
+	return len(zotero.FilterItems(items, nil)), nil
+}
+
+// Items returns the library's items. Unlike the API, Limit 0 returns every item.
+func (l *Library) Items(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	return l.filterItems(nil, params)
+}
+
+// Top returns the library's top-level items
+func (l *Library) Top(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	return l.filterItems(isTop, params)
+}
+
+// Item returns an item by key
+func (l *Library) Item(ctx context.Context, itemKey string, params *zotero.QueryParams) (*zotero.Item, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.readItem(itemKey)
+}
+
+// Children returns the child items of an item
+func (l *Library) Children(ctx context.Context, itemKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if _, ok := l.items.index[itemKey]; !ok {
+		return nil, errNotFound()
+	}
+	items, err := l.readItems(func(item *zotero.Item) bool { return item.Data.ParentItem == itemKey })
+	if err != nil {
+		return nil, err
+	}
+	return zotero.FilterItems(items, params), nil
+}
+
+// Trash returns the items in the trash
+func (l *Library) Trash(ctx context.Context, params *zotero.QueryParams) ([]zotero.Item, error) {
+	trashParams := zotero.QueryParams{}
+	if params != nil {
+		trashParams = *params
+	}
+	trashParams.IncludeTrashed = true
+	return l.filterItems(func(item *zotero.Item) bool { return item.Data.InTrash() }, &trashParams)
+}
+
+// Tags returns the tags of the items outside the trash, with their number of items
+func (l *Library) Tags(ctx context.Context, params *zotero.QueryParams) ([]zotero.TagsResponse, error) {
+	items, err := l.filterItems(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return zotero.CountTags(items, params), nil
+}
+
+// ItemTags returns the tags of an item
+func (l *Library) ItemTags(ctx context.Context, itemKey string, params *zotero.QueryParams) ([]zotero.Tag, error) {
+	item, err := l.Item(ctx, itemKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return item.Data.Tags, nil
+}
+
+// Collections returns the library's collections
+func (l *Library) Collections(ctx context.Context, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	return l.filterCollections(nil, params)
+}
+
+// CollectionsTop returns the library's top-level collections
+func (l *Library) CollectionsTop(ctx context.Context, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	return l.filterCollections(func(c *zotero.Collection) bool { return c.Data.ParentCollection == "" }, params)
+}
+
+// Collection returns a collection by key
+func (l *Library) Collection(ctx context.Context, collectionKey string, params *zotero.QueryParams) (*zotero.Collection, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	collections, err := l.readCollections(func(c *zotero.Collection) bool { return c.Key == collectionKey })
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, errNotFound()
+	}
+	return &collections[0], nil
+}
+
+// CollectionsSub returns the subcollections of a collection
+func (l *Library) CollectionsSub(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Collection, error) {
+	return l.filterCollections(func(c *zotero.Collection) bool {
+		return string(c.Data.ParentCollection) == collectionKey
+	}, params, collectionKey)
+}
+
+// CollectionItems returns the items in a collection
+func (l *Library) CollectionItems(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	return l.filterItems(inCollection(collectionKey), params, collectionKey)
+}
+
+// CollectionItemsTop returns the top-level items in a collection
+func (l *Library) CollectionItemsTop(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	inCollection := inCollection(collectionKey)
+	return l.filterItems(func(item *zotero.Item) bool { return isTop(item) && inCollection(item) }, params, collectionKey)
+}
+
+// CollectionTags returns the tags of the items in a collection
+func (l *Library) CollectionTags(ctx context.Context, collectionKey string, params *zotero.QueryParams) ([]zotero.TagsResponse, error) {
+	items, err := l.CollectionItems(ctx, collectionKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return zotero.CountTags(items, params), nil
+}
+
+// Searches returns the library's saved searches
+func (l *Library) Searches(ctx context.Context, params *zotero.QueryParams) ([]zotero.Search, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	searches, err := l.readSearches()
+	if err != nil {
+		return nil, err
+	}
+	return zotero.FilterSearches(searches, params), nil
+}
+
+// Search returns a saved search by key
+func (l *Library) Search(ctx context.Context, searchKey string, params *zotero.QueryParams) (*zotero.Search, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.readSearch(searchKey)
+}
+
+// SearchItems runs a saved search with zotero.SearchEvaluator. Conditions on full-text content
+// return zotero.ErrUnsupportedCondition.
+func (l *Library) SearchItems(ctx context.Context, searchKey string, params *zotero.QueryParams) ([]zotero.Item, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	search, err := l.readSearch(searchKey)
+	if err != nil {
+		return nil, err
+	}
+	items, err := l.readItems(nil)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := l.readCollections(nil)
+	if err != nil {
+		return nil, err
+	}
+	searches, err := l.readSearches()
+	if err != nil {
+		return nil, err
+	}
+	eval := &zotero.SearchEvaluator{Items: items, Collections: collections, Searches: searches}
+	matches, err := eval.MatchSearch(search.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error running search %s: %w", searchKey, err)
+	}
+	return zotero.FilterItems(matches, params), nil
+}
+
+// Deleted returns the keys of the objects and the names of the tags deleted after version since
+func (l *Library) Deleted(ctx context.Context, since int) (*zotero.DeletedContent, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	deleted := &zotero.DeletedContent{
+		Items:       l.items.deletedSince(since),
+		Collections: l.collections.deletedSince(since),
+		Searches:    l.searches.deletedSince(since),
+	}
+	for tag, version := range l.deletedTags {
+		if version > since {
+			deleted.Tags = append(deleted.Tags, tag)
+		}
+	}
+	slices.Sort(deleted.Tags)
+	return deleted, nil
+}
+
+// File returns the content of an attachment's file
+func (l *Library) File(ctx context.Context, itemKey string) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	content, ok := l.files[itemKey]
+	if !ok {
+		return nil, errNotFound()
+	}
+	return slices.Clone(content), nil
+}
+
+// filterItems reads the items for which keep returns true and applies params. If a collection
+// key is given, the collection must exist.
+func (l *Library) filterItems(keep func(*zotero.Item) bool, params *zotero.QueryParams, collectionKey ...string) ([]zotero.Item, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, key := range collectionKey {
+		if _, ok := l.collections.index[key]; !ok {
+			return nil, errNotFound()
+		}
+	}
+	items, err := l.readItems(keep)
+	if err != nil {
+		return nil, err
+	}
+	return zotero.FilterItems(items, params), nil
+}
+
+// filterCollections reads the collections for which keep returns true and applies params. If a
+// collection key is given, the collection must exist.
+func (l *Library) filterCollections(keep func(*zotero.Collection) bool, params *zotero.QueryParams, collectionKey ...string) ([]zotero.Collection, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, key := range collectionKey {
+		if _, ok := l.collections.index[key]; !ok {
+			return nil, errNotFound()
+		}
+	}
+	collections, err := l.readCollections(keep)
+	if err != nil {
+		return nil, err
+	}
+	return zotero.FilterCollections(collections, params), nil
+}
+
+// The read functions below decode the objects held by the library. Callers hold l.mu.
+
+// readItems decodes the items for which keep returns true (all items if keep is nil), with the
+// meta the API computes for them
+func (l *Library) readItems(keep func(*zotero.Item) bool) ([]zotero.Item, error) {
+	all := make([]zotero.Item, len(l.items.list))
+	numChildren := make(map[string]int)
+	for i, o := range l.items.list {
+		item := &all[i]
+		if err := o.decode(&item.Data); err != nil {
+			return nil, err
+		}
+		item.Key, item.Version, item.Library = o.key, o.version, l.info
+		item.Meta.CreatorSummary = item.Data.CreatorSummary()
+		if item.Data.ParentItem != "" && item.Data.ItemType != zotero.ItemTypeAnnotation && !item.Data.InTrash() {
+			numChildren[item.Data.ParentItem]++
+		}
+	}
+
+	var items []zotero.Item
+	for _, item := range all {
+		item.Meta.NumChildren = numChildren[item.Key]
+		if keep == nil || keep(&item) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// readItem decodes an item by key
+func (l *Library) readItem(itemKey string) (*zotero.Item, error) {
+	if _, ok := l.items.index[itemKey]; !ok {
+		return nil, errNotFound()
+	}
+	items, err := l.readItems(func(item *zotero.Item) bool { return item.Key == itemKey })
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// readCollections decodes the collections for which keep returns true (all collections if keep
+// is nil), with their numbers of subcollections and items
+func (l *Library) readCollections(keep func(*zotero.Collection) bool) ([]zotero.Collection, error) {
+	all := make([]zotero.Collection, len(l.collections.list))
+	numCollections := make(map[string]int)
+	for i, o := range l.collections.list {
+		collection := &all[i]
+		if err := o.decode(&collection.Data); err != nil {
+			return nil, err
+		}
+		collection.Key, collection.Version, collection.Library = o.key, o.version, l.info
+		numCollections[string(collection.Data.ParentCollection)]++
+	}
+
+	items, err := l.readItems(nil)
+	if err != nil {
+		return nil, err
+	}
+	numItems := make(map[string]int)
+	for _, item := range zotero.FilterItems(items, nil) {
+		for _, key := range item.Data.Collections {
+			numItems[key]++
+		}
+	}
+
+	var collections []zotero.Collection
+	for _, collection := range all {
+		collection.Meta.NumCollections = numCollections[collection.Key]
+		collection.Meta.NumItems = numItems[collection.Key]
+		if keep == nil || keep(&collection) {
+			collections = append(collections, collection)
+		}
+	}
+	return collections, nil
+}
+
+// readSearches decodes the saved searches
+func (l *Library) readSearches() ([]zotero.Search, error) {
+	searches := make([]zotero.Search, len(l.searches.list))
+	for i, o := range l.searches.list {
+		search := &searches[i]
+		if err := o.decode(&search.Data); err != nil {
+			return nil, err
+		}
+		search.Key, search.Version, search.Library = o.key, o.version, l.info
+	}
+	return searches, nil
+}
+
+// readSearch decodes a saved search by key
+func (l *Library) readSearch(searchKey string) (*zotero.Search, error) {
+	o, ok := l.searches.index[searchKey]
+	if !ok {
+		return nil, errNotFound()
+	}
+	search := &zotero.Search{Key: o.key, Version: o.version, Library: l.info}
+	if err := o.decode(&search.Data); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+func isTop(item *zotero.Item) bool {
+	return item.Data.ParentItem == ""
+}
+
+func inCollection(collectionKey string) func(*zotero.Item) bool {
+	return func(item *zotero.Item) bool {
+		return slices.Contains(item.Data.Collections, collectionKey)
+	}
+}
+
+// now returns the current time as the API formats dateAdded and dateModified
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// errNotFound returns the error of the API for a missing object
+func errNotFound() error {
+	return &zotero.APIError{StatusCode: http.StatusNotFound, Message: "Not found"}
+}
+
+// errPreconditionFailed returns the error of the API for a write made with a stale version
+func errPreconditionFailed(name string, expected, found int) error {
+	return &zotero.APIError{
+		StatusCode: http.StatusPreconditionFailed,
+		Message:    fmt.Sprintf("%s has been modified since specified version (expected %d, found %d)", name, expected, found),
+	}
+}
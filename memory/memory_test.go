@@ -0,0 +1,466 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+func newLibrary(t *testing.T) *Library {
+	t.Helper()
+	return New(zotero.Library{Type: "user", ID: 12345, Name: "testuser"})
+}
+
+// create creates objects with CreateItems and returns their keys, failing on any failure
+func create(t *testing.T, l *Library, items ...zotero.Item) []string {
+	t.Helper()
+	resp, err := l.CreateItems(context.Background(), items)
+	if err != nil {
+		t.Fatalf("CreateItems() error = %v", err)
+	}
+	if len(resp.Failed) > 0 {
+		t.Fatalf("CreateItems() failed = %+v", resp.Failed)
+	}
+	keys := make([]string, len(items))
+	for i := range items {
+		keys[i] = resp.Success[strconv.Itoa(i)].(string)
+	}
+	return keys
+}
+
+func book(title string) zotero.Item {
+	return zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: title}}
+}
+
+func itemKeys(items []zotero.Item) []string {
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+func version(t *testing.T, l *Library) int {
+	t.Helper()
+	v, err := l.LastModifiedVersion(context.Background())
+	if err != nil {
+		t.Fatalf("LastModifiedVersion() error = %v", err)
+	}
+	return v
+}
+
+func TestCreateItems(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+
+	keys := create(t, l, zotero.Item{Data: zotero.ItemData{
+		ItemType: zotero.ItemTypeBook,
+		Title:    "The Book of Go",
+		Creators: []zotero.Creator{{CreatorType: "author", LastName: "Smith"}, {CreatorType: "author", LastName: "Jones"}},
+		Extra:    map[string]any{"date": "2019"},
+	}})
+	if v := version(t, l); v != 1 {
+		t.Errorf("LastModifiedVersion() = %d, want 1", v)
+	}
+	create(t, l, zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeNote, ParentItem: keys[0], Note: "<p>A note</p>"}})
+
+	item, err := l.Item(ctx, keys[0], nil)
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	if item.Version != 1 || item.Data.Version != 1 || item.Data.Key != keys[0] {
+		t.Errorf("Item() key/version = %s/%d (data %s/%d)", item.Key, item.Version, item.Data.Key, item.Data.Version)
+	}
+	if item.Library.ID != 12345 {
+		t.Errorf("Item().Library = %+v", item.Library)
+	}
+	if item.Meta.NumChildren != 1 || item.Meta.CreatorSummary != "Smith and Jones" {
+		t.Errorf("Item().Meta = %+v", item.Meta)
+	}
+	if item.Data.Field("date") != "2019" || item.Data.DateAdded == "" || item.Data.DateModified == "" {
+		t.Errorf("Item().Data = %+v", item.Data)
+	}
+
+	resp, err := l.CreateItems(ctx, []zotero.Item{
+		{Data: zotero.ItemData{Title: "No type"}},
+		{Data: zotero.ItemData{ItemType: zotero.ItemTypeNote, ParentItem: "MISSING1"}},
+		{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Collections: []string{"MISSING1"}}},
+		{Key: "lowercase", Data: zotero.ItemData{ItemType: zotero.ItemTypeBook}},
+		{Key: "ABCD2345", Version: 3, Data: zotero.ItemData{ItemType: zotero.ItemTypeBook}},
+	})
+	if err != nil {
+		t.Fatalf("CreateItems() error = %v", err)
+	}
+	wantCodes := map[string]int{"0": 400, "1": 400, "2": 409, "3": 400, "4": 404}
+	for index, code := range wantCodes {
+		if resp.Failed[index].Code != code {
+			t.Errorf("CreateItems() failed[%s] = %+v, want code %d", index, resp.Failed[index], code)
+		}
+	}
+	if v := version(t, l); v != 2 {
+		t.Errorf("LastModifiedVersion() after failed writes = %d, want 2", v)
+	}
+
+	if _, err := l.Item(ctx, "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("Item() missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	keys := create(t, l, zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: "Old", AbstractNote: "Abstract"}})
+
+	item, _ := l.Item(ctx, keys[0], nil)
+	item.Data.Title = "New"
+	item.Data.AbstractNote = ""
+	if err := l.UpdateItem(ctx, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	updated, _ := l.Item(ctx, keys[0], nil)
+	if updated.Version != 2 || updated.Data.Title != "New" || updated.Data.AbstractNote != "Abstract" {
+		t.Errorf("after UpdateItem() item = %d %q %q, want the title patched", updated.Version, updated.Data.Title, updated.Data.AbstractNote)
+	}
+
+	// The item read before the update is stale
+	err := l.UpdateItem(ctx, item)
+	var apiErr *zotero.APIError
+	if !errors.Is(err, zotero.ErrPreconditionFailed) || !errors.As(err, &apiErr) || apiErr.StatusCode != 412 {
+		t.Errorf("UpdateItem() stale error = %v, want 412", err)
+	}
+
+	// Writing the same data leaves the item and the library unchanged
+	if err := l.UpdateItem(ctx, updated); err != nil {
+		t.Fatalf("UpdateItem() unchanged error = %v", err)
+	}
+	if v := version(t, l); v != 2 {
+		t.Errorf("LastModifiedVersion() after unchanged write = %d, want 2", v)
+	}
+
+	updated.Data.AbstractNote = ""
+	if err := l.ReplaceItem(ctx, updated); err != nil {
+		t.Fatalf("ReplaceItem() error = %v", err)
+	}
+	replaced, _ := l.Item(ctx, keys[0], nil)
+	if replaced.Version != 3 || replaced.Data.AbstractNote != "" || replaced.Data.DateAdded != updated.Data.DateAdded {
+		t.Errorf("after ReplaceItem() item = %+v", replaced.Data)
+	}
+
+	if err := l.UpdateItem(ctx, &zotero.Item{Key: "MISSING1", Version: 1}); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("UpdateItem() missing error = %v, want ErrNotFound", err)
+	}
+	if err := l.UpdateItem(ctx, &zotero.Item{Key: keys[0], Data: zotero.ItemData{ItemType: zotero.ItemTypeBook}}); err == nil {
+		t.Error("UpdateItem() without version succeeded")
+	}
+}
+
+func TestUpdateItems(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	keys := create(t, l, book("A"), book("B"))
+	create(t, l, book("C"))
+
+	items, _ := l.Items(ctx, &zotero.QueryParams{ItemKey: keys, Sort: "title"})
+	items[0].Data.Title = "A2"
+	items[1].Data.Title = "B2"
+	items[1].Version = 0
+	items[1].Data.Version = 0
+	if _, err := l.UpdateItems(ctx, items); err == nil {
+		t.Error("UpdateItems() without version succeeded")
+	}
+
+	items[1].Version = 1
+	if err := l.UpdateItem(ctx, &zotero.Item{Key: keys[1], Version: 1, Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: "B1"}}); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	resp, err := l.UpdateItems(ctx, items)
+	if err != nil {
+		t.Fatalf("UpdateItems() error = %v", err)
+	}
+	if resp.Success["0"] != keys[0] || resp.Failed["1"].Code != 412 {
+		t.Errorf("UpdateItems() = %+v, want the first to succeed and the second to fail with 412", resp)
+	}
+
+	changed, _ := l.Items(ctx, &zotero.QueryParams{Since: 2})
+	if got := itemKeys(changed); len(got) != 2 || !slices.Contains(got, keys[0]) || !slices.Contains(got, keys[1]) {
+		t.Errorf("Items() since 2 = %v, want %v", got, keys)
+	}
+}
+
+func TestDeleteItems(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	keys := create(t, l, book("A"), book("B"), book("C"))
+	children := create(t, l, zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeNote, ParentItem: keys[0], Note: "<p>Child</p>"}})
+
+	if err := l.DeleteItem(ctx, keys[0], 2); !errors.Is(err, zotero.ErrPreconditionFailed) {
+		t.Errorf("DeleteItem() stale error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := l.DeleteItem(ctx, keys[0], 1); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if _, err := l.Item(ctx, children[0], nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("child note not deleted with its parent: %v", err)
+	}
+
+	if err := l.DeleteItems(ctx, keys[1:], 2); !errors.Is(err, zotero.ErrPreconditionFailed) {
+		t.Errorf("DeleteItems() with a stale library version error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := l.DeleteItems(ctx, []string{keys[1], "MISSING1"}, 3); err != nil {
+		t.Fatalf("DeleteItems() error = %v", err)
+	}
+
+	deleted, err := l.Deleted(ctx, 3)
+	if err != nil {
+		t.Fatalf("Deleted() error = %v", err)
+	}
+	if !slices.Equal(deleted.Items, []string{keys[1]}) {
+		t.Errorf("Deleted(3).Items = %v, want %v", deleted.Items, keys[1:2])
+	}
+	deleted, _ = l.Deleted(ctx, 0)
+	if len(deleted.Items) != 3 {
+		t.Errorf("Deleted(0).Items = %v, want 3 keys", deleted.Items)
+	}
+	if n, _ := l.NumItems(ctx); n != 1 {
+		t.Errorf("NumItems() = %d, want 1", n)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	trashed := book("Trashed")
+	trashed.Data.Extra = map[string]any{"deleted": true}
+	keys := create(t, l, book("Kept"), trashed)
+
+	items, _ := l.Items(ctx, nil)
+	if got := itemKeys(items); !slices.Equal(got, keys[:1]) {
+		t.Errorf("Items() = %v, want %v", got, keys[:1])
+	}
+	trash, _ := l.Trash(ctx, nil)
+	if got := itemKeys(trash); !slices.Equal(got, keys[1:]) {
+		t.Errorf("Trash() = %v, want %v", got, keys[1:])
+	}
+}
+
+func TestCollections(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+
+	resp, err := l.CreateCollections(ctx, []zotero.Collection{{Data: zotero.CollectionData{Name: "Research"}}})
+	if err != nil || len(resp.Success) != 1 {
+		t.Fatalf("CreateCollections() = %+v, %v", resp, err)
+	}
+	parent := resp.Success["0"].(string)
+	resp, _ = l.CreateCollections(ctx, []zotero.Collection{
+		{Data: zotero.CollectionData{Name: "Sub", ParentCollection: zotero.ParentCollectionRef(parent)}},
+		{Data: zotero.CollectionData{Name: ""}},
+		{Data: zotero.CollectionData{Name: "Orphan", ParentCollection: "MISSING1"}},
+	})
+	if len(resp.Success) != 1 || resp.Failed["1"].Code != 400 || resp.Failed["2"].Code != 409 {
+		t.Fatalf("CreateCollections() = %+v", resp)
+	}
+	sub := resp.Success["0"].(string)
+
+	item := book("In both")
+	item.Data.Collections = []string{parent, sub}
+	keys := create(t, l, item)
+
+	collection, err := l.Collection(ctx, parent, nil)
+	if err != nil {
+		t.Fatalf("Collection() error = %v", err)
+	}
+	if collection.Meta.NumCollections != 1 || collection.Meta.NumItems != 1 {
+		t.Errorf("Collection().Meta = %+v", collection.Meta)
+	}
+	if top, _ := l.CollectionsTop(ctx, nil); len(top) != 1 || top[0].Key != parent {
+		t.Errorf("CollectionsTop() = %+v", top)
+	}
+	if subs, _ := l.CollectionsSub(ctx, parent, nil); len(subs) != 1 || subs[0].Key != sub {
+		t.Errorf("CollectionsSub() = %+v", subs)
+	}
+	if items, _ := l.CollectionItems(ctx, sub, nil); !slices.Equal(itemKeys(items), keys) {
+		t.Errorf("CollectionItems() = %v, want %v", itemKeys(items), keys)
+	}
+	if _, err := l.CollectionItems(ctx, "MISSING1", nil); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("CollectionItems() missing error = %v, want ErrNotFound", err)
+	}
+
+	// A collection cannot be moved into its own subcollection
+	collection.Data.ParentCollection = zotero.ParentCollectionRef(sub)
+	if err := l.UpdateCollection(ctx, collection); err == nil {
+		t.Error("UpdateCollection() into a subcollection succeeded")
+	}
+
+	v := version(t, l)
+	if err := l.DeleteCollection(ctx, parent, collection.Version); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	if all, _ := l.Collections(ctx, nil); len(all) != 0 {
+		t.Errorf("Collections() after delete = %+v, want the subcollection deleted too", all)
+	}
+	updated, _ := l.Item(ctx, keys[0], nil)
+	if len(updated.Data.Collections) != 0 || updated.Version != v+1 {
+		t.Errorf("item after deleting its collections = %d %v", updated.Version, updated.Data.Collections)
+	}
+	deleted, _ := l.Deleted(ctx, v)
+	if len(deleted.Collections) != 2 {
+		t.Errorf("Deleted().Collections = %v, want 2 keys", deleted.Collections)
+	}
+}
+
+func TestCollectionMoveToTopLevel(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+
+	resp, _ := l.CreateCollections(ctx, []zotero.Collection{{Data: zotero.CollectionData{Name: "Research"}}})
+	parent := resp.Success["0"].(string)
+	resp, _ = l.CreateCollections(ctx, []zotero.Collection{
+		{Data: zotero.CollectionData{Name: "Sub", ParentCollection: zotero.ParentCollectionRef(parent)}},
+	})
+	sub := resp.Success["0"].(string)
+
+	// An update without a parent, such as a rename, keeps the collection where it is
+	collection := zotero.Collection{Key: sub, Version: version(t, l), Data: zotero.CollectionData{Name: "Renamed"}}
+	if err := l.UpdateCollection(ctx, &collection); err != nil {
+		t.Fatalf("UpdateCollection() rename error = %v", err)
+	}
+	if got, _ := l.Collection(ctx, sub, nil); got.Data.Name != "Renamed" || got.Data.ParentCollection != zotero.ParentCollectionRef(parent) {
+		t.Errorf("renamed collection = %+v, want it still in %s", got.Data, parent)
+	}
+
+	collection = zotero.Collection{Key: sub, Version: version(t, l), Data: zotero.CollectionData{Name: "Renamed", ParentCollection: zotero.TopLevelCollection}}
+	if err := l.UpdateCollection(ctx, &collection); err != nil {
+		t.Fatalf("UpdateCollection() move error = %v", err)
+	}
+	if top, _ := l.CollectionsTop(ctx, nil); len(top) != 2 {
+		t.Errorf("CollectionsTop() = %+v, want both collections", top)
+	}
+}
+
+func TestSearches(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	tagged := book("Tagged")
+	tagged.Data.Tags = []zotero.Tag{{Tag: "to read"}}
+	keys := create(t, l, tagged, book("Untagged"))
+
+	resp, err := l.CreateSearches(ctx, []zotero.Search{
+		{Data: zotero.SearchData{Name: "To Read", Conditions: []zotero.SearchCondition{{Condition: "tag", Operator: "is", Value: "to read"}}}},
+		{Data: zotero.SearchData{Name: "Empty"}},
+	})
+	if err != nil || len(resp.Success) != 1 || resp.Failed["1"].Code != 400 {
+		t.Fatalf("CreateSearches() = %+v, %v", resp, err)
+	}
+	key := resp.Success["0"].(string)
+
+	items, err := l.SearchItems(ctx, key, nil)
+	if err != nil {
+		t.Fatalf("SearchItems() error = %v", err)
+	}
+	if got := itemKeys(items); !slices.Equal(got, keys[:1]) {
+		t.Errorf("SearchItems() = %v, want %v", got, keys[:1])
+	}
+
+	search, _ := l.Search(ctx, key, nil)
+	search.Data.Name = "Reading list"
+	if err := l.UpdateSearch(ctx, search); err != nil {
+		t.Fatalf("UpdateSearch() error = %v", err)
+	}
+	if err := l.DeleteSearch(ctx, key, search.Version); !errors.Is(err, zotero.ErrPreconditionFailed) {
+		t.Errorf("DeleteSearch() stale error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := l.DeleteSearches(ctx, []string{key}, version(t, l)); err != nil {
+		t.Fatalf("DeleteSearches() error = %v", err)
+	}
+	if deleted, _ := l.Deleted(ctx, 0); !slices.Equal(deleted.Searches, []string{key}) {
+		t.Errorf("Deleted().Searches = %v", deleted.Searches)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	keys := create(t, l, book("A"), book("B"))
+
+	for _, key := range keys {
+		if err := l.AddTags(ctx, key, "go", "to read"); err != nil {
+			t.Fatalf("AddTags() error = %v", err)
+		}
+	}
+	if err := l.RemoveTags(ctx, keys[1], "to read"); err != nil {
+		t.Fatalf("RemoveTags() error = %v", err)
+	}
+	tags, _ := l.Tags(ctx, nil)
+	if len(tags) != 2 || tags[0].Tag != "go" || tags[0].NumItems != 2 || tags[1].NumItems != 1 {
+		t.Errorf("Tags() = %+v", tags)
+	}
+
+	v := version(t, l)
+	if err := l.DeleteTags(ctx, v-1, "go"); !errors.Is(err, zotero.ErrPreconditionFailed) {
+		t.Errorf("DeleteTags() stale error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := l.DeleteTags(ctx, v, "go"); err != nil {
+		t.Fatalf("DeleteTags() error = %v", err)
+	}
+	if tags, _ := l.ItemTags(ctx, keys[0], nil); len(tags) != 1 || tags[0].Tag != "to read" {
+		t.Errorf("ItemTags() after DeleteTags() = %+v", tags)
+	}
+	if deleted, _ := l.Deleted(ctx, v); !slices.Equal(deleted.Tags, []string{"go"}) {
+		t.Errorf("Deleted().Tags = %v", deleted.Tags)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	l := newLibrary(t)
+	keys := create(t, l, book("Parent"))
+
+	content := []byte("%PDF-1.4 content")
+	var sent int64
+	attachment, err := l.UploadAttachmentReader(ctx, keys[0], bytes.NewReader(content), int64(len(content)), &zotero.UploadOptions{
+		Filename:    "paper.pdf",
+		ContentType: "application/pdf",
+		Progress:    func(n, total int64) { sent = n },
+	})
+	if err != nil {
+		t.Fatalf("UploadAttachmentReader() error = %v", err)
+	}
+	if attachment.Data.ParentItem != keys[0] || attachment.Data.MD5 != md5Hex(content) || attachment.Data.Title != "paper.pdf" {
+		t.Errorf("UploadAttachmentReader() = %+v", attachment.Data)
+	}
+	if sent != int64(len(content)) {
+		t.Errorf("progress reported %d bytes, want %d", sent, len(content))
+	}
+	if got, err := l.File(ctx, attachment.Key); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("File() = %q, %v", got, err)
+	}
+
+	updatedContent := []byte("%PDF-1.4 updated")
+	updated, err := l.UpdateAttachmentFile(ctx, attachment.Key, zotero.FileSource{
+		Reader:   bytes.NewReader(updatedContent),
+		Size:     int64(len(updatedContent)),
+		Filename: "paper-v2.pdf",
+	})
+	if err != nil {
+		t.Fatalf("UpdateAttachmentFile() error = %v", err)
+	}
+	if updated.Version <= attachment.Version || updated.Data.MD5 != md5Hex(updatedContent) || updated.Data.Filename != "paper-v2.pdf" {
+		t.Errorf("UpdateAttachmentFile() = %d %+v", updated.Version, updated.Data)
+	}
+	if _, err := l.UpdateAttachmentFile(ctx, keys[0], zotero.FileSource{Reader: bytes.NewReader(nil)}); err == nil {
+		t.Error("UpdateAttachmentFile() on a regular item succeeded")
+	}
+
+	if err := l.DeleteItem(ctx, keys[0], 1); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if _, err := l.File(ctx, attachment.Key); !errors.Is(err, zotero.ErrNotFound) {
+		t.Errorf("File() after deleting the parent error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// keyChars are the characters of object keys generated by Zotero
+const keyChars = "23456789ABCDEFGHIJKLMNPQRSTUVWXYZ"
+
+// object is an item, collection or search, held as the JSON data the API stores
+type object struct {
+	key     string
+	version int
+	data    map[string]any // Without "key" and "version"
+}
+
+// decode decodes the object's data, including its key and version, into v
+func (o *object) decode(v any) error {
+	data := maps.Clone(o.data)
+	data["key"] = o.key
+	data["version"] = o.version
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", o.key, err)
+	}
+	if err := json.Unmarshal(encoded, v); err != nil {
+		return fmt.Errorf("error decoding %s: %w", o.key, err)
+	}
+	return nil
+}
+
+// objects holds the objects of one kind in the order they were created
+type objects struct {
+	name    string // "Item", "Collection" or "Search", for messages
+	list    []*object
+	index   map[string]*object
+	deleted map[string]int // Keys of deleted objects and the library version that deleted them
+
+	// validate checks the data of an object about to be written, returning a failure in the
+	// API's terms
+	validate func(key string, data map[string]any) *zotero.FailedWrite
+	// touch updates the data of an object that is changed, given the object it replaces (nil
+	// for a new object)
+	touch func(data map[string]any, previous *object)
+}
+
+func newObjects(name string) *objects {
+	return &objects{
+		name:     name,
+		index:    make(map[string]*object),
+		deleted:  make(map[string]int),
+		validate: func(string, map[string]any) *zotero.FailedWrite { return nil },
+		touch:    func(map[string]any, *object) {},
+	}
+}
+
+// write is an object to write: its key ("" for a new object), the version it was read at and
+// its data
+type write struct {
+	key     string
+	version int
+	data    map[string]any
+}
+
+// writeAll writes objects as a batch POST does, each one succeeding, unchanged or failing on
+// its own. Existing objects are patched with the data given. Changed objects get version.
+func (objs *objects) writeAll(writes []write, version int) *zotero.WriteResponse {
+	resp := &zotero.WriteResponse{
+		Success:   make(map[string]any),
+		Unchanged: make(map[string]any),
+		Failed:    make(map[string]zotero.FailedWrite),
+	}
+	for i, w := range writes {
+		index := strconv.Itoa(i)
+		key, unchanged, failed := objs.put(w, false, version)
+		switch {
+		case failed != nil:
+			resp.Failed[index] = *failed
+		case unchanged:
+			resp.Unchanged[index] = key
+		default:
+			resp.Success[index] = key
+		}
+	}
+	return resp
+}
+
+// put writes an object, patching an existing object unless replace is set. It returns the
+// object's key and whether it was left unchanged, or a failure.
+func (objs *objects) put(w write, replace bool, version int) (string, bool, *zotero.FailedWrite) {
+	existing := objs.index[w.key]
+	switch {
+	case w.key != "" && !validKey(w.key):
+		return "", false, &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("'%s' is not a valid %s key", w.key, strings.ToLower(objs.name))}
+	case existing == nil && w.version > 0:
+		return "", false, &zotero.FailedWrite{Code: http.StatusNotFound, Message: fmt.Sprintf("%s doesn't exist", objs.name)}
+	case existing != nil && w.version == 0:
+		return "", false, &zotero.FailedWrite{Code: http.StatusPreconditionRequired, Message: "Either If-Unmodified-Since-Version or object version property must be provided for key-based writes"}
+	case existing != nil && w.version != existing.version:
+		return "", false, &zotero.FailedWrite{Code: http.StatusPreconditionFailed, Message: fmt.Sprintf("%s has been modified since specified version (expected %d, found %d)", objs.name, w.version, existing.version)}
+	}
+
+	data := maps.Clone(w.data)
+	delete(data, "key")
+	delete(data, "version")
+	if existing != nil && !replace {
+		data = merge(existing.data, data)
+	}
+	key := w.key
+	if key == "" {
+		key = newKey(objs.index)
+	}
+	if failed := objs.validate(key, data); failed != nil {
+		return "", false, failed
+	}
+	if existing != nil && reflect.DeepEqual(existing.data, data) {
+		return key, true, nil
+	}
+
+	objs.touch(data, existing)
+	if existing != nil {
+		existing.version = version
+		existing.data = data
+		return key, false, nil
+	}
+	o := &object{key: key, version: version, data: data}
+	objs.list = append(objs.list, o)
+	objs.index[key] = o
+	delete(objs.deleted, key)
+	return key, false, nil
+}
+
+// remove deletes objects by key, recording the deletion at version
+func (objs *objects) remove(keys map[string]bool, version int) {
+	kept := objs.list[:0]
+	for _, o := range objs.list {
+		if keys[o.key] {
+			delete(objs.index, o.key)
+			objs.deleted[o.key] = version
+			continue
+		}
+		kept = append(kept, o)
+	}
+	clear(objs.list[len(kept):])
+	objs.list = kept
+}
+
+// deletedSince returns the keys of the objects deleted after version since
+func (objs *objects) deletedSince(since int) []string {
+	var keys []string
+	for key, version := range objs.deleted {
+		if version > since {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// merge returns data with the top-level properties of patch applied, as a PATCH request does
+func merge(data, patch map[string]any) map[string]any {
+	merged := maps.Clone(data)
+	maps.Copy(merged, patch)
+	return merged
+}
+
+// toData encodes v as JSON data
+func toData(v any) (map[string]any, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// newKey generates a key that is not in use
+func newKey(index map[string]*object) string {
+	for {
+		b := make([]byte, 8)
+		for i := range b {
+			b[i] = keyChars[rand.IntN(len(keyChars))]
+		}
+		if _, ok := index[string(b)]; !ok {
+			return string(b)
+		}
+	}
+}
+
+func validKey(key string) bool {
+	if len(key) != 8 {
+		return false
+	}
+	for i := range len(key) {
+		if strings.IndexByte(keyChars, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
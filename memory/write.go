@@ -0,0 +1,722 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// CreateItems creates items, or updates those whose key exists as UpdateItems does
+func (l *Library) CreateItems(ctx context.Context, items []zotero.Item) (*zotero.WriteResponse, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items provided")
+	}
+	if len(items) > 50 {
+		return nil, fmt.Errorf("maximum 50 items per request, got %d", len(items))
+	}
+	writes, err := itemWrites(items)
+	if err != nil {
+		return nil, err
+	}
+	return l.writeAll(l.items, writes), nil
+}
+
+// UpdateItem patches an item with the fields set in item.Data. The item's version must be the
+// current one.
+func (l *Library) UpdateItem(ctx context.Context, item *zotero.Item) error {
+	return l.writeItem(item, false)
+}
+
+// ReplaceItem replaces an item with item.Data. The item's version must be the current one.
+func (l *Library) ReplaceItem(ctx context.Context, item *zotero.Item) error {
+	return l.writeItem(item, true)
+}
+
+// UpdateItems patches items, each failing on its own if its version is not the current one
+func (l *Library) UpdateItems(ctx context.Context, items []zotero.Item) (*zotero.WriteResponse, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items provided")
+	}
+	if len(items) > 50 {
+		return nil, fmt.Errorf("maximum 50 items per request, got %d", len(items))
+	}
+	writes, err := itemWrites(items)
+	if err != nil {
+		return nil, err
+	}
+	for i, w := range writes {
+		if w.key == "" {
+			return nil, fmt.Errorf("item %d missing key", i)
+		}
+		if w.version == 0 {
+			return nil, fmt.Errorf("item %d missing version", i)
+		}
+	}
+	return l.writeAll(l.items, writes), nil
+}
+
+// DeleteItem deletes an item and its child items. version must be the item's current version.
+func (l *Library) DeleteItem(ctx context.Context, itemKey string, version int) error {
+	if itemKey == "" {
+		return fmt.Errorf("item key is required")
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.items.index[itemKey]
+	if !ok {
+		return errNotFound()
+	}
+	if o.version != version {
+		return errPreconditionFailed(l.items.name, version, o.version)
+	}
+	l.deleteItems([]string{itemKey})
+	return nil
+}
+
+// DeleteItems deletes items and their child items. version must be the current library
+// version. Keys that do not exist are ignored.
+func (l *Library) DeleteItems(ctx context.Context, itemKeys []string, version int) error {
+	if len(itemKeys) == 0 {
+		return fmt.Errorf("no item keys provided")
+	}
+	if len(itemKeys) > 50 {
+		return fmt.Errorf("maximum 50 items per request, got %d", len(itemKeys))
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.version != version {
+		return errPreconditionFailed("Library", version, l.version)
+	}
+	l.deleteItems(itemKeys)
+	return nil
+}
+
+// AddTags adds tags to an item
+func (l *Library) AddTags(ctx context.Context, itemKey string, tags ...string) error {
+	if itemKey == "" {
+		return fmt.Errorf("item key is required")
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags provided")
+	}
+	return l.editTags(itemKey, func(itemTags []zotero.Tag) []zotero.Tag {
+		for _, tag := range tags {
+			if !slices.ContainsFunc(itemTags, func(t zotero.Tag) bool { return t.Tag == tag }) {
+				itemTags = append(itemTags, zotero.Tag{Tag: tag})
+			}
+		}
+		return itemTags
+	})
+}
+
+// RemoveTags removes tags from an item
+func (l *Library) RemoveTags(ctx context.Context, itemKey string, tags ...string) error {
+	if itemKey == "" {
+		return fmt.Errorf("item key is required")
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags provided")
+	}
+	return l.editTags(itemKey, func(itemTags []zotero.Tag) []zotero.Tag {
+		return slices.DeleteFunc(itemTags, func(t zotero.Tag) bool { return slices.Contains(tags, t.Tag) })
+	})
+}
+
+// DeleteTags removes tags from every item. version must be the current library version.
+func (l *Library) DeleteTags(ctx context.Context, version int, tags ...string) error {
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags provided")
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.version != version {
+		return errPreconditionFailed("Library", version, l.version)
+	}
+	l.version++
+	for _, o := range l.items.list {
+		if _, err := l.updateItemData(o, func(data *zotero.ItemData) {
+			data.Tags = slices.DeleteFunc(data.Tags, func(t zotero.Tag) bool { return slices.Contains(tags, t.Tag) })
+		}, l.version); err != nil {
+			return err
+		}
+	}
+	for _, tag := range tags {
+		l.deletedTags[tag] = l.version
+	}
+	return nil
+}
+
+// CreateCollections creates collections, or updates those whose key exists as
+// UpdateCollections does
+func (l *Library) CreateCollections(ctx context.Context, collections []zotero.Collection) (*zotero.WriteResponse, error) {
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("no collections provided")
+	}
+	if len(collections) > 50 {
+		return nil, fmt.Errorf("maximum 50 collections per request, got %d", len(collections))
+	}
+	writes, err := collectionWrites(collections)
+	if err != nil {
+		return nil, err
+	}
+	return l.writeAll(l.collections, writes), nil
+}
+
+// UpdateCollection patches a collection with collection.Data. The collection's version must be
+// the current one.
+func (l *Library) UpdateCollection(ctx context.Context, collection *zotero.Collection) error {
+	if collection == nil {
+		return fmt.Errorf("collection cannot be nil")
+	}
+	writes, err := collectionWrites([]zotero.Collection{*collection})
+	if err != nil {
+		return err
+	}
+	if writes[0].key == "" {
+		return fmt.Errorf("collection key is required")
+	}
+	return l.writeOne(l.collections, writes[0], false)
+}
+
+// UpdateCollections patches collections, each failing on its own if its version is not the
+// current one
+func (l *Library) UpdateCollections(ctx context.Context, collections []zotero.Collection) (*zotero.WriteResponse, error) {
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("no collections provided")
+	}
+	if len(collections) > 50 {
+		return nil, fmt.Errorf("maximum 50 collections per request, got %d", len(collections))
+	}
+	writes, err := collectionWrites(collections)
+	if err != nil {
+		return nil, err
+	}
+	for i, w := range writes {
+		if w.key == "" {
+			return nil, fmt.Errorf("collection %d missing key", i)
+		}
+		if w.version == 0 {
+			return nil, fmt.Errorf("collection %d missing version", i)
+		}
+	}
+	return l.writeAll(l.collections, writes), nil
+}
+
+// DeleteCollection deletes a collection and its subcollections, removing their items from them.
+// version must be the collection's current version.
+func (l *Library) DeleteCollection(ctx context.Context, collectionKey string, version int) error {
+	if collectionKey == "" {
+		return fmt.Errorf("collection key is required")
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.collections.index[collectionKey]
+	if !ok {
+		return errNotFound()
+	}
+	if o.version != version {
+		return errPreconditionFailed(l.collections.name, version, o.version)
+	}
+	return l.deleteCollections([]string{collectionKey})
+}
+
+// DeleteCollections deletes collections and their subcollections, removing their items from
+// them. version must be the current library version. Keys that do not exist are ignored.
+func (l *Library) DeleteCollections(ctx context.Context, collectionKeys []string, version int) error {
+	if len(collectionKeys) == 0 {
+		return fmt.Errorf("no collection keys provided")
+	}
+	if len(collectionKeys) > 50 {
+		return fmt.Errorf("maximum 50 collections per request, got %d", len(collectionKeys))
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.version != version {
+		return errPreconditionFailed("Library", version, l.version)
+	}
+	return l.deleteCollections(collectionKeys)
+}
+
+// CreateSearches creates saved searches, or updates those whose key exists
+func (l *Library) CreateSearches(ctx context.Context, searches []zotero.Search) (*zotero.WriteResponse, error) {
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("no searches provided")
+	}
+	if len(searches) > 50 {
+		return nil, fmt.Errorf("maximum 50 searches per request, got %d", len(searches))
+	}
+	writes, err := searchWrites(searches)
+	if err != nil {
+		return nil, err
+	}
+	return l.writeAll(l.searches, writes), nil
+}
+
+// UpdateSearch patches a saved search with search.Data. The search's version must be the
+// current one.
+func (l *Library) UpdateSearch(ctx context.Context, search *zotero.Search) error {
+	if search == nil {
+		return fmt.Errorf("search cannot be nil")
+	}
+	writes, err := searchWrites([]zotero.Search{*search})
+	if err != nil {
+		return err
+	}
+	if writes[0].key == "" {
+		return fmt.Errorf("search key is required")
+	}
+	return l.writeOne(l.searches, writes[0], false)
+}
+
+// DeleteSearch deletes a saved search. version must be the search's current version.
+func (l *Library) DeleteSearch(ctx context.Context, searchKey string, version int) error {
+	if searchKey == "" {
+		return fmt.Errorf("search key is required")
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.searches.index[searchKey]
+	if !ok {
+		return errNotFound()
+	}
+	if o.version != version {
+		return errPreconditionFailed(l.searches.name, version, o.version)
+	}
+	l.version++
+	l.searches.remove(map[string]bool{searchKey: true}, l.version)
+	return nil
+}
+
+// DeleteSearches deletes saved searches. version must be the current library version. Keys
+// that do not exist are ignored.
+func (l *Library) DeleteSearches(ctx context.Context, searchKeys []string, version int) error {
+	if len(searchKeys) == 0 {
+		return fmt.Errorf("no search keys provided")
+	}
+	if len(searchKeys) > 50 {
+		return fmt.Errorf("maximum 50 searches per request, got %d", len(searchKeys))
+	}
+	if version == 0 {
+		return fmt.Errorf("version is required for delete operations")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.version != version {
+		return errPreconditionFailed("Library", version, l.version)
+	}
+	keys := make(map[string]bool)
+	for _, key := range searchKeys {
+		if _, ok := l.searches.index[key]; ok {
+			keys[key] = true
+		}
+	}
+	if len(keys) > 0 {
+		l.version++
+		l.searches.remove(keys, l.version)
+	}
+	return nil
+}
+
+// UploadAttachmentReader creates an imported file attachment holding the content of r
+func (l *Library) UploadAttachmentReader(ctx context.Context, parentItemKey string, r io.ReaderAt, size int64, opts *zotero.UploadOptions) (*zotero.Item, error) {
+	if opts == nil || opts.Filename == "" {
+		return nil, fmt.Errorf("filename is required")
+	}
+	content, err := readContent(r, size, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = opts.Filename
+	}
+	mtime := opts.MTime
+	if mtime.IsZero() {
+		mtime = time.Now()
+	}
+	writes, err := itemWrites([]zotero.Item{{Data: zotero.ItemData{
+		ItemType:    zotero.ItemTypeAttachment,
+		LinkMode:    zotero.LinkModeImportedFile,
+		Title:       title,
+		ContentType: opts.ContentType,
+		Filename:    opts.Filename,
+		ParentItem:  parentItemKey,
+		MD5:         md5Hex(content),
+		MTime:       mtime.UnixMilli(),
+	}}})
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key, _, failed := l.items.put(writes[0], false, l.version+1)
+	if failed != nil {
+		return nil, fmt.Errorf("failed to create attachment: %s", failed.Message)
+	}
+	l.version++
+	l.files[key] = content
+	return l.readItem(key)
+}
+
+// UpdateAttachmentFile replaces the file of a stored attachment, updating its md5, mtime and
+// filename. Patches are applied by taking the full content of source.Reader.
+func (l *Library) UpdateAttachmentFile(ctx context.Context, attachmentKey string, source zotero.FileSource) (*zotero.Item, error) {
+	if source.Reader == nil {
+		return nil, fmt.Errorf("file content is required")
+	}
+	content, err := readContent(source.Reader, source.Size, source.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attachment, err := l.readItem(attachmentKey)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attachment: %w", err)
+	}
+	if attachment.Data.ItemType != zotero.ItemTypeAttachment || !attachment.Data.LinkMode.HasFile() {
+		return nil, fmt.Errorf("item %s is not a stored file attachment", attachmentKey)
+	}
+	if source.Patch != nil && attachment.Data.MD5 == "" {
+		return nil, fmt.Errorf("attachment %s has no file to patch", attachmentKey)
+	}
+
+	mtime := source.MTime
+	if mtime.IsZero() {
+		mtime = time.Now()
+	}
+	changed, err := l.updateItemData(l.items.index[attachmentKey], func(data *zotero.ItemData) {
+		if source.Filename != "" {
+			data.Filename = source.Filename
+		}
+		data.MD5 = md5Hex(content)
+		data.MTime = mtime.UnixMilli()
+	}, l.version+1)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		l.version++
+	}
+	l.files[attachmentKey] = content
+	return l.readItem(attachmentKey)
+}
+
+// writeAll writes a batch of objects, incrementing the library version if any of them changed
+func (l *Library) writeAll(objs *objects, writes []write) *zotero.WriteResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	resp := objs.writeAll(writes, l.version+1)
+	if len(resp.Success) > 0 {
+		l.version++
+	}
+	return resp
+}
+
+// writeOne writes a single object as a PATCH (or with replace, a PUT) request does
+func (l *Library) writeOne(objs *objects, w write, replace bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existing, ok := objs.index[w.key]
+	switch {
+	case !ok:
+		return errNotFound()
+	case w.version == 0:
+		return &zotero.APIError{StatusCode: http.StatusPreconditionRequired, Message: "If-Unmodified-Since-Version not provided"}
+	case w.version != existing.version:
+		return errPreconditionFailed(objs.name, w.version, existing.version)
+	}
+
+	_, unchanged, failed := objs.put(w, replace, l.version+1)
+	if failed != nil {
+		return &zotero.APIError{StatusCode: failed.Code, Message: failed.Message}
+	}
+	if !unchanged {
+		l.version++
+	}
+	return nil
+}
+
+// writeItem writes a single item
+func (l *Library) writeItem(item *zotero.Item, replace bool) error {
+	if item == nil {
+		return fmt.Errorf("item cannot be nil")
+	}
+	writes, err := itemWrites([]zotero.Item{*item})
+	if err != nil {
+		return err
+	}
+	if writes[0].key == "" {
+		return fmt.Errorf("item key is required")
+	}
+	return l.writeOne(l.items, writes[0], replace)
+}
+
+// editTags changes the tags of an item at its current version
+func (l *Library) editTags(itemKey string, edit func([]zotero.Tag) []zotero.Tag) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.items.index[itemKey]
+	if !ok {
+		return fmt.Errorf("error fetching item: %w", errNotFound())
+	}
+	changed, err := l.updateItemData(o, func(data *zotero.ItemData) {
+		data.Tags = edit(data.Tags)
+	}, l.version+1)
+	if err != nil {
+		return err
+	}
+	if changed {
+		l.version++
+	}
+	return nil
+}
+
+// updateItemData applies update to the data of an item, giving it version if that changed
+// it. Callers hold l.mu.
+func (l *Library) updateItemData(o *object, update func(*zotero.ItemData), version int) (bool, error) {
+	var data zotero.ItemData
+	if err := o.decode(&data); err != nil {
+		return false, err
+	}
+	update(&data)
+	encoded, err := toData(data)
+	if err != nil {
+		return false, fmt.Errorf("error encoding item %s: %w", o.key, err)
+	}
+	_, unchanged, failed := l.items.put(write{key: o.key, version: o.version, data: encoded}, true, version)
+	if failed != nil {
+		return false, &zotero.APIError{StatusCode: failed.Code, Message: failed.Message}
+	}
+	return !unchanged, nil
+}
+
+// deleteItems deletes items with their child items and files. Callers hold l.mu.
+func (l *Library) deleteItems(itemKeys []string) {
+	keys := make(map[string]bool)
+	for _, key := range itemKeys {
+		if _, ok := l.items.index[key]; ok {
+			keys[key] = true
+		}
+	}
+	// Children of children are the annotations of child attachments
+	for range 2 {
+		for _, o := range l.items.list {
+			if parent, _ := o.data["parentItem"].(string); keys[parent] {
+				keys[o.key] = true
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	l.version++
+	l.items.remove(keys, l.version)
+	for key := range keys {
+		delete(l.files, key)
+	}
+}
+
+// deleteCollections deletes collections with their subcollections and removes the items in
+// them from them. Callers hold l.mu.
+func (l *Library) deleteCollections(collectionKeys []string) error {
+	keys := make(map[string]bool)
+	for _, key := range collectionKeys {
+		if _, ok := l.collections.index[key]; ok {
+			keys[key] = true
+		}
+	}
+	for added := true; added; {
+		added = false
+		for _, o := range l.collections.list {
+			if parent, _ := o.data["parentCollection"].(string); keys[parent] && !keys[o.key] {
+				keys[o.key] = true
+				added = true
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	l.version++
+	l.collections.remove(keys, l.version)
+	for _, o := range l.items.list {
+		if _, err := l.updateItemData(o, func(data *zotero.ItemData) {
+			data.Collections = slices.DeleteFunc(data.Collections, func(key string) bool { return keys[key] })
+		}, l.version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateItem checks that an item has a type and that its parent item and collections exist
+func (l *Library) validateItem(key string, data map[string]any) *zotero.FailedWrite {
+	if itemType, _ := data["itemType"].(string); itemType == "" {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "'itemType' property not provided"}
+	}
+	if parent, _ := data["parentItem"].(string); parent != "" {
+		if _, ok := l.items.index[parent]; !ok || parent == key {
+			return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("Parent item %s not found", parent)}
+		}
+	}
+	collections, _ := data["collections"].([]any)
+	for _, collection := range collections {
+		key, _ := collection.(string)
+		if _, ok := l.collections.index[key]; !ok {
+			return &zotero.FailedWrite{Code: http.StatusConflict, Message: fmt.Sprintf("Collection %s doesn't exist", key)}
+		}
+	}
+	return nil
+}
+
+// touchItem sets the dates of an item that is written
+func touchItem(data map[string]any, previous *object) {
+	if _, ok := data["dateAdded"]; !ok {
+		if previous != nil && previous.data["dateAdded"] != nil {
+			data["dateAdded"] = previous.data["dateAdded"]
+		} else {
+			data["dateAdded"] = now()
+		}
+	}
+	data["dateModified"] = now()
+}
+
+// validateCollection checks that a collection has a name and a parent that exists outside it
+func (l *Library) validateCollection(key string, data map[string]any) *zotero.FailedWrite {
+	if name, _ := data["name"].(string); name == "" {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "Collection name cannot be empty"}
+	}
+	for parent, _ := data["parentCollection"].(string); parent != ""; {
+		o, ok := l.collections.index[parent]
+		if !ok {
+			return &zotero.FailedWrite{Code: http.StatusConflict, Message: fmt.Sprintf("Parent collection %s doesn't exist", parent)}
+		}
+		if parent == key {
+			return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "Cannot move collection into itself or one of its subcollections"}
+		}
+		parent, _ = o.data["parentCollection"].(string)
+	}
+	return nil
+}
+
+// validateSearch checks that a saved search has a name and conditions
+func validateSearch(key string, data map[string]any) *zotero.FailedWrite {
+	if name, _ := data["name"].(string); name == "" {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "Search name cannot be empty"}
+	}
+	if conditions, _ := data["conditions"].([]any); len(conditions) == 0 {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "'conditions' cannot be empty"}
+	}
+	return nil
+}
+
+// itemWrites returns the writes of items, with the key and version of each taken from the item
+// or its data
+func itemWrites(items []zotero.Item) ([]write, error) {
+	writes := make([]write, len(items))
+	for i, item := range items {
+		data, err := toData(item.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling item %d: %w", i, err)
+		}
+		writes[i] = write{key: cmp.Or(item.Key, item.Data.Key), version: cmp.Or(item.Version, item.Data.Version), data: data}
+	}
+	return writes, nil
+}
+
+// collectionWrites returns the writes of collections
+func collectionWrites(collections []zotero.Collection) ([]write, error) {
+	writes := make([]write, len(collections))
+	for i, collection := range collections {
+		data, err := toData(collection.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling collection %d: %w", i, err)
+		}
+		writes[i] = write{key: cmp.Or(collection.Key, collection.Data.Key), version: cmp.Or(collection.Version, collection.Data.Version), data: data}
+	}
+	return writes, nil
+}
+
+// searchWrites returns the writes of saved searches
+func searchWrites(searches []zotero.Search) ([]write, error) {
+	writes := make([]write, len(searches))
+	for i, search := range searches {
+		data, err := toData(search.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling search %d: %w", i, err)
+		}
+		writes[i] = write{key: cmp.Or(search.Key, search.Data.Key), version: cmp.Or(search.Version, search.Data.Version), data: data}
+	}
+	return writes, nil
+}
+
+// readContent reads size bytes from r, reporting them to progress if it is set
+func readContent(r io.ReaderAt, size int64, progress zotero.ProgressFunc) ([]byte, error) {
+	content, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if int64(len(content)) != size {
+		return nil, fmt.Errorf("error reading file: read %d of %d bytes", len(content), size)
+	}
+	if progress != nil {
+		progress(size, size)
+	}
+	return content, nil
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
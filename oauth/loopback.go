@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrDenied is returned when the user declines the authorization
+var ErrDenied = errors.New("authorization was denied")
+
+// AuthorizeLoopback runs the whole flow for command-line tools. It listens for the callback on a
+// loopback port, calls open with the authorize URL (e.g. to launch a browser or print the URL) and
+// waits until the user approves or ctx is done. CallbackURL is ignored.
+func (c *Config) AuthorizeLoopback(ctx context.Context, perms Permissions, open func(authorizeURL string) error) (*Credentials, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error starting callback server: %w", err)
+	}
+
+	cfg := *c
+	cfg.CallbackURL = "http://" + listener.Addr().String() + "/callback"
+
+	token, err := cfg.RequestToken(ctx)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	type callback struct {
+		verifier string
+		err      error
+	}
+	results := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("oauth_token") != token.Token {
+			http.Error(w, "Unexpected OAuth token", http.StatusBadRequest)
+			return
+		}
+
+		result := callback{verifier: query.Get("oauth_verifier")}
+		if result.verifier == "" {
+			result.err = ErrDenied
+			fmt.Fprintln(w, "Authorization was denied. You can close this window.")
+		} else {
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := open(cfg.AuthorizeURL(token, perms)); err != nil {
+		return nil, fmt.Errorf("error opening authorize URL: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return cfg.AccessToken(ctx, token, result.verifier)
+	}
+}
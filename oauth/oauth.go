@@ -0,0 +1,278 @@
+// Package oauth implements Zotero's OAuth 1.0a flow for obtaining API keys on behalf of users.
+//
+// The flow has three steps: fetch a temporary request token, send the user to the authorize URL
+// where they choose the key's permissions, then exchange the request token and the verifier
+// returned to the callback URL for credentials. The access token secret is the API key.
+//
+//	cfg := &oauth.Config{ConsumerKey: "...", ConsumerSecret: "...", CallbackURL: "https://example.com/callback"}
+//	token, err := cfg.RequestToken(ctx)
+//	url := cfg.AuthorizeURL(token, oauth.Permissions{Library: true, Write: true})
+//	// ... redirect the user to url; the callback receives oauth_token and oauth_verifier
+//	creds, err := cfg.AccessToken(ctx, token, verifier)
+package oauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zotero's OAuth endpoints
+const (
+	DefaultRequestTokenURL = "https://www.zotero.org/oauth/request"
+	DefaultAuthorizeURL    = "https://www.zotero.org/oauth/authorize"
+	DefaultAccessTokenURL  = "https://www.zotero.org/oauth/access"
+)
+
+// Config holds the client credentials of a registered application and the endpoints to use
+type Config struct {
+	ConsumerKey    string
+	ConsumerSecret string
+	CallbackURL    string // Where Zotero redirects after authorization ("oob" for out-of-band)
+
+	// Endpoints default to Zotero's
+	RequestTokenEndpoint string
+	AuthorizeEndpoint    string
+	AccessTokenEndpoint  string
+
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+}
+
+// GroupAccess is the access a key grants to all of the user's groups
+type GroupAccess string
+
+const (
+	GroupAccessNone  GroupAccess = "none"
+	GroupAccessRead  GroupAccess = "read"
+	GroupAccessWrite GroupAccess = "write"
+)
+
+// Permissions are the key permissions suggested to the user on the authorize page
+type Permissions struct {
+	Name      string      // Key description shown to the user
+	Library   bool        // Read access to the user's library
+	Notes     bool        // Read access to notes
+	Write     bool        // Write access to the user's library
+	AllGroups GroupAccess // Access to all groups
+	Identity  bool        // Only identify the user, granting no library access
+}
+
+// RequestToken is the temporary token that starts an authorization
+type RequestToken struct {
+	Token  string
+	Secret string
+}
+
+// Credentials are the result of a completed authorization
+type Credentials struct {
+	APIKey   string `json:"apiKey"`
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+}
+
+// RequestToken obtains a temporary request token
+func (c *Config) RequestToken(ctx context.Context) (*RequestToken, error) {
+	callback := c.CallbackURL
+	if callback == "" {
+		callback = "oob"
+	}
+
+	values, err := c.post(ctx, c.endpoint(c.RequestTokenEndpoint, DefaultRequestTokenURL), "", map[string]string{
+		"oauth_callback": callback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token: %w", err)
+	}
+
+	token := &RequestToken{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}
+	if token.Token == "" {
+		return nil, fmt.Errorf("error requesting token: response has no oauth_token")
+	}
+	return token, nil
+}
+
+// AuthorizeURL returns the page where the user approves the request token with the given permissions
+func (c *Config) AuthorizeURL(token *RequestToken, perms Permissions) string {
+	values := url.Values{"oauth_token": {token.Token}}
+	if perms.Name != "" {
+		values.Set("name", perms.Name)
+	}
+	if perms.Identity {
+		values.Set("identity", "1")
+	} else {
+		values.Set("library_access", boolParam(perms.Library))
+		values.Set("notes_access", boolParam(perms.Notes))
+		values.Set("write_access", boolParam(perms.Write))
+		if perms.AllGroups != "" {
+			values.Set("all_groups", string(perms.AllGroups))
+		}
+	}
+
+	base := c.endpoint(c.AuthorizeEndpoint, DefaultAuthorizeURL)
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + values.Encode()
+}
+
+// AccessToken exchanges an approved request token and the verifier passed to the callback for credentials
+func (c *Config) AccessToken(ctx context.Context, token *RequestToken, verifier string) (*Credentials, error) {
+	values, err := c.post(ctx, c.endpoint(c.AccessTokenEndpoint, DefaultAccessTokenURL), token.Secret, map[string]string{
+		"oauth_token":    token.Token,
+		"oauth_verifier": verifier,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging token: %w", err)
+	}
+
+	creds := &Credentials{
+		APIKey:   values.Get("oauth_token_secret"),
+		UserID:   values.Get("userID"),
+		Username: values.Get("username"),
+	}
+	if creds.APIKey == "" {
+		return nil, fmt.Errorf("error exchanging token: response has no oauth_token_secret")
+	}
+	return creds, nil
+}
+
+// post sends a signed POST request and parses the form-encoded response
+func (c *Config) post(ctx context.Context, endpoint, tokenSecret string, extra map[string]string) (url.Values, error) {
+	params := map[string]string{
+		"oauth_consumer_key":     c.ConsumerKey,
+		"oauth_nonce":            nonce(),
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	params["oauth_signature"] = signature(http.MethodPost, endpoint, params, c.ConsumerSecret, tokenSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", authorizationHeader(params))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OAuth error: %s (status %d)", strings.TrimSpace(string(body)), resp.StatusCode)
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return values, nil
+}
+
+func (c *Config) endpoint(configured, fallback string) string {
+	if configured != "" {
+		return configured
+	}
+	return fallback
+}
+
+// signature computes the HMAC-SHA1 signature of a request (RFC 5849 section 3.4).
+// params holds the oauth_* parameters and any query or form parameters.
+func signature(method, endpoint string, params map[string]string, consumerSecret, tokenSecret string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	all := make(map[string]string, len(params))
+	for k, v := range params {
+		all[k] = v
+	}
+	for k, vs := range u.Query() {
+		if len(vs) > 0 {
+			all[k] = vs[0]
+		}
+	}
+	delete(all, "oauth_signature")
+
+	pairs := make([]string, 0, len(all))
+	for k, v := range all {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(v))
+	}
+	slices.Sort(pairs)
+
+	baseURL := strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + u.EscapedPath()
+	base := strings.ToUpper(method) + "&" + percentEncode(baseURL) + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(percentEncode(consumerSecret)+"&"+percentEncode(tokenSecret)))
+	mac.Write([]byte(base))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// authorizationHeader formats OAuth parameters as an Authorization header
+func authorizationHeader(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf(`%s="%s"`, percentEncode(k), percentEncode(params[k]))
+	}
+	return "OAuth " + strings.Join(parts, ", ")
+}
+
+// percentEncode encodes a string as RFC 3986 requires, leaving only unreserved characters
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '.' || ch == '_' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// nonce returns a random string for the oauth_nonce parameter
+func nonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
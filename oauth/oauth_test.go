@@ -0,0 +1,246 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	// Example from RFC 5849 section 1.2
+	params := map[string]string{
+		"oauth_consumer_key":     "dpf43f3p2l4k3l03",
+		"oauth_token":            "nnch734d00sl2jdk",
+		"oauth_nonce":            "kllo9940pd9333jh",
+		"oauth_timestamp":        "1191242096",
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_version":          "1.0",
+	}
+	got := signature(http.MethodGet, "http://photos.example.net/photos?file=vacation.jpg&size=original", params, "kd94hf93k423kf44", "pfkkdhi9sl3r4s00")
+	if got != "tR3+Ty81lMeYAr/Fid0kMTYa/WM=" {
+		t.Errorf("signature() = %s, want tR3+Ty81lMeYAr/Fid0kMTYa/WM=", got)
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	tests := map[string]string{
+		"abc-._~":     "abc-._~",
+		"a b+c":       "a%20b%2Bc",
+		"http://x/?y": "http%3A%2F%2Fx%2F%3Fy",
+		"é":           "%C3%A9",
+	}
+	for in, want := range tests {
+		if got := percentEncode(in); got != want {
+			t.Errorf("percentEncode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// standInServer imitates zotero.org's OAuth endpoints. It checks request signatures and
+// approves authorizations immediately unless deny is set.
+type standInServer struct {
+	t        *testing.T
+	deny     bool
+	callback string
+}
+
+const (
+	testConsumerKey    = "consumer-key"
+	testConsumerSecret = "consumer-secret"
+	testRequestToken   = "request-token"
+	testRequestSecret  = "request-secret"
+	testVerifier       = "verifier-123"
+)
+
+func (s *standInServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/oauth/request":
+		params := s.verify(r, "")
+		if params == nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		s.callback = params["oauth_callback"]
+		w.Write([]byte("oauth_token=" + testRequestToken + "&oauth_token_secret=" + testRequestSecret + "&oauth_callback_confirmed=true"))
+
+	case "/oauth/authorize":
+		if r.URL.Query().Get("oauth_token") != testRequestToken {
+			http.Error(w, "unknown token", http.StatusBadRequest)
+			return
+		}
+		target := s.callback + "?oauth_token=" + testRequestToken
+		if !s.deny {
+			target += "&oauth_verifier=" + testVerifier
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+
+	case "/oauth/access":
+		params := s.verify(r, testRequestSecret)
+		if params == nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if params["oauth_token"] != testRequestToken || params["oauth_verifier"] != testVerifier {
+			http.Error(w, "invalid verifier", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("oauth_token=api-key&oauth_token_secret=api-key&userID=12345&username=testuser"))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// verify parses the Authorization header and checks its signature, returning the OAuth parameters
+func (s *standInServer) verify(r *http.Request, tokenSecret string) map[string]string {
+	header, ok := strings.CutPrefix(r.Header.Get("Authorization"), "OAuth ")
+	if !ok {
+		s.t.Errorf("%s: missing OAuth Authorization header", r.URL.Path)
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		value, err := url.PathUnescape(strings.Trim(v, `"`))
+		if err != nil {
+			s.t.Errorf("invalid header parameter %q", part)
+			return nil
+		}
+		params[k] = value
+	}
+
+	if params["oauth_consumer_key"] != testConsumerKey {
+		s.t.Errorf("oauth_consumer_key = %q", params["oauth_consumer_key"])
+		return nil
+	}
+	endpoint := "http://" + r.Host + r.URL.Path
+	if want := signature(r.Method, endpoint, params, testConsumerSecret, tokenSecret); params["oauth_signature"] != want {
+		s.t.Errorf("%s: signature = %q, want %q", r.URL.Path, params["oauth_signature"], want)
+		return nil
+	}
+	return params
+}
+
+func newStandInConfig(t *testing.T) (*httptest.Server, *standInServer, *Config) {
+	standIn := &standInServer{t: t}
+	server := httptest.NewServer(standIn)
+	cfg := &Config{
+		ConsumerKey:          testConsumerKey,
+		ConsumerSecret:       testConsumerSecret,
+		RequestTokenEndpoint: server.URL + "/oauth/request",
+		AuthorizeEndpoint:    server.URL + "/oauth/authorize",
+		AccessTokenEndpoint:  server.URL + "/oauth/access",
+	}
+	return server, standIn, cfg
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	cfg := &Config{}
+	token := &RequestToken{Token: "abc"}
+
+	got, err := url.Parse(cfg.AuthorizeURL(token, Permissions{Name: "CLI", Library: true, Write: true, AllGroups: GroupAccessRead}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Host != "www.zotero.org" {
+		t.Errorf("host = %s, want www.zotero.org", got.Host)
+	}
+	want := url.Values{
+		"oauth_token":    {"abc"},
+		"name":           {"CLI"},
+		"library_access": {"1"},
+		"notes_access":   {"0"},
+		"write_access":   {"1"},
+		"all_groups":     {"read"},
+	}
+	if got.Query().Encode() != want.Encode() {
+		t.Errorf("query = %s, want %s", got.Query().Encode(), want.Encode())
+	}
+
+	identity, _ := url.Parse(cfg.AuthorizeURL(token, Permissions{Identity: true, Write: true}))
+	if identity.Query().Get("identity") != "1" || identity.Query().Has("write_access") {
+		t.Errorf("identity query = %s", identity.RawQuery)
+	}
+}
+
+func TestAuthorizeFlow(t *testing.T) {
+	server, _, cfg := newStandInConfig(t)
+	defer server.Close()
+	cfg.CallbackURL = "https://example.com/callback"
+	ctx := context.Background()
+
+	token, err := cfg.RequestToken(ctx)
+	if err != nil {
+		t.Fatalf("RequestToken() error = %v", err)
+	}
+	if token.Token != testRequestToken || token.Secret != testRequestSecret {
+		t.Errorf("RequestToken() = %+v", token)
+	}
+
+	if _, err := cfg.AccessToken(ctx, token, "wrong"); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("AccessToken() with wrong verifier error = %v, want 401", err)
+	}
+
+	creds, err := cfg.AccessToken(ctx, token, testVerifier)
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+	want := Credentials{APIKey: "api-key", UserID: "12345", Username: "testuser"}
+	if *creds != want {
+		t.Errorf("AccessToken() = %+v, want %+v", *creds, want)
+	}
+}
+
+func TestAuthorizeLoopback(t *testing.T) {
+	server, standIn, cfg := newStandInConfig(t)
+	defer server.Close()
+
+	// The browser follows the authorize page's redirect to the loopback callback
+	browse := func(authorizeURL string) error {
+		go func() {
+			resp, err := http.Get(authorizeURL)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	creds, err := cfg.AuthorizeLoopback(context.Background(), Permissions{Library: true}, browse)
+	if err != nil {
+		t.Fatalf("AuthorizeLoopback() error = %v", err)
+	}
+	if creds.APIKey != "api-key" || creds.UserID != "12345" {
+		t.Errorf("AuthorizeLoopback() = %+v", creds)
+	}
+	if !strings.HasPrefix(standIn.callback, "http://127.0.0.1:") {
+		t.Errorf("callback = %q, want loopback address", standIn.callback)
+	}
+	if cfg.CallbackURL != "" {
+		t.Error("AuthorizeLoopback() modified the config")
+	}
+
+	standIn.deny = true
+	if _, err := cfg.AuthorizeLoopback(context.Background(), Permissions{Library: true}, browse); !errors.Is(err, ErrDenied) {
+		t.Errorf("AuthorizeLoopback() error = %v, want ErrDenied", err)
+	}
+}
+
+func TestAuthorizeLoopbackCancel(t *testing.T) {
+	server, _, cfg := newStandInConfig(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := cfg.AuthorizeLoopback(ctx, Permissions{Library: true}, func(string) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("AuthorizeLoopback() error = %v, want context.Canceled", err)
+	}
+}
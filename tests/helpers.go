@@ -1,11 +1,18 @@
 package tests
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Epistemic-Technology/zotero/zotero"
+	"github.com/Epistemic-Technology/zotero/zoterotest"
 )
 
 // TestConfig holds configuration for integration tests
@@ -24,8 +31,8 @@ func getTestConfig() *TestConfig {
 	libraryType := os.Getenv("ZOTERO_LIBRARY_TYPE")
 	baseURL := os.Getenv("TEST_API_URL")
 
-	// Required fields
-	if apiKey == "" || libraryID == "" {
+	// Required fields; the local API needs no API key
+	if (apiKey == "" && !isLocalAPI()) || libraryID == "" {
 		return nil
 	}
 
@@ -57,30 +64,191 @@ func getTestConfig() *TestConfig {
 	}
 }
 
-// newTestClient creates a new Zotero client configured for integration testing.
-// Returns nil if credentials are not available.
-func newTestClient() *zotero.Client {
+// cassettes is the -cassettes flag: "record" records the interactions of each test with the
+// API configured by the environment to testdata/cassettes/<test>.json, and "replay" runs the
+// tests offline from those cassettes. Without it, tests talk to the API or the fake server.
+var cassettes = flag.String("cassettes", "", "record or replay the API interactions of each test in testdata/cassettes")
+
+// newTestClient creates a new Zotero client configured for integration testing, with opts
+// applied last. Without credentials, the client talks to a fake server (see fakeServer), and
+// the test fails if that cannot be started.
+func newTestClient(t *testing.T, opts ...zotero.ClientOption) *zotero.Client {
+	t.Helper()
+
 	config := getTestConfig()
 	if config == nil {
-		return nil
+		srv, err := fakeServer()
+		if err != nil {
+			t.Fatalf("starting fake server: %v", err)
+		}
+		return srv.Client(opts...)
+	}
+
+	baseURL := config.BaseURL
+	var clientOpts []zotero.ClientOption
+	if isLocalAPI() {
+		// The local API is served under /api by the desktop app
+		baseURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api") + "/api"
+		clientOpts = append(clientOpts, zotero.WithLocalAPI())
 	}
 
-	return zotero.NewClient(
-		config.LibraryID,
-		config.LibraryType,
+	clientOpts = append(clientOpts,
 		zotero.WithAPIKey(config.APIKey),
-		zotero.WithBaseURL(config.BaseURL),
+		zotero.WithBaseURL(baseURL),
 		zotero.WithRateLimit(0), // Disable rate limiting for faster tests
 	)
+	return zotero.NewClient(config.LibraryID, config.LibraryType, append(clientOpts, opts...)...)
 }
 
-// skipIfNoCredentials skips the test if integration test credentials are not available
+// skipIfNoCredentials returns the client for an integration test. Without credentials the
+// test runs against the fake server instead of being skipped.
 func skipIfNoCredentials(t *testing.T) *zotero.Client {
 	t.Helper()
 
-	client := newTestClient()
-	if client == nil {
-		t.Skip("Skipping integration test: ZOTERO_API_KEY and ZOTERO_LIBRARY_ID not set")
+	if *cassettes != "" {
+		return newCassetteClient(t)
+	}
+	return newTestClient(t)
+}
+
+// newCassetteClient returns a client recording the test to its cassette or replaying it,
+// according to the -cassettes flag. Replayed tests use the library they were recorded with.
+func newCassetteClient(t *testing.T) *zotero.Client {
+	t.Helper()
+
+	mode, err := zoterotest.ParseMode(*cassettes)
+	if err != nil {
+		t.Fatalf("-cassettes: %v", err)
+	}
+	path := filepath.Join("testdata", "cassettes", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+
+	switch mode {
+	case zoterotest.ModeRecord:
+		if getTestConfig() == nil {
+			t.Skip("Skipping recording: ZOTERO_API_KEY and ZOTERO_LIBRARY_ID not set")
+		}
+		if isLocalAPI() {
+			t.Skip("Skipping recording: cassettes are recorded against the Web API")
+		}
+	case zoterotest.ModeReplay:
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("no cassette at %s: record one with -cassettes=record", path)
+		}
+	}
+
+	rec, err := zoterotest.NewRecorder(path, mode)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	if mode == zoterotest.ModeRecord {
+		// A failed test is not recorded, so that its replay does not fail the same way
+		t.Cleanup(func() {
+			if t.Failed() {
+				return
+			}
+			if err := rec.Save(); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		})
+		return newTestClient(t, zotero.WithHTTPClient(rec.Client()))
+	}
+
+	libraryID, libraryType := recordedLibrary(rec)
+	return zotero.NewClient(libraryID, libraryType,
+		zotero.WithAPIKey(zoterotest.Redacted),
+		zotero.WithRateLimit(0),
+		zotero.WithHTTPClient(rec.Client()),
+	)
+}
+
+// recordedLibrary returns the library of the requests in a cassette
+func recordedLibrary(rec *zoterotest.Recorder) (string, zotero.LibraryType) {
+	for _, interaction := range rec.Interactions() {
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		if len(parts) < 2 {
+			continue
+		}
+		switch libraryType := zotero.LibraryType(parts[0]); libraryType {
+		case zotero.LibraryTypeUser, zotero.LibraryTypeGroup:
+			return parts[1], libraryType
+		}
+	}
+	return "0", zotero.LibraryTypeUser
+}
+
+// fakeServer returns the zoterotest server the integration tests run against when no
+// credentials are set, started on first use and shared by every test. Its user library is
+// seeded so that the read tests find items, children, collections and tags to work with.
+// A seeding error is returned to every caller.
+var fakeServer = sync.OnceValues(func() (*zoterotest.Server, error) {
+	srv := zoterotest.NewServer()
+	if err := seedLibrary(context.Background(), srv.UserLibrary()); err != nil {
+		srv.Close()
+		return nil, fmt.Errorf("seeding fake library: %w", err)
+	}
+	return srv, nil
+})
+
+// seedLibrary fills a library with a collection, items in and out of it, tags and a child note
+func seedLibrary(ctx context.Context, lib zotero.LibraryStore) error {
+	collections, err := lib.CreateCollections(ctx, []zotero.Collection{{Data: zotero.CollectionData{Name: "Reading"}}})
+	if err != nil {
+		return err
+	}
+	collectionKey := collections.Success["0"].(string)
+
+	items := []zotero.Item{
+		{Data: zotero.ItemData{
+			ItemType:    zotero.ItemTypeBook,
+			Title:       "The Structure of Scientific Revolutions",
+			Creators:    []zotero.Creator{{CreatorType: "author", FirstName: "Thomas", LastName: "Kuhn"}},
+			Tags:        []zotero.Tag{{Tag: "philosophy"}, {Tag: "science"}},
+			Collections: []string{collectionKey},
+			Extra:       map[string]any{"date": "1962"},
+		}},
+		{Data: zotero.ItemData{
+			ItemType: zotero.ItemTypeJournalArticle,
+			Title:    "Computing Machinery and Intelligence",
+			Creators: []zotero.Creator{{CreatorType: "author", FirstName: "Alan", LastName: "Turing"}},
+			Tags:     []zotero.Tag{{Tag: "computing"}},
+			Extra:    map[string]any{"publicationTitle": "Mind", "date": "1950"},
+		}},
+	}
+	for i := range 8 {
+		items = append(items, zotero.Item{Data: zotero.ItemData{
+			ItemType: zotero.ItemTypeBook,
+			Title:    fmt.Sprintf("Seeded Book %d", i+1),
+		}})
+	}
+	created, err := lib.CreateItems(ctx, items)
+	if err != nil {
+		return err
+	}
+	if len(created.Failed) > 0 {
+		return fmt.Errorf("creating items: %+v", created.Failed)
+	}
+
+	note := zotero.Item{Data: zotero.ItemData{
+		ItemType:   zotero.ItemTypeNote,
+		Note:       "<p>Paradigm shifts</p>",
+		ParentItem: created.Success["0"].(string),
+	}}
+	_, err = lib.CreateItems(ctx, []zotero.Item{note})
+	return err
+}
+
+// skipIfReadOnly skips a write test if credentials are not available or the tests run
+// against the read-only local API
+func skipIfReadOnly(t *testing.T) *zotero.Client {
+	t.Helper()
+
+	client := skipIfNoCredentials(t)
+	if client.IsLocal() {
+		t.Skip("Skipping write test: the local API is read-only")
 	}
 
 	return client
@@ -0,0 +1,286 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Annotation types as used in the annotationType field
+const (
+	AnnotationTypeHighlight = "highlight"
+	AnnotationTypeUnderline = "underline"
+	AnnotationTypeNote      = "note"
+	AnnotationTypeImage     = "image"
+	AnnotationTypeInk       = "ink"
+	AnnotationTypeText      = "text"
+)
+
+// AnnotationRect is a rectangle on a PDF page as [x1, y1, x2, y2] in PDF points
+type AnnotationRect [4]float64
+
+// AnnotationPosition describes where an annotation is located in its attachment.
+// PDF annotations use PageIndex with Rects (or Paths for ink annotations);
+// EPUB and snapshot annotations use a selector in Type/Value (e.g., an EPUB CFI).
+type AnnotationPosition struct {
+	PageIndex    int              `json:"pageIndex"`
+	Rects        []AnnotationRect `json:"rects,omitempty"`
+	Paths        [][]float64      `json:"paths,omitempty"`
+	Width        float64          `json:"width,omitempty"`
+	RotateOffset float64          `json:"rotateOffset,omitempty"`
+	FontSize     float64          `json:"fontSize,omitempty"`
+
+	// Selector-based positions (EPUB and HTML snapshots)
+	Type       string `json:"type,omitempty"`
+	ConformsTo string `json:"conformsTo,omitempty"`
+	Value      string `json:"value,omitempty"`
+}
+
+// IsSelector reports whether the position is a selector (EPUB/snapshot) rather than a PDF page position
+func (p AnnotationPosition) IsSelector() bool {
+	return p.Type != ""
+}
+
+// Annotation represents a PDF/EPUB/snapshot annotation item.
+// ParentItem is the key of the attachment the annotation belongs to.
+type Annotation struct {
+	Key          string
+	Version      int
+	ParentItem   string
+	Type         string
+	Text         string
+	Comment      string
+	Color        string
+	PageLabel    string
+	SortIndex    string
+	Position     AnnotationPosition
+	Tags         []Tag
+	DateAdded    string
+	DateModified string
+}
+
+// AnnotationFromItem converts an annotation item into an Annotation.
+// Returns an error if the item is not an annotation or its position cannot be parsed.
+func AnnotationFromItem(item *Item) (*Annotation, error) {
+	if item == nil {
+		return nil, fmt.Errorf("item cannot be nil")
+	}
+	if item.Data.ItemType != ItemTypeAnnotation {
+		return nil, fmt.Errorf("item %s is not an annotation (itemType %q)", item.Key, item.Data.ItemType)
+	}
+
+	key := item.Key
+	if key == "" {
+		key = item.Data.Key
+	}
+	version := item.Version
+	if version == 0 {
+		version = item.Data.Version
+	}
+
+	annotation := &Annotation{
+		Key:          key,
+		Version:      version,
+		ParentItem:   item.Data.ParentItem,
+		Type:         item.Data.AnnotationType,
+		Text:         item.Data.AnnotationText,
+		Comment:      item.Data.AnnotationComment,
+		Color:        item.Data.AnnotationColor,
+		PageLabel:    item.Data.AnnotationPageLabel,
+		SortIndex:    item.Data.AnnotationSortIndex,
+		Tags:         item.Data.Tags,
+		DateAdded:    item.Data.DateAdded,
+		DateModified: item.Data.DateModified,
+	}
+
+	if item.Data.AnnotationPosition != "" {
+		if err := json.Unmarshal([]byte(item.Data.AnnotationPosition), &annotation.Position); err != nil {
+			return nil, fmt.Errorf("error parsing annotation position for %s: %w", key, err)
+		}
+	}
+
+	return annotation, nil
+}
+
+// Item converts the annotation into an Item suitable for CreateItems or UpdateItems
+func (a *Annotation) Item() (Item, error) {
+	position, err := json.Marshal(a.Position)
+	if err != nil {
+		return Item{}, fmt.Errorf("error marshaling annotation position: %w", err)
+	}
+
+	return Item{
+		Key:     a.Key,
+		Version: a.Version,
+		Data: ItemData{
+			Key:                 a.Key,
+			Version:             a.Version,
+			ItemType:            ItemTypeAnnotation,
+			ParentItem:          a.ParentItem,
+			AnnotationType:      a.Type,
+			AnnotationText:      a.Text,
+			AnnotationComment:   a.Comment,
+			AnnotationColor:     a.Color,
+			AnnotationPageLabel: a.PageLabel,
+			AnnotationSortIndex: a.SortIndex,
+			AnnotationPosition:  string(position),
+			Tags:                a.Tags,
+		},
+	}, nil
+}
+
+// PDFSortIndex builds the annotationSortIndex for a PDF annotation in Zotero's
+// "page|offset|top" format (e.g., "00002|000153|00412")
+func PDFSortIndex(pageIndex, offset int, top float64) string {
+	return fmt.Sprintf("%05d|%06d|%05d", pageIndex, offset, max(0, int(top)))
+}
+
+// Annotations retrieves all annotations of a specific attachment item, a page at a time.
+// Limit and Start in params are ignored.
+func (c *Client) Annotations(ctx context.Context, attachmentKey string, params *QueryParams) ([]Annotation, error) {
+	if attachmentKey == "" {
+		return nil, fmt.Errorf("attachment key is required")
+	}
+
+	annotationParams := QueryParams{}
+	if params != nil {
+		annotationParams = *params
+	}
+	annotationParams.ItemType = []string{ItemTypeAnnotation}
+
+	items, err := fetchAll(ctx, &annotationParams, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+		return c.Children(ctx, attachmentKey, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]Annotation, 0, len(items))
+	for i := range items {
+		annotation, err := AnnotationFromItem(&items[i])
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, *annotation)
+	}
+
+	return annotations, nil
+}
+
+// AnnotationGroup holds the annotations of one attachment together with its parent item
+type AnnotationGroup struct {
+	Parent      Item
+	Attachment  Item
+	Annotations []Annotation
+}
+
+// CollectionAnnotations retrieves the annotations of every attachment of the top-level
+// items in a collection, grouped by attachment. Attachments without annotations are omitted.
+func (c *Client) CollectionAnnotations(ctx context.Context, collectionKey string) ([]AnnotationGroup, error) {
+	items, err := fetchAll(ctx, nil, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+		return c.CollectionItemsTop(ctx, collectionKey, params)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching collection items: %w", err)
+	}
+
+	var groups []AnnotationGroup
+	for _, item := range items {
+		// Standalone attachments are their own source
+		attachments := []Item{item}
+		if item.Data.ItemType != ItemTypeAttachment {
+			attachments, err = fetchAll(ctx, &QueryParams{ItemType: []string{ItemTypeAttachment}}, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+				return c.Children(ctx, item.Key, params)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error fetching attachments of %s: %w", item.Key, err)
+			}
+		}
+
+		for _, attachment := range attachments {
+			annotations, err := c.Annotations(ctx, attachment.Key, nil)
+			if err != nil {
+				return nil, fmt.Errorf("error fetching annotations of %s: %w", attachment.Key, err)
+			}
+			if len(annotations) == 0 {
+				continue
+			}
+			groups = append(groups, AnnotationGroup{
+				Parent:      item,
+				Attachment:  attachment,
+				Annotations: annotations,
+			})
+		}
+	}
+
+	return groups, nil
+}
+
+// AnnotationsMarkdown renders annotation groups as Markdown, with one section per source
+// and annotations in reading order. Highlights and underlines are rendered as quotes
+// followed by the comment and page label.
+func AnnotationsMarkdown(groups []AnnotationGroup) string {
+	var b strings.Builder
+
+	for i, group := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		title := group.Parent.Data.Title
+		if title == "" {
+			title = group.Attachment.Data.Title
+		}
+		fmt.Fprintf(&b, "## %s\n", title)
+		if byline := annotationByline(group.Parent.Meta); byline != "" {
+			fmt.Fprintf(&b, "\n%s\n", byline)
+		}
+		if group.Attachment.Key != group.Parent.Key && group.Attachment.Data.Title != "" {
+			fmt.Fprintf(&b, "\nSource: %s\n", group.Attachment.Data.Title)
+		}
+
+		annotations := append([]Annotation(nil), group.Annotations...)
+		sort.SliceStable(annotations, func(i, j int) bool {
+			return annotations[i].SortIndex < annotations[j].SortIndex
+		})
+
+		for _, annotation := range annotations {
+			b.WriteString("\n")
+			if annotation.Text != "" {
+				for _, line := range strings.Split(strings.TrimSpace(annotation.Text), "\n") {
+					fmt.Fprintf(&b, "> %s\n", line)
+				}
+			} else if annotation.Type == AnnotationTypeImage || annotation.Type == AnnotationTypeInk {
+				fmt.Fprintf(&b, "*[%s annotation]*\n", annotation.Type)
+			}
+			if annotation.Comment != "" {
+				if annotation.Text != "" {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(&b, "%s\n", strings.TrimSpace(annotation.Comment))
+			}
+			if annotation.PageLabel != "" {
+				fmt.Fprintf(&b, "\n(p. %s)\n", annotation.PageLabel)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// annotationByline formats the creator summary and year of a source, e.g. "Doe (2023)"
+func annotationByline(meta Meta) string {
+	year := ""
+	if len(meta.ParsedDate) >= 4 {
+		year = meta.ParsedDate[:4]
+	}
+	switch {
+	case meta.CreatorSummary != "" && year != "":
+		return fmt.Sprintf("%s (%s)", meta.CreatorSummary, year)
+	case meta.CreatorSummary != "":
+		return meta.CreatorSummary
+	}
+	return year
+}
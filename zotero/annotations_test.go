@@ -0,0 +1,237 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAnnotationFromItem(t *testing.T) {
+	item := &Item{
+		Key:     "ANNO1234",
+		Version: 4,
+		Data: ItemData{
+			ItemType:            ItemTypeAnnotation,
+			ParentItem:          "ATTA1234",
+			AnnotationType:      AnnotationTypeHighlight,
+			AnnotationText:      "An important sentence.",
+			AnnotationComment:   "Check this",
+			AnnotationColor:     "#ffd400",
+			AnnotationPageLabel: "12",
+			AnnotationSortIndex: "00011|000120|00300",
+			AnnotationPosition:  `{"pageIndex":11,"rects":[[72.0,500.5,300.25,512.0],[72.0,488.0,150.0,499.5]]}`,
+		},
+	}
+
+	annotation, err := AnnotationFromItem(item)
+	if err != nil {
+		t.Fatalf("AnnotationFromItem() error = %v", err)
+	}
+	if annotation.Type != AnnotationTypeHighlight {
+		t.Errorf("Type = %v, want highlight", annotation.Type)
+	}
+	if annotation.ParentItem != "ATTA1234" {
+		t.Errorf("ParentItem = %v, want ATTA1234", annotation.ParentItem)
+	}
+	if annotation.Position.PageIndex != 11 {
+		t.Errorf("Position.PageIndex = %v, want 11", annotation.Position.PageIndex)
+	}
+	if len(annotation.Position.Rects) != 2 {
+		t.Fatalf("len(Position.Rects) = %v, want 2", len(annotation.Position.Rects))
+	}
+	if annotation.Position.Rects[0][2] != 300.25 {
+		t.Errorf("Rects[0][2] = %v, want 300.25", annotation.Position.Rects[0][2])
+	}
+	if annotation.Position.IsSelector() {
+		t.Error("IsSelector() = true for PDF position")
+	}
+
+	if _, err := AnnotationFromItem(&Item{Data: ItemData{ItemType: ItemTypeNote}}); err == nil {
+		t.Error("expected error for non-annotation item, got nil")
+	}
+
+	item.Data.AnnotationPosition = "not json"
+	if _, err := AnnotationFromItem(item); err == nil {
+		t.Error("expected error for invalid position, got nil")
+	}
+}
+
+func TestAnnotationEPUBPosition(t *testing.T) {
+	item := &Item{
+		Key: "ANNO5678",
+		Data: ItemData{
+			ItemType:           ItemTypeAnnotation,
+			AnnotationType:     AnnotationTypeHighlight,
+			AnnotationPosition: `{"type":"FragmentSelector","conformsTo":"http://www.idpf.org/epub/linking/cfi/epub-cfi.html","value":"epubcfi(/6/4!/4/2/1:0,/1:25)"}`,
+		},
+	}
+
+	annotation, err := AnnotationFromItem(item)
+	if err != nil {
+		t.Fatalf("AnnotationFromItem() error = %v", err)
+	}
+	if !annotation.Position.IsSelector() {
+		t.Error("IsSelector() = false for EPUB position")
+	}
+	if annotation.Position.Value != "epubcfi(/6/4!/4/2/1:0,/1:25)" {
+		t.Errorf("Position.Value = %v", annotation.Position.Value)
+	}
+}
+
+func TestAnnotationItemRoundTrip(t *testing.T) {
+	annotation := Annotation{
+		ParentItem: "ATTA1234",
+		Type:       AnnotationTypeHighlight,
+		Text:       "Quoted text",
+		Color:      "#ff6666",
+		PageLabel:  "3",
+		SortIndex:  PDFSortIndex(2, 153, 412.7),
+		Position: AnnotationPosition{
+			PageIndex: 2,
+			Rects:     []AnnotationRect{{10, 20, 30, 40}},
+		},
+	}
+
+	item, err := annotation.Item()
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	if item.Data.ItemType != ItemTypeAnnotation {
+		t.Errorf("ItemType = %v, want annotation", item.Data.ItemType)
+	}
+	if item.Data.AnnotationSortIndex != "00002|000153|00412" {
+		t.Errorf("AnnotationSortIndex = %v, want 00002|000153|00412", item.Data.AnnotationSortIndex)
+	}
+
+	parsed, err := AnnotationFromItem(&item)
+	if err != nil {
+		t.Fatalf("AnnotationFromItem() error = %v", err)
+	}
+	if parsed.Position.PageIndex != 2 || parsed.Position.Rects[0] != (AnnotationRect{10, 20, 30, 40}) {
+		t.Errorf("Position = %+v, want page 2 with rect [10 20 30 40]", parsed.Position)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/12345/items/ATTA1234/children" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("itemType") != ItemTypeAnnotation {
+			t.Errorf("itemType = %v, want annotation", r.URL.Query().Get("itemType"))
+		}
+		json.NewEncoder(w).Encode([]Item{
+			{Key: "ANNO0001", Data: ItemData{ItemType: ItemTypeAnnotation, AnnotationType: AnnotationTypeHighlight, AnnotationPosition: `{"pageIndex":0}`}},
+		})
+	})
+	defer server.Close()
+
+	annotations, err := client.Annotations(context.Background(), "ATTA1234", nil)
+	if err != nil {
+		t.Fatalf("Annotations() error = %v", err)
+	}
+	if len(annotations) != 1 {
+		t.Fatalf("len(annotations) = %v, want 1", len(annotations))
+	}
+	if annotations[0].Key != "ANNO0001" {
+		t.Errorf("annotations[0].Key = %v, want ANNO0001", annotations[0].Key)
+	}
+}
+
+func TestCollectionAnnotations(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/12345/collections/COLL1234/items/top":
+			json.NewEncoder(w).Encode([]Item{
+				{Key: "BOOK0001", Data: ItemData{ItemType: ItemTypeBook, Title: "A Book"}, Meta: Meta{CreatorSummary: "Doe", ParsedDate: "2021-05-01"}},
+				{Key: "BOOK0002", Data: ItemData{ItemType: ItemTypeBook, Title: "Unannotated"}},
+			})
+		case "/users/12345/items/BOOK0001/children":
+			json.NewEncoder(w).Encode([]Item{
+				{Key: "ATTA0001", Data: ItemData{ItemType: ItemTypeAttachment, Title: "Full Text PDF"}},
+			})
+		case "/users/12345/items/BOOK0002/children":
+			json.NewEncoder(w).Encode([]Item{
+				{Key: "ATTA0002", Data: ItemData{ItemType: ItemTypeAttachment, Title: "Full Text PDF"}},
+			})
+		case "/users/12345/items/ATTA0001/children":
+			json.NewEncoder(w).Encode([]Item{
+				{Key: "ANNO0002", Data: ItemData{ItemType: ItemTypeAnnotation, AnnotationType: AnnotationTypeHighlight, AnnotationText: "Second", AnnotationSortIndex: "00003|000000|00100", AnnotationPageLabel: "4"}},
+				{Key: "ANNO0001", Data: ItemData{ItemType: ItemTypeAnnotation, AnnotationType: AnnotationTypeHighlight, AnnotationText: "First", AnnotationComment: "Key claim", AnnotationSortIndex: "00001|000000|00100", AnnotationPageLabel: "2"}},
+			})
+		case "/users/12345/items/ATTA0002/children":
+			w.Write([]byte("[]"))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	groups, err := client.CollectionAnnotations(context.Background(), "COLL1234")
+	if err != nil {
+		t.Fatalf("CollectionAnnotations() error = %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("len(groups) = %v, want 1", len(groups))
+	}
+	if len(groups[0].Annotations) != 2 {
+		t.Errorf("len(Annotations) = %v, want 2", len(groups[0].Annotations))
+	}
+
+	want := "## A Book\n\nDoe (2021)\n\nSource: Full Text PDF\n\n> First\n\nKey claim\n\n(p. 2)\n\n> Second\n\n(p. 4)\n"
+	if got := AnnotationsMarkdown(groups); got != want {
+		t.Errorf("AnnotationsMarkdown() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCollectionAnnotationsPaging(t *testing.T) {
+	// page returns the items from start of count items, at most 100 of them
+	page := func(w http.ResponseWriter, r *http.Request, count int, item func(i int) Item) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		items := []Item{}
+		for i := start; i < count && i < start+100; i++ {
+			items = append(items, item(i))
+		}
+		json.NewEncoder(w).Encode(items)
+	}
+
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/users/12345/collections/COLL1234/items/top":
+			json.NewEncoder(w).Encode([]Item{{Key: "BOOK0001", Data: ItemData{ItemType: ItemTypeBook, Title: "A Book"}}})
+		case r.URL.Path == "/users/12345/items/BOOK0001/children":
+			page(w, r, 101, func(i int) Item {
+				return Item{Key: fmt.Sprintf("ATTA%04d", i), Data: ItemData{ItemType: ItemTypeAttachment}}
+			})
+		case r.URL.Path == "/users/12345/items/ATTA0100/children":
+			page(w, r, 130, func(i int) Item {
+				return Item{Key: fmt.Sprintf("ANNO%04d", i), Data: ItemData{ItemType: ItemTypeAnnotation, AnnotationType: AnnotationTypeHighlight}}
+			})
+		case strings.HasPrefix(r.URL.Path, "/users/12345/items/ATTA"):
+			w.Write([]byte("[]"))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	groups, err := client.CollectionAnnotations(context.Background(), "COLL1234")
+	if err != nil {
+		t.Fatalf("CollectionAnnotations() error = %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("len(groups) = %v, want 1", len(groups))
+	}
+	if groups[0].Attachment.Key != "ATTA0100" {
+		t.Errorf("Attachment.Key = %v, want ATTA0100 from the second page", groups[0].Attachment.Key)
+	}
+	if len(groups[0].Annotations) != 130 {
+		t.Errorf("len(Annotations) = %v, want 130", len(groups[0].Annotations))
+	}
+}
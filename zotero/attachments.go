@@ -0,0 +1,139 @@
+package zotero
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LinkMode describes how an attachment's content is stored
+type LinkMode string
+
+const (
+	// LinkModeImportedFile is a file stored in Zotero storage
+	LinkModeImportedFile LinkMode = "imported_file"
+	// LinkModeImportedURL is a web page snapshot stored in Zotero storage
+	LinkModeImportedURL LinkMode = "imported_url"
+	// LinkModeLinkedFile is a link to a file on the local filesystem (not synced)
+	LinkModeLinkedFile LinkMode = "linked_file"
+	// LinkModeLinkedURL is a link to a URL
+	LinkModeLinkedURL LinkMode = "linked_url"
+	// LinkModeEmbeddedImage is an image embedded in a note
+	LinkModeEmbeddedImage LinkMode = "embedded_image"
+)
+
+// HasFile reports whether attachments with this link mode have a file in Zotero storage
+func (m LinkMode) HasFile() bool {
+	return m == LinkModeImportedFile || m == LinkModeImportedURL || m == LinkModeEmbeddedImage
+}
+
+// CreateLinkedURLAttachment creates an attachment that links to a URL.
+// parentItemKey: The key of the parent item (empty string for a standalone attachment)
+// title: Title of the attachment (if empty, the URL is used)
+func (c *Client) CreateLinkedURLAttachment(ctx context.Context, parentItemKey, url, title string) (*Item, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if title == "" {
+		title = url
+	}
+
+	attachment := Item{
+		Data: ItemData{
+			ItemType:   ItemTypeAttachment,
+			LinkMode:   LinkModeLinkedURL,
+			Title:      title,
+			URL:        url,
+			ParentItem: parentItemKey,
+		},
+	}
+
+	attachmentKey, err := c.createAttachmentItem(ctx, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Item(ctx, attachmentKey, nil)
+}
+
+// CreateLinkedFileAttachment creates an attachment that links to a file on the local filesystem.
+// The file is not uploaded; Zotero clients resolve the path on the local machine.
+// parentItemKey: The key of the parent item (empty string for a standalone attachment)
+// path: Absolute path to the file
+// contentType: MIME type of the file (e.g., "application/pdf")
+func (c *Client) CreateLinkedFileAttachment(ctx context.Context, parentItemKey, path, contentType string) (*Item, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving path: %w", err)
+	}
+
+	attachment := Item{
+		Data: ItemData{
+			ItemType:    ItemTypeAttachment,
+			LinkMode:    LinkModeLinkedFile,
+			Title:       filepath.Base(absPath),
+			ContentType: contentType,
+			Path:        absPath,
+			ParentItem:  parentItemKey,
+		},
+	}
+
+	attachmentKey, err := c.createAttachmentItem(ctx, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Item(ctx, attachmentKey, nil)
+}
+
+// CreateImportedURL creates a web page snapshot attachment and uploads its content.
+// parentItemKey: The key of the parent item (empty string for a standalone attachment)
+// url: The URL the snapshot was taken from
+// title: Title of the attachment (if empty, the URL is used)
+// snapshotPath: Path to the saved snapshot file
+// contentType: MIME type of the snapshot (if empty, "text/html")
+func (c *Client) CreateImportedURL(ctx context.Context, parentItemKey, url, title, snapshotPath, contentType string) (*Item, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if title == "" {
+		title = url
+	}
+	if contentType == "" {
+		contentType = "text/html"
+	}
+
+	file, err := os.Open(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	attachment := Item{
+		Data: ItemData{
+			ItemType:    ItemTypeAttachment,
+			LinkMode:    LinkModeImportedURL,
+			Title:       title,
+			URL:         url,
+			ContentType: contentType,
+			Filename:    filepath.Base(snapshotPath),
+			MTime:       info.ModTime().UnixMilli(),
+			ParentItem:  parentItemKey,
+		},
+	}
+	if contentType == "text/html" {
+		attachment.Data.Charset = "utf-8"
+	}
+
+	return c.createAndUploadAttachment(ctx, attachment, file, info.Size(), nil)
+}
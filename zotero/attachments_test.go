@@ -0,0 +1,177 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinkModeHasFile(t *testing.T) {
+	tests := []struct {
+		mode LinkMode
+		want bool
+	}{
+		{LinkModeImportedFile, true},
+		{LinkModeImportedURL, true},
+		{LinkModeEmbeddedImage, true},
+		{LinkModeLinkedFile, false},
+		{LinkModeLinkedURL, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.HasFile(); got != tt.want {
+			t.Errorf("%s.HasFile() = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+// attachmentCreateHandler returns a handler that records the created attachment data
+// and serves it back for the follow-up item fetch
+func attachmentCreateHandler(t *testing.T, created *map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users/12345/items":
+			var data []map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				t.Fatalf("invalid request body: %v", err)
+			}
+			*created = data[0]
+			w.Write([]byte(`{"success": {"0": "ATTA1234"}, "unchanged": {}, "failed": {}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/12345/items/ATTA1234":
+			body, _ := json.Marshal(*created)
+			w.Write([]byte(`{"key": "ATTA1234", "version": 1, "data": ` + string(body) + `}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestCreateLinkedURLAttachment(t *testing.T) {
+	var created map[string]any
+	server, client := setupMockServer(t, attachmentCreateHandler(t, &created))
+	defer server.Close()
+
+	item, err := client.CreateLinkedURLAttachment(context.Background(), "ABCD1234", "https://example.com/paper", "Publisher page")
+	if err != nil {
+		t.Fatalf("CreateLinkedURLAttachment() error = %v", err)
+	}
+
+	if created["linkMode"] != string(LinkModeLinkedURL) {
+		t.Errorf("linkMode = %v, want linked_url", created["linkMode"])
+	}
+	if created["url"] != "https://example.com/paper" {
+		t.Errorf("url = %v, want https://example.com/paper", created["url"])
+	}
+	if created["parentItem"] != "ABCD1234" {
+		t.Errorf("parentItem = %v, want ABCD1234", created["parentItem"])
+	}
+	if item.Data.LinkMode != LinkModeLinkedURL {
+		t.Errorf("item.Data.LinkMode = %v, want linked_url", item.Data.LinkMode)
+	}
+
+	if _, err := client.CreateLinkedURLAttachment(context.Background(), "ABCD1234", "", ""); err == nil {
+		t.Error("expected error for empty URL, got nil")
+	}
+}
+
+func TestCreateLinkedFileAttachment(t *testing.T) {
+	var created map[string]any
+	server, client := setupMockServer(t, attachmentCreateHandler(t, &created))
+	defer server.Close()
+
+	item, err := client.CreateLinkedFileAttachment(context.Background(), "ABCD1234", "/data/papers/scan.pdf", "application/pdf")
+	if err != nil {
+		t.Fatalf("CreateLinkedFileAttachment() error = %v", err)
+	}
+
+	if created["linkMode"] != string(LinkModeLinkedFile) {
+		t.Errorf("linkMode = %v, want linked_file", created["linkMode"])
+	}
+	if created["path"] != "/data/papers/scan.pdf" {
+		t.Errorf("path = %v, want /data/papers/scan.pdf", created["path"])
+	}
+	if created["title"] != "scan.pdf" {
+		t.Errorf("title = %v, want scan.pdf", created["title"])
+	}
+	if item.Data.ContentType != "application/pdf" {
+		t.Errorf("item.Data.ContentType = %v, want application/pdf", item.Data.ContentType)
+	}
+}
+
+func TestCreateImportedURL(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(snapshot, []byte("<html><body>Snapshot</body></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var created map[string]any
+	var uploaded string
+	var registered bool
+	var serverURL string
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users/12345/items":
+			json.NewDecoder(r.Body).Decode(&[]any{&created})
+			w.Write([]byte(`{"success": {"0": "ATTA1234"}, "unchanged": {}, "failed": {}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/users/12345/items/ATTA1234/file":
+			body, _ := io.ReadAll(r.Body)
+			if strings.HasPrefix(string(body), "md5=") {
+				if r.Header.Get("If-None-Match") != "*" {
+					t.Errorf("If-None-Match = %v, want *", r.Header.Get("If-None-Match"))
+				}
+				w.Header().Set("Last-Modified-Version", "2")
+				w.Write([]byte(`{"url": "` + serverURL + `/upload", "contentType": "multipart/form-data", "params": {"key": "value"}, "uploadKey": "UPLOADKEY"}`))
+				return
+			}
+			if !strings.Contains(string(body), "UPLOADKEY") {
+				t.Errorf("register body = %s, want upload key", body)
+			}
+			registered = true
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("missing file in upload: %v", err)
+			}
+			data, _ := io.ReadAll(file)
+			uploaded = string(data)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/users/12345/items/ATTA1234":
+			json.NewEncoder(w).Encode(Item{Key: "ATTA1234", Data: ItemData{ItemType: ItemTypeAttachment, LinkMode: LinkModeImportedURL}})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+	defer server.Close()
+	serverURL = server.URL
+
+	item, err := client.CreateImportedURL(context.Background(), "ABCD1234", "https://example.com", "Example", snapshot, "")
+	if err != nil {
+		t.Fatalf("CreateImportedURL() error = %v", err)
+	}
+
+	if created["linkMode"] != string(LinkModeImportedURL) {
+		t.Errorf("linkMode = %v, want imported_url", created["linkMode"])
+	}
+	if created["contentType"] != "text/html" || created["charset"] != "utf-8" {
+		t.Errorf("contentType/charset = %v/%v, want text/html/utf-8", created["contentType"], created["charset"])
+	}
+	if created["filename"] != "page.html" {
+		t.Errorf("filename = %v, want page.html", created["filename"])
+	}
+	if uploaded != "<html><body>Snapshot</body></html>" {
+		t.Errorf("uploaded = %q", uploaded)
+	}
+	if !registered {
+		t.Error("upload was not registered")
+	}
+	if item.Data.LinkMode != LinkModeImportedURL {
+		t.Errorf("item.Data.LinkMode = %v, want imported_url", item.Data.LinkMode)
+	}
+}
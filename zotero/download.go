@@ -0,0 +1,392 @@
+package zotero
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when downloaded content does not match the attachment's MD5 hash
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DownloadTo streams the file of an attachment item to w.
+// The storage redirect is followed without forwarding the API key, and the content is
+// verified against the attachment's md5 field. Because the content is streamed, w has
+// already received it when ErrChecksumMismatch is returned.
+// Returns the attachment item whose file was downloaded.
+func (c *Client) DownloadTo(ctx context.Context, itemKey string, w io.Writer) (*Item, error) {
+	item, err := c.Item(ctx, itemKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attachment: %w", err)
+	}
+
+	resp, err := c.openFile(ctx, itemKey, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), resp.Body); err != nil {
+		return nil, fmt.Errorf("error downloading file: %w", err)
+	}
+
+	if err := verifyMD5(hash, item.Data.MD5); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ConflictPolicy decides what happens when a download target already exists
+type ConflictPolicy string
+
+const (
+	// ConflictOverwrite replaces the existing file
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictSkip keeps the existing file and skips the download
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictSuffix downloads to a new name such as "paper (1).pdf"
+	ConflictSuffix ConflictPolicy = "suffix"
+	// ConflictSkipIfMD5Matches skips the download if the existing file has the attachment's MD5 hash,
+	// and replaces it otherwise
+	ConflictSkipIfMD5Matches ConflictPolicy = "skip-if-md5-matches"
+)
+
+// DumpOptions configures how DumpWithOptions names and writes an attachment file
+type DumpOptions struct {
+	Dir      string         // Output directory, created if missing (empty for the current directory)
+	Filename string         // Explicit filename (takes precedence over Template)
+	Template string         // Filename template, see RenderFilenameTemplate (e.g. "{creator}-{year}-{title}.{ext}")
+	Conflict ConflictPolicy // What to do if the file exists (default ConflictOverwrite)
+}
+
+// DumpResult describes the outcome of DumpWithOptions
+type DumpResult struct {
+	Path    string // Path of the file on disk
+	Item    *Item  // The attachment item
+	Skipped bool   // True if an existing file was kept
+}
+
+// DumpWithOptions downloads an attachment file to disk with a safe filename.
+// The filename is taken from opts.Filename, opts.Template or the stored filename (falling back
+// to the title and then the item key), and is always sanitized with SanitizeFilename so that
+// server-supplied names cannot escape opts.Dir. Missing directories are created.
+// The download itself is atomic, MD5-verified and resumable, as described for Dump.
+func (c *Client) DumpWithOptions(ctx context.Context, itemKey string, opts *DumpOptions) (*DumpResult, error) {
+	if opts == nil {
+		opts = &DumpOptions{}
+	}
+	switch opts.Conflict {
+	case "", ConflictOverwrite, ConflictSkip, ConflictSuffix, ConflictSkipIfMD5Matches:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", opts.Conflict)
+	}
+
+	item, err := c.Item(ctx, itemKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching item: %w", err)
+	}
+
+	name, err := c.dumpFilename(ctx, item, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath := filepath.Join(opts.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %w", err)
+	}
+
+	result := &DumpResult{Path: fullPath, Item: item}
+	if _, err := os.Stat(fullPath); err == nil {
+		switch opts.Conflict {
+		case ConflictOverwrite, "":
+		case ConflictSkip:
+			result.Skipped = true
+			return result, nil
+		case ConflictSkipIfMD5Matches:
+			if item.Data.MD5 != "" && fileMD5(fullPath) == item.Data.MD5 {
+				result.Skipped = true
+				return result, nil
+			}
+		case ConflictSuffix:
+			result.Path = nextFreePath(fullPath)
+		}
+	}
+
+	if err := c.downloadToFile(ctx, item, result.Path); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// dumpFilename picks the relative path for an attachment download
+func (c *Client) dumpFilename(ctx context.Context, item *Item, opts *DumpOptions) (string, error) {
+	if opts.Filename != "" {
+		if name := SanitizeFilename(opts.Filename); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("invalid filename %q", opts.Filename)
+	}
+
+	if opts.Template != "" {
+		var parent *Item
+		if item.Data.ParentItem != "" {
+			var err error
+			parent, err = c.Item(ctx, item.Data.ParentItem, nil)
+			if err != nil {
+				return "", fmt.Errorf("error fetching parent item: %w", err)
+			}
+		}
+		return RenderFilenameTemplate(opts.Template, item, parent)
+	}
+
+	for _, candidate := range []string{item.Data.Filename, item.Data.Title} {
+		if name := SanitizeFilename(candidate); name != "" {
+			return name, nil
+		}
+	}
+	return item.Key, nil
+}
+
+// nextFreePath returns the first of "name (1).ext", "name (2).ext", ... that does not exist
+func nextFreePath(path string) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// fileMD5 returns the hex-encoded MD5 hash of a file, or an empty string if it cannot be read
+func fileMD5(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// downloadToFile downloads the file of an attachment item to fullPath.
+// Content is written to fullPath + ".part" and renamed into place once its MD5 hash has been
+// verified, so fullPath never holds a partial file. An existing part file left by an
+// interrupted download is resumed with an HTTP Range request when the attachment has an MD5
+// hash to verify the result against; if resuming fails for any reason (including a 416 for
+// a part file that is already complete or too long), the part file is discarded and the
+// download restarts from the beginning. The file's modification time is set from the
+// attachment's mtime.
+func (c *Client) downloadToFile(ctx context.Context, item *Item, fullPath string) error {
+	partPath := fullPath + ".part"
+	file, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer file.Close()
+
+	// Hash what an earlier attempt already downloaded. Without an MD5 hash a stale part
+	// file cannot be told apart from a prefix of the current file, so it is not resumed.
+	hash := md5.New()
+	var offset int64
+	if item.Data.MD5 != "" {
+		if offset, err = io.Copy(hash, file); err != nil {
+			return fmt.Errorf("error reading partial file: %w", err)
+		}
+	}
+
+	complete := offset > 0 && verifyMD5(hash, item.Data.MD5) == nil
+	if !complete {
+		if offset > 0 {
+			c.logger.Printf("Resuming download of %s at byte %d", item.Key, offset)
+			err = c.downloadPart(ctx, item, file, hash, offset)
+			if err != nil && ctx.Err() == nil {
+				c.logger.Printf("Resuming download of %s failed, restarting: %v", item.Key, err)
+				err = c.downloadPart(ctx, item, file, hash, 0)
+			}
+		} else {
+			err = c.downloadPart(ctx, item, file, hash, 0)
+		}
+		if err != nil {
+			// Start over next time rather than resuming corrupt content
+			file.Close()
+			os.Remove(partPath)
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+
+	if item.Data.MTime > 0 {
+		mtime := time.UnixMilli(item.Data.MTime)
+		if err := os.Chtimes(partPath, mtime, mtime); err != nil {
+			return fmt.Errorf("error setting file modification time: %w", err)
+		}
+	}
+
+	if err := os.Rename(partPath, fullPath); err != nil {
+		return fmt.Errorf("error moving file into place: %w", err)
+	}
+
+	return nil
+}
+
+// downloadPart downloads the file of an attachment item into file from offset onwards and
+// verifies the complete content against the attachment's MD5 hash. h must hold the hash of
+// the first offset bytes of file; with an offset of 0, file is emptied first.
+func (c *Client) downloadPart(ctx context.Context, item *Item, file *os.File, h hash.Hash, offset int64) error {
+	restart := func() error {
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("error truncating partial file: %w", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("error truncating partial file: %w", err)
+		}
+		h.Reset()
+		return nil
+	}
+
+	if offset == 0 {
+		if err := restart(); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.openFile(ctx, item.Key, offset)
+	if err != nil {
+		return fmt.Errorf("error downloading file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The server may ignore the range and send the whole file
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		if err := restart(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(io.MultiWriter(file, h), resp.Body); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+
+	return verifyMD5(h, item.Data.MD5)
+}
+
+// openFile requests the file of an attachment item starting at offset.
+// The API answers with a redirect to file storage, which is followed without the API key,
+// or with the local API, to a file:// URL, which is opened directly.
+// Returns the response with its body unread; the caller must close it.
+func (c *Client) openFile(ctx context.Context, itemKey string, offset int64) (*http.Response, error) {
+	// Apply rate limiting
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limiter error: %w", err)
+		}
+	}
+
+	urlStr := fmt.Sprintf("%s/%s/%s/items/%s/file",
+		c.BaseURL,
+		c.LibraryType,
+		c.LibraryID,
+		itemKey,
+	)
+
+	c.logger.Printf("Making file request: GET %s", urlStr)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Zotero-API-Key", c.APIKey)
+	}
+	req.Header.Set("Zotero-API-Version", "3")
+	setRange(req, offset)
+
+	// Handle the storage redirect ourselves so the API key is not sent to storage
+	apiClient := *c.httpClient
+	apiClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		resp.Body.Close()
+
+		storageURL, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage redirect: %w", err)
+		}
+		// The local API redirects to the file in the Zotero data directory
+		if storageURL.Scheme == "file" {
+			c.logger.Printf("Opening local file: %s", storageURL)
+			return openLocalFile(storageURL, offset)
+		}
+		c.logger.Printf("Following storage redirect: %s", storageURL.Redacted())
+
+		storageReq, err := http.NewRequestWithContext(ctx, http.MethodGet, storageURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating storage request: %w", err)
+		}
+		setRange(storageReq, offset)
+
+		resp, err = c.httpClient.Do(storageReq)
+		if err != nil {
+			return nil, fmt.Errorf("error executing storage request: %w", err)
+		}
+	}
+
+	c.logger.Printf("Response status: %d %s", resp.StatusCode, resp.Status)
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+
+	return resp, nil
+}
+
+// setRange requests content from offset onwards
+func setRange(req *http.Request, offset int64) {
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+}
+
+// verifyMD5 compares the hash of downloaded content with the expected hex-encoded MD5.
+// Content without an expected hash is accepted.
+func verifyMD5(h hash.Hash, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package zotero
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// storageHandler serves an attachment whose file endpoint redirects to storage.
+// Storage honours Range requests unless ignoreRange is set, and records the
+// Range header and whether the API key was forwarded.
+type storageHandler struct {
+	t           *testing.T
+	content     []byte
+	md5         string
+	ignoreRange bool
+	gotRange    string
+	gotAPIKey   bool
+}
+
+func newStorageHandler(t *testing.T, content []byte) *storageHandler {
+	sum := md5.Sum(content)
+	return &storageHandler{t: t, content: content, md5: hex.EncodeToString(sum[:])}
+}
+
+func (s *storageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/users/12345/items/ATTA1234":
+		json.NewEncoder(w).Encode(Item{Key: "ATTA1234", Data: ItemData{
+			ItemType: ItemTypeAttachment,
+			LinkMode: LinkModeImportedFile,
+			Filename: "paper.pdf",
+			MD5:      s.md5,
+			MTime:    1600000000000,
+		}})
+	case "/users/12345/items/ATTA1234/file":
+		http.Redirect(w, r, "/storage/paper.pdf?X-Amz-Signature=secret", http.StatusFound)
+	case "/storage/paper.pdf":
+		s.gotRange = r.Header.Get("Range")
+		s.gotAPIKey = r.Header.Get("Zotero-API-Key") != ""
+		if s.ignoreRange {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, "paper.pdf", time.Time{}, bytes.NewReader(s.content))
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDownloadTo(t *testing.T) {
+	storage := newStorageHandler(t, []byte("%PDF-1.4 streamed content"))
+	server, client := setupMockServer(t, storage.ServeHTTP)
+	defer server.Close()
+	client.APIKey = "test-key"
+
+	var buf bytes.Buffer
+	item, err := client.DownloadTo(context.Background(), "ATTA1234", &buf)
+	if err != nil {
+		t.Fatalf("DownloadTo() error = %v", err)
+	}
+
+	if buf.String() != "%PDF-1.4 streamed content" {
+		t.Errorf("content = %q", buf.String())
+	}
+	if storage.gotAPIKey {
+		t.Error("API key was forwarded to storage")
+	}
+	if item.Data.Filename != "paper.pdf" {
+		t.Errorf("item.Data.Filename = %q, want paper.pdf", item.Data.Filename)
+	}
+
+	storage.md5 = "0123456789abcdef0123456789abcdef"
+	_, err = client.DownloadTo(context.Background(), "ATTA1234", &bytes.Buffer{})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("DownloadTo() error = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestDumpResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+
+	tests := []struct {
+		name        string
+		ignoreRange bool
+	}{
+		{"range supported", false},
+		{"range ignored", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorageHandler(t, content)
+			storage.ignoreRange = tt.ignoreRange
+			server, client := setupMockServer(t, storage.ServeHTTP)
+			defer server.Close()
+
+			dir := t.TempDir()
+			partPath := filepath.Join(dir, "paper.pdf.part")
+			if err := os.WriteFile(partPath, content[:400], 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			fullPath, err := client.Dump(context.Background(), "ATTA1234", "", dir)
+			if err != nil {
+				t.Fatalf("Dump() error = %v", err)
+			}
+
+			if storage.gotRange != "bytes=400-" {
+				t.Errorf("Range = %q, want bytes=400-", storage.gotRange)
+			}
+			got, err := os.ReadFile(fullPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("dumped %d bytes, want %d matching bytes", len(got), len(content))
+			}
+			if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+				t.Error("part file was left behind")
+			}
+			info, err := os.Stat(fullPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(time.UnixMilli(1600000000000)) {
+				t.Errorf("mtime = %v, want attachment mtime", info.ModTime())
+			}
+		})
+	}
+}
+
+func TestDumpStalePart(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+
+	tests := []struct {
+		name  string
+		part  []byte
+		noMD5 bool
+	}{
+		{"part longer than the file", append(bytes.Clone(content), "extra"...), false},
+		{"part from another file", []byte(strings.Repeat("x", 400)), false},
+		{"no md5 to verify a resume", content[:400], true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorageHandler(t, content)
+			if tt.noMD5 {
+				storage.md5 = ""
+			}
+			server, client := setupMockServer(t, storage.ServeHTTP)
+			defer server.Close()
+
+			dir := t.TempDir()
+			partPath := filepath.Join(dir, "paper.pdf.part")
+			if err := os.WriteFile(partPath, tt.part, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			fullPath, err := client.Dump(context.Background(), "ATTA1234", "", dir)
+			if err != nil {
+				t.Fatalf("Dump() error = %v", err)
+			}
+
+			// The last request downloads the whole file
+			if storage.gotRange != "" {
+				t.Errorf("Range = %q, want none", storage.gotRange)
+			}
+			got, err := os.ReadFile(fullPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("dumped %d bytes, want %d matching bytes", len(got), len(content))
+			}
+			if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+				t.Error("part file was left behind")
+			}
+		})
+	}
+}
+
+func TestDumpChecksumMismatch(t *testing.T) {
+	storage := newStorageHandler(t, []byte("corrupted"))
+	storage.md5 = "0123456789abcdef0123456789abcdef"
+	server, client := setupMockServer(t, storage.ServeHTTP)
+	defer server.Close()
+
+	dir := t.TempDir()
+	_, err := client.Dump(context.Background(), "ATTA1234", "", dir)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Dump() error = %v, want ErrChecksumMismatch", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("directory has %d entries after failed download, want none", len(entries))
+	}
+}
+
+func TestDumpWithOptionsConflicts(t *testing.T) {
+	content := []byte("%PDF-1.4 server copy")
+
+	tests := []struct {
+		name        string
+		policy      ConflictPolicy
+		existing    string
+		wantPath    string
+		wantSkipped bool
+		wantContent string
+	}{
+		{"overwrite", ConflictOverwrite, "local copy", "paper.pdf", false, string(content)},
+		{"default overwrites", "", "local copy", "paper.pdf", false, string(content)},
+		{"skip", ConflictSkip, "local copy", "paper.pdf", true, "local copy"},
+		{"suffix", ConflictSuffix, "local copy", "paper (1).pdf", false, string(content)},
+		{"skip if md5 matches", ConflictSkipIfMD5Matches, string(content), "paper.pdf", true, string(content)},
+		{"replace if md5 differs", ConflictSkipIfMD5Matches, "local copy", "paper.pdf", false, string(content)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorageHandler(t, content)
+			server, client := setupMockServer(t, storage.ServeHTTP)
+			defer server.Close()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "paper.pdf"), []byte(tt.existing), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := client.DumpWithOptions(context.Background(), "ATTA1234", &DumpOptions{Dir: dir, Conflict: tt.policy})
+			if err != nil {
+				t.Fatalf("DumpWithOptions() error = %v", err)
+			}
+
+			if result.Path != filepath.Join(dir, tt.wantPath) {
+				t.Errorf("Path = %q, want %q", result.Path, filepath.Join(dir, tt.wantPath))
+			}
+			if result.Skipped != tt.wantSkipped {
+				t.Errorf("Skipped = %v, want %v", result.Skipped, tt.wantSkipped)
+			}
+			got, _ := os.ReadFile(result.Path)
+			if string(got) != tt.wantContent {
+				t.Errorf("content = %q, want %q", got, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestDumpWithOptionsUnknownConflict(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	})
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "out")
+	_, err := client.DumpWithOptions(context.Background(), "ATTA1234", &DumpOptions{Dir: dir, Conflict: "rename"})
+	if err == nil || !strings.Contains(err.Error(), "unknown conflict policy") {
+		t.Fatalf("DumpWithOptions() error = %v, want unknown conflict policy", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("output directory was created")
+	}
+}
+
+func TestDumpWithOptionsTemplate(t *testing.T) {
+	storage := newStorageHandler(t, []byte("%PDF-1.4"))
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/12345/items/ATTA1234":
+			json.NewEncoder(w).Encode(Item{Key: "ATTA1234", Data: ItemData{
+				ItemType:   ItemTypeAttachment,
+				Filename:   "../../escape.pdf",
+				ParentItem: "PARENT01",
+				MD5:        storage.md5,
+			}})
+		case "/users/12345/items/PARENT01":
+			json.NewEncoder(w).Encode(Item{
+				Key:  "PARENT01",
+				Meta: Meta{CreatorSummary: "Doe", ParsedDate: "2020"},
+				Data: ItemData{ItemType: ItemTypeBook, Title: "A Title: With Colons"},
+			})
+		default:
+			storage.ServeHTTP(w, r)
+		}
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	result, err := client.DumpWithOptions(context.Background(), "ATTA1234", &DumpOptions{
+		Dir:      filepath.Join(dir, "new", "nested"),
+		Template: "{year}/{creator}-{year}-{title}.pdf",
+	})
+	if err != nil {
+		t.Fatalf("DumpWithOptions() error = %v", err)
+	}
+
+	want := filepath.Join(dir, "new", "nested", "2020", "Doe-2020-A Title_ With Colons.pdf")
+	if result.Path != want {
+		t.Errorf("Path = %q, want %q", result.Path, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("file not written: %v", err)
+	}
+
+	// The stored filename is sanitized when no template is given
+	result, err = client.DumpWithOptions(context.Background(), "ATTA1234", &DumpOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("DumpWithOptions() error = %v", err)
+	}
+	if filepath.Dir(result.Path) != dir {
+		t.Errorf("Path = %q escapes %q", result.Path, dir)
+	}
+}
@@ -0,0 +1,47 @@
+package zotero
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors matched by APIError according to its status code, for use with errors.Is
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrRateLimited        = errors.New("rate limited")
+)
+
+// APIError is returned when the API responds with an error status
+type APIError struct {
+	StatusCode int
+	Message    string // Response body
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is reports whether the status code corresponds to target, so callers can write
+// errors.Is(err, zotero.ErrNotFound)
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
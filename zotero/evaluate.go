@@ -0,0 +1,516 @@
+package zotero
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedCondition is returned when a search condition needs data that is not available
+// locally, such as full-text content
+var ErrUnsupportedCondition = errors.New("condition cannot be evaluated locally")
+
+// SearchEvaluator evaluates saved search conditions against items held in memory, so searches
+// can be run offline against cached data. Text comparisons are case-insensitive, as in Zotero.
+//
+//	eval := &zotero.SearchEvaluator{Items: items, Collections: collections, Searches: searches}
+//	matches, err := eval.MatchSearch(search.Data)
+type SearchEvaluator struct {
+	// Items is the set searched. Parents and children of matches are taken from it for
+	// includeParentsAndChildren and childNote conditions.
+	Items []Item
+
+	// Collections resolves subcollections for recursive collection conditions
+	Collections []Collection
+
+	// Searches resolves savedSearch conditions
+	Searches []Search
+
+	// Now is the reference time for isInTheLast (defaults to time.Now)
+	Now time.Time
+}
+
+// MatchSearch returns the items matching a saved search, in the order of e.Items
+func (e *SearchEvaluator) MatchSearch(search SearchData) ([]Item, error) {
+	return e.Match(search.Conditions)
+}
+
+// Match returns the items matching the conditions, in the order of e.Items
+func (e *SearchEvaluator) Match(conditions []SearchCondition) ([]Item, error) {
+	keys, err := e.match(conditions, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Item
+	for _, item := range e.Items {
+		if keys[item.Key] {
+			matches = append(matches, item)
+		}
+	}
+	return matches, nil
+}
+
+// searchFlags holds the boolean conditions that modify how a search runs
+type searchFlags struct {
+	any, recursive, noChildren, includeParentsAndChildren, deleted bool
+}
+
+// match returns the keys of matching items. visiting holds the saved searches being evaluated,
+// to detect cycles.
+func (e *SearchEvaluator) match(conditions []SearchCondition, visiting []string) (map[string]bool, error) {
+	var flags searchFlags
+	var tests []SearchCondition
+	for _, c := range conditions {
+		switch c.Condition {
+		case ConditionJoinMode:
+			flags.any = c.Operator == OperatorAny
+		case ConditionRecursive:
+			flags.recursive = c.Operator == OperatorTrue
+		case ConditionNoChildren:
+			flags.noChildren = c.Operator == OperatorTrue
+		case ConditionIncludeParentsAndChildren:
+			flags.includeParentsAndChildren = c.Operator == OperatorTrue
+		case ConditionDeleted:
+			flags.deleted = c.Operator == OperatorTrue
+		default:
+			tests = append(tests, c)
+		}
+	}
+
+	index := e.newSearchIndex(flags.recursive)
+	keys := make(map[string]bool)
+	for i := range e.Items {
+		item := &e.Items[i]
+		if item.Data.InTrash() != flags.deleted {
+			continue
+		}
+		if flags.noChildren && item.Data.ParentItem != "" {
+			continue
+		}
+
+		ok, err := e.matchAll(item, tests, flags.any, index, visiting)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			keys[item.Key] = true
+		}
+	}
+
+	if flags.includeParentsAndChildren {
+		for _, item := range e.Items {
+			if keys[item.Key] && item.Data.ParentItem != "" {
+				keys[item.Data.ParentItem] = true
+			}
+		}
+		for _, item := range e.Items {
+			if keys[item.Data.ParentItem] {
+				keys[item.Key] = true
+			}
+		}
+	}
+	return keys, nil
+}
+
+// matchAll applies the join mode to the conditions. A search without conditions matches everything.
+func (e *SearchEvaluator) matchAll(item *Item, tests []SearchCondition, any bool, index *searchIndex, visiting []string) (bool, error) {
+	if len(tests) == 0 {
+		return true, nil
+	}
+	for _, c := range tests {
+		ok, err := e.matchCondition(item, c, index, visiting)
+		if err != nil {
+			return false, err
+		}
+		if ok && any {
+			return true, nil
+		}
+		if !ok && !any {
+			return false, nil
+		}
+	}
+	return !any, nil
+}
+
+// searchIndex holds lookups shared by all items in one evaluation
+type searchIndex struct {
+	subcollections map[string][]string // collection key -> child collection keys (recursive searches only)
+	childNotes     map[string][]string // parent item key -> child note HTML
+	savedSearches  map[string]map[string]bool
+}
+
+func (e *SearchEvaluator) newSearchIndex(recursive bool) *searchIndex {
+	index := &searchIndex{
+		childNotes:    make(map[string][]string),
+		savedSearches: make(map[string]map[string]bool),
+	}
+	if recursive {
+		index.subcollections = make(map[string][]string)
+		for _, c := range e.Collections {
+			if parent := c.Data.ParentCollection.String(); parent != "" {
+				index.subcollections[parent] = append(index.subcollections[parent], c.Key)
+			}
+		}
+	}
+	for _, item := range e.Items {
+		if item.Data.ItemType == ItemTypeNote && item.Data.ParentItem != "" {
+			index.childNotes[item.Data.ParentItem] = append(index.childNotes[item.Data.ParentItem], item.Data.Note)
+		}
+	}
+	return index
+}
+
+// matchCondition tests a single condition against an item
+func (e *SearchEvaluator) matchCondition(item *Item, c SearchCondition, index *searchIndex, visiting []string) (bool, error) {
+	data := &item.Data
+	switch c.Condition {
+	case ConditionCollection:
+		key := collectionKeyValue(c.Value)
+		return matchMembership(c.Operator, slices.ContainsFunc(data.Collections, func(k string) bool {
+			return k == key || index.isSubcollection(k, key)
+		}))
+
+	case ConditionSavedSearch:
+		keys, err := e.savedSearchKeys(c.Value, index, visiting)
+		if err != nil {
+			return false, err
+		}
+		return matchMembership(c.Operator, keys[item.Key])
+
+	case ConditionUnfiled:
+		unfiled := len(data.Collections) == 0 && data.ParentItem == ""
+		return unfiled == (c.Operator == OperatorTrue), nil
+
+	case ConditionItemType:
+		return matchText(c.Operator, c.Value, []string{data.ItemType})
+
+	case ConditionTag:
+		tags := make([]string, len(data.Tags))
+		for i, tag := range data.Tags {
+			tags[i] = tag.Tag
+		}
+		return matchText(c.Operator, c.Value, tags)
+
+	case ConditionCreator:
+		var names []string
+		for _, creator := range data.Creators {
+			names = append(names, creatorNames(creator)...)
+		}
+		return matchText(c.Operator, c.Value, names)
+
+	case ConditionLastName:
+		var names []string
+		for _, creator := range data.Creators {
+			names = append(names, cmp.Or(creator.LastName, creator.Name))
+		}
+		return matchText(c.Operator, c.Value, names)
+
+	case ConditionNote:
+		if data.ItemType != ItemTypeNote {
+			return matchText(c.Operator, c.Value, nil)
+		}
+		return matchText(c.Operator, c.Value, []string{NoteHTMLToText(data.Note)})
+
+	case ConditionChildNote:
+		var notes []string
+		for _, note := range index.childNotes[item.Key] {
+			notes = append(notes, NoteHTMLToText(note))
+		}
+		return matchText(c.Operator, c.Value, notes)
+
+	case ConditionDate:
+		return e.matchDate(c.Operator, c.Value, itemDate(item))
+
+	case ConditionDateAdded, ConditionDateModified:
+		return e.matchDate(c.Operator, c.Value, timestampDate(data.Field(string(c.Condition))))
+
+	case ConditionAnyField:
+		return matchText(c.Operator, c.Value, fieldValues(data))
+
+	case ConditionQuickTitleCreatorYear:
+		values := []string{data.Title, parsedYear(itemDate(item))}
+		for _, creator := range data.Creators {
+			values = append(values, creatorNames(creator)...)
+		}
+		return matchQuickSearch(c.Operator, c.Value, values)
+
+	case ConditionQuickFields, ConditionQuickEverything:
+		// Full-text content is not available locally, so "everything" searches fields, tags and notes
+		values := fieldValues(data)
+		for _, creator := range data.Creators {
+			values = append(values, creatorNames(creator)...)
+		}
+		for _, tag := range data.Tags {
+			values = append(values, tag.Tag)
+		}
+		for _, note := range index.childNotes[item.Key] {
+			values = append(values, NoteHTMLToText(note))
+		}
+		return matchQuickSearch(c.Operator, c.Value, values)
+
+	case ConditionFulltextContent, "fulltextWord":
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedCondition, c.Condition)
+	}
+
+	// Any other condition names an item field
+	value := data.Field(string(c.Condition))
+	switch c.Operator {
+	case OperatorIsLessThan, OperatorIsGreaterThan:
+		return matchNumber(c.Operator, c.Value, value)
+	case OperatorIsBefore, OperatorIsAfter, OperatorIsInTheLast:
+		return e.matchDate(c.Operator, c.Value, sqlDate(value))
+	}
+	var values []string
+	if value != "" {
+		values = []string{value}
+	}
+	return matchText(c.Operator, c.Value, values)
+}
+
+// isSubcollection reports whether key is below ancestor in a recursive search
+func (index *searchIndex) isSubcollection(key, ancestor string) bool {
+	for _, child := range index.subcollections[ancestor] {
+		if child == key || index.isSubcollection(key, child) {
+			return true
+		}
+	}
+	return false
+}
+
+// savedSearchKeys evaluates a nested saved search once per evaluation
+func (e *SearchEvaluator) savedSearchKeys(key string, index *searchIndex, visiting []string) (map[string]bool, error) {
+	if keys, ok := index.savedSearches[key]; ok {
+		return keys, nil
+	}
+	if slices.Contains(visiting, key) {
+		return nil, fmt.Errorf("saved search %s refers to itself", key)
+	}
+	i := slices.IndexFunc(e.Searches, func(s Search) bool { return s.Key == key })
+	if i < 0 {
+		return nil, fmt.Errorf("saved search %s not found", key)
+	}
+
+	keys, err := e.match(e.Searches[i].Data.Conditions, append(visiting, key))
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating saved search %s: %w", key, err)
+	}
+	index.savedSearches[key] = keys
+	return keys, nil
+}
+
+// matchMembership applies is/isNot to a yes/no test
+func matchMembership(op Operator, member bool) (bool, error) {
+	switch op {
+	case OperatorIs:
+		return member, nil
+	case OperatorIsNot:
+		return !member, nil
+	}
+	return false, fmt.Errorf("operator %q is not supported here", op)
+}
+
+// matchText applies a text operator to a field with any number of values. Positive operators
+// match if any value matches; negative operators match if no value matches the positive form.
+func matchText(op Operator, want string, values []string) (bool, error) {
+	want = strings.ToLower(want)
+	test := func(positive func(string) bool) bool {
+		return slices.ContainsFunc(values, func(v string) bool { return positive(strings.ToLower(v)) })
+	}
+	equals := func(v string) bool { return v == want }
+	contains := func(v string) bool { return strings.Contains(v, want) }
+
+	switch op {
+	case OperatorIs:
+		return test(equals), nil
+	case OperatorIsNot:
+		return !test(equals), nil
+	case OperatorContains:
+		return test(contains), nil
+	case OperatorDoesNotContain:
+		return !test(contains), nil
+	case OperatorBeginsWith:
+		return test(func(v string) bool { return strings.HasPrefix(v, want) }), nil
+	}
+	return false, fmt.Errorf("operator %q is not supported for text", op)
+}
+
+// matchQuickSearch requires every word of the query to appear in one of the values
+func matchQuickSearch(op Operator, query string, values []string) (bool, error) {
+	if op != OperatorContains && op != OperatorDoesNotContain {
+		return false, fmt.Errorf("operator %q is not supported for quick search", op)
+	}
+	all := true
+	for _, word := range strings.Fields(query) {
+		if ok, _ := matchText(OperatorContains, word, values); !ok {
+			all = false
+			break
+		}
+	}
+	return all == (op == OperatorContains), nil
+}
+
+// matchNumber compares a field numerically; non-numeric values never match
+func matchNumber(op Operator, want, value string) (bool, error) {
+	w, err := strconv.ParseFloat(strings.TrimSpace(want), 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid number %q", want)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return false, nil
+	}
+	if op == OperatorIsLessThan {
+		return v < w, nil
+	}
+	return v > w, nil
+}
+
+// matchDate applies a date operator to a date in "YYYY-MM-DD" form, where unknown parts are
+// "00" as in Zotero's multipart dates. An empty date never matches a positive operator.
+func (e *SearchEvaluator) matchDate(op Operator, want, date string) (bool, error) {
+	if op == OperatorIsInTheLast {
+		since, err := e.inTheLast(want)
+		if err != nil {
+			return false, err
+		}
+		return date != "" && date >= since, nil
+	}
+
+	target := sqlDate(want)
+	if target == "" {
+		return false, fmt.Errorf("invalid date %q", want)
+	}
+
+	switch op {
+	case OperatorIs, OperatorIsNot:
+		// Partial values match every date they contain: "2021" is any day in 2021
+		prefix := strings.TrimSuffix(strings.TrimSuffix(target, "-00"), "-00")
+		is := date != "" && strings.HasPrefix(date, prefix)
+		return is == (op == OperatorIs), nil
+	case OperatorIsBefore:
+		return date != "" && date < target, nil
+	case OperatorIsAfter:
+		return date != "" && date > target, nil
+	}
+	return false, fmt.Errorf("operator %q is not supported for dates", op)
+}
+
+var inTheLastPattern = regexp.MustCompile(`^\s*(\d+)\s*(day|week|month|year)s?\s*$`)
+
+// inTheLast returns the earliest date within a period such as "7 days" or "2 months"
+func (e *SearchEvaluator) inTheLast(period string) (string, error) {
+	m := inTheLastPattern.FindStringSubmatch(strings.ToLower(period))
+	if m == nil {
+		return "", fmt.Errorf("invalid period %q (expected e.g. \"7 days\")", period)
+	}
+	n, _ := strconv.Atoi(m[1])
+
+	now := e.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+
+	var since time.Time
+	switch m[2] {
+	case "day":
+		since = now.AddDate(0, 0, -n)
+	case "week":
+		since = now.AddDate(0, 0, -7*n)
+	case "month":
+		since = now.AddDate(0, -n, 0)
+	case "year":
+		since = now.AddDate(-n, 0, 0)
+	}
+	return since.Format(time.DateOnly), nil
+}
+
+var (
+	isoDatePattern  = regexp.MustCompile(`^(\d{4})(?:-(\d{1,2})(?:-(\d{1,2}))?)?`)
+	yearDatePattern = regexp.MustCompile(`\b(\d{4})\b`)
+)
+
+// sqlDate converts a date value to "YYYY-MM-DD", using "00" for unknown parts.
+// Returns an empty string if no year can be found.
+func sqlDate(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if m := isoDatePattern.FindStringSubmatch(value); m != nil {
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		return fmt.Sprintf("%s-%02d-%02d", m[1], month, day)
+	}
+	for _, layout := range []string{"January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006", "01/02/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.DateOnly)
+		}
+	}
+	for _, layout := range []string{"January 2006", "Jan 2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01") + "-00"
+		}
+	}
+	if m := yearDatePattern.FindStringSubmatch(value); m != nil {
+		return m[1] + "-00-00"
+	}
+	return ""
+}
+
+// timestampDate returns the UTC day of an ISO 8601 timestamp such as dateAdded
+func timestampDate(value string) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.DateOnly)
+	}
+	return sqlDate(value)
+}
+
+// itemDate returns the item's date field as "YYYY-MM-DD", preferring the server's parsed date
+func itemDate(item *Item) string {
+	if item.Meta.ParsedDate != "" {
+		return sqlDate(item.Meta.ParsedDate)
+	}
+	return sqlDate(item.Data.Field("date"))
+}
+
+// collectionKeyValue strips a library prefix such as "0_" or "1/" from a collection condition value
+func collectionKeyValue(value string) string {
+	if i := strings.LastIndexAny(value, "/_"); i >= 0 {
+		return value[i+1:]
+	}
+	return value
+}
+
+// creatorNames returns the names a creator can be matched by
+func creatorNames(creator Creator) []string {
+	if creator.Name != "" {
+		return []string{creator.Name}
+	}
+	return []string{strings.TrimSpace(creator.FirstName + " " + creator.LastName), creator.LastName}
+}
+
+// fieldValues returns the non-empty text fields of an item
+func fieldValues(data *ItemData) []string {
+	var values []string
+	for _, v := range []string{data.Title, data.AbstractNote, data.URL, data.AnnotationText, data.AnnotationComment} {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	if data.Note != "" {
+		values = append(values, NoteHTMLToText(data.Note))
+	}
+	for _, v := range data.Extra {
+		if s, ok := v.(string); ok && s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
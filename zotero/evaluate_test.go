@@ -0,0 +1,228 @@
+package zotero
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// evaluatorLibrary is a small library covering the condition types the evaluator supports
+func evaluatorLibrary() *SearchEvaluator {
+	return &SearchEvaluator{
+		Items: []Item{
+			{Key: "BOOK0001", Data: ItemData{
+				ItemType:     ItemTypeBook,
+				Title:        "Deep Learning",
+				Creators:     []Creator{{CreatorType: "author", FirstName: "Ian", LastName: "Goodfellow"}},
+				Tags:         []Tag{{Tag: "ml"}, {Tag: "neural networks"}},
+				Collections:  []string{"COLLML01"},
+				DateAdded:    "2024-01-10T10:00:00Z",
+				DateModified: "2024-06-15T08:30:00Z",
+				Extra:        map[string]any{"date": "2016-11-18"},
+			}},
+			{Key: "ATTA0001", Data: ItemData{
+				ItemType:   ItemTypeAttachment,
+				Title:      "Full Text PDF",
+				ParentItem: "BOOK0001",
+			}},
+			{Key: "ARTI0001", Data: ItemData{
+				ItemType:     ItemTypeJournalArticle,
+				Title:        "Attention Is All You Need",
+				Creators:     []Creator{{CreatorType: "author", FirstName: "Ashish", LastName: "Vaswani"}},
+				Tags:         []Tag{{Tag: "ml"}, {Tag: "transformers"}},
+				Collections:  []string{"COLLSUB1"},
+				DateAdded:    "2024-06-20T12:00:00Z",
+				DateModified: "2024-05-01T00:00:00Z",
+				Extra:        map[string]any{"date": "June 2017", "publicationTitle": "NeurIPS"},
+			}},
+			{Key: "NOTE0001", Data: ItemData{
+				ItemType:   ItemTypeNote,
+				ParentItem: "ARTI0001",
+				Note:       "<p>Key <strong>insight</strong> about attention</p>",
+				DateAdded:  "2024-06-21T09:00:00Z",
+			}},
+			{Key: "THES0001", Meta: Meta{ParsedDate: "2010"}, Data: ItemData{
+				ItemType:  ItemTypeThesis,
+				Title:     "Graph Methods",
+				Creators:  []Creator{{CreatorType: "author", Name: "Research Group"}},
+				Tags:      []Tag{{Tag: "graphs"}},
+				DateAdded: "2020-01-01T00:00:00Z",
+			}},
+			{Key: "TRASH001", Data: ItemData{
+				ItemType: ItemTypeBook,
+				Title:    "Old Draft",
+				Tags:     []Tag{{Tag: "ml"}},
+				Extra:    map[string]any{"deleted": float64(1)},
+			}},
+		},
+		Collections: []Collection{
+			{Key: "COLLML01", Data: CollectionData{Name: "Machine Learning"}},
+			{Key: "COLLSUB1", Data: CollectionData{Name: "Transformers", ParentCollection: "COLLML01"}},
+		},
+		Searches: []Search{
+			{Key: "SRCHML01", Data: SearchData{Name: "ML", Conditions: []SearchCondition{
+				{Condition: ConditionTag, Operator: OperatorIs, Value: "ml"},
+			}}},
+			{Key: "SRCHLOOP", Data: SearchData{Name: "Loop", Conditions: []SearchCondition{
+				{Condition: ConditionSavedSearch, Operator: OperatorIs, Value: "SRCHLOOP"},
+			}}},
+		},
+		Now: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// cond is shorthand for building conditions in test tables
+func cond(condition Condition, op Operator, value string) SearchCondition {
+	return SearchCondition{Condition: condition, Operator: op, Value: value}
+}
+
+func TestSearchEvaluatorConformance(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []SearchCondition
+		want       []string
+	}{
+		{"no conditions", nil, []string{"BOOK0001", "ATTA0001", "ARTI0001", "NOTE0001", "THES0001"}},
+
+		// Tags
+		{"tag is", []SearchCondition{cond(ConditionTag, OperatorIs, "ml")}, []string{"BOOK0001", "ARTI0001"}},
+		{"tag is ignores case", []SearchCondition{cond(ConditionTag, OperatorIs, "ML")}, []string{"BOOK0001", "ARTI0001"}},
+		{"tag isNot", []SearchCondition{cond(ConditionTag, OperatorIsNot, "ml")}, []string{"ATTA0001", "NOTE0001", "THES0001"}},
+		{"tag contains", []SearchCondition{cond(ConditionTag, OperatorContains, "net")}, []string{"BOOK0001"}},
+		{"tag doesNotContain", []SearchCondition{cond(ConditionTag, OperatorDoesNotContain, "n")}, []string{"ATTA0001", "NOTE0001", "THES0001"}},
+		{"tag beginsWith", []SearchCondition{cond(ConditionTag, OperatorBeginsWith, "trans")}, []string{"ARTI0001"}},
+
+		// Item type
+		{"itemType is", []SearchCondition{cond(ConditionItemType, OperatorIs, "book")}, []string{"BOOK0001"}},
+		{"itemType isNot", []SearchCondition{cond(ConditionItemType, OperatorIsNot, "note")}, []string{"BOOK0001", "ATTA0001", "ARTI0001", "THES0001"}},
+
+		// Creators
+		{"creator is full name", []SearchCondition{cond(ConditionCreator, OperatorIs, "Ian Goodfellow")}, []string{"BOOK0001"}},
+		{"creator is single field", []SearchCondition{cond(ConditionCreator, OperatorIs, "research group")}, []string{"THES0001"}},
+		{"creator contains", []SearchCondition{cond(ConditionCreator, OperatorContains, "vaswani")}, []string{"ARTI0001"}},
+		{"lastName beginsWith", []SearchCondition{cond(ConditionLastName, OperatorBeginsWith, "good")}, []string{"BOOK0001"}},
+
+		// Collections
+		{"collection is", []SearchCondition{cond(ConditionCollection, OperatorIs, "COLLML01")}, []string{"BOOK0001"}},
+		{"collection is recursive", []SearchCondition{
+			cond(ConditionCollection, OperatorIs, "COLLML01"),
+			cond(ConditionRecursive, OperatorTrue, ""),
+		}, []string{"BOOK0001", "ARTI0001"}},
+		{"collection with library prefix", []SearchCondition{cond(ConditionCollection, OperatorIs, "0_COLLSUB1")}, []string{"ARTI0001"}},
+		{"collection isNot", []SearchCondition{cond(ConditionCollection, OperatorIsNot, "COLLML01")}, []string{"ATTA0001", "ARTI0001", "NOTE0001", "THES0001"}},
+		{"unfiled", []SearchCondition{cond(ConditionUnfiled, OperatorTrue, "")}, []string{"THES0001"}},
+
+		// Dates
+		{"date isBefore", []SearchCondition{cond(ConditionDate, OperatorIsBefore, "2016-12-01")}, []string{"BOOK0001", "THES0001"}},
+		{"date isAfter partial date", []SearchCondition{cond(ConditionDate, OperatorIsAfter, "2017-01-01")}, []string{"ARTI0001"}},
+		{"date is year", []SearchCondition{cond(ConditionDate, OperatorIs, "2017")}, []string{"ARTI0001"}},
+		{"date is day", []SearchCondition{cond(ConditionDate, OperatorIs, "2016-11-18")}, []string{"BOOK0001"}},
+		{"dateAdded isInTheLast days", []SearchCondition{cond(ConditionDateAdded, OperatorIsInTheLast, "30 days")}, []string{"ARTI0001", "NOTE0001"}},
+		{"dateAdded isInTheLast year", []SearchCondition{cond(ConditionDateAdded, OperatorIsInTheLast, "1 year")}, []string{"BOOK0001", "ARTI0001", "NOTE0001"}},
+		{"dateModified isAfter", []SearchCondition{cond(ConditionDateModified, OperatorIsAfter, "2024-05-31")}, []string{"BOOK0001"}},
+
+		// Join modes
+		{"joinMode all", []SearchCondition{
+			cond(ConditionJoinMode, OperatorAll, ""),
+			cond(ConditionTag, OperatorIs, "ml"),
+			cond(ConditionItemType, OperatorIs, "book"),
+		}, []string{"BOOK0001"}},
+		{"joinMode any", []SearchCondition{
+			cond(ConditionJoinMode, OperatorAny, ""),
+			cond(ConditionTag, OperatorIs, "transformers"),
+			cond(ConditionItemType, OperatorIs, "thesis"),
+		}, []string{"ARTI0001", "THES0001"}},
+
+		// Parents and children
+		{"includeParentsAndChildren adds children", []SearchCondition{
+			cond(ConditionTag, OperatorIs, "transformers"),
+			cond(ConditionIncludeParentsAndChildren, OperatorTrue, ""),
+		}, []string{"ARTI0001", "NOTE0001"}},
+		{"includeParentsAndChildren adds parents", []SearchCondition{
+			cond(ConditionTitle, OperatorIs, "Full Text PDF"),
+			cond(ConditionIncludeParentsAndChildren, OperatorTrue, ""),
+		}, []string{"BOOK0001", "ATTA0001"}},
+		{"noChildren", []SearchCondition{
+			cond(ConditionTag, OperatorIsNot, "ml"),
+			cond(ConditionNoChildren, OperatorTrue, ""),
+		}, []string{"THES0001"}},
+		{"note contains", []SearchCondition{cond(ConditionNote, OperatorContains, "insight")}, []string{"NOTE0001"}},
+		{"childNote contains", []SearchCondition{cond(ConditionChildNote, OperatorContains, "insight")}, []string{"ARTI0001"}},
+
+		// Other fields and special conditions
+		{"field from extra", []SearchCondition{cond("publicationTitle", OperatorIs, "NeurIPS")}, []string{"ARTI0001"}},
+		{"any field contains", []SearchCondition{cond(ConditionAnyField, OperatorContains, "neurips")}, []string{"ARTI0001"}},
+		{"deleted", []SearchCondition{cond(ConditionDeleted, OperatorTrue, "")}, []string{"TRASH001"}},
+		{"quick search title creator year", []SearchCondition{cond(ConditionQuickTitleCreatorYear, OperatorContains, "attention 2017")}, []string{"ARTI0001"}},
+		{"quick search creator", []SearchCondition{cond(ConditionQuickTitleCreatorYear, OperatorContains, "goodfellow")}, []string{"BOOK0001"}},
+		{"savedSearch is", []SearchCondition{cond(ConditionSavedSearch, OperatorIs, "SRCHML01")}, []string{"BOOK0001", "ARTI0001"}},
+		{"savedSearch isNot", []SearchCondition{
+			cond(ConditionSavedSearch, OperatorIsNot, "SRCHML01"),
+			cond(ConditionNoChildren, OperatorTrue, ""),
+		}, []string{"THES0001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := evaluatorLibrary().Match(tt.conditions)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+
+			var got []string
+			for _, item := range matches {
+				got = append(got, item.Key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchEvaluatorMatchSearch(t *testing.T) {
+	data, err := NewSearch("Recent ML").
+		MatchAll().
+		Tag("ml").
+		Where(ConditionDateAdded, OperatorIsInTheLast, "2 weeks").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := evaluatorLibrary().MatchSearch(data)
+	if err != nil {
+		t.Fatalf("MatchSearch() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Key != "ARTI0001" {
+		t.Errorf("MatchSearch() = %v, want ARTI0001", matches)
+	}
+}
+
+func TestSearchEvaluatorErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []SearchCondition
+		wantErr    error
+	}{
+		{"full text", []SearchCondition{cond(ConditionFulltextContent, OperatorContains, "x")}, ErrUnsupportedCondition},
+		{"invalid period", []SearchCondition{cond(ConditionDateAdded, OperatorIsInTheLast, "soon")}, nil},
+		{"invalid date", []SearchCondition{cond(ConditionDate, OperatorIsBefore, "someday")}, nil},
+		{"unknown saved search", []SearchCondition{cond(ConditionSavedSearch, OperatorIs, "MISSING1")}, nil},
+		{"saved search cycle", []SearchCondition{cond(ConditionSavedSearch, OperatorIs, "SRCHLOOP")}, nil},
+		{"number comparison", []SearchCondition{cond("pages", OperatorIsLessThan, "many")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evaluatorLibrary().Match(tt.conditions)
+			if err == nil {
+				t.Fatal("Match() error = nil, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Match() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package zotero
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultExportWorkers is the number of concurrent downloads used when ExportOptions.Workers is zero
+const DefaultExportWorkers = 4
+
+// ManifestFilename is the name of the manifest written to the export directory
+const ManifestFilename = "manifest.json"
+
+// ExportOptions configures a bulk attachment export
+type ExportOptions struct {
+	Dir      string                  // Output directory, created if missing (empty for the current directory)
+	Template string                  // Filename template, see RenderFilenameTemplate (default: stored filename)
+	Workers  int                     // Concurrent downloads (default DefaultExportWorkers)
+	Progress func(entry ExportEntry) // Called after each attachment is processed (optional, never concurrently)
+}
+
+// ExportStatus is the outcome of exporting one attachment
+type ExportStatus string
+
+const (
+	// ExportDownloaded means the file was downloaded
+	ExportDownloaded ExportStatus = "downloaded"
+	// ExportUnchanged means a file with the same MD5 hash was already on disk
+	ExportUnchanged ExportStatus = "unchanged"
+	// ExportFailed means the download failed; see ExportEntry.Error
+	ExportFailed ExportStatus = "failed"
+)
+
+// ExportEntry maps an exported file to its Zotero items
+type ExportEntry struct {
+	Path          string       `json:"path"` // Relative to the export directory, with "/" separators
+	AttachmentKey string       `json:"attachmentKey"`
+	ParentKey     string       `json:"parentKey,omitempty"`
+	Title         string       `json:"title,omitempty"` // Title of the parent item (or of a standalone attachment)
+	LinkMode      LinkMode     `json:"linkMode"`
+	ContentType   string       `json:"contentType,omitempty"`
+	MD5           string       `json:"md5,omitempty"`
+	Status        ExportStatus `json:"status"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// ExportManifest lists the files written by an export
+type ExportManifest struct {
+	Created    time.Time     `json:"created"`
+	Collection string        `json:"collection,omitempty"`
+	Files      []ExportEntry `json:"files"`
+}
+
+// Count returns the number of entries with the given status
+func (m *ExportManifest) Count(status ExportStatus) int {
+	n := 0
+	for _, entry := range m.Files {
+		if entry.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ExportCollectionAttachments downloads the stored files (imported_file and imported_url attachments)
+// of all items in a collection, and of its subcollections if recursive is set.
+// See ExportAttachments for how files are written.
+func (c *Client) ExportCollectionAttachments(ctx context.Context, collectionKey string, recursive bool, opts *ExportOptions) (*ExportManifest, error) {
+	items, err := c.collectionItemsTree(ctx, collectionKey, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.exportAttachments(ctx, items, opts, collectionKey)
+}
+
+// ExportAttachments downloads the stored files of the given top-level items: their
+// imported_file and imported_url child attachments, or the items themselves if they are
+// standalone attachments. Downloads run concurrently under the client's rate limiter.
+// Files already on disk with a matching MD5 hash are skipped. Web page snapshots are written
+// to a directory named after the snapshot, and unzipped if storage serves them as a ZIP archive.
+// A manifest mapping files to item keys is written to ManifestFilename in the export directory.
+// Failed downloads are recorded in the manifest rather than aborting the export.
+func (c *Client) ExportAttachments(ctx context.Context, items []Item, opts *ExportOptions) (*ExportManifest, error) {
+	return c.exportAttachments(ctx, items, opts, "")
+}
+
+// exportAttachments implements ExportAttachments, recording the exported collection in the manifest
+func (c *Client) exportAttachments(ctx context.Context, items []Item, opts *ExportOptions, collectionKey string) (*ExportManifest, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+
+	jobs, err := c.exportJobs(ctx, items, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(exportDir(opts), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %w", err)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultExportWorkers
+	}
+
+	manifest := &ExportManifest{Created: time.Now().UTC(), Collection: collectionKey, Files: make([]ExportEntry, len(jobs))}
+	queue := make(chan int)
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	for range min(workers, max(len(jobs), 1)) {
+		wg.Go(func() {
+			for i := range queue {
+				manifest.Files[i] = c.exportAttachment(ctx, jobs[i], opts)
+				if opts.Progress != nil {
+					progressMu.Lock()
+					opts.Progress(manifest.Files[i])
+					progressMu.Unlock()
+				}
+			}
+		})
+	}
+
+	for i := range jobs {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return manifest, writeManifest(opts, manifest)
+}
+
+// exportJob is an attachment to export and its planned location
+type exportJob struct {
+	attachment Item
+	parent     *Item
+	path       string // Relative path of the file, or of the main file inside a snapshot directory
+	snapshot   bool
+}
+
+// collectionItemsTree returns the top-level items of a collection and, if recursive is set,
+// of all its subcollections, without duplicates
+func (c *Client) collectionItemsTree(ctx context.Context, collectionKey string, recursive bool) ([]Item, error) {
+	var items []Item
+	seenItems := map[string]bool{}
+	seenCollections := map[string]bool{}
+
+	var walk func(key string) error
+	walk = func(key string) error {
+		if seenCollections[key] {
+			return nil
+		}
+		seenCollections[key] = true
+
+		collectionItems, err := fetchAll(ctx, nil, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+			return c.CollectionItemsTop(ctx, key, params)
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching items of collection %s: %w", key, err)
+		}
+		for _, item := range collectionItems {
+			if !seenItems[item.Key] {
+				seenItems[item.Key] = true
+				items = append(items, item)
+			}
+		}
+
+		if !recursive {
+			return nil
+		}
+		subcollections, err := fetchAll(ctx, nil, func(ctx context.Context, params *QueryParams) ([]Collection, error) {
+			return c.CollectionsSub(ctx, key, params)
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching subcollections of %s: %w", key, err)
+		}
+		for _, sub := range subcollections {
+			if err := walk(sub.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(collectionKey); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// exportJobs finds the stored attachments of items and assigns each a unique path.
+// Paths are planned up front, in item order, so that repeated exports map attachments to
+// the same files.
+func (c *Client) exportJobs(ctx context.Context, items []Item, opts *ExportOptions) ([]exportJob, error) {
+	var jobs []exportJob
+	claimed := map[string]bool{}
+	seen := map[string]bool{}
+
+	for i := range items {
+		item := &items[i]
+
+		var attachments []Item
+		var parent *Item
+		if item.Data.ItemType == ItemTypeAttachment {
+			// Standalone attachments are their own source
+			attachments = []Item{*item}
+		} else {
+			children, err := fetchAll(ctx, &QueryParams{ItemType: []string{ItemTypeAttachment}}, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+				return c.Children(ctx, item.Key, params)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error fetching attachments of %s: %w", item.Key, err)
+			}
+			attachments, parent = children, item
+		}
+
+		for _, attachment := range attachments {
+			mode := attachment.Data.LinkMode
+			if (mode != LinkModeImportedFile && mode != LinkModeImportedURL) || seen[attachment.Key] {
+				continue
+			}
+			seen[attachment.Key] = true
+
+			name, err := exportName(opts.Template, &attachment, parent)
+			if err != nil {
+				return nil, err
+			}
+
+			job := exportJob{attachment: attachment, parent: parent, snapshot: mode == LinkModeImportedURL}
+			if job.snapshot {
+				// Snapshots get a directory that holds the main file and its resources
+				dir := claimPath(claimed, strings.TrimSuffix(name, filepath.Ext(name)))
+				main := SanitizeFilename(attachment.Data.Filename)
+				if main == "" {
+					main = filepath.Base(name)
+				}
+				job.path = filepath.Join(dir, main)
+			} else {
+				job.path = claimPath(claimed, name)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// exportName returns the relative path for an attachment before de-duplication
+func exportName(template string, attachment *Item, parent *Item) (string, error) {
+	if template != "" {
+		return RenderFilenameTemplate(template, attachment, parent)
+	}
+	for _, candidate := range []string{attachment.Data.Filename, attachment.Data.Title} {
+		if name := SanitizeFilename(candidate); name != "" {
+			return name, nil
+		}
+	}
+	return attachment.Key, nil
+}
+
+// claimPath reserves a relative path, adding " (1)", " (2)", ... before the extension if it is taken
+func claimPath(claimed map[string]bool, path string) string {
+	candidate := path
+	ext := filepath.Ext(path)
+	for i := 1; claimed[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(path, ext), i, ext)
+	}
+	// Compare case-insensitively so exports work on case-insensitive filesystems
+	claimed[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// exportAttachment downloads one attachment and reports the outcome
+func (c *Client) exportAttachment(ctx context.Context, job exportJob, opts *ExportOptions) ExportEntry {
+	attachment := job.attachment
+	entry := ExportEntry{
+		Path:          filepath.ToSlash(job.path),
+		AttachmentKey: attachment.Key,
+		ParentKey:     attachment.Data.ParentItem,
+		Title:         attachment.Data.Title,
+		LinkMode:      attachment.Data.LinkMode,
+		ContentType:   attachment.Data.ContentType,
+		MD5:           attachment.Data.MD5,
+	}
+	if job.parent != nil {
+		entry.Title = job.parent.Data.Title
+	}
+
+	fullPath := filepath.Join(exportDir(opts), job.path)
+	if attachment.Data.MD5 != "" && fileMD5(fullPath) == attachment.Data.MD5 {
+		entry.Status = ExportUnchanged
+		return entry
+	}
+
+	var err error
+	if err = os.MkdirAll(filepath.Dir(fullPath), 0o755); err == nil {
+		if job.snapshot {
+			err = c.downloadSnapshot(ctx, &attachment, fullPath)
+		} else {
+			err = c.downloadToFile(ctx, &attachment, fullPath)
+		}
+	}
+	if err != nil {
+		entry.Status = ExportFailed
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.Status = ExportDownloaded
+	return entry
+}
+
+// downloadSnapshot downloads a web page snapshot to mainPath. Zotero clients upload snapshots
+// as ZIP archives of the page and its resources; those are extracted into the directory of
+// mainPath and the main file is verified against the attachment's MD5 hash.
+func (c *Client) downloadSnapshot(ctx context.Context, attachment *Item, mainPath string) error {
+	dir := filepath.Dir(mainPath)
+	archivePath := filepath.Join(dir, "."+attachment.Key+".download")
+
+	// The attachment's MD5 hash describes the main file, not the archive. Without a hash
+	// the archive cannot be verified, so a part file left by an earlier attempt is
+	// discarded rather than resumed.
+	archive := *attachment
+	archive.Data.MD5 = ""
+	os.Remove(archivePath + ".part")
+	if err := c.downloadToFile(ctx, &archive, archivePath); err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	if !isZipFile(archivePath) {
+		if attachment.Data.MD5 != "" && fileMD5(archivePath) != attachment.Data.MD5 {
+			return fmt.Errorf("%w: snapshot %s", ErrChecksumMismatch, attachment.Key)
+		}
+		if err := os.Rename(archivePath, mainPath); err != nil {
+			return fmt.Errorf("error moving file into place: %w", err)
+		}
+		return nil
+	}
+
+	if err := extractZip(archivePath, dir); err != nil {
+		return fmt.Errorf("error extracting snapshot: %w", err)
+	}
+
+	if _, err := os.Stat(mainPath); err != nil {
+		return fmt.Errorf("snapshot archive does not contain %s", filepath.Base(mainPath))
+	}
+	if attachment.Data.MD5 != "" && fileMD5(mainPath) != attachment.Data.MD5 {
+		return fmt.Errorf("%w: snapshot %s", ErrChecksumMismatch, attachment.Key)
+	}
+	if attachment.Data.MTime > 0 {
+		mtime := time.UnixMilli(attachment.Data.MTime)
+		os.Chtimes(mainPath, mtime, mtime)
+	}
+	return nil
+}
+
+// isZipFile reports whether a file starts with the ZIP local file header signature
+func isZipFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, []byte("PK\x03\x04"))
+}
+
+// extractZip extracts an archive into dir. Every path component is sanitized,
+// so entries cannot be written outside dir.
+func extractZip(archivePath, dir string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		var parts []string
+		for _, part := range strings.FieldsFunc(file.Name, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if clean := SanitizeFilename(part); clean != "" {
+				parts = append(parts, clean)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		target := filepath.Join(append([]string{dir}, parts...)...)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes a single archive entry to target
+func extractZipFile(file *zip.File, target string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// writeManifest writes the manifest to the export directory atomically
+func writeManifest(opts *ExportOptions, manifest *ExportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+
+	path := filepath.Join(exportDir(opts), ManifestFilename)
+	if err := os.WriteFile(path+".part", append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// exportDir returns the output directory of an export
+func exportDir(opts *ExportOptions) string {
+	if opts == nil || opts.Dir == "" {
+		return "."
+	}
+	return opts.Dir
+}
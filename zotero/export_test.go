@@ -0,0 +1,254 @@
+package zotero
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// exportLibrary serves a collection with a subcollection, attachments of every link mode
+// and a zipped web page snapshot
+type exportLibrary struct {
+	t         *testing.T
+	files     map[string][]byte
+	mu        sync.Mutex
+	downloads map[string]int
+}
+
+func newExportLibrary(t *testing.T) *exportLibrary {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"page.html":         "<html>snapshot</html>",
+		"images/logo.png":   "PNG",
+		"../../escaped.txt": "outside",
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	return &exportLibrary{
+		t: t,
+		files: map[string][]byte{
+			"PDF00001": []byte("%PDF first"),
+			"PDF00002": []byte("%PDF second"),
+			"PDF00003": []byte("%PDF standalone"),
+			"SNAP0001": archive.Bytes(),
+		},
+		downloads: map[string]int{},
+	}
+}
+
+func (l *exportLibrary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	attachment := func(key, parent string, mode LinkMode, filename, md5 string) Item {
+		return Item{Key: key, Data: ItemData{
+			ItemType: ItemTypeAttachment, LinkMode: mode, ParentItem: parent,
+			Filename: filename, Title: filename, MD5: md5, ContentType: "application/pdf",
+		}}
+	}
+	write := func(v any) { json.NewEncoder(w).Encode(v) }
+
+	switch r.URL.Path {
+	case "/users/12345/collections/COLL0001/items/top":
+		write([]Item{
+			{Key: "BOOK0001", Data: ItemData{ItemType: ItemTypeBook, Title: "First Book"}},
+			attachment("PDF00003", "", LinkModeImportedFile, "paper.pdf", md5Hex(l.files["PDF00003"])),
+		})
+	case "/users/12345/collections/COLL0001/collections":
+		write([]Collection{{Key: "COLL0002"}})
+	case "/users/12345/collections/COLL0002/items/top":
+		write([]Item{
+			{Key: "BOOK0001", Data: ItemData{ItemType: ItemTypeBook, Title: "First Book"}},
+			{Key: "BOOK0002", Data: ItemData{ItemType: ItemTypeBook, Title: "Second Book"}},
+		})
+	case "/users/12345/collections/COLL0002/collections":
+		write([]Collection{})
+	case "/users/12345/items/BOOK0001/children":
+		if r.URL.Query().Get("itemType") != ItemTypeAttachment {
+			l.t.Errorf("children query = %v, want itemType=attachment", r.URL.Query())
+		}
+		write([]Item{
+			attachment("PDF00001", "BOOK0001", LinkModeImportedFile, "paper.pdf", md5Hex(l.files["PDF00001"])),
+			attachment("LINK0001", "BOOK0001", LinkModeLinkedURL, "", ""),
+			attachment("SNAP0001", "BOOK0001", LinkModeImportedURL, "page.html", md5Hex([]byte("<html>snapshot</html>"))),
+		})
+	case "/users/12345/items/BOOK0002/children":
+		write([]Item{attachment("PDF00002", "BOOK0002", LinkModeImportedFile, "paper.pdf", md5Hex(l.files["PDF00002"]))})
+	default:
+		key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/12345/items/"), "/file")
+		content, ok := l.files[key]
+		if !ok || !strings.HasSuffix(r.URL.Path, "/file") {
+			l.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		l.mu.Lock()
+		l.downloads[key]++
+		l.mu.Unlock()
+		w.Write(content)
+	}
+}
+
+func TestExportCollectionAttachments(t *testing.T) {
+	library := newExportLibrary(t)
+	server, client := setupMockServer(t, library.ServeHTTP)
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "out")
+	var progressCalls int
+	opts := &ExportOptions{Dir: dir, Workers: 2, Progress: func(ExportEntry) { progressCalls++ }}
+
+	manifest, err := client.ExportCollectionAttachments(context.Background(), "COLL0001", true, opts)
+	if err != nil {
+		t.Fatalf("ExportCollectionAttachments() error = %v", err)
+	}
+
+	wantFiles := map[string]string{
+		"paper.pdf":            "%PDF first",
+		"paper (1).pdf":        "%PDF standalone",
+		"paper (2).pdf":        "%PDF second",
+		"page/page.html":       "<html>snapshot</html>",
+		"page/images/logo.png": "PNG",
+		"page/escaped.txt":     "outside",
+	}
+	for path, want := range wantFiles {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Errorf("missing %s: %v", path, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+
+	if manifest.Collection != "COLL0001" || len(manifest.Files) != 4 || progressCalls != 4 {
+		t.Fatalf("manifest = %+v, progress calls = %d, want 4 files", manifest, progressCalls)
+	}
+	if manifest.Count(ExportDownloaded) != 4 {
+		t.Errorf("downloaded = %d, want 4 (manifest %+v)", manifest.Count(ExportDownloaded), manifest.Files)
+	}
+	byKey := map[string]ExportEntry{}
+	for _, entry := range manifest.Files {
+		byKey[entry.AttachmentKey] = entry
+	}
+	if entry := byKey["SNAP0001"]; entry.Path != "page/page.html" || entry.ParentKey != "BOOK0001" || entry.Title != "First Book" {
+		t.Errorf("snapshot entry = %+v", entry)
+	}
+	if _, ok := byKey["LINK0001"]; ok {
+		t.Error("linked URL attachment was exported")
+	}
+
+	var written ExportManifest
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	if err := json.Unmarshal(data, &written); err != nil || len(written.Files) != 4 {
+		t.Errorf("manifest file = %s (error %v)", data, err)
+	}
+
+	// A second export skips files that are already up to date
+	manifest, err = client.ExportCollectionAttachments(context.Background(), "COLL0001", true, opts)
+	if err != nil {
+		t.Fatalf("second ExportCollectionAttachments() error = %v", err)
+	}
+	if manifest.Count(ExportUnchanged) != 4 {
+		t.Errorf("unchanged = %d, want 4 (manifest %+v)", manifest.Count(ExportUnchanged), manifest.Files)
+	}
+	for key, n := range library.downloads {
+		if n != 1 {
+			t.Errorf("%s downloaded %d times, want 1", key, n)
+		}
+	}
+}
+
+func TestExportAttachmentsRecordsFailures(t *testing.T) {
+	library := newExportLibrary(t)
+	library.files["PDF00002"] = []byte("corrupted in storage")
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/12345/items/BOOK0002/children" {
+			json.NewEncoder(w).Encode([]Item{{Key: "PDF00002", Data: ItemData{
+				ItemType: ItemTypeAttachment, LinkMode: LinkModeImportedFile,
+				ParentItem: "BOOK0002", Filename: "paper.pdf", MD5: md5Hex([]byte("%PDF second")),
+			}}})
+			return
+		}
+		library.ServeHTTP(w, r)
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	manifest, err := client.ExportAttachments(context.Background(), []Item{
+		{Key: "BOOK0002", Data: ItemData{ItemType: ItemTypeBook, Title: "Second Book"}},
+	}, &ExportOptions{Dir: dir, Template: "{title}.{ext}"})
+	if err != nil {
+		t.Fatalf("ExportAttachments() error = %v", err)
+	}
+
+	if len(manifest.Files) != 1 || manifest.Files[0].Status != ExportFailed || !strings.Contains(manifest.Files[0].Error, "checksum") {
+		t.Fatalf("manifest = %+v, want one checksum failure", manifest.Files)
+	}
+	if manifest.Files[0].Path != "Second Book.pdf" {
+		t.Errorf("path = %q, want templated name", manifest.Files[0].Path)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Second Book.pdf")); !os.IsNotExist(err) {
+		t.Error("corrupt file was written")
+	}
+}
+
+func TestExportAttachmentsPagingAndStaleSnapshot(t *testing.T) {
+	library := newExportLibrary(t)
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// The first page of children only holds links, the attachments follow on the second
+		if r.URL.Path == "/users/12345/items/BOOK0001/children" && r.URL.Query().Get("start") != "100" {
+			links := make([]Item, 100)
+			for i := range links {
+				links[i] = Item{Key: fmt.Sprintf("LINK%04d", i), Data: ItemData{ItemType: ItemTypeAttachment, LinkMode: LinkModeLinkedURL}}
+			}
+			json.NewEncoder(w).Encode(links)
+			return
+		}
+		library.ServeHTTP(w, r)
+	})
+	defer server.Close()
+
+	// A part file left by an interrupted snapshot download
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "page"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "page", ".SNAP0001.download.part"), []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := client.ExportAttachments(context.Background(), []Item{
+		{Key: "BOOK0001", Data: ItemData{ItemType: ItemTypeBook, Title: "First Book"}},
+	}, &ExportOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("ExportAttachments() error = %v", err)
+	}
+
+	if len(manifest.Files) != 2 || manifest.Count(ExportDownloaded) != 2 {
+		t.Fatalf("manifest = %+v, want the PDF and the snapshot downloaded", manifest.Files)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "page", "page.html")); err != nil || string(got) != "<html>snapshot</html>" {
+		t.Errorf("page.html = %q, %v", got, err)
+	}
+}
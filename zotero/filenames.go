@@ -0,0 +1,180 @@
+package zotero
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFilenameBytes is the longest filename most filesystems accept
+const maxFilenameBytes = 255
+
+// windowsReservedNames are device names that cannot be used as filenames on Windows, with or without an extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename turns a server-supplied name into a single path component that is safe on
+// Linux, macOS and Windows. Path separators, characters reserved on Windows and control characters
+// are replaced with "_", whitespace runs become a single space, leading and trailing spaces and dots
+// are removed, reserved device names are prefixed with "_" and the result is truncated to 255 bytes,
+// keeping the extension. Returns an empty string if nothing usable remains.
+func SanitizeFilename(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r' || (unicode.IsSpace(r) && r != ' '):
+			r = ' '
+		case r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r):
+			r = '_'
+		case r == utf8.RuneError:
+			r = '_'
+		}
+		if r == ' ' {
+			if space {
+				continue
+			}
+			space = true
+		} else {
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	clean := strings.Trim(b.String(), " .")
+	if clean == "" {
+		return ""
+	}
+
+	stem := strings.ToUpper(clean)
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	if windowsReservedNames[stem] {
+		clean = "_" + clean
+	}
+
+	return truncateFilename(clean, maxFilenameBytes)
+}
+
+// truncateFilename shortens name to at most limit bytes on a rune boundary, keeping a short extension
+func truncateFilename(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if len(ext) > 16 {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+
+	max := limit - len(ext)
+	for max > 0 && !utf8.RuneStart(stem[max]) {
+		max--
+	}
+	return strings.TrimRight(stem[:max], " .") + ext
+}
+
+// FilenameTemplateFields lists the placeholders supported by RenderFilenameTemplate
+var FilenameTemplateFields = []string{"creator", "year", "title", "itemType", "key", "parentKey", "filename", "ext"}
+
+// RenderFilenameTemplate builds a relative file path for an attachment from a template such as
+// "{creator}-{year}-{title}.{ext}". Bibliographic placeholders are filled from the parent item
+// (or from the attachment itself if it is standalone):
+//
+//	{creator}   First creator summary, e.g. "Doe", "Doe and Smith" or "Doe et al."
+//	{year}      Year of the item's parsed date
+//	{title}     Item title
+//	{itemType}  Item type
+//	{key}       Attachment key
+//	{parentKey} Parent item key
+//	{filename}  Stored filename without its extension
+//	{ext}       Extension of the stored filename, without the dot
+//
+// "/" in the template creates subdirectories. Every path component is sanitized with
+// SanitizeFilename, so placeholder values cannot escape the target directory. Separators left
+// dangling by empty placeholders are removed.
+func RenderFilenameTemplate(template string, attachment *Item, parent *Item) (string, error) {
+	if parent == nil {
+		parent = attachment
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(attachment.Data.Filename), ".")
+	values := map[string]string{
+		"creator":   cmp.Or(parent.Meta.CreatorSummary, parent.Data.CreatorSummary()),
+		"year":      parsedYear(parent.Meta.ParsedDate),
+		"title":     parent.Data.Title,
+		"itemType":  parent.Data.ItemType,
+		"key":       attachment.Key,
+		"parentKey": attachment.Data.ParentItem,
+		"filename":  strings.TrimSuffix(attachment.Data.Filename, filepath.Ext(attachment.Data.Filename)),
+		"ext":       ext,
+	}
+
+	var parts []string
+	for _, segment := range strings.Split(template, "/") {
+		rendered, err := expandPlaceholders(segment, values)
+		if err != nil {
+			return "", err
+		}
+		if clean := SanitizeFilename(tidySeparators(rendered)); clean != "" {
+			parts = append(parts, clean)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("template %q produced an empty filename", template)
+	}
+	return filepath.Join(parts...), nil
+}
+
+// expandPlaceholders replaces {name} placeholders in s with their values
+func expandPlaceholders(s string, values map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in %q", s)
+		}
+		name := s[start+1 : start+end]
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder {%s}", name)
+		}
+		b.WriteString(s[:start])
+		// Keep placeholder values from introducing path separators
+		b.WriteString(strings.NewReplacer("/", "_", "\\", "_").Replace(value))
+		s = s[start+end+1:]
+	}
+}
+
+// tidySeparators collapses repeated separators and strips leading and trailing ones,
+// which appear when placeholders are empty
+func tidySeparators(s string) string {
+	for _, sep := range []string{"--", "__", "  ", "-.", "_."} {
+		for strings.Contains(s, sep) {
+			s = strings.ReplaceAll(s, sep, sep[1:])
+		}
+	}
+	return strings.Trim(s, "-_ ")
+}
+
+// parsedYear returns the year of a parsed date such as "2021-03-00"
+func parsedYear(parsedDate string) string {
+	if len(parsedDate) >= 4 && parsedDate[:4] != "0000" {
+		return parsedDate[:4]
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package zotero
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "paper.pdf", "paper.pdf"},
+		{"path traversal", "../../etc/passwd", "_.._etc_passwd"},
+		{"dot dot", "..", ""},
+		{"windows separators", `C:\Users\doc.pdf`, "C__Users_doc.pdf"},
+		{"reserved characters", `What? "Really" <yes>|no*.pdf`, "What_ _Really_ _yes__no_.pdf"},
+		{"control characters", "a\x00b\x1fc.pdf", "a_b_c.pdf"},
+		{"whitespace", "  A\ttitle \n with   gaps  ", "A title with gaps"},
+		{"trailing dots", "name...", "name"},
+		{"reserved device name", "con.txt", "_con.txt"},
+		{"reserved device name without extension", "LPT1", "_LPT1"},
+		{"not a device name", "console.txt", "console.txt"},
+		{"unicode", "Über Straße.pdf", "Über Straße.pdf"},
+		{"empty", "", ""},
+		{"only separators", "///", "___"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.in); got != tt.want {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilenameTruncates(t *testing.T) {
+	long := strings.Repeat("é", 200) + ".pdf"
+	got := SanitizeFilename(long)
+	if len(got) > maxFilenameBytes {
+		t.Errorf("len = %d, want at most %d", len(got), maxFilenameBytes)
+	}
+	if !strings.HasSuffix(got, "é.pdf") {
+		t.Errorf("got %q, want truncated stem with extension kept on a rune boundary", got[len(got)-10:])
+	}
+}
+
+func TestRenderFilenameTemplate(t *testing.T) {
+	attachment := &Item{
+		Key:  "ATTA1234",
+		Data: ItemData{ItemType: ItemTypeAttachment, Filename: "full text.pdf", ParentItem: "PARENT01", Title: "Full Text PDF"},
+	}
+	parent := &Item{
+		Key:  "PARENT01",
+		Meta: Meta{ParsedDate: "2021-03-00"},
+		Data: ItemData{
+			ItemType: ItemTypeJournalArticle,
+			Title:    "Deep Learning: A/B Testing?",
+			Creators: []Creator{
+				{CreatorType: CreatorTypeAuthor, LastName: "Doe"},
+				{CreatorType: CreatorTypeAuthor, LastName: "Smith"},
+				{CreatorType: CreatorTypeAuthor, LastName: "Lee"},
+			},
+		},
+	}
+	undated := &Item{Data: ItemData{ItemType: ItemTypeBook, Title: "Untitled Draft", Creators: []Creator{{Name: "ACME Corp"}}}}
+
+	tests := []struct {
+		name     string
+		template string
+		parent   *Item
+		want     string
+		wantErr  bool
+	}{
+		{"creator year title", "{creator}-{year}-{title}.{ext}", parent, "Doe et al.-2021-Deep Learning_ A_B Testing_.pdf", false},
+		{"creator summary from meta", "{creator}.pdf", &Item{Meta: Meta{CreatorSummary: "Doe and Smith"}}, "Doe and Smith.pdf", false},
+		{"missing year", "{creator}-{year}-{title}.pdf", undated, "ACME Corp-Untitled Draft.pdf", false},
+		{"subdirectories", "{itemType}/{year}/{key}.{ext}", parent, filepath.Join("journalArticle", "2021", "ATTA1234.pdf"), false},
+		{"traversal", "../{filename}", parent, "full text", false},
+		{"standalone", "{title}", nil, "Full Text PDF", false},
+		{"unknown placeholder", "{author}.pdf", parent, "", true},
+		{"unterminated placeholder", "{title.pdf", parent, "", true},
+		{"empty result", "{year}", undated, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderFilenameTemplate(tt.template, attachment, tt.parent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderFilenameTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderFilenameTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
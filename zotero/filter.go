@@ -0,0 +1,199 @@
+package zotero
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// FilterItems applies the filters, sorting and paging of params to items held in memory, as
+// the API applies them to a library: trashed items are left out unless IncludeTrashed is set,
+// and Since, ItemKey, ItemType, Tag and Q select items. Limit 0 returns every match.
+// Full-text content is not available, so "everything" quick searches look at fields, tags and
+// the child notes found in items.
+func FilterItems(items []Item, params *QueryParams) []Item {
+	p := QueryParams{}
+	if params != nil {
+		p = *params
+	}
+
+	itemTypes := slices.Clone(p.ItemTypeFilters)
+	if len(p.ItemType) > 0 {
+		itemTypes = append(itemTypes, joinWithOR(p.ItemType))
+	}
+	tags := slices.Clone(p.TagFilters)
+	if len(p.Tag) > 0 {
+		tags = append(tags, joinWithOR(p.Tag))
+	}
+	childNotes := make(map[string][]string)
+	if p.Q != "" {
+		for _, item := range items {
+			if item.Data.ItemType == ItemTypeNote && item.Data.ParentItem != "" {
+				childNotes[item.Data.ParentItem] = append(childNotes[item.Data.ParentItem], item.Data.Note)
+			}
+		}
+	}
+
+	var matches []Item
+	for _, item := range items {
+		switch {
+		case !p.IncludeTrashed && item.Data.InTrash():
+		case p.Since > 0 && item.Version <= p.Since:
+		case len(p.ItemKey) > 0 && !slices.Contains(p.ItemKey, item.Key):
+		case !matchFilters(itemTypes, []string{item.Data.ItemType}):
+		case !matchFilters(tags, itemTagNames(&item)):
+		case p.Q != "" && !matchQ(&item, p.Q, QMode(p.QMode), childNotes[item.Key]):
+		default:
+			matches = append(matches, item)
+		}
+	}
+
+	sortItems(matches, SortField(p.Sort), SortDirection(p.Direction))
+	return paginate(matches, p.Start, p.Limit)
+}
+
+// matchFilters reports whether values satisfy every filter expression, as the API evaluates
+// repeated tag and itemType parameters: "a || b" needs one of a and b, "-a" needs no a
+func matchFilters(filters []string, values []string) bool {
+	for _, filter := range filters {
+		if negated, ok := strings.CutPrefix(filter, "-"); ok {
+			if slices.Contains(values, negated) {
+				return false
+			}
+			continue
+		}
+		if !slices.ContainsFunc(strings.Split(filter, " || "), func(v string) bool { return slices.Contains(values, v) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// itemTagNames returns the names of an item's tags
+func itemTagNames(item *Item) []string {
+	names := make([]string, len(item.Data.Tags))
+	for i, tag := range item.Data.Tags {
+		names[i] = tag.Tag
+	}
+	return names
+}
+
+// matchQ applies a quick search to an item, like the quick search conditions of saved searches
+func matchQ(item *Item, q string, mode QMode, childNotes []string) bool {
+	condition := ConditionQuickTitleCreatorYear
+	if mode == QModeEverything {
+		condition = ConditionQuickEverything
+	}
+	eval := &SearchEvaluator{}
+	index := &searchIndex{childNotes: map[string][]string{item.Key: childNotes}}
+	ok, _ := eval.matchCondition(item, SearchCondition{Condition: condition, Operator: OperatorContains, Value: q}, index, nil)
+	return ok
+}
+
+// sortItems sorts items by a field as the API does. Dates sort in descending order unless a
+// direction is given, and the default field is dateModified.
+func sortItems(items []Item, field SortField, direction SortDirection) {
+	field = cmp.Or(field, SortDateModified)
+	if direction == "" {
+		direction = SortAscending
+		switch field {
+		case SortDateAdded, SortDateModified, SortAccessDate, SortDate:
+			direction = SortDescending
+		}
+	}
+
+	value := func(item *Item) string {
+		switch field {
+		case SortCreator:
+			if item.Meta.CreatorSummary != "" || len(item.Data.Creators) == 0 {
+				return item.Meta.CreatorSummary
+			}
+			return cmp.Or(item.Data.Creators[0].LastName, item.Data.Creators[0].Name)
+		case SortDate:
+			return itemDate(item)
+		}
+		return item.Data.Field(string(field))
+	}
+	slices.SortStableFunc(items, func(a, b Item) int {
+		c := cmp.Compare(strings.ToLower(value(&a)), strings.ToLower(value(&b)))
+		if direction == SortDescending {
+			c = -c
+		}
+		return c
+	})
+}
+
+// FilterCollections applies Since, sorting by name ("title", the default) and paging of params
+// to collections held in memory. Limit 0 returns every match.
+func FilterCollections(collections []Collection, params *QueryParams) []Collection {
+	return filterByName(collections, params, func(c *Collection) (int, string) { return c.Version, c.Data.Name })
+}
+
+// FilterSearches applies Since, sorting by name ("title", the default) and paging of params to
+// searches held in memory. Limit 0 returns every match.
+func FilterSearches(searches []Search, params *QueryParams) []Search {
+	return filterByName(searches, params, func(s *Search) (int, string) { return s.Version, s.Data.Name })
+}
+
+// filterByName filters objects by version and sorts them by name
+func filterByName[T any](objects []T, params *QueryParams, fields func(*T) (version int, name string)) []T {
+	p := QueryParams{}
+	if params != nil {
+		p = *params
+	}
+
+	var matches []T
+	for _, object := range objects {
+		if version, _ := fields(&object); p.Since == 0 || version > p.Since {
+			matches = append(matches, object)
+		}
+	}
+	if p.Sort == "" || p.Sort == string(SortTitle) {
+		slices.SortStableFunc(matches, func(a, b T) int {
+			_, nameA := fields(&a)
+			_, nameB := fields(&b)
+			c := cmp.Compare(strings.ToLower(nameA), strings.ToLower(nameB))
+			if p.Direction == string(SortDescending) {
+				c = -c
+			}
+			return c
+		})
+	}
+	return paginate(matches, p.Start, p.Limit)
+}
+
+// CountTags counts the tags of items as the tags endpoints do: sorted by name, with the number
+// of items for each tag, and paged by the Start and Limit of params
+func CountTags(items []Item, params *QueryParams) []TagsResponse {
+	counts := make(map[Tag]int)
+	for _, item := range items {
+		for _, tag := range item.Data.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagsResponse, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, TagsResponse{Tag: tag.Tag, Type: tag.Type, NumItems: n, Meta: Meta{NumItems: n}})
+	}
+	slices.SortFunc(tags, func(a, b TagsResponse) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Tag), strings.ToLower(b.Tag)), cmp.Compare(a.Type, b.Type))
+	})
+
+	if params == nil {
+		return tags
+	}
+	return paginate(tags, params.Start, params.Limit)
+}
+
+// paginate returns the results from start, at most limit of them if limit is positive
+func paginate[T any](results []T, start, limit int) []T {
+	if start >= len(results) {
+		return nil
+	}
+	results = results[max(start, 0):]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
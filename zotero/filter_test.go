@@ -0,0 +1,96 @@
+package zotero
+
+import (
+	"slices"
+	"testing"
+)
+
+func filterTestItems() []Item {
+	return []Item{
+		{Key: "AAAA0001", Version: 10, Meta: Meta{CreatorSummary: "Zeta"}, Data: ItemData{
+			ItemType: ItemTypeBook, Title: "Banana Book", DateModified: "2024-01-03T00:00:00Z",
+			Tags:  []Tag{{Tag: "fruit"}, {Tag: "to read"}},
+			Extra: map[string]any{"date": "2020"},
+		}},
+		{Key: "AAAA0002", Version: 20, Meta: Meta{CreatorSummary: "Alpha"}, Data: ItemData{
+			ItemType: ItemTypeJournalArticle, Title: "apple article", DateModified: "2024-01-01T00:00:00Z",
+			Creators: []Creator{{CreatorType: "author", LastName: "Alpha"}},
+			Tags:     []Tag{{Tag: "fruit"}},
+			Extra:    map[string]any{"date": "2022-03-01"},
+		}},
+		{Key: "AAAA0003", Version: 30, Data: ItemData{
+			ItemType: ItemTypeNote, ParentItem: "AAAA0002", Note: "<p>Cherry notes</p>", DateModified: "2024-01-02T00:00:00Z",
+		}},
+		{Key: "AAAA0004", Version: 40, Data: ItemData{
+			ItemType: ItemTypeBook, Title: "Trashed", DateModified: "2024-01-04T00:00:00Z",
+			Extra: map[string]any{"deleted": float64(1)},
+		}},
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *QueryParams
+		want   []string
+	}{
+		{"default", nil, []string{"AAAA0001", "AAAA0003", "AAAA0002"}},
+		{"include trashed", &QueryParams{IncludeTrashed: true}, []string{"AAAA0004", "AAAA0001", "AAAA0003", "AAAA0002"}},
+		{"since", &QueryParams{Since: 10}, []string{"AAAA0003", "AAAA0002"}},
+		{"item keys", &QueryParams{ItemKey: []string{"AAAA0002", "AAAA0004"}}, []string{"AAAA0002"}},
+		{"item type", &QueryParams{ItemType: []string{ItemTypeBook, ItemTypeNote}}, []string{"AAAA0001", "AAAA0003"}},
+		{"excluded item type", &QueryParams{ItemTypeFilters: []string{"-" + ItemTypeNote}}, []string{"AAAA0001", "AAAA0002"}},
+		{"tags ORed", &QueryParams{Tag: []string{"to read", "missing"}}, []string{"AAAA0001"}},
+		{"tag filters ANDed", &QueryParams{TagFilters: []string{"fruit", "-to read"}}, []string{"AAAA0002"}},
+		{"quick search", &QueryParams{Q: "APPLE alpha"}, []string{"AAAA0002"}},
+		{"quick search year", &QueryParams{Q: "2020"}, []string{"AAAA0001"}},
+		{"everything searches child notes", &QueryParams{Q: "cherry", QMode: string(QModeEverything)}, []string{"AAAA0003", "AAAA0002"}},
+		{"title ascending", &QueryParams{Sort: "title", ItemType: []string{"-note"}}, []string{"AAAA0002", "AAAA0001"}},
+		{"title descending", &QueryParams{Sort: "title", Direction: "desc", ItemType: []string{"-note"}}, []string{"AAAA0001", "AAAA0002"}},
+		{"creator", &QueryParams{Sort: "creator", ItemType: []string{"-note"}}, []string{"AAAA0002", "AAAA0001"}},
+		{"date", &QueryParams{Sort: "date", ItemType: []string{"-note"}}, []string{"AAAA0002", "AAAA0001"}},
+		{"paged", &QueryParams{Start: 1, Limit: 1}, []string{"AAAA0003"}},
+		{"past the end", &QueryParams{Start: 5}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, item := range FilterItems(filterTestItems(), tt.params) {
+				got = append(got, item.Key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FilterItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCollections(t *testing.T) {
+	collections := []Collection{
+		{Key: "COLL0001", Version: 5, Data: CollectionData{Name: "beta"}},
+		{Key: "COLL0002", Version: 7, Data: CollectionData{Name: "Alpha"}},
+		{Key: "COLL0003", Version: 9, Data: CollectionData{Name: "Gamma"}},
+	}
+	keys := func(collections []Collection) []string {
+		var keys []string
+		for _, c := range collections {
+			keys = append(keys, c.Key)
+		}
+		return keys
+	}
+
+	if got := keys(FilterCollections(collections, nil)); !slices.Equal(got, []string{"COLL0002", "COLL0001", "COLL0003"}) {
+		t.Errorf("FilterCollections() = %v", got)
+	}
+	if got := keys(FilterCollections(collections, &QueryParams{Since: 5, Direction: "desc"})); !slices.Equal(got, []string{"COLL0003", "COLL0002"}) {
+		t.Errorf("FilterCollections() since 5 = %v", got)
+	}
+	if got := keys(FilterCollections(collections, &QueryParams{Start: 2, Limit: 5})); !slices.Equal(got, []string{"COLL0003"}) {
+		t.Errorf("FilterCollections() paged = %v", got)
+	}
+
+	searches := []Search{{Key: "SRCH0001", Data: SearchData{Name: "b"}}, {Key: "SRCH0002", Data: SearchData{Name: "a"}}}
+	if got := FilterSearches(searches, nil); got[0].Key != "SRCH0002" {
+		t.Errorf("FilterSearches() = %+v", got)
+	}
+}
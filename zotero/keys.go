@@ -0,0 +1,101 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrNoAPIKey is returned by operations that need an API key when the client has none
+var ErrNoAPIKey = errors.New("no API key configured")
+
+// KeyInfo describes an API key and the access it grants
+type KeyInfo struct {
+	Key         string    `json:"key"`
+	UserID      int       `json:"userID"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Access      KeyAccess `json:"access"`
+}
+
+// KeyAccess holds the access an API key grants to the owner's library and to groups
+type KeyAccess struct {
+	User   *LibraryAccess           `json:"user,omitempty"`
+	Groups map[string]LibraryAccess `json:"groups,omitempty"` // Keyed by group ID, or "all" for every group
+}
+
+// LibraryAccess holds the permissions an API key has on a library
+type LibraryAccess struct {
+	Library bool `json:"library,omitempty"` // Read access to items
+	Files   bool `json:"files,omitempty"`   // Read access to attachment files (user library)
+	Notes   bool `json:"notes,omitempty"`   // Read access to notes (user library)
+	Write   bool `json:"write,omitempty"`   // Write access
+}
+
+// KeyInfo retrieves the user and permissions of the client's API key
+func (c *Client) KeyInfo(ctx context.Context) (*KeyInfo, error) {
+	if c.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return c.fetchKeyInfo(ctx, "current")
+}
+
+// LookupKey retrieves the user and permissions of any API key
+func (c *Client) LookupKey(ctx context.Context, apiKey string) (*KeyInfo, error) {
+	if apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return c.fetchKeyInfo(ctx, url.PathEscape(apiKey))
+}
+
+func (c *Client) fetchKeyInfo(ctx context.Context, key string) (*KeyInfo, error) {
+	if c.local {
+		return nil, errNotSupportedLocally(http.MethodGet, "/keys/"+key)
+	}
+	body, _, err := c.doURLRequest(ctx, http.MethodGet, c.BaseURL+"/keys/"+key)
+	if err != nil {
+		return nil, err
+	}
+
+	var info KeyInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("error unmarshaling key info: %w", err)
+	}
+
+	return &info, nil
+}
+
+// LibraryAccess returns the permissions the key has on a library. Access to another user's
+// library is not granted by keys, so it is always empty.
+func (k *KeyInfo) LibraryAccess(libraryType LibraryType, libraryID string) LibraryAccess {
+	switch libraryType {
+	case LibraryTypeUser:
+		if k.Access.User != nil && libraryID == strconv.Itoa(k.UserID) {
+			return *k.Access.User
+		}
+	case LibraryTypeGroup:
+		if access, ok := k.Access.Groups[libraryID]; ok {
+			return access
+		}
+		return k.Access.Groups["all"]
+	}
+	return LibraryAccess{}
+}
+
+// CanWrite reports whether the client's API key can write to the client's library. Use it as a
+// preflight check before write operations, which otherwise fail with 403 Forbidden.
+// The local API is read-only, so with WithLocalAPI it always reports false.
+func (c *Client) CanWrite(ctx context.Context) (bool, error) {
+	if c.local {
+		return false, nil
+	}
+	info, err := c.KeyInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	return info.LibraryAccess(c.LibraryType, c.LibraryID).Write, nil
+}
@@ -0,0 +1,91 @@
+package zotero
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const keyInfoJSON = `{
+	"key": "test-key",
+	"userID": 12345,
+	"username": "testuser",
+	"displayName": "Test User",
+	"access": {
+		"user": {"library": true, "files": true, "notes": true, "write": false},
+		"groups": {
+			"all": {"library": true, "write": false},
+			"777": {"library": true, "write": true}
+		}
+	}
+}`
+
+func TestKeyInfo(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/keys/current" {
+			t.Errorf("path = %s, want /keys/current", r.URL.Path)
+		}
+		if r.Header.Get("Zotero-API-Key") != "test-key" {
+			t.Errorf("Zotero-API-Key = %q, want test-key", r.Header.Get("Zotero-API-Key"))
+		}
+		w.Write([]byte(keyInfoJSON))
+	})
+	defer server.Close()
+
+	info, err := client.KeyInfo(context.Background())
+	if err != nil {
+		t.Fatalf("KeyInfo() error = %v", err)
+	}
+
+	if info.UserID != 12345 || info.Username != "testuser" {
+		t.Errorf("KeyInfo() = %+v, want user 12345 testuser", info)
+	}
+
+	tests := []struct {
+		libraryType LibraryType
+		libraryID   string
+		want        LibraryAccess
+	}{
+		{LibraryTypeUser, "12345", LibraryAccess{Library: true, Files: true, Notes: true}},
+		{LibraryTypeUser, "99999", LibraryAccess{}},
+		{LibraryTypeGroup, "777", LibraryAccess{Library: true, Write: true}},
+		{LibraryTypeGroup, "888", LibraryAccess{Library: true}},
+	}
+	for _, tt := range tests {
+		if got := info.LibraryAccess(tt.libraryType, tt.libraryID); got != tt.want {
+			t.Errorf("LibraryAccess(%s, %s) = %+v, want %+v", tt.libraryType, tt.libraryID, got, tt.want)
+		}
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(keyInfoJSON))
+	})
+	defer server.Close()
+
+	client.APIKey = ""
+	if _, err := client.CanWrite(context.Background()); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("CanWrite() without key error = %v, want ErrNoAPIKey", err)
+	}
+
+	client.APIKey = "test-key"
+	canWrite, err := client.CanWrite(context.Background())
+	if err != nil {
+		t.Fatalf("CanWrite() error = %v", err)
+	}
+	if canWrite {
+		t.Error("CanWrite() = true for read-only user key")
+	}
+
+	client.LibraryType = LibraryTypeGroup
+	client.LibraryID = "777"
+	canWrite, err = client.CanWrite(context.Background())
+	if err != nil {
+		t.Fatalf("CanWrite() error = %v", err)
+	}
+	if !canWrite {
+		t.Error("CanWrite() = false for writable group")
+	}
+}
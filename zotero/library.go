@@ -0,0 +1,103 @@
+package zotero
+
+import (
+	"context"
+	"io"
+)
+
+// The interfaces below split the Client's library methods by what they touch, so code can
+// depend on the part of a library it uses and be given another implementation, such as the
+// in-memory library of the memory package or a database read by the localdb package.
+
+// ItemReader reads the items and tags of a library
+type ItemReader interface {
+	Items(ctx context.Context, params *QueryParams) ([]Item, error)
+	Top(ctx context.Context, params *QueryParams) ([]Item, error)
+	Item(ctx context.Context, itemKey string, params *QueryParams) (*Item, error)
+	Children(ctx context.Context, itemKey string, params *QueryParams) ([]Item, error)
+	Trash(ctx context.Context, params *QueryParams) ([]Item, error)
+	Tags(ctx context.Context, params *QueryParams) ([]TagsResponse, error)
+	ItemTags(ctx context.Context, itemKey string, params *QueryParams) ([]Tag, error)
+	NumItems(ctx context.Context) (int, error)
+	LastModifiedVersion(ctx context.Context) (int, error)
+}
+
+// ItemWriter creates, updates and deletes the items and tags of a library
+type ItemWriter interface {
+	CreateItems(ctx context.Context, items []Item) (*WriteResponse, error)
+	UpdateItem(ctx context.Context, item *Item) error
+	ReplaceItem(ctx context.Context, item *Item) error
+	UpdateItems(ctx context.Context, items []Item) (*WriteResponse, error)
+	DeleteItem(ctx context.Context, itemKey string, version int) error
+	DeleteItems(ctx context.Context, itemKeys []string, version int) error
+	AddTags(ctx context.Context, itemKey string, tags ...string) error
+	RemoveTags(ctx context.Context, itemKey string, tags ...string) error
+	DeleteTags(ctx context.Context, version int, tags ...string) error
+}
+
+// CollectionReader reads the collections of a library and the items in them
+type CollectionReader interface {
+	Collections(ctx context.Context, params *QueryParams) ([]Collection, error)
+	CollectionsTop(ctx context.Context, params *QueryParams) ([]Collection, error)
+	Collection(ctx context.Context, collectionKey string, params *QueryParams) (*Collection, error)
+	CollectionsSub(ctx context.Context, collectionKey string, params *QueryParams) ([]Collection, error)
+	CollectionItems(ctx context.Context, collectionKey string, params *QueryParams) ([]Item, error)
+	CollectionItemsTop(ctx context.Context, collectionKey string, params *QueryParams) ([]Item, error)
+	CollectionTags(ctx context.Context, collectionKey string, params *QueryParams) ([]TagsResponse, error)
+}
+
+// CollectionWriter creates, updates and deletes the collections of a library
+type CollectionWriter interface {
+	CreateCollections(ctx context.Context, collections []Collection) (*WriteResponse, error)
+	UpdateCollection(ctx context.Context, collection *Collection) error
+	UpdateCollections(ctx context.Context, collections []Collection) (*WriteResponse, error)
+	DeleteCollection(ctx context.Context, collectionKey string, version int) error
+	DeleteCollections(ctx context.Context, collectionKeys []string, version int) error
+}
+
+// CollectionStore reads and writes the collections of a library
+type CollectionStore interface {
+	CollectionReader
+	CollectionWriter
+}
+
+// SearchReader reads the saved searches of a library and runs them
+type SearchReader interface {
+	Searches(ctx context.Context, params *QueryParams) ([]Search, error)
+	Search(ctx context.Context, searchKey string, params *QueryParams) (*Search, error)
+	SearchItems(ctx context.Context, searchKey string, params *QueryParams) ([]Item, error)
+}
+
+// SearchWriter creates, updates and deletes the saved searches of a library
+type SearchWriter interface {
+	CreateSearches(ctx context.Context, searches []Search) (*WriteResponse, error)
+	UpdateSearch(ctx context.Context, search *Search) error
+	DeleteSearch(ctx context.Context, searchKey string, version int) error
+	DeleteSearches(ctx context.Context, searchKeys []string, version int) error
+}
+
+// SearchStore reads and writes the saved searches of a library
+type SearchStore interface {
+	SearchReader
+	SearchWriter
+}
+
+// FileStore reads and stores the files of attachments
+type FileStore interface {
+	File(ctx context.Context, itemKey string) ([]byte, error)
+	UploadAttachmentReader(ctx context.Context, parentItemKey string, r io.ReaderAt, size int64, opts *UploadOptions) (*Item, error)
+	UpdateAttachmentFile(ctx context.Context, attachmentKey string, source FileSource) (*Item, error)
+}
+
+// LibraryStore is a whole library: its items, collections, saved searches and files, and the
+// objects deleted from it, for syncing with Deleted and the Since parameter
+type LibraryStore interface {
+	ItemReader
+	ItemWriter
+	CollectionStore
+	SearchStore
+	FileStore
+	Deleted(ctx context.Context, since int) (*DeletedContent, error)
+}
+
+var _ LibraryStore = (*Client)(nil)
@@ -0,0 +1,99 @@
+package zotero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// LocalAPIURL is the address of the local API of the Zotero desktop app (Zotero 7 and later),
+// which is enabled in Settings > Advanced > "Allow other applications on this computer to
+// communicate with Zotero"
+const LocalAPIURL = "http://localhost:23119/api"
+
+// ErrNotSupportedLocally is returned for operations the local API does not support: writes,
+// file uploads, API key lookups and endpoints the desktop app does not implement
+var ErrNotSupportedLocally = errors.New("not supported by the local API")
+
+// WithLocalAPI configures the client for the local API of the Zotero desktop app.
+// The local API serves the logged-in user's library as user ID 0 (so the library ID passed to
+// NewClient is ignored for user libraries) and group libraries by their IDs. It needs no API
+// key and is not rate limited. It is read-only: writes return ErrNotSupportedLocally without
+// making a request. Attachment files are read directly from the Zotero data directory.
+// Use WithBaseURL after WithLocalAPI if the app listens on another address.
+func WithLocalAPI() ClientOption {
+	return func(c *Client) {
+		c.BaseURL = LocalAPIURL
+		c.RateLimit = 0
+		c.local = true
+	}
+}
+
+// IsLocal reports whether the client uses the local API of the Zotero desktop app
+func (c *Client) IsLocal() bool {
+	return c.local
+}
+
+// LocalFilePath returns the path of an attachment's file in the Zotero data directory.
+// It requires WithLocalAPI, since the Web API only serves file contents.
+func (c *Client) LocalFilePath(ctx context.Context, itemKey string) (string, error) {
+	if !c.local {
+		return "", fmt.Errorf("local file paths require the local API")
+	}
+
+	resp, err := c.openFile(ctx, itemKey, 0)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	file, ok := resp.Body.(*os.File)
+	if !ok {
+		return "", fmt.Errorf("attachment %s is not a local file", itemKey)
+	}
+	return file.Name(), nil
+}
+
+// errNotSupportedLocally returns ErrNotSupportedLocally for a request
+func errNotSupportedLocally(method, path string) error {
+	return fmt.Errorf("%s %s: %w", method, path, ErrNotSupportedLocally)
+}
+
+// openLocalFile opens a file:// URL as a download response starting at offset, so local
+// files are read like files from storage
+func openLocalFile(fileURL *url.URL, offset int64) (*http.Response, error) {
+	file, err := os.Open(localFilePath(fileURL))
+	if err != nil {
+		return nil, fmt.Errorf("error opening local file: %w", err)
+	}
+
+	status := http.StatusOK
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error opening local file: %w", err)
+		}
+		status = http.StatusPartialContent
+	}
+
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       file,
+	}, nil
+}
+
+// localFilePath converts a file:// URL into a path. Windows paths arrive as /C:/...
+func localFilePath(fileURL *url.URL) string {
+	path := fileURL.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,191 @@
+package zotero
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupLocalServer starts a mock local API and returns a client configured with WithLocalAPI
+func setupLocalServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *Client) {
+	server := httptest.NewServer(handler)
+	client := NewClient("12345", LibraryTypeUser, WithLocalAPI(), WithBaseURL(server.URL+"/api"))
+	return server, client
+}
+
+func TestLocalAPIRequests(t *testing.T) {
+	server, client := setupLocalServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/users/0/items/top" {
+			t.Errorf("path = %s, want /api/users/0/items/top", r.URL.Path)
+		}
+		if key := r.Header.Get("Zotero-API-Key"); key != "" {
+			t.Errorf("Zotero-API-Key = %q, want none", key)
+		}
+		w.Write([]byte(`[{"key":"ABCD1234","version":1,"data":{"key":"ABCD1234","itemType":"book","title":"Local"}}]`))
+	})
+	defer server.Close()
+
+	if !client.IsLocal() || client.LibraryID != "0" || client.RateLimit != 0 {
+		t.Errorf("IsLocal = %v, LibraryID = %q, RateLimit = %v", client.IsLocal(), client.LibraryID, client.RateLimit)
+	}
+
+	items, err := client.Top(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Top() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Data.Title != "Local" {
+		t.Errorf("items = %+v", items)
+	}
+}
+
+func TestLocalAPIGroupLibrary(t *testing.T) {
+	client := NewClient("777", LibraryTypeGroup, WithLocalAPI())
+	if client.BaseURL != LocalAPIURL || client.LibraryID != "777" {
+		t.Errorf("BaseURL = %q, LibraryID = %q", client.BaseURL, client.LibraryID)
+	}
+}
+
+func TestLocalAPIWritesNotSupported(t *testing.T) {
+	requests := 0
+	server, client := setupLocalServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotImplemented)
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	writes := map[string]func() error{
+		"CreateItems": func() error {
+			_, err := client.CreateItems(ctx, []Item{{Data: ItemData{ItemType: "book"}}})
+			return err
+		},
+		"UpdateItem":       func() error { return client.UpdateItem(ctx, &Item{Key: "ABCD1234", Version: 1}) },
+		"DeleteItem":       func() error { return client.DeleteItem(ctx, "ABCD1234", 1) },
+		"DeleteCollection": func() error { return client.DeleteCollection(ctx, "COLL1234", 1) },
+		"UploadAttachmentReader": func() error {
+			_, err := client.UploadAttachmentReader(ctx, "ABCD1234", strings.NewReader("data"), 4, &UploadOptions{Filename: "a.txt"})
+			return err
+		},
+		"KeyInfo": func() error {
+			_, err := client.LookupKey(ctx, "somekey")
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrNotSupportedLocally) {
+			t.Errorf("%s error = %v, want ErrNotSupportedLocally", name, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("made %d requests, want none", requests)
+	}
+
+	if canWrite, err := client.CanWrite(ctx); canWrite || err != nil {
+		t.Errorf("CanWrite() = %v, %v, want false, nil", canWrite, err)
+	}
+}
+
+func TestLocalAPINotImplemented(t *testing.T) {
+	server, client := setupLocalServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+		w.Write([]byte("Not implemented"))
+	})
+	defer server.Close()
+
+	_, err := client.Deleted(context.Background(), 0)
+	if !errors.Is(err, ErrNotSupportedLocally) {
+		t.Errorf("error = %v, want ErrNotSupportedLocally", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotImplemented {
+		t.Errorf("error = %v, want APIError with status 501", err)
+	}
+
+	// The Web API has no local-only errors
+	server2, webClient := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+	})
+	defer server2.Close()
+	if _, err := webClient.Deleted(context.Background(), 0); errors.Is(err, ErrNotSupportedLocally) {
+		t.Errorf("Web API error = %v, want no ErrNotSupportedLocally", err)
+	}
+}
+
+func TestLocalAPIFiles(t *testing.T) {
+	content := []byte("%PDF-1.4 local file")
+	sum := md5.Sum(content)
+	dataDir := t.TempDir()
+	storagePath := filepath.Join(dataDir, "storage", "ATTACH01", "paper.pdf")
+	if err := os.MkdirAll(filepath.Dir(storagePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(storagePath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(storagePath)}).String()
+
+	server, client := setupLocalServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users/0/items/ATTACH01":
+			fmt.Fprintf(w, `{"key":"ATTACH01","version":1,"data":{"key":"ATTACH01","itemType":"attachment","linkMode":"imported_file","filename":"paper.pdf","md5":%q}}`, hex.EncodeToString(sum[:]))
+		case "/api/users/0/items/ATTACH01/file":
+			http.Redirect(w, r, fileURL, http.StatusFound)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	path, err := client.LocalFilePath(ctx, "ATTACH01")
+	if err != nil || path != storagePath {
+		t.Errorf("LocalFilePath() = %q, %v, want %q", path, err, storagePath)
+	}
+
+	data, err := client.File(ctx, "ATTACH01")
+	if err != nil || string(data) != string(content) {
+		t.Errorf("File() = %q, %v", data, err)
+	}
+
+	result, err := client.DumpWithOptions(ctx, "ATTACH01", &DumpOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("DumpWithOptions() error = %v", err)
+	}
+	if data, _ := os.ReadFile(result.Path); string(data) != string(content) {
+		t.Errorf("dumped content = %q, want %q", data, content)
+	}
+}
+
+func TestLocalFilePath(t *testing.T) {
+	tests := map[string]string{
+		"file:///home/me/Zotero/storage/ABC/a.pdf": "/home/me/Zotero/storage/ABC/a.pdf",
+		"file:///C:/Users/me/Zotero/storage/a.pdf": "C:/Users/me/Zotero/storage/a.pdf",
+		"file:///home/me/My%20Papers/a%20b.pdf":    "/home/me/My Papers/a b.pdf",
+	}
+	for raw, want := range tests {
+		fileURL, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := localFilePath(fileURL); got != filepath.FromSlash(want) {
+			t.Errorf("localFilePath(%s) = %q, want %q", raw, got, filepath.FromSlash(want))
+		}
+	}
+}
+
+func TestLocalFilePathRequiresLocalAPI(t *testing.T) {
+	client := NewClient("12345", LibraryTypeUser)
+	if _, err := client.LocalFilePath(context.Background(), "ATTACH01"); err == nil {
+		t.Error("LocalFilePath() error = nil, want error")
+	}
+}
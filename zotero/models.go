@@ -3,6 +3,10 @@ package zotero
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -34,17 +38,218 @@ type ItemData struct {
 	DateModified string    `json:"dateModified,omitempty"`
 
 	// Attachment-specific fields
-	LinkMode    string `json:"linkMode,omitempty"`    // imported_file, imported_url, linked_file, linked_url
-	ContentType string `json:"contentType,omitempty"` // MIME type (e.g., application/pdf)
-	Filename    string `json:"filename,omitempty"`    // Filename for the attachment
-	MD5         string `json:"md5,omitempty"`         // MD5 hash of the file
-	MTime       int64  `json:"mtime,omitempty"`       // Modification time in milliseconds
-	ParentItem  string `json:"parentItem,omitempty"`  // Parent item key
-
-	// Additional fields that vary by item type
+	LinkMode    LinkMode `json:"linkMode,omitempty"`    // imported_file, imported_url, linked_file, linked_url
+	ContentType string   `json:"contentType,omitempty"` // MIME type (e.g., application/pdf)
+	Charset     string   `json:"charset,omitempty"`     // Character set of text attachments
+	Filename    string   `json:"filename,omitempty"`    // Filename for the attachment
+	MD5         string   `json:"md5,omitempty"`         // MD5 hash of the file
+	MTime       int64    `json:"mtime,omitempty"`       // Modification time in milliseconds
+	ParentItem  string   `json:"parentItem,omitempty"`  // Parent item key
+	URL         string   `json:"url,omitempty"`         // Source URL (linked_url and imported_url attachments, web items)
+	Path        string   `json:"path,omitempty"`        // Local file path (linked_file attachments)
+
+	// Note-specific fields
+	Note string `json:"note,omitempty"` // HTML content of a note item
+
+	// Annotation-specific fields
+	AnnotationType      string `json:"annotationType,omitempty"`      // highlight, underline, note, image, ink, text
+	AnnotationText      string `json:"annotationText,omitempty"`      // Highlighted or underlined text
+	AnnotationComment   string `json:"annotationComment,omitempty"`   // User comment
+	AnnotationColor     string `json:"annotationColor,omitempty"`     // Hex color (e.g., #ffd400)
+	AnnotationPageLabel string `json:"annotationPageLabel,omitempty"` // Page label shown in the reader
+	AnnotationSortIndex string `json:"annotationSortIndex,omitempty"` // Position-based sort key
+	AnnotationPosition  string `json:"annotationPosition,omitempty"`  // JSON-encoded position (see AnnotationPosition)
+
+	// Additional fields that vary by item type (e.g. "date", "publicationTitle", "DOI").
+	// Decoded from and encoded into the same JSON object as the fields above, so an item
+	// fetched and written back with UpdateItem, ReplaceItem or UpdateItems keeps these
+	// fields. When Extra is empty, item data encodes exactly as its struct fields.
 	Extra map[string]any `json:"-"`
 }
 
+// itemDataFields maps the JSON names of ItemData's struct fields to their index
+var itemDataFields = sync.OnceValue(func() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeFor[ItemData]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+})
+
+// UnmarshalJSON decodes item data, keeping fields without a struct field (e.g. "date" or "DOI") in Extra
+func (d *ItemData) UnmarshalJSON(data []byte) error {
+	type itemData ItemData // avoids recursion
+	var known itemData
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fields := itemDataFields()
+	for name, value := range raw {
+		if _, ok := fields[name]; ok {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		if known.Extra == nil {
+			known.Extra = make(map[string]any)
+		}
+		known.Extra[name] = v
+	}
+
+	*d = ItemData(known)
+	return nil
+}
+
+// MarshalJSON encodes item data, including the fields held in Extra
+func (d ItemData) MarshalJSON() ([]byte, error) {
+	type itemData ItemData // avoids recursion
+	data, err := json.Marshal(itemData(d))
+	if err != nil || len(d.Extra) == 0 {
+		return data, err
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	fields := itemDataFields()
+	for name, value := range d.Extra {
+		if _, ok := fields[name]; ok {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding field %s: %w", name, err)
+		}
+		merged[name] = encoded
+	}
+	return json.Marshal(merged)
+}
+
+// Field returns the value of a field by its Zotero name, e.g. "title", "date" or "publicationTitle".
+// Non-string values are formatted with fmt; missing fields return an empty string.
+func (d *ItemData) Field(name string) string {
+	if i, ok := itemDataFields()[name]; ok {
+		v := reflect.ValueOf(d).Elem().Field(i)
+		switch v.Kind() {
+		case reflect.String:
+			return v.String()
+		case reflect.Int, reflect.Int64:
+			if v.Int() == 0 {
+				return ""
+			}
+			return strconv.FormatInt(v.Int(), 10)
+		default:
+			return ""
+		}
+	}
+
+	switch v := d.Extra[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// InTrash reports whether the item is in the trash, as marked by the "deleted" field
+func (d *ItemData) InTrash() bool {
+	switch v := d.Extra["deleted"].(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	return false
+}
+
+// CreatorSummary summarizes the creators of the first creator's type as the API does in
+// Meta.CreatorSummary: "Smith", "Smith and Jones" or "Smith et al."
+func (d *ItemData) CreatorSummary() string {
+	var names []string
+	for _, creator := range d.Creators {
+		if creator.CreatorType == d.Creators[0].CreatorType {
+			names = append(names, creator.LastName+creator.Name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	}
+	return names[0] + " et al."
+}
+
+// CitationKey returns the item's citation key: the citationKey field if set, or else a
+// "Citation Key: ..." line in the Extra field, as stored by Better BibTeX. Returns an empty
+// string if the item has no citation key.
+func (d *ItemData) CitationKey() string {
+	if key := strings.TrimSpace(d.Field("citationKey")); key != "" {
+		return key
+	}
+	for line := range strings.SplitSeq(d.Field("extra"), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Citation Key") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// SetField sets a field by its Zotero name, storing fields without a struct field in Extra.
+// An empty value clears the field. Returns an error for fields that are not plain values,
+// such as "creators" or "tags", and for read-only fields such as "key" and "version".
+func (d *ItemData) SetField(name, value string) error {
+	switch name {
+	case "key", "version", "dateAdded", "dateModified":
+		return fmt.Errorf("field %s is read-only", name)
+	}
+
+	if i, ok := itemDataFields()[name]; ok {
+		v := reflect.ValueOf(d).Elem().Field(i)
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(value)
+		case reflect.Int, reflect.Int64:
+			n := int64(0)
+			if value != "" {
+				var err error
+				if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+					return fmt.Errorf("field %s must be a number: %w", name, err)
+				}
+			}
+			v.SetInt(n)
+		default:
+			return fmt.Errorf("field %s cannot be set from a string", name)
+		}
+		return nil
+	}
+
+	if d.Extra == nil {
+		d.Extra = make(map[string]any)
+	}
+	d.Extra[name] = value
+	return nil
+}
+
 // Creator represents a creator (author, editor, etc.)
 type Creator struct {
 	CreatorType string `json:"creatorType"`
@@ -56,7 +261,7 @@ type Creator struct {
 // Tag represents an item tag
 type Tag struct {
 	Tag  string `json:"tag"`
-	Type int    `json:"type,omitempty"` // 0 for automatic, 1 for manual
+	Type int    `json:"type,omitempty"` // 0 for manual, 1 for automatic
 }
 
 // Relations represents relationships to other items
@@ -85,13 +290,19 @@ type CollectionData struct {
 	Key              string              `json:"key,omitempty"`
 	Version          int                 `json:"version,omitempty"`
 	Name             string              `json:"name"`
-	ParentCollection ParentCollectionRef `json:"parentCollection,omitempty"`
+	ParentCollection ParentCollectionRef `json:"parentCollection,omitempty"` // Empty leaves the parent unchanged in updates; see TopLevelCollection
 	Relations        Relations           `json:"relations,omitempty"`
 }
 
 // ParentCollectionRef represents a parent collection reference that can be either a string key or false
 type ParentCollectionRef string
 
+// TopLevelCollection is set as a collection's ParentCollection to move it to the top level.
+// It is encoded as false. Collections decoded from the API have an empty ParentCollection
+// at the top level, which is omitted when encoding, so updating such a collection leaves
+// its parent unchanged.
+const TopLevelCollection ParentCollectionRef = "false"
+
 // UnmarshalJSON handles the case where parentCollection can be false (no parent) or a string (parent key)
 func (p *ParentCollectionRef) UnmarshalJSON(data []byte) error {
 	// Try to unmarshal as bool first (handles false case)
@@ -113,16 +324,19 @@ func (p *ParentCollectionRef) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// MarshalJSON handles serialization - empty string becomes false, non-empty becomes string
+// MarshalJSON handles serialization - empty string and TopLevelCollection become false, others a string
 func (p ParentCollectionRef) MarshalJSON() ([]byte, error) {
-	if p == "" {
+	if p == "" || p == TopLevelCollection {
 		return json.Marshal(false)
 	}
 	return json.Marshal(string(p))
 }
 
-// String returns the parent collection key as a string
+// String returns the parent collection key as a string, or an empty string at the top level
 func (p ParentCollectionRef) String() string {
+	if p == TopLevelCollection {
+		return ""
+	}
 	return string(p)
 }
 
@@ -147,11 +361,11 @@ type SearchData struct {
 	Conditions []SearchCondition `json:"conditions"`
 }
 
-// SearchCondition represents a single search condition
+// SearchCondition represents a single search condition (see search.go for the known conditions and operators)
 type SearchCondition struct {
-	Condition string `json:"condition"`
-	Operator  string `json:"operator"`
-	Value     string `json:"value"`
+	Condition Condition `json:"condition"`
+	Operator  Operator  `json:"operator"`
+	Value     string    `json:"value"`
 }
 
 // Group represents a Zotero group
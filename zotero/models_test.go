@@ -3,6 +3,7 @@ package zotero
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -323,3 +324,153 @@ func TestTag(t *testing.T) {
 		t.Errorf("manual tag not unmarshaled correctly: %+v", unmarshaledManualTag)
 	}
 }
+
+func TestItemDataExtraFields(t *testing.T) {
+	input := `{"itemType":"journalArticle","title":"A Paper","date":"2021-03-15","DOI":"10.1000/xyz","pages":12}`
+
+	var data ItemData
+	if err := json.Unmarshal([]byte(input), &data); err != nil {
+		t.Fatalf("failed to unmarshal item data: %v", err)
+	}
+
+	if data.Title != "A Paper" {
+		t.Errorf("Title = %v, want A Paper", data.Title)
+	}
+	if _, ok := data.Extra["title"]; ok {
+		t.Error("known field title was copied to Extra")
+	}
+	if data.Field("date") != "2021-03-15" {
+		t.Errorf("Field(date) = %q, want 2021-03-15", data.Field("date"))
+	}
+	if data.Field("DOI") != "10.1000/xyz" {
+		t.Errorf("Field(DOI) = %q, want 10.1000/xyz", data.Field("DOI"))
+	}
+	if data.Field("pages") != "12" {
+		t.Errorf("Field(pages) = %q, want 12", data.Field("pages"))
+	}
+	if data.Field("title") != "A Paper" {
+		t.Errorf("Field(title) = %q, want A Paper", data.Field("title"))
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal item data: %v", err)
+	}
+	var roundTrip map[string]any
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if roundTrip["date"] != "2021-03-15" || roundTrip["DOI"] != "10.1000/xyz" || roundTrip["title"] != "A Paper" {
+		t.Errorf("round trip = %v, want extra fields preserved", roundTrip)
+	}
+}
+
+func TestItemDataSetField(t *testing.T) {
+	data := ItemData{ItemType: ItemTypeJournalArticle, Title: "Old"}
+
+	if err := data.SetField("title", "New"); err != nil || data.Title != "New" {
+		t.Errorf("SetField(title) = %v, Title = %q", err, data.Title)
+	}
+	if err := data.SetField("DOI", "10.1000/xyz"); err != nil || data.Field("DOI") != "10.1000/xyz" {
+		t.Errorf("SetField(DOI) = %v, Field(DOI) = %q", err, data.Field("DOI"))
+	}
+	if err := data.SetField("mtime", "1700000000000"); err != nil || data.MTime != 1700000000000 {
+		t.Errorf("SetField(mtime) = %v, MTime = %d", err, data.MTime)
+	}
+	if err := data.SetField("title", ""); err != nil || data.Title != "" {
+		t.Errorf("SetField(title, \"\") = %v, Title = %q", err, data.Title)
+	}
+
+	for _, name := range []string{"key", "version", "dateAdded", "tags", "creators", "collections"} {
+		if err := data.SetField(name, "x"); err == nil {
+			t.Errorf("SetField(%s) error = nil, want error", name)
+		}
+	}
+	if err := data.SetField("mtime", "soon"); err == nil {
+		t.Error("SetField(mtime, soon) error = nil, want error")
+	}
+}
+
+func TestCollectionDataTopLevel(t *testing.T) {
+	// An empty parent is left out, so a rename does not move a subcollection
+	data, err := json.Marshal(CollectionData{Name: "Renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "parentCollection") {
+		t.Errorf("json = %s, want no parentCollection", data)
+	}
+
+	data, err = json.Marshal(CollectionData{Name: "Top", ParentCollection: TopLevelCollection})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"parentCollection":false`) {
+		t.Errorf("json = %s, want parentCollection false", data)
+	}
+	if TopLevelCollection.String() != "" {
+		t.Errorf("TopLevelCollection.String() = %q, want empty", TopLevelCollection.String())
+	}
+}
+
+func TestItemDataCitationKey(t *testing.T) {
+	tests := []struct {
+		data ItemData
+		want string
+	}{
+		{ItemData{Extra: map[string]any{"citationKey": "smith2020"}}, "smith2020"},
+		{ItemData{Extra: map[string]any{"extra": "PMID: 123\nCitation Key: doe2021\n"}}, "doe2021"},
+		{ItemData{Extra: map[string]any{"extra": "citation key:  lee2019"}}, "lee2019"},
+		{ItemData{Extra: map[string]any{"extra": "PMID: 123"}}, ""},
+		{ItemData{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.data.CitationKey(); got != tt.want {
+			t.Errorf("CitationKey() = %q, want %q (extra %v)", got, tt.want, tt.data.Extra)
+		}
+	}
+}
+
+func TestItemDataInTrash(t *testing.T) {
+	tests := []struct {
+		deleted any
+		want    bool
+	}{
+		{nil, false},
+		{true, true},
+		{false, false},
+		{float64(1), true},
+		{1, true},
+		{"1", false},
+	}
+	for _, tt := range tests {
+		data := ItemData{Extra: map[string]any{"deleted": tt.deleted}}
+		if got := data.InTrash(); got != tt.want {
+			t.Errorf("InTrash() with deleted %#v = %v, want %v", tt.deleted, got, tt.want)
+		}
+	}
+}
+
+func TestItemDataCreatorSummary(t *testing.T) {
+	smith := Creator{CreatorType: "author", LastName: "Smith"}
+	jones := Creator{CreatorType: "author", LastName: "Jones"}
+	team := Creator{CreatorType: "author", Name: "Go Team"}
+	editor := Creator{CreatorType: "editor", LastName: "Itor"}
+	tests := []struct {
+		creators []Creator
+		want     string
+	}{
+		{nil, ""},
+		{[]Creator{team}, "Go Team"},
+		{[]Creator{smith, editor}, "Smith"},
+		{[]Creator{smith, editor, jones}, "Smith and Jones"},
+		{[]Creator{smith, jones, team}, "Smith et al."},
+		{[]Creator{editor, smith}, "Itor"},
+	}
+	for _, tt := range tests {
+		data := ItemData{Creators: tt.creators}
+		if got := data.CreatorSummary(); got != tt.want {
+			t.Errorf("CreatorSummary() of %v = %q, want %q", tt.creators, got, tt.want)
+		}
+	}
+}
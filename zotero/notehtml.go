@@ -0,0 +1,1158 @@
+package zotero
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// NoteSchemaVersion is the note editor schema version written by MarkdownToNoteHTML.
+// Zotero's note editor upgrades notes written with older schema versions when they are opened.
+const NoteSchemaVersion = 8
+
+// attachmentImagePrefix marks Markdown image sources that refer to embedded note images,
+// which Zotero stores as attachments referenced by data-attachment-key.
+const attachmentImagePrefix = "attachment:"
+
+// allowedURLSchemes lists the link schemes preserved when converting notes
+var allowedURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"zotero": true,
+}
+
+// sanitizeURL returns the URL if it is relative or uses an allowed scheme
+func sanitizeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" && !allowedURLSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return raw, true
+}
+
+// NoteHTMLToMarkdown converts note HTML (as produced by Zotero's note editor) to Markdown.
+// Scripts, styles and unsafe links are dropped; formatting without a Markdown equivalent
+// (underline, colors, highlights) is reduced to its text.
+func NoteHTMLToMarkdown(noteHTML string) string {
+	nodes, err := parseNoteHTML(noteHTML)
+	if err != nil {
+		return ""
+	}
+
+	var blocks []mdBlock
+	for _, n := range nodes {
+		blocks = append(blocks, containerBlocks(n)...)
+	}
+
+	return joinBlocks(blocks, "\n\n")
+}
+
+// NoteHTMLToText converts note HTML to plain text with one line per block
+func NoteHTMLToText(noteHTML string) string {
+	nodes, err := parseNoteHTML(noteHTML)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		switch n.Type {
+		case xhtml.TextNode:
+			b.WriteString(n.Data)
+			return
+		case xhtml.ElementNode:
+			if isDroppedElement(n) {
+				return
+			}
+			if n.DataAtom == atom.Br || isBlockElement(n) {
+				b.WriteString("\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == xhtml.ElementNode && isBlockElement(n) {
+			b.WriteString("\n")
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// noteAttributes lists the elements and attributes of Zotero's note editor schema, which
+// SanitizeNoteHTML keeps. Elements not listed are replaced by their content.
+var noteAttributes = map[atom.Atom][]string{
+	atom.Div:        {"data-schema-version"},
+	atom.P:          {"dir", "style"},
+	atom.H1:         {"dir", "style"},
+	atom.H2:         {"dir", "style"},
+	atom.H3:         {"dir", "style"},
+	atom.H4:         {"dir", "style"},
+	atom.H5:         {"dir", "style"},
+	atom.H6:         {"dir", "style"},
+	atom.Pre:        {"class"},
+	atom.Blockquote: nil,
+	atom.Hr:         nil,
+	atom.Ol:         {"start"},
+	atom.Ul:         nil,
+	atom.Li:         nil,
+	atom.Table:      nil,
+	atom.Thead:      nil,
+	atom.Tbody:      nil,
+	atom.Tr:         nil,
+	atom.Th:         {"colspan", "rowspan", "colwidth"},
+	atom.Td:         {"colspan", "rowspan", "colwidth"},
+	atom.Img:        {"src", "alt", "width", "height", "data-attachment-key", "data-annotation"},
+	atom.Br:         nil,
+	atom.A:          {"href", "title"},
+	atom.Span:       {"class", "style", "data-citation", "data-annotation"},
+	atom.Strong:     nil,
+	atom.B:          nil,
+	atom.Em:         nil,
+	atom.I:          nil,
+	atom.U:          nil,
+	atom.S:          nil,
+	atom.Strike:     nil,
+	atom.Del:        nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Code:       nil,
+}
+
+// noteStyleProperties lists the style properties the note editor writes: text and
+// highlight colors, alignment and indentation
+var noteStyleProperties = map[string]bool{
+	"color":            true,
+	"background-color": true,
+	"text-align":       true,
+	"padding-left":     true,
+}
+
+// styleValuePattern matches plain style values: keywords, lengths, hex and rgb() colors
+var styleValuePattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z-]+|rgba?\([0-9\s.,%]+\)|[0-9.]+(px|em|%)?)$`)
+
+// SanitizeNoteHTML cleans note HTML from an untrusted source before it is saved. Only the
+// elements and attributes of the note editor's schema are kept (see noteAttributes): other
+// elements are replaced by their content, scripts, styles, embedded documents, SVG and MathML
+// are dropped with theirs, links and image sources must be relative or use an allowed scheme,
+// and styles are limited to colors, alignment and indentation.
+func SanitizeNoteHTML(noteHTML string) string {
+	nodes, err := parseNoteHTML(noteHTML)
+	if err != nil {
+		return ""
+	}
+
+	root := &xhtml.Node{Type: xhtml.ElementNode, DataAtom: atom.Body, Data: "body"}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	sanitizeChildren(root)
+
+	var b strings.Builder
+	for n := root.FirstChild; n != nil; n = n.NextSibling {
+		if err := xhtml.Render(&b, n); err != nil {
+			return ""
+		}
+	}
+	return b.String()
+}
+
+// sanitizeChildren applies the note schema to the children of n
+func sanitizeChildren(n *xhtml.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case xhtml.ElementNode:
+			allowed, ok := noteAttributes[c.DataAtom]
+			switch {
+			case isDroppedElement(c) || c.Namespace != "":
+				n.RemoveChild(c)
+			case !ok:
+				// Unwrap the element, and sanitize its children in its place
+				if c.FirstChild != nil {
+					next = c.FirstChild
+				}
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+			default:
+				c.Attr = sanitizeAttributes(c.Attr, allowed)
+				sanitizeChildren(c)
+			}
+		case xhtml.TextNode:
+		default:
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// sanitizeAttributes keeps the allowed attributes, dropping links with other schemes and
+// style properties the note editor does not write
+func sanitizeAttributes(attrs []xhtml.Attribute, allowed []string) []xhtml.Attribute {
+	kept := attrs[:0]
+	for _, a := range attrs {
+		key := strings.ToLower(a.Key)
+		if a.Namespace != "" || !slices.Contains(allowed, key) {
+			continue
+		}
+		switch key {
+		case "href", "src":
+			safe, ok := sanitizeURL(a.Val)
+			if !ok {
+				continue
+			}
+			a.Val = safe
+		case "style":
+			a.Val = sanitizeStyle(a.Val)
+			if a.Val == "" {
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
+
+// sanitizeStyle keeps the declarations of a style attribute that set an allowed property
+// to a plain value
+func sanitizeStyle(style string) string {
+	var kept []string
+	for _, declaration := range strings.Split(style, ";") {
+		property, value, ok := strings.Cut(declaration, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if ok && noteStyleProperties[property] && styleValuePattern.MatchString(value) {
+			kept = append(kept, property+": "+value)
+		}
+	}
+	return strings.Join(kept, "; ")
+}
+
+// parseNoteHTML parses an HTML fragment in a body context
+func parseNoteHTML(noteHTML string) ([]*xhtml.Node, error) {
+	context := &xhtml.Node{Type: xhtml.ElementNode, DataAtom: atom.Body, Data: "body"}
+	return xhtml.ParseFragment(strings.NewReader(noteHTML), context)
+}
+
+// mdBlock is a rendered Markdown block
+type mdBlock struct {
+	text string
+	list bool
+}
+
+// joinBlocks joins non-empty blocks with the given separator
+func joinBlocks(blocks []mdBlock, sep string) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.text != "" {
+			parts = append(parts, block.text)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func isDroppedElement(n *xhtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Meta, atom.Link,
+		atom.Iframe, atom.Object, atom.Embed, atom.Template, atom.Noscript, atom.Svg, atom.Math:
+		return true
+	}
+	return false
+}
+
+func isBlockElement(n *xhtml.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Blockquote, atom.Ul, atom.Ol, atom.Li, atom.Pre, atom.Hr, atom.Table,
+		atom.Tr, atom.Html, atom.Body, atom.Section, atom.Article, atom.Figure:
+		return true
+	}
+	return false
+}
+
+// isContainerElement reports whether a block element only groups other content
+func isContainerElement(n *xhtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Body, atom.Html, atom.Section, atom.Article, atom.Figure, atom.Li:
+		return true
+	}
+	return false
+}
+
+// containerBlocks renders the children of a block container as Markdown blocks,
+// grouping runs of inline content into paragraphs
+func containerBlocks(n *xhtml.Node) []mdBlock {
+	if n.Type != xhtml.ElementNode || !isBlockElement(n) {
+		return paragraphBlock(inlineMarkdown(n))
+	}
+	if !isContainerElement(n) {
+		return elementBlocks(n)
+	}
+
+	var blocks []mdBlock
+	var inline strings.Builder
+	flush := func() {
+		blocks = append(blocks, paragraphBlock(inline.String())...)
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.ElementNode && isDroppedElement(c) {
+			continue
+		}
+		if c.Type == xhtml.ElementNode && isBlockElement(c) {
+			flush()
+			blocks = append(blocks, containerBlocks(c)...)
+			continue
+		}
+		inline.WriteString(inlineMarkdown(c))
+	}
+	flush()
+
+	return blocks
+}
+
+// elementBlocks renders a single block-level element
+func elementBlocks(n *xhtml.Node) []mdBlock {
+	switch n.DataAtom {
+	case atom.P:
+		return paragraphBlock(inlineChildren(n))
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := cleanInline(inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		return []mdBlock{{text: strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\\\n", " ")}}
+	case atom.Blockquote:
+		var inner []mdBlock
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			inner = append(inner, containerBlocks(c)...)
+		}
+		text := joinBlocks(inner, "\n\n")
+		if text == "" {
+			return nil
+		}
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			if line == "" {
+				lines[i] = ">"
+			} else {
+				lines[i] = "> " + line
+			}
+		}
+		return []mdBlock{{text: strings.Join(lines, "\n")}}
+	case atom.Ul, atom.Ol:
+		return []mdBlock{{text: listMarkdown(n), list: true}}
+	case atom.Pre:
+		code := strings.TrimSuffix(textContent(n), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return []mdBlock{{text: fence + "\n" + code + "\n" + fence}}
+	case atom.Hr:
+		return []mdBlock{{text: "---"}}
+	case atom.Table:
+		return []mdBlock{{text: tableMarkdown(n)}}
+	}
+	return paragraphBlock(inlineChildren(n))
+}
+
+// paragraphBlock wraps inline Markdown as a paragraph block, escaping block-level syntax
+func paragraphBlock(inline string) []mdBlock {
+	text := cleanInline(inline)
+	if text == "" {
+		return nil
+	}
+	return []mdBlock{{text: escapeBlockStart(text)}}
+}
+
+var (
+	orderedMarkerPattern = regexp.MustCompile(`^\d+([.)])`)
+	hardBreakSpaces      = regexp.MustCompile(`[ \t]*\\\n[ \t]*`)
+)
+
+// escapeBlockStart escapes characters at the start of a paragraph that Markdown would
+// otherwise interpret as block syntax
+func escapeBlockStart(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, ">"),
+			strings.HasPrefix(line, "- "), strings.HasPrefix(line, "+ "),
+			strings.HasPrefix(line, "|"), line == "-", line == "+":
+			lines[i] = "\\" + line
+		case orderedMarkerPattern.MatchString(line):
+			loc := orderedMarkerPattern.FindStringSubmatchIndex(line)
+			lines[i] = line[:loc[2]] + "\\" + line[loc[2]:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanInline trims whitespace around the paragraph and hard line breaks
+func cleanInline(s string) string {
+	s = hardBreakSpaces.ReplaceAllString(s, "\\\n")
+	s = strings.TrimSpace(s)
+	for strings.HasSuffix(s, "\\\n") {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "\\\n"))
+	}
+	for strings.HasPrefix(s, "\\\n") {
+		s = strings.TrimSpace(strings.TrimPrefix(s, "\\\n"))
+	}
+	return s
+}
+
+// listMarkdown renders a ul/ol element as a Markdown list
+func listMarkdown(n *xhtml.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil && ordered {
+		number = start
+	}
+
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != xhtml.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+
+		blocks := containerBlocks(c)
+		var body strings.Builder
+		for i, block := range blocks {
+			if i > 0 {
+				if block.list {
+					body.WriteString("\n")
+				} else {
+					body.WriteString("\n\n")
+				}
+			}
+			body.WriteString(block.text)
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(body.String(), "\n")
+		for i, line := range lines {
+			if i > 0 && line != "" {
+				lines[i] = indent + line
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// tableMarkdown renders a table element as a GitHub-flavored Markdown table
+func tableMarkdown(n *xhtml.Node) string {
+	var rows [][]string
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode && n.DataAtom == atom.Tr {
+			var row []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == xhtml.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					cell := strings.ReplaceAll(cleanInline(inlineChildren(c)), "\\\n", " ")
+					row = append(row, strings.ReplaceAll(cell, "|", "\\|"))
+				}
+			}
+			rows = append(rows, row)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+	}
+
+	writeRow(rows[0])
+	b.WriteString("\n|")
+	for i := 0; i < columns; i++ {
+		b.WriteString(" --- |")
+	}
+	for _, row := range rows[1:] {
+		b.WriteString("\n")
+		writeRow(row)
+	}
+
+	return b.String()
+}
+
+// inlineChildren renders the children of a node as inline Markdown
+func inlineChildren(n *xhtml.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inlineMarkdown(c))
+	}
+	return b.String()
+}
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// inlineMarkdown renders a node as inline Markdown
+func inlineMarkdown(n *xhtml.Node) string {
+	switch n.Type {
+	case xhtml.TextNode:
+		return escapeMarkdown(whitespaceRun.ReplaceAllString(n.Data, " "))
+	case xhtml.ElementNode:
+	default:
+		return ""
+	}
+
+	if isDroppedElement(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\n"
+	case atom.Strong, atom.B:
+		return wrapInline(inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(inlineChildren(n), "*")
+	case atom.S, atom.Del, atom.Strike:
+		return wrapInline(inlineChildren(n), "~~")
+	case atom.Code:
+		code := textContent(n)
+		if code == "" {
+			return ""
+		}
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			return fence + " " + code + " " + fence
+		}
+		return fence + code + fence
+	case atom.A:
+		text := inlineChildren(n)
+		href, ok := sanitizeURL(attr(n, "href"))
+		if !ok || strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "[" + strings.TrimSpace(text) + "](" + escapeLinkDestination(href) + ")"
+	case atom.Img:
+		alt := escapeMarkdown(attr(n, "alt"))
+		if key := attr(n, "data-attachment-key"); key != "" {
+			return "![" + alt + "](" + attachmentImagePrefix + key + ")"
+		}
+		if src, ok := sanitizeURL(attr(n, "src")); ok {
+			return "![" + alt + "](" + escapeLinkDestination(src) + ")"
+		}
+		return ""
+	}
+
+	if isBlockElement(n) {
+		return " " + inlineChildren(n) + " "
+	}
+	return inlineChildren(n)
+}
+
+// wrapInline surrounds inline content with a delimiter, keeping surrounding whitespace
+// outside the delimiters so the result is valid Markdown emphasis
+func wrapInline(inner, delim string) string {
+	trimmed := strings.TrimSpace(inner)
+	if trimmed == "" {
+		return inner
+	}
+	leading := inner[:len(inner)-len(strings.TrimLeft(inner, " \t\n"))]
+	trailing := inner[len(strings.TrimRight(inner, " \t\n")):]
+	return leading + delim + trimmed + delim + trailing
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`*`, `\*`,
+	`_`, `\_`,
+	"`", "\\`",
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`~~`, `\~\~`,
+)
+
+// escapeMarkdown escapes characters with inline Markdown meaning
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeLinkDestination escapes characters that would end a Markdown link destination
+func escapeLinkDestination(s string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(s)
+}
+
+// textContent returns the concatenated text of a node and its descendants
+func textContent(n *xhtml.Node) string {
+	if n.Type == xhtml.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.ElementNode && c.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// attr returns the value of an attribute, or "" if it is not set
+func attr(n *xhtml.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+var (
+	headingPattern      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	hrPattern           = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern        = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	blockquotePattern   = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemPattern     = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	tableDelimPattern   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	hardBreakLineEnding = regexp.MustCompile(`(\\| {2,})$`)
+)
+
+// MarkdownToNoteHTML converts Markdown to HTML compatible with Zotero's note editor.
+// Raw HTML in the input is escaped rather than passed through, and links with unsafe
+// schemes (e.g. javascript:) are reduced to their text.
+func MarkdownToNoteHTML(markdown string) string {
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	lines := strings.Split(markdown, "\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div data-schema-version="%d">`, NoteSchemaVersion)
+	renderMarkdownBlocks(&b, lines)
+	b.WriteString("</div>")
+	return b.String()
+}
+
+// renderMarkdownBlocks renders a sequence of Markdown lines as HTML blocks
+func renderMarkdownBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fencePattern.MatchString(line):
+			fence := fencePattern.FindStringSubmatch(line)[1]
+			var code []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // skip closing fence
+			b.WriteString("<pre>" + html.EscapeString(strings.Join(code, "\n")) + "</pre>")
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			fmt.Fprintf(b, "<h%d>%s</h%d>", len(m[1]), renderInline(m[2]), len(m[1]))
+			i++
+
+		case hrPattern.MatchString(line):
+			b.WriteString("<hr>")
+			i++
+
+		case blockquotePattern.MatchString(line):
+			var quoted []string
+			for i < len(lines) && blockquotePattern.MatchString(lines[i]) {
+				quoted = append(quoted, blockquotePattern.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			b.WriteString("<blockquote>")
+			renderMarkdownBlocks(b, quoted)
+			b.WriteString("</blockquote>")
+
+		case listItemPattern.MatchString(line):
+			i = renderMarkdownList(b, lines, i)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableDelimPattern.MatchString(lines[i+1]):
+			i = renderMarkdownTable(b, lines, i)
+
+		default:
+			var para []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				if len(para) > 0 && startsBlock(lines[i]) {
+					break
+				}
+				para = append(para, lines[i])
+				i++
+			}
+			b.WriteString("<p>" + renderParagraph(para) + "</p>")
+		}
+	}
+}
+
+// startsBlock reports whether a line interrupts a paragraph
+func startsBlock(line string) bool {
+	if fencePattern.MatchString(line) || headingPattern.MatchString(line) ||
+		hrPattern.MatchString(line) || blockquotePattern.MatchString(line) {
+		return true
+	}
+	m := listItemPattern.FindStringSubmatch(line)
+	return m != nil && m[3] != ""
+}
+
+// renderParagraph renders paragraph lines, converting hard line breaks to <br>
+func renderParagraph(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		hardBreak := false
+		if i < len(lines)-1 && hardBreakLineEnding.MatchString(line) && !strings.HasSuffix(line, `\\`) {
+			hardBreak = true
+			line = hardBreakLineEnding.ReplaceAllString(line, "")
+		}
+		line = strings.TrimRight(line, " \t")
+		b.WriteString(line)
+		if i < len(lines)-1 {
+			if hardBreak {
+				b.WriteString("\x00")
+			} else {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return renderInline(b.String())
+}
+
+// renderMarkdownList renders a (possibly nested) list starting at lines[start]
+// and returns the index of the first line after the list
+func renderMarkdownList(b *strings.Builder, lines []string, start int) int {
+	first := listItemPattern.FindStringSubmatch(lines[start])
+	baseIndent := len(first[1])
+	ordered := isOrderedMarker(first[2])
+
+	if ordered {
+		number, _ := strconv.Atoi(strings.TrimRight(first[2], ".)"))
+		if number != 1 {
+			fmt.Fprintf(b, `<ol start="%d">`, number)
+		} else {
+			b.WriteString("<ol>")
+		}
+	} else {
+		b.WriteString("<ul>")
+	}
+
+	i := start
+	for i < len(lines) {
+		m := listItemPattern.FindStringSubmatch(lines[i])
+		if m == nil || len(m[1]) != baseIndent || isOrderedMarker(m[2]) != ordered {
+			break
+		}
+
+		contentIndent := len(m[1]) + len(m[2]) + 1
+		body := []string{m[3]}
+		i++
+
+		for i < len(lines) {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented content follows
+				j := i + 1
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j < len(lines) && leadingSpaces(lines[j]) >= contentIndent {
+					for ; i < j; i++ {
+						body = append(body, "")
+					}
+					continue
+				}
+				break
+			}
+
+			indent := leadingSpaces(line)
+			if indent >= contentIndent || (indent > baseIndent && listItemPattern.MatchString(line)) {
+				body = append(body, line[min(indent, contentIndent):])
+				i++
+				continue
+			}
+			if listItemPattern.MatchString(line) || startsBlock(line) {
+				break
+			}
+			// Lazy continuation of the item's paragraph
+			body = append(body, strings.TrimLeft(line, " "))
+			i++
+		}
+
+		b.WriteString("<li>")
+		renderMarkdownBlocks(b, body)
+		b.WriteString("</li>")
+
+		// Skip blank lines between items of the same list
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) && j > i {
+			if next := listItemPattern.FindStringSubmatch(lines[j]); next != nil && len(next[1]) == baseIndent && isOrderedMarker(next[2]) == ordered {
+				i = j
+			}
+		}
+	}
+
+	if ordered {
+		b.WriteString("</ol>")
+	} else {
+		b.WriteString("</ul>")
+	}
+	return i
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// renderMarkdownTable renders a GitHub-flavored Markdown table starting at lines[start]
+// and returns the index of the first line after the table
+func renderMarkdownTable(b *strings.Builder, lines []string, start int) int {
+	b.WriteString("<table>")
+
+	b.WriteString("<tr>")
+	for _, cell := range splitTableRow(lines[start]) {
+		b.WriteString("<th>" + renderInline(cell) + "</th>")
+	}
+	b.WriteString("</tr>")
+
+	i := start + 2
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|") {
+		b.WriteString("<tr>")
+		for _, cell := range splitTableRow(lines[i]) {
+			b.WriteString("<td>" + renderInline(cell) + "</td>")
+		}
+		b.WriteString("</tr>")
+		i++
+	}
+
+	b.WriteString("</table>")
+	return i
+}
+
+// splitTableRow splits a table row into cells, honoring escaped pipes
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// renderInline renders inline Markdown (emphasis, code, links, images) as HTML.
+// A NUL byte marks a hard line break.
+func renderInline(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\x00':
+			b.WriteString("<br>")
+			i++
+
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			run := countRun(s, i, '`')
+			fence := s[i : i+run]
+			end := strings.Index(s[i+run:], fence)
+			if end < 0 {
+				b.WriteString(fence)
+				i += run
+				continue
+			}
+			code := s[i+run : i+run+end]
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + html.EscapeString(strings.ReplaceAll(code, "\n", " ")) + "</code>")
+			i += run + end + run
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, dest, n, ok := parseLink(s[i+1:]); ok {
+				b.WriteString(renderImage(text, dest))
+				i += 1 + n
+				continue
+			}
+			b.WriteString("!")
+			i++
+
+		case c == '[':
+			if text, dest, n, ok := parseLink(s[i:]); ok {
+				if href, safe := sanitizeURL(dest); safe {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+			b.WriteString("[")
+			i++
+
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				target := s[i+1 : i+end]
+				if !strings.ContainsAny(target, " \n<") {
+					if href, safe := sanitizeURL(target); safe && strings.Contains(target, ":") {
+						escaped := html.EscapeString(href)
+						b.WriteString(`<a href="` + escaped + `">` + escaped + "</a>")
+						i += end + 1
+						continue
+					}
+				}
+			}
+			b.WriteString("&lt;")
+			i++
+
+		case c == '*' || c == '_' || (c == '~' && i+1 < len(s) && s[i+1] == '~'):
+			if rendered, n, ok := renderEmphasis(s, i); ok {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+			run := countRun(s, i, c)
+			b.WriteString(html.EscapeString(s[i : i+run]))
+			i += run
+
+		default:
+			b.WriteString(html.EscapeString(s[i : i+1]))
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// renderEmphasis renders emphasis, strong or strikethrough starting at s[i].
+// Returns the rendered HTML and the number of bytes consumed.
+func renderEmphasis(s string, i int) (string, int, bool) {
+	c := s[i]
+	run := countRun(s, i, c)
+
+	delimLen := 1
+	tag := "em"
+	switch {
+	case c == '~':
+		if run != 2 {
+			return "", 0, false
+		}
+		delimLen, tag = 2, "s"
+	case run >= 2:
+		delimLen, tag = 2, "strong"
+	}
+
+	// Intraword underscores are literal (e.g. snake_case)
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false
+	}
+
+	open := i + delimLen
+	if open >= len(s) || s[open] == ' ' || s[open] == '\n' {
+		return "", 0, false
+	}
+
+	delim := s[i:open]
+	for j := open + 1; j <= len(s)-delimLen; j++ {
+		if s[j-1] == '\\' {
+			continue
+		}
+		if s[j:j+delimLen] != delim {
+			continue
+		}
+		// A single delimiter must not be part of a longer run
+		if delimLen == 1 && j+1 < len(s) && s[j+1] == c {
+			j++
+			continue
+		}
+		if s[j-1] == ' ' || s[j-1] == '\n' {
+			continue
+		}
+		if c == '_' && j+delimLen < len(s) && isWordByte(s[j+delimLen]) {
+			continue
+		}
+		inner := renderInline(s[open:j])
+		return "<" + tag + ">" + inner + "</" + tag + ">", j + delimLen - i, true
+	}
+
+	return "", 0, false
+}
+
+// parseLink parses "[text](destination)" at the start of s.
+// Returns the link text, destination and number of bytes consumed.
+func parseLink(s string) (text, dest string, n int, ok bool) {
+	depth := 0
+	closeText := -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = i
+			}
+		}
+		if closeText >= 0 {
+			break
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+
+	depth = 0
+	for i := closeText + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				dest = strings.TrimSpace(s[closeText+2 : i])
+				if sp := strings.IndexAny(dest, " \t"); sp >= 0 {
+					dest = dest[:sp] // drop link titles
+				}
+				dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+				return s[1:closeText], dest, i + 1, true
+			}
+		case '\n':
+			return "", "", 0, false
+		}
+	}
+
+	return "", "", 0, false
+}
+
+// renderImage renders a Markdown image, mapping attachment references back to
+// Zotero's embedded image markup
+func renderImage(alt, src string) string {
+	altText := html.EscapeString(unescapeMarkdown(alt))
+	if key, found := strings.CutPrefix(src, attachmentImagePrefix); found {
+		return `<img alt="` + altText + `" data-attachment-key="` + html.EscapeString(key) + `">`
+	}
+	if safe, ok := sanitizeURL(src); ok {
+		return `<img alt="` + altText + `" src="` + html.EscapeString(safe) + `">`
+	}
+	return altText
+}
+
+// unescapeMarkdown removes backslash escapes from s
+func unescapeMarkdown(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func countRun(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < 128 && unicode.IsPunct(rune(c)) || c == '`' || c == '~' || c == '|' || c == '<' || c == '>' || c == '+' || c == '$' || c == '^' || c == '='
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,241 @@
+package zotero
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoteHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and headings",
+			html: `<div data-schema-version="8"><h1>Title</h1><p>First paragraph.</p><h2>Section</h2><p>Second</p></div>`,
+			want: "# Title\n\nFirst paragraph.\n\n## Section\n\nSecond",
+		},
+		{
+			name: "inline formatting",
+			html: `<p><strong>bold</strong>, <em>italic</em>, <s>gone</s> and <code>x := 1</code></p>`,
+			want: "**bold**, *italic*, ~~gone~~ and `x := 1`",
+		},
+		{
+			name: "links",
+			html: `<p>See <a href="https://www.zotero.org">Zotero</a> and <a href="javascript:alert(1)">this</a></p>`,
+			want: "See [Zotero](https://www.zotero.org) and this",
+		},
+		{
+			name: "nested lists",
+			html: `<ul><li><p>One</p><ol><li>Sub</li></ol></li><li>Two</li></ul>`,
+			want: "- One\n  1. Sub\n- Two",
+		},
+		{
+			name: "blockquote",
+			html: `<blockquote><p>Quoted</p><p>Text</p></blockquote>`,
+			want: "> Quoted\n>\n> Text",
+		},
+		{
+			name: "code block",
+			html: "<pre>func main() {\n\treturn\n}</pre>",
+			want: "```\nfunc main() {\n\treturn\n}\n```",
+		},
+		{
+			name: "line breaks",
+			html: `<p>line one<br>line two</p>`,
+			want: "line one\\\nline two",
+		},
+		{
+			name: "escaping",
+			html: `<p># not a heading with *stars* and [brackets]</p>`,
+			want: `\# not a heading with \*stars\* and \[brackets\]`,
+		},
+		{
+			name: "scripts and styles dropped",
+			html: `<p>Safe</p><script>alert(1)</script><style>p{}</style>`,
+			want: "Safe",
+		},
+		{
+			name: "zotero highlight and citation spans",
+			html: `<p><span class="highlight" data-annotation="%7B%7D">"quoted"</span> <span class="citation" data-citation="%7B%7D">(<span class="citation-item">Doe, 2020</span>)</span></p>`,
+			want: `"quoted" (Doe, 2020)`,
+		},
+		{
+			name: "embedded image",
+			html: `<p><img alt="figure" data-attachment-key="IMG12345"></p>`,
+			want: "![figure](attachment:IMG12345)",
+		},
+		{
+			name: "table",
+			html: `<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>`,
+			want: "| A | B |\n| --- | --- |\n| 1 | 2 |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NoteHTMLToMarkdown(tt.html)
+			if got != tt.want {
+				t.Errorf("NoteHTMLToMarkdown() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToNoteHTML(t *testing.T) {
+	wrap := func(s string) string {
+		return `<div data-schema-version="8">` + s + "</div>"
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "headings and paragraphs",
+			markdown: "# Title\n\nSome *emphasis* and **strong** text\ncontinued.",
+			want:     wrap("<h1>Title</h1><p>Some <em>emphasis</em> and <strong>strong</strong> text\ncontinued.</p>"),
+		},
+		{
+			name:     "lists",
+			markdown: "- one\n- two\n  1. nested\n\n3. three",
+			want:     wrap("<ul><li><p>one</p></li><li><p>two</p><ol><li><p>nested</p></li></ol></li></ul><ol start=\"3\"><li><p>three</p></li></ol>"),
+		},
+		{
+			name:     "code",
+			markdown: "Use `a < b`\n\n```go\nif a < b {\n}\n```",
+			want:     wrap("<p>Use <code>a &lt; b</code></p><pre>if a &lt; b {\n}</pre>"),
+		},
+		{
+			name:     "blockquote and rule",
+			markdown: "> quoted\n> text\n\n---",
+			want:     wrap("<blockquote><p>quoted\ntext</p></blockquote><hr>"),
+		},
+		{
+			name:     "raw html is escaped",
+			markdown: "<script>alert(1)</script>",
+			want:     wrap("<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"),
+		},
+		{
+			name:     "unsafe links are dropped",
+			markdown: "[ok](https://example.com) [bad](javascript:alert(1))",
+			want:     wrap(`<p><a href="https://example.com">ok</a> bad</p>`),
+		},
+		{
+			name:     "hard breaks",
+			markdown: "one\\\ntwo",
+			want:     wrap("<p>one<br>two</p>"),
+		},
+		{
+			name:     "intraword underscores",
+			markdown: "snake_case_name and _em_",
+			want:     wrap("<p>snake_case_name and <em>em</em></p>"),
+		},
+		{
+			name:     "attachment image",
+			markdown: "![figure](attachment:IMG12345)",
+			want:     wrap(`<p><img alt="figure" data-attachment-key="IMG12345"></p>`),
+		},
+		{
+			name:     "table",
+			markdown: "| A | B |\n| --- | --- |\n| 1 | 2 |",
+			want:     wrap("<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MarkdownToNoteHTML(tt.markdown)
+			if got != tt.want {
+				t.Errorf("MarkdownToNoteHTML() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteMarkdownRoundTrip(t *testing.T) {
+	markdowns := []string{
+		"# Reading notes\n\nThe **key** finding is *surprising*.\n\n- first\n- second\n  - nested\n\n> A quote\n\n```\ncode\n```",
+		"Escaped \\*stars\\* and \\[brackets\\]\n\n1\\. not a list",
+		"[Zotero](https://www.zotero.org) and ~~struck~~ text\\\nafter break",
+	}
+
+	for _, markdown := range markdowns {
+		got := NoteHTMLToMarkdown(MarkdownToNoteHTML(markdown))
+		if got != markdown {
+			t.Errorf("round trip mismatch:\n got %q\nwant %q", got, markdown)
+		}
+	}
+}
+
+func TestNoteHTMLToText(t *testing.T) {
+	got := NoteHTMLToText(`<div><h1>First  line</h1><p>Second<br>Third</p><script>x</script></div>`)
+	want := strings.Join([]string{"First line", "Second", "Third"}, "\n")
+	if got != want {
+		t.Errorf("NoteHTMLToText() = %q, want %q", got, want)
+	}
+}
+
+func TestSanitizeNoteHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "formatting kept",
+			html: `<div data-schema-version="8"><h1>Title</h1><p><strong>bold</strong> <span style="color: #ff2020">red</span></p></div>`,
+			want: `<div data-schema-version="8"><h1>Title</h1><p><strong>bold</strong> <span style="color: #ff2020">red</span></p></div>`,
+		},
+		{
+			name: "scripts dropped",
+			html: `<p>Safe</p><script>alert(1)</script><div><style>p{}</style><iframe src="https://example.com"></iframe>Text</div>`,
+			want: `<p>Safe</p><div>Text</div>`,
+		},
+		{
+			name: "unsafe links and handlers removed",
+			html: `<p><a href="javascript:alert(1)" onclick="alert(2)">this</a> <a href="https://www.zotero.org">Zotero</a> <img src="x" ONERROR="alert(3)"></p>`,
+			want: `<p><a>this</a> <a href="https://www.zotero.org">Zotero</a> <img src="x"/></p>`,
+		},
+		{
+			name: "form actions removed",
+			html: `<form action="javascript:alert(1)"><p>Text</p><button formaction="javascript:alert(2)">Go</button></form>`,
+			want: `<p>Text</p>Go`,
+		},
+		{
+			name: "srcset removed",
+			html: `<p><img src="https://example.com/a.png" srcset="javascript:alert(1) 2x"></p>`,
+			want: `<p><img src="https://example.com/a.png"/></p>`,
+		},
+		{
+			name: "poster removed",
+			html: `<p><video poster="javascript:alert(1)"><source src="javascript:alert(2)"></video>Text</p>`,
+			want: `<p>Text</p>`,
+		},
+		{
+			name: "svg and math dropped",
+			html: `<p>a<svg><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>b<math><maction actiontype="statusline" xlink:href="javascript:alert(2)">y</maction></math>c</p>`,
+			want: `<p>abc</p>`,
+		},
+		{
+			name: "styles limited",
+			html: `<p style="text-align: center; background-image: url(javascript:alert(1))"><span style="color: rgb(255, 32, 32); position: fixed">red</span><span style="background-color: expression(alert(1))">x</span></p>`,
+			want: `<p style="text-align: center"><span style="color: rgb(255, 32, 32)">red</span><span>x</span></p>`,
+		},
+		{
+			name: "other elements unwrapped",
+			html: `<section><p class="x" id="y"><font color="red">Text</font> <mark>marked</mark></p></section><!-- comment -->`,
+			want: `<p>Text marked</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeNoteHTML(tt.html); got != tt.want {
+				t.Errorf("SanitizeNoteHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
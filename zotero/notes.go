@@ -0,0 +1,162 @@
+package zotero
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Note represents a Zotero note item.
+// The content is stored as HTML in the format produced by Zotero's note editor.
+type Note struct {
+	Key          string
+	Version      int
+	ParentItem   string
+	HTML         string
+	Tags         []Tag
+	Collections  []string
+	DateAdded    string
+	DateModified string
+}
+
+// NoteFromItem converts a note item into a Note.
+// Returns an error if the item is not a note.
+func NoteFromItem(item *Item) (*Note, error) {
+	if item == nil {
+		return nil, fmt.Errorf("item cannot be nil")
+	}
+	if item.Data.ItemType != ItemTypeNote {
+		return nil, fmt.Errorf("item %s is not a note (itemType %q)", item.Key, item.Data.ItemType)
+	}
+
+	key := item.Key
+	if key == "" {
+		key = item.Data.Key
+	}
+	version := item.Version
+	if version == 0 {
+		version = item.Data.Version
+	}
+
+	return &Note{
+		Key:          key,
+		Version:      version,
+		ParentItem:   item.Data.ParentItem,
+		HTML:         item.Data.Note,
+		Tags:         item.Data.Tags,
+		Collections:  item.Data.Collections,
+		DateAdded:    item.Data.DateAdded,
+		DateModified: item.Data.DateModified,
+	}, nil
+}
+
+// Item converts the note back into an Item suitable for write operations
+func (n *Note) Item() Item {
+	return Item{
+		Key:     n.Key,
+		Version: n.Version,
+		Data: ItemData{
+			Key:         n.Key,
+			Version:     n.Version,
+			ItemType:    ItemTypeNote,
+			ParentItem:  n.ParentItem,
+			Note:        n.HTML,
+			Tags:        n.Tags,
+			Collections: n.Collections,
+		},
+	}
+}
+
+// Markdown returns the note content converted to Markdown
+func (n *Note) Markdown() string {
+	return NoteHTMLToMarkdown(n.HTML)
+}
+
+// Title returns the note title as displayed by Zotero (the first line of text)
+func (n *Note) Title() string {
+	text := NoteHTMLToText(n.HTML)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
+
+// CreateNote creates a note containing the given HTML.
+// parentKey: The key of the parent item (empty string for a standalone note)
+// Use MarkdownToNoteHTML to create a note from Markdown.
+func (c *Client) CreateNote(ctx context.Context, parentKey, html string) (*Note, error) {
+	note := Item{
+		Data: ItemData{
+			ItemType:   ItemTypeNote,
+			ParentItem: parentKey,
+			Note:       html,
+		},
+	}
+
+	resp, err := c.CreateItems(ctx, []Item{note})
+	if err != nil {
+		return nil, fmt.Errorf("error creating note: %w", err)
+	}
+
+	noteKey, ok := resp.Success["0"].(string)
+	if !ok {
+		if failed, ok := resp.Failed["0"]; ok {
+			return nil, fmt.Errorf("failed to create note: %s", failed.Message)
+		}
+		return nil, fmt.Errorf("failed to create note: no success or error reported")
+	}
+
+	item, err := c.Item(ctx, noteKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching created note: %w", err)
+	}
+
+	return NoteFromItem(item)
+}
+
+// UpdateNote updates the content of an existing note.
+// The note must contain version information for concurrency control.
+// Returns nil on success, error otherwise.
+func (c *Client) UpdateNote(ctx context.Context, note *Note) error {
+	if note == nil {
+		return fmt.Errorf("note cannot be nil")
+	}
+	if note.Key == "" {
+		return fmt.Errorf("note key is required")
+	}
+
+	item := note.Item()
+	return c.UpdateItem(ctx, &item)
+}
+
+// Notes retrieves all child notes of a specific item, a page at a time. Limit and Start in
+// params are ignored.
+func (c *Client) Notes(ctx context.Context, parentKey string, params *QueryParams) ([]Note, error) {
+	if parentKey == "" {
+		return nil, fmt.Errorf("parent key is required")
+	}
+
+	noteParams := QueryParams{}
+	if params != nil {
+		noteParams = *params
+	}
+	noteParams.ItemType = []string{ItemTypeNote}
+
+	items, err := fetchAll(ctx, &noteParams, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+		return c.Children(ctx, parentKey, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]Note, 0, len(items))
+	for i := range items {
+		note, err := NoteFromItem(&items[i])
+		if err != nil {
+			continue
+		}
+		notes = append(notes, *note)
+	}
+
+	return notes, nil
+}
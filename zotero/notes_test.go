@@ -0,0 +1,187 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNoteFromItem(t *testing.T) {
+	item := &Item{
+		Key:     "NOTE1234",
+		Version: 7,
+		Data: ItemData{
+			ItemType:   ItemTypeNote,
+			ParentItem: "ABCD1234",
+			Note:       "<div data-schema-version=\"8\"><h1>Reading notes</h1><p>Body</p></div>",
+			Tags:       []Tag{{Tag: "todo"}},
+		},
+	}
+
+	note, err := NoteFromItem(item)
+	if err != nil {
+		t.Fatalf("NoteFromItem() error = %v", err)
+	}
+	if note.Key != "NOTE1234" || note.Version != 7 {
+		t.Errorf("Key/Version = %v/%v, want NOTE1234/7", note.Key, note.Version)
+	}
+	if note.ParentItem != "ABCD1234" {
+		t.Errorf("ParentItem = %v, want ABCD1234", note.ParentItem)
+	}
+	if note.Title() != "Reading notes" {
+		t.Errorf("Title() = %q, want %q", note.Title(), "Reading notes")
+	}
+	if got := note.Markdown(); got != "# Reading notes\n\nBody" {
+		t.Errorf("Markdown() = %q", got)
+	}
+
+	if _, err := NoteFromItem(&Item{Data: ItemData{ItemType: ItemTypeBook}}); err == nil {
+		t.Error("expected error for non-note item, got nil")
+	}
+}
+
+func TestCreateNote(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			var data []map[string]any
+			if err := json.Unmarshal(body, &data); err != nil {
+				t.Fatalf("invalid request body: %v", err)
+			}
+			if data[0]["itemType"] != ItemTypeNote {
+				t.Errorf("itemType = %v, want note", data[0]["itemType"])
+			}
+			if data[0]["parentItem"] != "ABCD1234" {
+				t.Errorf("parentItem = %v, want ABCD1234", data[0]["parentItem"])
+			}
+			if data[0]["note"] != "<p>Hello</p>" {
+				t.Errorf("note = %v, want <p>Hello</p>", data[0]["note"])
+			}
+			w.Write([]byte(`{"success": {"0": "NOTE1234"}, "unchanged": {}, "failed": {}}`))
+		case http.MethodGet:
+			if r.URL.Path != "/users/12345/items/NOTE1234" {
+				t.Errorf("unexpected path: %s", r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(Item{
+				Key:     "NOTE1234",
+				Version: 1,
+				Data: ItemData{
+					Key:        "NOTE1234",
+					Version:    1,
+					ItemType:   ItemTypeNote,
+					ParentItem: "ABCD1234",
+					Note:       "<p>Hello</p>",
+				},
+			})
+		}
+	})
+	defer server.Close()
+
+	note, err := client.CreateNote(context.Background(), "ABCD1234", "<p>Hello</p>")
+	if err != nil {
+		t.Fatalf("CreateNote() error = %v", err)
+	}
+	if note.Key != "NOTE1234" {
+		t.Errorf("Key = %v, want NOTE1234", note.Key)
+	}
+	if note.HTML != "<p>Hello</p>" {
+		t.Errorf("HTML = %v, want <p>Hello</p>", note.HTML)
+	}
+}
+
+func TestCreateNoteFailed(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": {}, "unchanged": {}, "failed": {"0": {"code": 400, "message": "Parent item not found"}}}`))
+	})
+	defer server.Close()
+
+	if _, err := client.CreateNote(context.Background(), "MISSING1", "<p>Hello</p>"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestUpdateNote(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		if r.URL.Path != "/users/12345/items/NOTE1234" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("If-Unmodified-Since-Version") != "3" {
+			t.Errorf("If-Unmodified-Since-Version = %v, want 3", r.Header.Get("If-Unmodified-Since-Version"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	err := client.UpdateNote(context.Background(), &Note{Key: "NOTE1234", Version: 3, HTML: "<p>Updated</p>"})
+	if err != nil {
+		t.Errorf("UpdateNote() error = %v", err)
+	}
+
+	if err := client.UpdateNote(context.Background(), &Note{}); err == nil {
+		t.Error("expected error for note without key, got nil")
+	}
+}
+
+func TestNotes(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/12345/items/ABCD1234/children" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("itemType") != ItemTypeNote {
+			t.Errorf("itemType = %v, want note", r.URL.Query().Get("itemType"))
+		}
+		json.NewEncoder(w).Encode([]Item{
+			{Key: "NOTE0001", Version: 1, Data: ItemData{ItemType: ItemTypeNote, Note: "<p>One</p>"}},
+			{Key: "NOTE0002", Version: 2, Data: ItemData{ItemType: ItemTypeNote, Note: "<p>Two</p>"}},
+		})
+	})
+	defer server.Close()
+
+	notes, err := client.Notes(context.Background(), "ABCD1234", nil)
+	if err != nil {
+		t.Fatalf("Notes() error = %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("len(notes) = %v, want 2", len(notes))
+	}
+	if notes[1].Title() != "Two" {
+		t.Errorf("notes[1].Title() = %v, want Two", notes[1].Title())
+	}
+}
+
+func TestNotesPaging(t *testing.T) {
+	var starts []string
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		starts = append(starts, query.Get("start"))
+
+		count := 100
+		if query.Get("start") == "100" {
+			count = 30
+		}
+		items := make([]Item, count)
+		for i := range items {
+			items[i] = Item{Key: fmt.Sprintf("NOTE%04d", i), Data: ItemData{ItemType: ItemTypeNote, Note: "<p>Note</p>"}}
+		}
+		json.NewEncoder(w).Encode(items)
+	})
+	defer server.Close()
+
+	notes, err := client.Notes(context.Background(), "ABCD1234", &QueryParams{Limit: 25})
+	if err != nil {
+		t.Fatalf("Notes() error = %v", err)
+	}
+	if len(notes) != 130 {
+		t.Errorf("len(notes) = %v, want 130", len(notes))
+	}
+	if len(starts) != 2 || starts[1] != "100" {
+		t.Errorf("start params = %v, want [\"\" 100]", starts)
+	}
+}
@@ -0,0 +1,294 @@
+package zotero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// QMode is the scope of a quick search
+type QMode string
+
+const (
+	QModeTitleCreatorYear QMode = "titleCreatorYear" // Titles, creators and years (the default)
+	QModeEverything       QMode = "everything"       // All fields, notes and full-text content
+)
+
+// SortField is a field results can be sorted by
+type SortField string
+
+const (
+	SortDateAdded           SortField = "dateAdded"
+	SortDateModified        SortField = "dateModified"
+	SortTitle               SortField = "title"
+	SortCreator             SortField = "creator"
+	SortItemType            SortField = "itemType"
+	SortDate                SortField = "date"
+	SortPublisher           SortField = "publisher"
+	SortPublicationTitle    SortField = "publicationTitle"
+	SortJournalAbbreviation SortField = "journalAbbreviation"
+	SortLanguage            SortField = "language"
+	SortAccessDate          SortField = "accessDate"
+	SortLibraryCatalog      SortField = "libraryCatalog"
+	SortCallNumber          SortField = "callNumber"
+	SortRights              SortField = "rights"
+	SortAddedBy             SortField = "addedBy"
+	SortNumItems            SortField = "numItems"
+)
+
+// SortDirection is the order results are sorted in
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// sortFields lists the sort values the API accepts
+var sortFields = []SortField{
+	SortDateAdded, SortDateModified, SortTitle, SortCreator, SortItemType, SortDate, SortPublisher,
+	SortPublicationTitle, SortJournalAbbreviation, SortLanguage, SortAccessDate, SortLibraryCatalog,
+	SortCallNumber, SortRights, SortAddedBy, SortNumItems,
+}
+
+// maxItemKeys is the most item keys the API accepts in a single itemKey parameter
+const maxItemKeys = 50
+
+// Query builds QueryParams with a fluent API. Consecutive tags are ORed within a clause, And
+// starts a new clause, and each clause is sent as its own tag parameter, which the API ANDs.
+// Errors are collected and returned by Build.
+//
+//	params, err := zotero.NewQuery().
+//		Tag("a").And().NotTag("b").
+//		ItemType(zotero.ItemTypeBook, zotero.ItemTypeThesis).
+//		Exclude(zotero.ItemTypeAnnotation).
+//		Q("foo", zotero.QModeEverything).
+//		SortDesc(zotero.SortDateModified).
+//		Build()
+type Query struct {
+	params     QueryParams
+	tagClauses [][]string
+	itemTypes  []string
+	excluded   []string
+	errs       []error
+}
+
+// NewQuery starts building query parameters
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Tag matches items with any of the tags, ORed with the other tags in the current clause
+func (q *Query) Tag(tags ...string) *Query {
+	for _, tag := range tags {
+		if tag == "" {
+			q.errs = append(q.errs, fmt.Errorf("tag must not be empty"))
+			continue
+		}
+		q.addTag(tag)
+	}
+	return q
+}
+
+// NotTag matches items without the tag. A negated tag must be in a clause of its own.
+func (q *Query) NotTag(tag string) *Query {
+	if tag == "" {
+		q.errs = append(q.errs, fmt.Errorf("tag must not be empty"))
+		return q
+	}
+	q.addTag("-" + tag)
+	return q
+}
+
+// Or is a no-op for readability: consecutive tags are ORed
+func (q *Query) Or() *Query {
+	return q
+}
+
+// And starts a new tag clause, so the tags before and after must both match
+func (q *Query) And() *Query {
+	if n := len(q.tagClauses); n > 0 && len(q.tagClauses[n-1]) > 0 {
+		q.tagClauses = append(q.tagClauses, nil)
+	}
+	return q
+}
+
+func (q *Query) addTag(tag string) {
+	if len(q.tagClauses) == 0 {
+		q.tagClauses = append(q.tagClauses, nil)
+	}
+	n := len(q.tagClauses) - 1
+	q.tagClauses[n] = append(q.tagClauses[n], tag)
+}
+
+// ItemType matches items of any of the given types
+func (q *Query) ItemType(itemTypes ...string) *Query {
+	q.itemTypes = append(q.itemTypes, itemTypes...)
+	return q
+}
+
+// Exclude omits items of the given types
+func (q *Query) Exclude(itemTypes ...string) *Query {
+	q.excluded = append(q.excluded, itemTypes...)
+	return q
+}
+
+// ItemKeys restricts results to the given items. Keys beyond the API's limit of 50 are split
+// across requests by Batches and Client.QueryItems.
+func (q *Query) ItemKeys(keys ...string) *Query {
+	q.params.ItemKey = append(q.params.ItemKey, keys...)
+	return q
+}
+
+// Q sets a quick search query
+func (q *Query) Q(query string, mode QMode) *Query {
+	if mode != "" && mode != QModeTitleCreatorYear && mode != QModeEverything {
+		q.errs = append(q.errs, fmt.Errorf("invalid qmode %q", mode))
+	}
+	q.params.Q = query
+	q.params.QMode = string(mode)
+	return q
+}
+
+// Sort sorts results by field in the given direction
+func (q *Query) Sort(field SortField, direction SortDirection) *Query {
+	if !slices.Contains(sortFields, field) {
+		q.errs = append(q.errs, fmt.Errorf("invalid sort field %q", field))
+	}
+	if direction != SortAscending && direction != SortDescending {
+		q.errs = append(q.errs, fmt.Errorf("invalid sort direction %q (expected asc or desc)", direction))
+	}
+	q.params.Sort = string(field)
+	q.params.Direction = string(direction)
+	return q
+}
+
+// SortAsc sorts results by field in ascending order
+func (q *Query) SortAsc(field SortField) *Query {
+	return q.Sort(field, SortAscending)
+}
+
+// SortDesc sorts results by field in descending order
+func (q *Query) SortDesc(field SortField) *Query {
+	return q.Sort(field, SortDescending)
+}
+
+// Limit sets the maximum number of results (1-100)
+func (q *Query) Limit(limit int) *Query {
+	if limit < 1 || limit > maxPageSize {
+		q.errs = append(q.errs, fmt.Errorf("limit %d is out of range (1-%d)", limit, maxPageSize))
+	}
+	q.params.Limit = limit
+	return q
+}
+
+// Start sets the index of the first result
+func (q *Query) Start(start int) *Query {
+	if start < 0 {
+		q.errs = append(q.errs, fmt.Errorf("start must not be negative"))
+	}
+	q.params.Start = start
+	return q
+}
+
+// Since only returns objects modified after the given library version
+func (q *Query) Since(version int) *Query {
+	q.params.Since = version
+	return q
+}
+
+// IncludeTrashed includes items in the trash
+func (q *Query) IncludeTrashed() *Query {
+	q.params.IncludeTrashed = true
+	return q
+}
+
+// Param adds a query parameter without a dedicated method. Repeated calls with the same key
+// send the parameter several times.
+func (q *Query) Param(key, value string) *Query {
+	if q.params.Extra == nil {
+		q.params.Extra = url.Values{}
+	}
+	q.params.Extra.Add(key, value)
+	return q
+}
+
+// Build returns the query parameters, or the errors collected while building. Use Batches
+// for queries with more than 50 item keys.
+func (q *Query) Build() (*QueryParams, error) {
+	batches, err := q.Batches()
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) > 1 {
+		return nil, fmt.Errorf("invalid query: %d item keys exceed the limit of %d per request (use Batches)", len(q.params.ItemKey), maxItemKeys)
+	}
+	return batches[0], nil
+}
+
+// Batches returns one set of query parameters per 50 item keys, or a single set if the query
+// has no more than 50 item keys
+func (q *Query) Batches() ([]*QueryParams, error) {
+	errs := slices.Clone(q.errs)
+	for _, clause := range q.tagClauses {
+		if len(clause) > 1 && slices.ContainsFunc(clause, func(tag string) bool { return strings.HasPrefix(tag, "-") }) {
+			errs = append(errs, fmt.Errorf("negated tag cannot be ORed with other tags (use And)"))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	params := q.params
+	params.ItemKey = nil
+	params.TagFilters = nil
+	for _, clause := range q.tagClauses {
+		if len(clause) > 0 {
+			params.TagFilters = append(params.TagFilters, joinWithOR(clause))
+		}
+	}
+	params.ItemTypeFilters = nil
+	if len(q.itemTypes) > 0 {
+		params.ItemTypeFilters = append(params.ItemTypeFilters, joinWithOR(q.itemTypes))
+	}
+	for _, itemType := range q.excluded {
+		params.ItemTypeFilters = append(params.ItemTypeFilters, "-"+itemType)
+	}
+
+	if len(q.params.ItemKey) == 0 {
+		return []*QueryParams{&params}, nil
+	}
+
+	var batches []*QueryParams
+	for keys := range slices.Chunk(q.params.ItemKey, maxItemKeys) {
+		batch := params
+		batch.ItemKey = keys
+		if batch.Limit == 0 {
+			batch.Limit = maxItemKeys
+		}
+		batches = append(batches, &batch)
+	}
+	return batches, nil
+}
+
+// QueryItems retrieves the items matching a query. Queries with more than 50 item keys are
+// split across requests and the results concatenated, so sorting applies within each batch.
+func (c *Client) QueryItems(ctx context.Context, q *Query) ([]Item, error) {
+	batches, err := q.Batches()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, params := range batches {
+		batch, err := c.Items(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+	}
+	return items, nil
+}
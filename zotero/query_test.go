@@ -0,0 +1,130 @@
+package zotero
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	client := NewClient("12345", LibraryTypeUser)
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{
+			name:  "tags and item types",
+			query: NewQuery().Tag("a").And().NotTag("b").ItemType(ItemTypeBook).Exclude(ItemTypeAnnotation).Q("foo", QModeEverything).SortDesc(SortDateModified),
+			want:  "?direction=desc&itemType=book&itemType=-annotation&q=foo&qmode=everything&sort=dateModified&tag=a&tag=-b",
+		},
+		{
+			name:  "ORed tags",
+			query: NewQuery().Tag("a", "b").Or().Tag("c").And().Tag("d"),
+			want:  "?tag=a+%7C%7C+b+%7C%7C+c&tag=d",
+		},
+		{
+			name:  "repeated And",
+			query: NewQuery().And().Tag("a").And().And().Tag("b"),
+			want:  "?tag=a&tag=b",
+		},
+		{
+			name:  "ORed item types",
+			query: NewQuery().ItemType(ItemTypeBook, ItemTypeThesis),
+			want:  "?itemType=book+%7C%7C+thesis",
+		},
+		{
+			name:  "paging, since and trash",
+			query: NewQuery().Limit(50).Start(100).Since(42).IncludeTrashed().SortAsc(SortTitle),
+			want:  "?direction=asc&includeTrashed=1&limit=50&since=42&sort=title&start=100",
+		},
+		{
+			name:  "item keys",
+			query: NewQuery().ItemKeys("KEY1", "KEY2"),
+			want:  "?itemKey=KEY1%2CKEY2&limit=50",
+		},
+		{
+			name:  "repeated params",
+			query: NewQuery().Param("locale", "de-DE").Param("x", "1").Param("x", "2"),
+			want:  "?locale=de-DE&x=1&x=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := tt.query.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := client.buildQueryString(params); got != tt.want {
+				t.Errorf("query string = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr string
+	}{
+		{"invalid sort", NewQuery().SortAsc("relevance"), `invalid sort field "relevance"`},
+		{"invalid direction", NewQuery().Sort(SortTitle, "up"), `invalid sort direction "up"`},
+		{"invalid qmode", NewQuery().Q("x", "fulltext"), `invalid qmode "fulltext"`},
+		{"limit too large", NewQuery().Limit(500), "limit 500 is out of range"},
+		{"empty tag", NewQuery().Tag(""), "tag must not be empty"},
+		{"negated tag in OR", NewQuery().Tag("a").NotTag("b"), "negated tag cannot be ORed"},
+		{"too many keys", NewQuery().ItemKeys(testKeys(51)...), "exceed the limit of 50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.query.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Build() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testKeys returns n distinct item keys
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("KEY%05d", i)
+	}
+	return keys
+}
+
+func TestQueryItemsBatchesItemKeys(t *testing.T) {
+	var requests []string
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		keys := strings.Split(r.URL.Query().Get("itemKey"), ",")
+		requests = append(requests, r.URL.Query().Get("limit"))
+		var items []string
+		for _, key := range keys {
+			items = append(items, fmt.Sprintf(`{"key":%q,"data":{"itemType":"book"}}`, key))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+	})
+	defer server.Close()
+
+	items, err := client.QueryItems(context.Background(), NewQuery().ItemKeys(testKeys(120)...).Exclude(ItemTypeAttachment))
+	if err != nil {
+		t.Fatalf("QueryItems() error = %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("made %d requests, want 3", len(requests))
+	}
+	if requests[0] != "50" {
+		t.Errorf("limit = %q, want 50", requests[0])
+	}
+	if len(items) != 120 || items[119].Key != "KEY00119" {
+		t.Errorf("got %d items, want 120 in key order", len(items))
+	}
+}
@@ -6,25 +6,56 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
 )
 
 // QueryParams represents optional parameters for API requests
 type QueryParams struct {
-	Limit    int               // Maximum number of results (default 100)
-	Start    int               // Starting index for results
-	Sort     string            // Field to sort by (dateAdded, dateModified, title, creator, itemType, etc.)
-	Format   string            // Response format (atom, bib, json, keys, versions, etc.)
-	Include  string            // Additional data to include (data, bib, citation, etc.)
-	Style    string            // Citation style for bib/citation formats
-	Q        string            // Quick search query
-	QMode    string            // Quick search mode (titleCreatorYear, everything)
-	Tag      []string          // Filter by tag(s)
-	ItemKey  []string          // Filter by item key(s)
-	ItemType []string          // Filter by item type(s); prefix with "-" to exclude (e.g., "-annotation")
-	Since    int               // Return only objects modified since version
-	Extra    map[string]string // Additional query parameters
+	Limit           int        // Maximum number of results (default 100)
+	Start           int        // Starting index for results
+	Sort            string     // Field to sort by (dateAdded, dateModified, title, creator, itemType, etc.)
+	Direction       string     // Sort direction (asc, desc)
+	Format          string     // Response format (atom, bib, json, keys, versions, etc.)
+	Include         string     // Additional data to include (data, bib, citation, etc.)
+	Style           string     // Citation style for bib/citation formats
+	Q               string     // Quick search query
+	QMode           string     // Quick search mode (titleCreatorYear, everything)
+	Tag             []string   // Filter by tag(s), joined with OR
+	TagFilters      []string   // Tag expressions, each sent as a separate tag parameter and ANDed (e.g. "a || b", "-c")
+	ItemKey         []string   // Filter by item key(s), at most 50
+	ItemType        []string   // Filter by item type(s); prefix with "-" to exclude (e.g., "-annotation")
+	ItemTypeFilters []string   // Item type expressions, each sent as a separate itemType parameter
+	Since           int        // Return only objects modified since version
+	IncludeTrashed  bool       // Include items in the trash
+	Extra           url.Values // Additional query parameters
+}
+
+// maxPageSize is the maximum number of results the API returns per request
+const maxPageSize = 100
+
+// fetchAll pages through a list endpoint until every result has been retrieved.
+// Limit and Start in params are overridden.
+func fetchAll[T any](ctx context.Context, params *QueryParams, fetch func(context.Context, *QueryParams) ([]T, error)) ([]T, error) {
+	pageParams := QueryParams{}
+	if params != nil {
+		pageParams = *params
+	}
+	pageParams.Limit = maxPageSize
+	pageParams.Start = 0
+
+	var all []T
+	for {
+		page, err := fetch(ctx, &pageParams)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < maxPageSize {
+			return all, nil
+		}
+		pageParams.Start += len(page)
+	}
 }
 
 // Items retrieves all library items
@@ -213,6 +244,12 @@ func (c *Client) Searches(ctx context.Context, params *QueryParams) ([]Search, e
 	return searches, nil
 }
 
+// SearchesAll retrieves every saved search, a page at a time. Limit and Start in params are
+// ignored.
+func (c *Client) SearchesAll(ctx context.Context, params *QueryParams) ([]Search, error) {
+	return fetchAll(ctx, params, c.Searches)
+}
+
 // Search retrieves a specific saved search by key
 func (c *Client) Search(ctx context.Context, searchKey string, params *QueryParams) (*Search, error) {
 	path := fmt.Sprintf("/searches/%s", searchKey)
@@ -229,6 +266,30 @@ func (c *Client) Search(ctx context.Context, searchKey string, params *QueryPara
 	return &search, nil
 }
 
+// SearchItems retrieves the items matching a saved search
+func (c *Client) SearchItems(ctx context.Context, searchKey string, params *QueryParams) ([]Item, error) {
+	path := fmt.Sprintf("/searches/%s/items", searchKey)
+	body, _, err := c.doRequest(ctx, http.MethodGet, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("error unmarshaling items: %w", err)
+	}
+
+	return items, nil
+}
+
+// SearchItemsAll retrieves every item matching a saved search, a page at a time. Limit and
+// Start in params are ignored.
+func (c *Client) SearchItemsAll(ctx context.Context, searchKey string, params *QueryParams) ([]Item, error) {
+	return fetchAll(ctx, params, func(ctx context.Context, params *QueryParams) ([]Item, error) {
+		return c.SearchItems(ctx, searchKey, params)
+	})
+}
+
 // TagsResponse represents the response from the tags endpoint
 type TagsResponse struct {
 	Tag      string `json:"tag"`
@@ -320,7 +381,7 @@ func (c *Client) Groups(ctx context.Context, params *QueryParams) ([]Group, erro
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error: %s (status %d)", string(body), resp.StatusCode)
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: string(body)}
 	}
 
 	var groups []Group
@@ -398,64 +459,37 @@ func (c *Client) Deleted(ctx context.Context, since int) (*DeletedContent, error
 // File downloads the raw file content of an attachment item
 // Returns the file content as a byte slice
 func (c *Client) File(ctx context.Context, itemKey string) ([]byte, error) {
-	path := fmt.Sprintf("/items/%s/file", itemKey)
-	body, _, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	resp, err := c.openFile(ctx, itemKey, 0)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
 
 	return body, nil
 }
 
-// Dump is a convenience wrapper around File() that writes an attachment to disk
-// If filename is empty, it will fetch the item to determine the stored filename
+// Dump downloads an attachment file to disk.
+// If filename is empty, the stored filename is used (falling back to the title, then the item key)
 // If path is empty, it writes to the current working directory
+// Filenames are sanitized and an existing file is overwritten; use DumpWithOptions for
+// naming templates and other conflict policies.
+// The file is written atomically and verified against the attachment's MD5 hash;
+// an interrupted download is resumed on the next call.
 // Returns the full path to the written file
 func (c *Client) Dump(ctx context.Context, itemKey string, filename string, path string) (string, error) {
-	// If no filename provided, fetch the item to get the stored filename
-	if filename == "" {
-		item, err := c.Item(ctx, itemKey, nil)
-		if err != nil {
-			return "", fmt.Errorf("error fetching item to determine filename: %w", err)
-		}
-
-		// Try to get filename from item data
-		if item.Data.Filename != "" {
-			filename = item.Data.Filename
-		} else if item.Data.Title != "" {
-			// Fall back to title if filename not available
-			filename = item.Data.Title
-		} else {
-			// Last resort: use the item key
-			filename = itemKey
-		}
-	}
-
-	// Download the file content
-	fileContent, err := c.File(ctx, itemKey)
-	if err != nil {
-		return "", fmt.Errorf("error downloading file: %w", err)
-	}
-
-	// Build the full file path
-	var fullPath string
-	if path != "" {
-		fullPath = fmt.Sprintf("%s/%s", path, filename)
-	} else {
-		fullPath = filename
-	}
-
-	// Write the file to disk
-	file, err := os.Create(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("error creating file: %w", err)
-	}
-	defer file.Close()
-
-	_, err = file.Write(fileContent)
+	result, err := c.DumpWithOptions(ctx, itemKey, &DumpOptions{
+		Dir:      path,
+		Filename: filename,
+		Conflict: ConflictOverwrite,
+	})
 	if err != nil {
-		return "", fmt.Errorf("error writing file: %w", err)
+		return "", err
 	}
 
-	return fullPath, nil
+	return result.Path, nil
 }
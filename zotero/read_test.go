@@ -3,9 +3,12 @@ package zotero
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -452,7 +455,38 @@ func TestAPIError(t *testing.T) {
 
 	_, err := client.Item(context.Background(), "NOTFOUND", nil)
 	if err == nil {
-		t.Error("expected error for 404 response")
+		t.Fatal("expected error for 404 response")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("errors.Is(err, ErrNotFound) = false for %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("errors.As(err, *APIError) = %+v, want status 404", apiErr)
+	}
+	if !strings.Contains(err.Error(), "(status 404)") {
+		t.Errorf("err.Error() = %q, want status in message", err.Error())
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrForbidden, ErrConflict, ErrPreconditionFailed, ErrRateLimited}
+	tests := map[int]error{
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusUnauthorized:        ErrUnauthorized,
+		http.StatusForbidden:           ErrForbidden,
+		http.StatusConflict:            ErrConflict,
+		http.StatusPreconditionFailed:  ErrPreconditionFailed,
+		http.StatusTooManyRequests:     ErrRateLimited,
+		http.StatusInternalServerError: nil,
+	}
+	for status, want := range tests {
+		err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: status})
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == want) {
+				t.Errorf("status %d: errors.Is(err, %v) = %v", status, sentinel, got)
+			}
+		}
 	}
 }
 
@@ -740,3 +774,36 @@ func TestDumpWithTitleFallback(t *testing.T) {
 		t.Errorf("file content = %v, want %v", string(content), string(expectedContent))
 	}
 }
+
+func TestFetchAll(t *testing.T) {
+	var starts []string
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		starts = append(starts, query.Get("start"))
+		if query.Get("limit") != "100" {
+			t.Errorf("limit = %v, want 100", query.Get("limit"))
+		}
+
+		count := 100
+		if query.Get("start") == "100" {
+			count = 20
+		}
+		items := make([]Item, count)
+		for i := range items {
+			items[i] = Item{Key: "ITEM", Data: ItemData{ItemType: ItemTypeBook}}
+		}
+		json.NewEncoder(w).Encode(items)
+	})
+	defer server.Close()
+
+	items, err := fetchAll(context.Background(), &QueryParams{Sort: "title"}, client.Items)
+	if err != nil {
+		t.Fatalf("fetchAll() error = %v", err)
+	}
+	if len(items) != 120 {
+		t.Errorf("len(items) = %v, want 120", len(items))
+	}
+	if len(starts) != 2 || starts[0] != "" || starts[1] != "100" {
+		t.Errorf("start params = %v, want [\"\" 100]", starts)
+	}
+}
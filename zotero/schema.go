@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // SchemaItemType represents an item type from the Zotero schema
@@ -45,7 +46,7 @@ type NewItemTemplate map[string]any
 func (c *Client) ItemTypes(ctx context.Context, locale string) ([]SchemaItemType, error) {
 	params := &QueryParams{}
 	if locale != "" {
-		params.Extra = map[string]string{"locale": locale}
+		params.Extra = url.Values{"locale": {locale}}
 	}
 
 	body, _, err := c.doRequest(ctx, http.MethodGet, "/itemTypes", params)
@@ -66,7 +67,7 @@ func (c *Client) ItemTypes(ctx context.Context, locale string) ([]SchemaItemType
 func (c *Client) ItemFields(ctx context.Context, locale string) ([]SchemaField, error) {
 	params := &QueryParams{}
 	if locale != "" {
-		params.Extra = map[string]string{"locale": locale}
+		params.Extra = url.Values{"locale": {locale}}
 	}
 
 	body, _, err := c.doRequest(ctx, http.MethodGet, "/itemFields", params)
@@ -88,7 +89,7 @@ func (c *Client) ItemTypeFields(ctx context.Context, itemType string, locale str
 	path := fmt.Sprintf("/itemTypeFields?itemType=%s", itemType)
 	params := &QueryParams{}
 	if locale != "" {
-		params.Extra = map[string]string{"locale": locale}
+		params.Extra = url.Values{"locale": {locale}}
 	}
 
 	body, _, err := c.doRequest(ctx, http.MethodGet, path, params)
@@ -110,7 +111,7 @@ func (c *Client) ItemTypeCreatorTypes(ctx context.Context, itemType string, loca
 	path := fmt.Sprintf("/itemTypeCreatorTypes?itemType=%s", itemType)
 	params := &QueryParams{}
 	if locale != "" {
-		params.Extra = map[string]string{"locale": locale}
+		params.Extra = url.Values{"locale": {locale}}
 	}
 
 	body, _, err := c.doRequest(ctx, http.MethodGet, path, params)
@@ -131,7 +132,7 @@ func (c *Client) ItemTypeCreatorTypes(ctx context.Context, itemType string, loca
 func (c *Client) CreatorFields(ctx context.Context, locale string) ([]SchemaField, error) {
 	params := &QueryParams{}
 	if locale != "" {
-		params.Extra = map[string]string{"locale": locale}
+		params.Extra = url.Values{"locale": {locale}}
 	}
 
 	body, _, err := c.doRequest(ctx, http.MethodGet, "/creatorFields", params)
@@ -0,0 +1,223 @@
+package zotero
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Condition is the field or special criterion a saved search condition tests.
+// Any item field name (e.g. "publicationTitle" or "DOI") is also a valid condition.
+type Condition string
+
+const (
+	// Special conditions
+	ConditionJoinMode                  Condition = "joinMode"                  // Operator: OperatorAll or OperatorAny
+	ConditionCollection                Condition = "collection"                // Value: collection key
+	ConditionSavedSearch               Condition = "savedSearch"               // Value: saved search key
+	ConditionRecursive                 Condition = "recursive"                 // Search subcollections of collection conditions
+	ConditionNoChildren                Condition = "noChildren"                // Only match top-level items
+	ConditionIncludeParentsAndChildren Condition = "includeParentsAndChildren" // Also return parents and children of matches
+	ConditionUnfiled                   Condition = "unfiled"                   // Only match items in no collection
+	ConditionDeleted                   Condition = "deleted"                   // Match items in the trash
+
+	// Item conditions
+	ConditionItemType     Condition = "itemType"
+	ConditionTag          Condition = "tag"
+	ConditionNote         Condition = "note"
+	ConditionChildNote    Condition = "childNote"
+	ConditionCreator      Condition = "creator"
+	ConditionLastName     Condition = "lastName"
+	ConditionTitle        Condition = "title"
+	ConditionAbstract     Condition = "abstractNote"
+	ConditionDate         Condition = "date"
+	ConditionDateAdded    Condition = "dateAdded"
+	ConditionDateModified Condition = "dateModified"
+	ConditionAnyField     Condition = "field"
+
+	// Full-text and annotation conditions
+	ConditionFulltextContent   Condition = "fulltextContent"
+	ConditionAnnotationText    Condition = "annotationText"
+	ConditionAnnotationComment Condition = "annotationComment"
+
+	// Quick search conditions
+	ConditionQuickTitleCreatorYear Condition = "quicksearch-titleCreatorYear"
+	ConditionQuickFields           Condition = "quicksearch-fields"
+	ConditionQuickEverything       Condition = "quicksearch-everything"
+)
+
+// Operator is the comparison a saved search condition applies
+type Operator string
+
+const (
+	OperatorIs             Operator = "is"
+	OperatorIsNot          Operator = "isNot"
+	OperatorContains       Operator = "contains"
+	OperatorDoesNotContain Operator = "doesNotContain"
+	OperatorBeginsWith     Operator = "beginsWith"
+	OperatorIsLessThan     Operator = "isLessThan"
+	OperatorIsGreaterThan  Operator = "isGreaterThan"
+	OperatorIsBefore       Operator = "isBefore"
+	OperatorIsAfter        Operator = "isAfter"
+	OperatorIsInTheLast    Operator = "isInTheLast" // Value: e.g. "7 days", "2 months"
+
+	// Join modes (ConditionJoinMode)
+	OperatorAll Operator = "all"
+	OperatorAny Operator = "any"
+
+	// Flags (ConditionRecursive, ConditionNoChildren, ...)
+	OperatorTrue  Operator = "true"
+	OperatorFalse Operator = "false"
+)
+
+var (
+	textOperators = []Operator{OperatorIs, OperatorIsNot, OperatorContains, OperatorDoesNotContain, OperatorBeginsWith}
+	dateOperators = []Operator{OperatorIs, OperatorIsNot, OperatorIsBefore, OperatorIsAfter, OperatorIsInTheLast}
+	isOperators   = []Operator{OperatorIs, OperatorIsNot}
+	flagOperators = []Operator{OperatorTrue, OperatorFalse}
+)
+
+// conditionOperators lists the operators Zotero accepts for conditions with a restricted set
+var conditionOperators = map[Condition][]Operator{
+	ConditionJoinMode:                  {OperatorAll, OperatorAny},
+	ConditionCollection:                isOperators,
+	ConditionSavedSearch:               isOperators,
+	ConditionItemType:                  isOperators,
+	ConditionRecursive:                 flagOperators,
+	ConditionNoChildren:                flagOperators,
+	ConditionIncludeParentsAndChildren: flagOperators,
+	ConditionUnfiled:                   flagOperators,
+	ConditionDeleted:                   flagOperators,
+	ConditionTag:                       textOperators,
+	ConditionCreator:                   textOperators,
+	ConditionLastName:                  textOperators,
+	ConditionDate:                      dateOperators,
+	ConditionDateAdded:                 dateOperators,
+	ConditionDateModified:              dateOperators,
+}
+
+// allOperators are accepted for item field conditions without a restricted set
+var allOperators = []Operator{
+	OperatorIs, OperatorIsNot, OperatorContains, OperatorDoesNotContain, OperatorBeginsWith,
+	OperatorIsLessThan, OperatorIsGreaterThan, OperatorIsBefore, OperatorIsAfter, OperatorIsInTheLast,
+}
+
+// ValidOperator reports whether op can be used with condition
+func ValidOperator(condition Condition, op Operator) bool {
+	if ops, ok := conditionOperators[condition]; ok {
+		return slices.Contains(ops, op)
+	}
+	return slices.Contains(allOperators, op)
+}
+
+// SearchBuilder builds SearchData with a fluent API. Errors are collected and returned by Build.
+//
+//	data, err := zotero.NewSearch("Recent ML papers").
+//		MatchAll().
+//		Where(zotero.ConditionTag, zotero.OperatorIs, "machine learning").
+//		Where(zotero.ConditionDateAdded, zotero.OperatorIsInTheLast, "30 days").
+//		InCollection("ABCD1234", true).
+//		Build()
+type SearchBuilder struct {
+	name       string
+	joinMode   Operator
+	conditions []SearchCondition
+	errs       []error
+}
+
+// NewSearch starts building a saved search with the given name
+func NewSearch(name string) *SearchBuilder {
+	return &SearchBuilder{name: name}
+}
+
+// MatchAll requires items to match every condition (the default)
+func (b *SearchBuilder) MatchAll() *SearchBuilder {
+	b.joinMode = OperatorAll
+	return b
+}
+
+// MatchAny requires items to match at least one condition
+func (b *SearchBuilder) MatchAny() *SearchBuilder {
+	b.joinMode = OperatorAny
+	return b
+}
+
+// Where adds a condition
+func (b *SearchBuilder) Where(condition Condition, op Operator, value string) *SearchBuilder {
+	if condition == ConditionJoinMode {
+		b.errs = append(b.errs, fmt.Errorf("use MatchAll or MatchAny to set the join mode"))
+		return b
+	}
+	if !ValidOperator(condition, op) {
+		b.errs = append(b.errs, fmt.Errorf("operator %q is not valid for condition %q", op, condition))
+		return b
+	}
+	b.conditions = append(b.conditions, SearchCondition{Condition: condition, Operator: op, Value: value})
+	return b
+}
+
+// Tag matches items with the given tag
+func (b *SearchBuilder) Tag(tag string) *SearchBuilder {
+	return b.Where(ConditionTag, OperatorIs, tag)
+}
+
+// ItemType matches items of the given type
+func (b *SearchBuilder) ItemType(itemType string) *SearchBuilder {
+	return b.Where(ConditionItemType, OperatorIs, itemType)
+}
+
+// InCollection matches items in a collection, and in its subcollections if recursive is set
+func (b *SearchBuilder) InCollection(collectionKey string, recursive bool) *SearchBuilder {
+	b.Where(ConditionCollection, OperatorIs, collectionKey)
+	if recursive {
+		b.flag(ConditionRecursive)
+	}
+	return b
+}
+
+// InSavedSearch matches items that match another saved search
+func (b *SearchBuilder) InSavedSearch(searchKey string) *SearchBuilder {
+	return b.Where(ConditionSavedSearch, OperatorIs, searchKey)
+}
+
+// NoChildren only matches top-level items
+func (b *SearchBuilder) NoChildren() *SearchBuilder {
+	return b.flag(ConditionNoChildren)
+}
+
+// IncludeParentsAndChildren also returns the parents and children of matching items
+func (b *SearchBuilder) IncludeParentsAndChildren() *SearchBuilder {
+	return b.flag(ConditionIncludeParentsAndChildren)
+}
+
+// flag adds a boolean condition once
+func (b *SearchBuilder) flag(condition Condition) *SearchBuilder {
+	for _, existing := range b.conditions {
+		if existing.Condition == condition {
+			return b
+		}
+	}
+	return b.Where(condition, OperatorTrue, "")
+}
+
+// Build returns the search data, or the errors collected while building
+func (b *SearchBuilder) Build() (SearchData, error) {
+	errs := slices.Clone(b.errs)
+	if b.name == "" {
+		errs = append(errs, fmt.Errorf("search name is required"))
+	}
+	if len(b.conditions) == 0 {
+		errs = append(errs, fmt.Errorf("at least one condition is required"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return SearchData{}, fmt.Errorf("invalid search: %w", err)
+	}
+
+	conditions := make([]SearchCondition, 0, len(b.conditions)+1)
+	if b.joinMode != "" {
+		conditions = append(conditions, SearchCondition{Condition: ConditionJoinMode, Operator: b.joinMode})
+	}
+	conditions = append(conditions, b.conditions...)
+
+	return SearchData{Name: b.name, Conditions: conditions}, nil
+}
@@ -0,0 +1,162 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchBuilder(t *testing.T) {
+	data, err := NewSearch("Recent ML").
+		MatchAny().
+		Tag("machine learning").
+		Where(ConditionDateAdded, OperatorIsInTheLast, "30 days").
+		InCollection("COLL1234", true).
+		InSavedSearch("SRCH1234").
+		NoChildren().
+		NoChildren().
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	want := SearchData{
+		Name: "Recent ML",
+		Conditions: []SearchCondition{
+			{Condition: ConditionJoinMode, Operator: OperatorAny},
+			{Condition: ConditionTag, Operator: OperatorIs, Value: "machine learning"},
+			{Condition: ConditionDateAdded, Operator: OperatorIsInTheLast, Value: "30 days"},
+			{Condition: ConditionCollection, Operator: OperatorIs, Value: "COLL1234"},
+			{Condition: ConditionRecursive, Operator: OperatorTrue},
+			{Condition: ConditionSavedSearch, Operator: OperatorIs, Value: "SRCH1234"},
+			{Condition: ConditionNoChildren, Operator: OperatorTrue},
+		},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Build() = %+v, want %+v", data, want)
+	}
+
+	encoded, _ := json.Marshal(data.Conditions[0])
+	if string(encoded) != `{"condition":"joinMode","operator":"any","value":""}` {
+		t.Errorf("joinMode condition JSON = %s", encoded)
+	}
+}
+
+func TestSearchBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *SearchBuilder
+		wantErr string
+	}{
+		{"missing name", NewSearch("").Tag("x"), "name is required"},
+		{"no conditions", NewSearch("Empty").MatchAll(), "at least one condition"},
+		{"invalid operator", NewSearch("Bad").Where(ConditionItemType, OperatorContains, "book"), `operator "contains" is not valid for condition "itemType"`},
+		{"unknown operator", NewSearch("Bad").Where(ConditionTitle, "matches", "x"), `operator "matches"`},
+		{"join mode condition", NewSearch("Bad").Where(ConditionJoinMode, OperatorAny, ""), "MatchAll or MatchAny"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Build() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchBuilderBuildTwice(t *testing.T) {
+	b := NewSearch("")
+	_, first := b.Build()
+	_, second := b.Build()
+	if first == nil || second == nil || first.Error() != second.Error() {
+		t.Errorf("Build() errors = %v, then %v; want the same error", first, second)
+	}
+
+	// A builder fixed after a failed Build succeeds
+	b = NewSearch("Later")
+	if _, err := b.Build(); err == nil {
+		t.Fatal("Build() without conditions error = nil")
+	}
+	if _, err := b.Tag("x").Build(); err != nil {
+		t.Errorf("Build() after adding a condition error = %v", err)
+	}
+}
+
+func TestValidOperator(t *testing.T) {
+	tests := []struct {
+		condition Condition
+		op        Operator
+		want      bool
+	}{
+		{ConditionTag, OperatorBeginsWith, true},
+		{ConditionDateModified, OperatorIsInTheLast, true},
+		{ConditionDateModified, OperatorContains, false},
+		{ConditionCollection, OperatorIsNot, true},
+		{ConditionNoChildren, OperatorTrue, true},
+		{"publicationTitle", OperatorContains, true},
+		{"publicationTitle", OperatorAny, false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidOperator(tt.condition, tt.op); got != tt.want {
+			t.Errorf("ValidOperator(%s, %s) = %v, want %v", tt.condition, tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestSearchItems(t *testing.T) {
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/12345/searches/SRCH1234/items" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("limit") != "5" {
+			t.Errorf("limit = %s, want 5", r.URL.Query().Get("limit"))
+		}
+		w.Write([]byte(`[{"key": "ITEM0001", "data": {"itemType": "book", "title": "Match"}}]`))
+	})
+	defer server.Close()
+
+	items, err := client.SearchItems(context.Background(), "SRCH1234", &QueryParams{Limit: 5})
+	if err != nil {
+		t.Fatalf("SearchItems() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Data.Title != "Match" {
+		t.Errorf("items = %+v", items)
+	}
+}
+
+func TestSearchItemsAll(t *testing.T) {
+	var starts []string
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/12345/searches/SRCH1234/items" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		starts = append(starts, query.Get("start"))
+		if query.Get("limit") != "100" {
+			t.Errorf("limit = %v, want 100", query.Get("limit"))
+		}
+
+		count := 100
+		if query.Get("start") == "100" {
+			count = 5
+		}
+		json.NewEncoder(w).Encode(make([]Item, count))
+	})
+	defer server.Close()
+
+	items, err := client.SearchItemsAll(context.Background(), "SRCH1234", nil)
+	if err != nil {
+		t.Fatalf("SearchItemsAll() error = %v", err)
+	}
+	if len(items) != 105 {
+		t.Errorf("len(items) = %v, want 105", len(items))
+	}
+	if len(starts) != 2 || starts[1] != "100" {
+		t.Errorf("start params = %v, want [\"\" 100]", starts)
+	}
+}
@@ -0,0 +1,438 @@
+package zotero
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// ProgressFunc reports upload progress as the number of bytes sent out of the total file size
+type ProgressFunc func(sent, total int64)
+
+// UploadOptions configures an attachment upload
+type UploadOptions struct {
+	Filename    string       // Name of the attachment file (required)
+	Title       string       // Attachment title (if empty, Filename is used)
+	ContentType string       // MIME type of the file (e.g., "application/pdf")
+	MTime       time.Time    // File modification time (if zero, the current time is used)
+	Progress    ProgressFunc // Called as file content is sent to storage (optional)
+}
+
+// UploadAttachmentReader uploads the content of r as an imported file attachment.
+// The content is read twice without being buffered in memory: once to compute its MD5 hash
+// and once while streaming it to storage. Cancelling ctx aborts the upload.
+//
+// parentItemKey: The key of the parent item to attach to (empty string for standalone attachment)
+// r: Source of the file content
+// size: Size of the file content in bytes
+// opts: Upload options; Filename is required
+func (c *Client) UploadAttachmentReader(ctx context.Context, parentItemKey string, r io.ReaderAt, size int64, opts *UploadOptions) (*Item, error) {
+	if opts == nil || opts.Filename == "" {
+		return nil, fmt.Errorf("filename is required")
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = opts.Filename
+	}
+
+	attachment := Item{
+		Data: ItemData{
+			ItemType:    ItemTypeAttachment,
+			LinkMode:    LinkModeImportedFile,
+			Title:       title,
+			ContentType: opts.ContentType,
+			Filename:    opts.Filename,
+			ParentItem:  parentItemKey,
+		},
+	}
+	if !opts.MTime.IsZero() {
+		attachment.Data.MTime = opts.MTime.UnixMilli()
+	}
+
+	return c.createAndUploadAttachment(ctx, attachment, r, size, opts.Progress)
+}
+
+// createAndUploadAttachment hashes the file content, creates the attachment item
+// and uploads the content for it. Returns the updated attachment item.
+func (c *Client) createAndUploadAttachment(ctx context.Context, attachment Item, r io.ReaderAt, size int64, progress ProgressFunc) (*Item, error) {
+	md5String, err := hashReaderAt(ctx, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.Data.MD5 = md5String
+	if attachment.Data.MTime == 0 {
+		attachment.Data.MTime = time.Now().UnixMilli()
+	}
+
+	// Step 1: Create attachment item
+	attachmentKey, err := c.createAttachmentItem(ctx, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Steps 2-4: Authorize, upload and register the file
+	return c.uploadAttachmentFile(ctx, attachmentKey, fileUpload{
+		filename: attachment.Data.Filename,
+		content:  r,
+		size:     size,
+		md5:      md5String,
+		mtime:    attachment.Data.MTime,
+		progress: progress,
+	})
+}
+
+// PatchAlgorithm identifies the binary diff format of a file patch
+type PatchAlgorithm string
+
+const (
+	// PatchBSDiff is a diff produced by bsdiff
+	PatchBSDiff PatchAlgorithm = "bsdiff"
+	// PatchXDelta is a diff produced by xdelta3
+	PatchXDelta PatchAlgorithm = "xdelta"
+	// PatchVCDiff is a VCDIFF (RFC 3284) diff
+	PatchVCDiff PatchAlgorithm = "vcdiff"
+)
+
+// FilePatch is a binary diff that transforms the current attachment file into the new content
+type FilePatch struct {
+	Algorithm PatchAlgorithm
+	Diff      []byte
+}
+
+// FileSource describes replacement content for an attachment file
+type FileSource struct {
+	Reader   io.ReaderAt  // New file content (required, also used to compute the new MD5 hash)
+	Size     int64        // Size of the new file content in bytes
+	Filename string       // New filename (if empty, the current filename is kept)
+	MTime    time.Time    // File modification time (if zero, the current time is used)
+	Progress ProgressFunc // Called as file content is sent to storage (optional)
+	Patch    *FilePatch   // Send a diff instead of the full content (optional)
+}
+
+// UpdateAttachmentFile replaces the file of an existing stored attachment.
+// The upload is conditional on the attachment's current MD5 hash (If-Match), so it fails with
+// a 412 error if the file was changed elsewhere in the meantime. On success, the API updates
+// the attachment's md5, mtime and filename, and the refreshed item is returned.
+// When source.Patch is set, only the diff is uploaded; the full content is still read to
+// compute the new MD5 hash.
+func (c *Client) UpdateAttachmentFile(ctx context.Context, attachmentKey string, source FileSource) (*Item, error) {
+	if source.Reader == nil {
+		return nil, fmt.Errorf("file content is required")
+	}
+	if source.Patch != nil && source.Patch.Algorithm == "" {
+		return nil, fmt.Errorf("patch algorithm is required")
+	}
+
+	attachment, err := c.Item(ctx, attachmentKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attachment: %w", err)
+	}
+	if attachment.Data.ItemType != ItemTypeAttachment || !attachment.Data.LinkMode.HasFile() {
+		return nil, fmt.Errorf("item %s is not a stored file attachment", attachmentKey)
+	}
+	if source.Patch != nil && attachment.Data.MD5 == "" {
+		return nil, fmt.Errorf("attachment %s has no file to patch", attachmentKey)
+	}
+
+	md5String, err := hashReaderAt(ctx, source.Reader, source.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := source.Filename
+	if filename == "" {
+		filename = attachment.Data.Filename
+	}
+	mtime := source.MTime
+	if mtime.IsZero() {
+		mtime = time.Now()
+	}
+
+	return c.uploadAttachmentFile(ctx, attachmentKey, fileUpload{
+		filename:    filename,
+		content:     source.Reader,
+		size:        source.Size,
+		md5:         md5String,
+		mtime:       mtime.UnixMilli(),
+		progress:    source.Progress,
+		previousMD5: attachment.Data.MD5,
+		patch:       source.Patch,
+	})
+}
+
+// fileUpload describes file content to upload for an attachment item
+type fileUpload struct {
+	filename    string
+	content     io.ReaderAt
+	size        int64
+	md5         string
+	mtime       int64
+	progress    ProgressFunc
+	previousMD5 string     // MD5 of the file being replaced (empty for a new file)
+	patch       *FilePatch // Binary diff to send instead of the full content
+}
+
+// uploadAttachmentFile uploads file content for an existing attachment item:
+// it requests upload authorization, uploads the file to storage and registers the upload.
+// Returns the updated attachment item.
+func (c *Client) uploadAttachmentFile(ctx context.Context, attachmentKey string, upload fileUpload) (*Item, error) {
+	// Step 2: Request upload authorization
+	// Build form-encoded request body (not JSON!)
+	authBody := []byte(fmt.Sprintf("md5=%s&filename=%s&filesize=%d&mtime=%d",
+		upload.md5, url.QueryEscape(upload.filename), upload.size, upload.mtime))
+
+	// Replacing a file requires the MD5 of the current file; a new file must not exist yet
+	ifNoneMatch, ifMatch := "*", ""
+	if upload.previousMD5 != "" {
+		ifNoneMatch, ifMatch = "", upload.previousMD5
+	}
+
+	path := fmt.Sprintf("/items/%s/file", attachmentKey)
+	authRespBody, authResp, err := c.doFileAuthRequest(ctx, path, authBody, ifNoneMatch, ifMatch)
+
+	// If we get a 412 with "file exists", try again with If-Match header using the file's MD5
+	if err != nil && upload.previousMD5 == "" && authResp != nil && authResp.StatusCode == http.StatusPreconditionFailed {
+		c.logger.Printf("File exists on server (412), retrying with If-Match header")
+		ifNoneMatch, ifMatch = "", upload.md5
+		authRespBody, authResp, err = c.doFileAuthRequest(ctx, path, authBody, ifNoneMatch, ifMatch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload authorization: %w", err)
+	}
+
+	// Parse authorization response
+	var authResponse map[string]any
+	if err := json.Unmarshal(authRespBody, &authResponse); err != nil {
+		return nil, fmt.Errorf("error parsing auth response: %w", err)
+	}
+
+	// Check if file already exists
+	if exists, ok := authResponse["exists"].(float64); ok && exists == 1 {
+		c.logger.Printf("File already exists on server")
+		// Fetch and return the attachment item
+		return c.Item(ctx, attachmentKey, nil)
+	}
+
+	uploadKey, ok := authResponse["uploadKey"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing upload key in auth response")
+	}
+
+	// A patch is applied by the API directly and needs no separate registration
+	if upload.patch != nil {
+		if err := c.uploadPatch(ctx, path, uploadKey, upload); err != nil {
+			return nil, err
+		}
+		return c.Item(ctx, attachmentKey, nil)
+	}
+
+	// Step 3: Upload the file
+	uploadURL, ok := authResponse["url"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing upload URL in auth response")
+	}
+
+	uploadParams, ok := authResponse["params"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("missing upload params in auth response")
+	}
+
+	fields := make(map[string]string, len(uploadParams))
+	for key, val := range uploadParams {
+		if valStr, ok := val.(string); ok {
+			fields[key] = valStr
+		}
+	}
+
+	if err := c.uploadToStorage(ctx, uploadURL, fields, upload); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Register the upload with the same precondition as the authorization
+	registerBody := []byte("upload=" + url.QueryEscape(uploadKey))
+	_, registerResp, err := c.doFileAuthRequest(ctx, path, registerBody, ifNoneMatch, ifMatch)
+	if err != nil {
+		return nil, fmt.Errorf("error registering upload: %w", err)
+	}
+	if registerResp.StatusCode != http.StatusNoContent {
+		return nil, fmt.Errorf("unexpected status code from register: %d", registerResp.StatusCode)
+	}
+
+	// Fetch and return the final attachment item
+	return c.Item(ctx, attachmentKey, nil)
+}
+
+// uploadPatch sends a binary diff against the current attachment file
+func (c *Client) uploadPatch(ctx context.Context, path, uploadKey string, upload fileUpload) error {
+	query := url.Values{}
+	query.Set("algorithm", string(upload.patch.Algorithm))
+	query.Set("upload", uploadKey)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("If-Match", upload.previousMD5)
+
+	_, resp, err := c.doSendRequest(ctx, http.MethodPatch, path+"?"+query.Encode(), header, upload.patch.Diff)
+	if err != nil {
+		return fmt.Errorf("error uploading patch: %w", err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code from patch: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// uploadToStorage streams the multipart upload form to the storage URL.
+// The body is produced through a pipe while the request is sent, so the file is never
+// buffered in memory. The content length is computed up front because storage
+// providers reject chunked uploads.
+func (c *Client) uploadToStorage(ctx context.Context, uploadURL string, fields map[string]string, upload fileUpload) error {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	// Measure the form overhead without the file content
+	var counter countingWriter
+	if err := writeUploadForm(&counter, boundary, fields, upload.filename, nil); err != nil {
+		return fmt.Errorf("error building upload form: %w", err)
+	}
+	contentLength := counter.n + upload.size
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	// Closing the reader unblocks the writer if the request ends before the body is consumed;
+	// waiting for it guarantees no progress callbacks happen after returning
+	defer func() {
+		pr.Close()
+		<-done
+	}()
+
+	go func() {
+		defer close(done)
+		content := &progressReader{
+			r:        &contextReader{ctx: ctx, r: io.NewSectionReader(upload.content, 0, upload.size)},
+			total:    upload.size,
+			progress: upload.progress,
+		}
+		pw.CloseWithError(writeUploadForm(pw, boundary, fields, upload.filename, content))
+	}()
+
+	uploadReq, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, pr)
+	if err != nil {
+		return fmt.Errorf("error creating upload request: %w", err)
+	}
+	uploadReq.ContentLength = contentLength
+	uploadReq.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+
+	c.logger.Printf("Uploading %d bytes to storage", upload.size)
+	uploadResp, err := c.httpClient.Do(uploadReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("error uploading file: %w", ctxErr)
+		}
+		return fmt.Errorf("error uploading file: %w", err)
+	}
+	defer uploadResp.Body.Close()
+
+	if uploadResp.StatusCode != http.StatusOK && uploadResp.StatusCode != http.StatusCreated && uploadResp.StatusCode != http.StatusNoContent {
+		uploadRespBody, _ := io.ReadAll(uploadResp.Body)
+		return fmt.Errorf("upload failed with status %d: %s", uploadResp.StatusCode, string(uploadRespBody))
+	}
+
+	return nil
+}
+
+// writeUploadForm writes the multipart upload form: the storage parameters in sorted order,
+// then the file part. If content is nil, the file part is written without its content.
+func writeUploadForm(w io.Writer, boundary string, fields map[string]string, filename string, content io.Reader) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if err := writer.WriteField(key, fields[key]); err != nil {
+			return fmt.Errorf("error writing field %s: %w", key, err)
+		}
+	}
+
+	// The file must be the last field of the form
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("error creating form file: %w", err)
+	}
+	if content != nil {
+		if _, err := io.Copy(part, content); err != nil {
+			return fmt.Errorf("error writing file data: %w", err)
+		}
+	}
+
+	return writer.Close()
+}
+
+// hashReaderAt computes the hex-encoded MD5 hash of the first size bytes of r
+func hashReaderAt(ctx context.Context, r io.ReaderAt, size int64) (string, error) {
+	hash := md5.New()
+	n, err := io.Copy(hash, &contextReader{ctx: ctx, r: io.NewSectionReader(r, 0, size)})
+	if err != nil {
+		return "", fmt.Errorf("error hashing file: %w", err)
+	}
+	if n != size {
+		return "", fmt.Errorf("error hashing file: read %d of %d bytes", n, size)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// progressReader reports the number of bytes read to a ProgressFunc
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 && r.progress != nil {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
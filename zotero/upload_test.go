@@ -0,0 +1,401 @@
+package zotero
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// uploadFlowHandler serves the attachment creation, authorization, storage and registration
+// steps of an upload. The storage endpoint is handled by upload.
+func uploadFlowHandler(t *testing.T, serverURL *string, auth *url.Values, upload http.HandlerFunc) http.HandlerFunc {
+	var created map[string]any
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users/12345/items":
+			json.NewDecoder(r.Body).Decode(&[]any{&created})
+			w.Write([]byte(`{"success": {"0": "ATTA1234"}, "unchanged": {}, "failed": {}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/users/12345/items/ATTA1234/file":
+			body, _ := io.ReadAll(r.Body)
+			if strings.HasPrefix(string(body), "md5=") {
+				*auth, _ = url.ParseQuery(string(body))
+				w.Header().Set("Last-Modified-Version", "2")
+				w.Write([]byte(`{"url": "` + *serverURL + `/upload", "contentType": "multipart/form-data", "params": {"key": "value", "acl": "private"}, "uploadKey": "UPLOADKEY"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			upload(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == "/users/12345/items/ATTA1234":
+			data, _ := json.Marshal(created)
+			w.Write([]byte(`{"key": "ATTA1234", "version": 3, "data": ` + string(data) + `}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestUploadAttachmentReader(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	sum := md5.Sum(content)
+	wantMD5 := hex.EncodeToString(sum[:])
+
+	var serverURL string
+	var auth url.Values
+	var uploaded []byte
+	var fields map[string]string
+	server, client := setupMockServer(t, uploadFlowHandler(t, &serverURL, &auth, func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TransferEncoding) > 0 {
+			t.Errorf("upload used transfer encoding %v, want Content-Length", r.TransferEncoding)
+		}
+		if r.ContentLength <= int64(len(content)) {
+			t.Errorf("Content-Length = %d, want more than file size", r.ContentLength)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("invalid multipart body: %v", err)
+		}
+		fields = map[string]string{"key": r.FormValue("key"), "acl": r.FormValue("acl")}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("missing file in upload: %v", err)
+		}
+		if header.Filename != "data file.bin" {
+			t.Errorf("upload filename = %q, want %q", header.Filename, "data file.bin")
+		}
+		uploaded, _ = io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	var calls int
+	var lastSent, lastTotal int64
+	progress := func(sent, total int64) {
+		if sent < lastSent {
+			t.Errorf("progress went backwards: %d after %d", sent, lastSent)
+		}
+		calls++
+		lastSent, lastTotal = sent, total
+	}
+
+	mtime := time.UnixMilli(1700000000000)
+	item, err := client.UploadAttachmentReader(context.Background(), "ABCD1234", bytes.NewReader(content), int64(len(content)), &UploadOptions{
+		Filename:    "data file.bin",
+		ContentType: "application/octet-stream",
+		MTime:       mtime,
+		Progress:    progress,
+	})
+	if err != nil {
+		t.Fatalf("UploadAttachmentReader() error = %v", err)
+	}
+
+	if !bytes.Equal(uploaded, content) {
+		t.Errorf("uploaded %d bytes, want %d matching bytes", len(uploaded), len(content))
+	}
+	if fields["key"] != "value" || fields["acl"] != "private" {
+		t.Errorf("upload fields = %v, want storage params", fields)
+	}
+	if auth.Get("md5") != wantMD5 {
+		t.Errorf("auth md5 = %q, want %q", auth.Get("md5"), wantMD5)
+	}
+	if auth.Get("filename") != "data file.bin" {
+		t.Errorf("auth filename = %q, want %q", auth.Get("filename"), "data file.bin")
+	}
+	if auth.Get("mtime") != "1700000000000" {
+		t.Errorf("auth mtime = %q, want 1700000000000", auth.Get("mtime"))
+	}
+	if calls == 0 || lastSent != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("progress calls = %d, last = %d/%d, want final %d/%d", calls, lastSent, lastTotal, len(content), len(content))
+	}
+	if item.Data.MD5 != wantMD5 || item.Data.LinkMode != LinkModeImportedFile || item.Data.ParentItem != "ABCD1234" {
+		t.Errorf("item data = %+v", item.Data)
+	}
+
+	if _, err := client.UploadAttachmentReader(context.Background(), "", bytes.NewReader(content), 1, nil); err == nil {
+		t.Error("expected error for missing filename, got nil")
+	}
+}
+
+func TestUploadAttachmentReaderCancel(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 4<<20)
+
+	var serverURL string
+	var auth url.Values
+	server, client := setupMockServer(t, uploadFlowHandler(t, &serverURL, &auth, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lastSent int64
+	_, err := client.UploadAttachmentReader(ctx, "", bytes.NewReader(content), int64(len(content)), &UploadOptions{
+		Filename: "large.bin",
+		Progress: func(sent, total int64) {
+			lastSent = sent
+			cancel()
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("UploadAttachmentReader() error = %v, want context.Canceled", err)
+	}
+	if lastSent >= int64(len(content)) {
+		t.Errorf("upload sent %d bytes, want cancellation before completion", lastSent)
+	}
+}
+
+func TestUploadAttachmentStreamsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paper.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4 test"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.UnixMilli(1600000000000)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	var serverURL string
+	var auth url.Values
+	var uploaded string
+	server, client := setupMockServer(t, uploadFlowHandler(t, &serverURL, &auth, func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("missing file in upload: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		uploaded = string(data)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	item, err := client.UploadAttachment(context.Background(), "ABCD1234", path, "", "application/pdf")
+	if err != nil {
+		t.Fatalf("UploadAttachment() error = %v", err)
+	}
+
+	if uploaded != "%PDF-1.4 test" {
+		t.Errorf("uploaded = %q", uploaded)
+	}
+	if auth.Get("filename") != "paper.pdf" || auth.Get("filesize") != "13" {
+		t.Errorf("auth filename/filesize = %q/%q, want paper.pdf/13", auth.Get("filename"), auth.Get("filesize"))
+	}
+	if item.Data.MTime != 1600000000000 {
+		t.Errorf("item.Data.MTime = %d, want file modification time", item.Data.MTime)
+	}
+}
+
+// fakeFileServer emulates the Zotero file endpoints and storage for a single attachment
+type fakeFileServer struct {
+	t        *testing.T
+	url      string
+	item     ItemData
+	fileMD5  string // MD5 of the stored file, checked against If-Match
+	file     []byte
+	pending  url.Values
+	uploaded []byte
+	patch    []byte
+	query    url.Values
+}
+
+func (s *fakeFileServer) checkPrecondition(w http.ResponseWriter, r *http.Request) bool {
+	if s.fileMD5 == "" && r.Header.Get("If-None-Match") == "*" {
+		return true
+	}
+	if s.fileMD5 != "" && r.Header.Get("If-Match") == s.fileMD5 {
+		return true
+	}
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write([]byte("The file has changed remotely"))
+	return false
+}
+
+func (s *fakeFileServer) apply(content []byte) {
+	s.file = content
+	s.fileMD5 = s.pending.Get("md5")
+	s.item.MD5 = s.pending.Get("md5")
+	s.item.Filename = s.pending.Get("filename")
+	s.item.MTime, _ = strconv.ParseInt(s.pending.Get("mtime"), 10, 64)
+	s.item.Version++
+}
+
+func (s *fakeFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const filePath = "/users/12345/items/ATTA1234/file"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/users/12345/items/ATTA1234":
+		json.NewEncoder(w).Encode(Item{Key: "ATTA1234", Version: s.item.Version, Data: s.item})
+	case r.Method == http.MethodPost && r.URL.Path == filePath:
+		if !s.checkPrecondition(w, r) {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		if form.Has("upload") {
+			if form.Get("upload") != "UPLOADKEY" || s.uploaded == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.apply(s.uploaded)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if form.Get("md5") == s.fileMD5 {
+			w.Write([]byte(`{"exists": 1}`))
+			return
+		}
+		s.pending = form
+		w.Write([]byte(`{"url": "` + s.url + `/upload", "contentType": "multipart/form-data", "params": {"key": "value"}, "uploadKey": "UPLOADKEY"}`))
+	case r.Method == http.MethodPatch && r.URL.Path == filePath:
+		if !s.checkPrecondition(w, r) {
+			return
+		}
+		s.query = r.URL.Query()
+		s.patch, _ = io.ReadAll(r.Body)
+		s.apply(nil)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/upload":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			s.t.Fatalf("missing file in upload: %v", err)
+		}
+		s.uploaded, _ = io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeFileServer(t *testing.T, content string) (*fakeFileServer, *Client, func()) {
+	sum := md5.Sum([]byte(content))
+	fake := &fakeFileServer{
+		t: t,
+		item: ItemData{
+			Key:      "ATTA1234",
+			Version:  5,
+			ItemType: ItemTypeAttachment,
+			LinkMode: LinkModeImportedFile,
+			Filename: "paper.pdf",
+			MD5:      hex.EncodeToString(sum[:]),
+			MTime:    1500000000000,
+		},
+		fileMD5: hex.EncodeToString(sum[:]),
+		file:    []byte(content),
+	}
+	server, client := setupMockServer(t, fake.ServeHTTP)
+	fake.url = server.URL
+	return fake, client, server.Close
+}
+
+func TestUpdateAttachmentFile(t *testing.T) {
+	fake, client, closeServer := newFakeFileServer(t, "original content")
+	defer closeServer()
+
+	updated := []byte("annotated content")
+	sum := md5.Sum(updated)
+	wantMD5 := hex.EncodeToString(sum[:])
+
+	item, err := client.UpdateAttachmentFile(context.Background(), "ATTA1234", FileSource{
+		Reader:   bytes.NewReader(updated),
+		Size:     int64(len(updated)),
+		Filename: "paper-annotated.pdf",
+		MTime:    time.UnixMilli(1700000000000),
+	})
+	if err != nil {
+		t.Fatalf("UpdateAttachmentFile() error = %v", err)
+	}
+
+	if string(fake.file) != string(updated) {
+		t.Errorf("stored file = %q, want %q", fake.file, updated)
+	}
+	if item.Data.MD5 != wantMD5 {
+		t.Errorf("item.Data.MD5 = %q, want %q", item.Data.MD5, wantMD5)
+	}
+	if item.Data.Filename != "paper-annotated.pdf" {
+		t.Errorf("item.Data.Filename = %q, want paper-annotated.pdf", item.Data.Filename)
+	}
+	if item.Data.MTime != 1700000000000 {
+		t.Errorf("item.Data.MTime = %d, want 1700000000000", item.Data.MTime)
+	}
+
+	// Uploading identical content is a no-op
+	fake.uploaded = nil
+	if _, err := client.UpdateAttachmentFile(context.Background(), "ATTA1234", FileSource{
+		Reader: bytes.NewReader(updated),
+		Size:   int64(len(updated)),
+	}); err != nil {
+		t.Fatalf("UpdateAttachmentFile() with unchanged content error = %v", err)
+	}
+	if fake.uploaded != nil {
+		t.Error("unchanged content was uploaded to storage")
+	}
+}
+
+func TestUpdateAttachmentFilePatch(t *testing.T) {
+	fake, client, closeServer := newFakeFileServer(t, "original content")
+	defer closeServer()
+	previousMD5 := fake.fileMD5
+
+	updated := []byte("original content, annotated")
+	item, err := client.UpdateAttachmentFile(context.Background(), "ATTA1234", FileSource{
+		Reader: bytes.NewReader(updated),
+		Size:   int64(len(updated)),
+		Patch:  &FilePatch{Algorithm: PatchBSDiff, Diff: []byte("BSDIFF40-diff")},
+	})
+	if err != nil {
+		t.Fatalf("UpdateAttachmentFile() error = %v", err)
+	}
+
+	if fake.uploaded != nil {
+		t.Error("full content was uploaded to storage for a patch")
+	}
+	if string(fake.patch) != "BSDIFF40-diff" {
+		t.Errorf("patch body = %q, want diff", fake.patch)
+	}
+	if fake.query.Get("algorithm") != "bsdiff" || fake.query.Get("upload") != "UPLOADKEY" {
+		t.Errorf("patch query = %v, want algorithm=bsdiff&upload=UPLOADKEY", fake.query)
+	}
+	if item.Data.MD5 == previousMD5 || item.Data.Filename != "paper.pdf" {
+		t.Errorf("item md5/filename = %q/%q, want new md5 and unchanged filename", item.Data.MD5, item.Data.Filename)
+	}
+}
+
+func TestUpdateAttachmentFileConflict(t *testing.T) {
+	fake, client, closeServer := newFakeFileServer(t, "original content")
+	defer closeServer()
+
+	// The file was replaced by another client after the item metadata was read
+	fake.fileMD5 = "0123456789abcdef0123456789abcdef"
+
+	_, err := client.UpdateAttachmentFile(context.Background(), "ATTA1234", FileSource{
+		Reader: strings.NewReader("new content"),
+		Size:   int64(len("new content")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "412") {
+		t.Fatalf("UpdateAttachmentFile() error = %v, want 412 precondition failure", err)
+	}
+	if fake.uploaded != nil {
+		t.Error("content was uploaded despite the precondition failure")
+	}
+
+	fake.item.LinkMode = LinkModeLinkedURL
+	if _, err := client.UpdateAttachmentFile(context.Background(), "ATTA1234", FileSource{Reader: strings.NewReader("x"), Size: 1}); err == nil {
+		t.Error("expected error for linked URL attachment, got nil")
+	}
+}
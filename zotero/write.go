@@ -3,17 +3,14 @@ package zotero
 import (
 	"bytes"
 	"context"
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // CreateItems creates one or more items in the library.
@@ -59,6 +56,21 @@ func (c *Client) CreateItems(ctx context.Context, items []Item) (*WriteResponse,
 // The item must contain version information for concurrency control.
 // Returns nil on success, error otherwise.
 func (c *Client) UpdateItem(ctx context.Context, item *Item) error {
+	return c.writeItem(ctx, http.MethodPatch, item)
+}
+
+// ReplaceItem replaces a single item in the library with a full update.
+// Unlike UpdateItem, fields missing from the item data are cleared on the server (including
+// empty tags and fields that are omitted when encoding), so item should be a complete item
+// as returned by Item. The item must contain version information for concurrency control.
+// Returns nil on success, error otherwise.
+func (c *Client) ReplaceItem(ctx context.Context, item *Item) error {
+	return c.writeItem(ctx, http.MethodPut, item)
+}
+
+// writeItem sends the data of a single item with PATCH, which changes only the fields sent,
+// or PUT, which replaces the item
+func (c *Client) writeItem(ctx context.Context, method string, item *Item) error {
 	if item == nil {
 		return fmt.Errorf("item cannot be nil")
 	}
@@ -76,13 +88,18 @@ func (c *Client) UpdateItem(ctx context.Context, item *Item) error {
 		version = item.Data.Version
 	}
 
-	body, err := json.Marshal(item.Data)
+	return c.writeItemData(ctx, method, key, version, item.Data)
+}
+
+// writeItemData sends data, item data or a subset of its fields, to a single item
+func (c *Client) writeItemData(ctx context.Context, method, key string, version int, data any) error {
+	body, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("error marshaling item: %w", err)
 	}
 
 	path := fmt.Sprintf("/items/%s", key)
-	respBody, resp, err := c.doWriteRequest(ctx, http.MethodPatch, path, body, version)
+	respBody, resp, err := c.doWriteRequest(ctx, method, path, body, version)
 	if err != nil {
 		return err
 	}
@@ -573,6 +590,41 @@ func (c *Client) AddTags(ctx context.Context, itemKey string, tags ...string) er
 	return c.UpdateItem(ctx, item)
 }
 
+// RemoveTags removes tags from an item.
+// This is a convenience method that fetches the item and updates its tags.
+// Returns nil on success, error otherwise.
+func (c *Client) RemoveTags(ctx context.Context, itemKey string, tags ...string) error {
+	if itemKey == "" {
+		return fmt.Errorf("item key is required")
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags provided")
+	}
+
+	// Fetch the current item
+	item, err := c.Item(ctx, itemKey, nil)
+	if err != nil {
+		return fmt.Errorf("error fetching item: %w", err)
+	}
+
+	remove := make(map[string]bool)
+	for _, tagName := range tags {
+		remove[tagName] = true
+	}
+	kept := make([]Tag, 0, len(item.Data.Tags))
+	for _, tag := range item.Data.Tags {
+		if !remove[tag.Tag] {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == len(item.Data.Tags) {
+		return nil
+	}
+
+	// Send only the tags, as an explicit list so that removing the last tag clears them
+	return c.writeItemData(ctx, http.MethodPatch, item.Key, item.Version, map[string][]Tag{"tags": kept})
+}
+
 // DeleteTags deletes tags from the library by name.
 // This removes the tags from all items in the library.
 // Returns nil on success, error otherwise.
@@ -584,12 +636,8 @@ func (c *Client) DeleteTags(ctx context.Context, version int, tags ...string) er
 		return fmt.Errorf("version is required for delete operations")
 	}
 
-	// Multiple tag deletes use tag query parameter
-	// URL encode the tags
-	encodedTags := make([]string, len(tags))
-	copy(tags, encodedTags)
-
-	path := fmt.Sprintf("/tags?tag=%s", strings.Join(encodedTags, "&tag="))
+	// Multiple tags are deleted with a single tag parameter delimited by " || "
+	path := fmt.Sprintf("/tags?tag=%s", url.QueryEscape(strings.Join(tags, " || ")))
 	respBody, resp, err := c.doWriteRequest(ctx, http.MethodDelete, path, nil, version)
 	if err != nil {
 		return err
@@ -604,57 +652,52 @@ func (c *Client) DeleteTags(ctx context.Context, version int, tags ...string) er
 
 // UploadAttachment uploads a file as an attachment to a parent item.
 // This is a multi-step process:
-// 1. Create an attachment item with linkMode "imported_file" or "imported_url"
+// 1. Create an attachment item with linkMode "imported_file"
 // 2. Get upload authorization
 // 3. Upload the file
 // 4. Register the upload
 //
+// The file is streamed from disk; see UploadAttachmentReader for uploading from other sources.
+//
 // parentItemKey: The key of the parent item to attach to (empty string for standalone attachment)
 // filepath: Path to the file to upload
 // filename: Name to use for the attachment (if empty, uses basename of filepath)
 // contentType: MIME type of the file (e.g., "application/pdf")
 func (c *Client) UploadAttachment(ctx context.Context, parentItemKey, filepath, filename, contentType string) (*Item, error) {
-	// Read file for MD5 and size
-	fileData, err := os.ReadFile(filepath)
+	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file: %w", err)
 	}
+	defer file.Close()
 
-	if filename == "" {
-		filename = filepath[strings.LastIndex(filepath, "/")+1:]
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
 	}
 
-	// Calculate MD5
-	md5Hash := md5.Sum(fileData)
-	md5String := hex.EncodeToString(md5Hash[:])
-
-	// Step 1: Create attachment item
-	attachment := Item{
-		Data: ItemData{
-			ItemType:    ItemTypeAttachment,
-			LinkMode:    "imported_file",
-			Title:       filename,
-			ContentType: contentType,
-			Filename:    filename,
-			MD5:         md5String,
-			MTime:       time.Now().UnixMilli(),
-		},
+	if filename == "" {
+		filename = filepath[strings.LastIndex(filepath, "/")+1:]
 	}
 
-	if parentItemKey != "" {
-		attachment.Data.ParentItem = parentItemKey
-	}
+	return c.UploadAttachmentReader(ctx, parentItemKey, file, info.Size(), &UploadOptions{
+		Filename:    filename,
+		ContentType: contentType,
+		MTime:       info.ModTime(),
+	})
+}
 
+// createAttachmentItem creates a single attachment item and returns its key
+func (c *Client) createAttachmentItem(ctx context.Context, attachment Item) (string, error) {
 	resp, err := c.CreateItems(ctx, []Item{attachment})
 	if err != nil {
-		return nil, fmt.Errorf("error creating attachment item: %w", err)
+		return "", fmt.Errorf("error creating attachment item: %w", err)
 	}
 
 	if len(resp.Success) == 0 {
 		if len(resp.Failed) > 0 {
-			return nil, fmt.Errorf("failed to create attachment: %s", resp.Failed["0"].Message)
+			return "", fmt.Errorf("failed to create attachment: %s", resp.Failed["0"].Message)
 		}
-		return nil, fmt.Errorf("failed to create attachment: no success or error reported")
+		return "", fmt.Errorf("failed to create attachment: no success or error reported")
 	}
 
 	// Get the attachment key from the response
@@ -666,192 +709,47 @@ func (c *Client) UploadAttachment(ctx context.Context, parentItemKey, filepath,
 		}
 	}
 
-	// Step 2: Request upload authorization
-	// Build form-encoded request body (not JSON!)
-	authBody := []byte(fmt.Sprintf("md5=%s&filename=%s&filesize=%d&mtime=%d",
-		md5String, filename, len(fileData), attachment.Data.MTime))
-
-	path := fmt.Sprintf("/items/%s/file", attachmentKey)
-	authRespBody, authResp, err := c.doFileAuthRequest(ctx, path, authBody, "*", "")
-
-	// If we get a 412 with "file exists", try again with If-Match header using the file's MD5
-	if err != nil && authResp != nil && authResp.StatusCode == http.StatusPreconditionFailed {
-		c.logger.Printf("File exists on server (412), retrying with If-Match header")
-		authRespBody, authResp, err = c.doFileAuthRequest(ctx, path, authBody, "", md5String)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("error requesting upload authorization: %w", err)
-	}
-
-	// Parse authorization response
-	var authResponse map[string]any
-	if err := json.Unmarshal(authRespBody, &authResponse); err != nil {
-		return nil, fmt.Errorf("error parsing auth response: %w", err)
-	}
-
-	// Check if file already exists
-	if exists, ok := authResponse["exists"].(float64); ok && exists == 1 {
-		c.logger.Printf("File already exists on server")
-		// Fetch and return the attachment item
-		return c.Item(ctx, attachmentKey, nil)
-	}
-
-	// Step 3: Upload the file
-	uploadURL, ok := authResponse["url"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing upload URL in auth response")
-	}
-
-	uploadParams, ok := authResponse["params"].(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("missing upload params in auth response")
-	}
-
-	// Create multipart form
-	var uploadBody bytes.Buffer
-	writer := multipart.NewWriter(&uploadBody)
-
-	// Add form fields from params
-	for key, val := range uploadParams {
-		if valStr, ok := val.(string); ok {
-			if err := writer.WriteField(key, valStr); err != nil {
-				return nil, fmt.Errorf("error writing field %s: %w", key, err)
-			}
-		}
-	}
-
-	// Add the file
-	part, err := writer.CreateFormFile("file", filename)
-	if err != nil {
-		return nil, fmt.Errorf("error creating form file: %w", err)
-	}
-	if _, err := part.Write(fileData); err != nil {
-		return nil, fmt.Errorf("error writing file data: %w", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("error closing multipart writer: %w", err)
-	}
-
-	// Upload to S3/storage
-	uploadReq, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &uploadBody)
-	if err != nil {
-		return nil, fmt.Errorf("error creating upload request: %w", err)
-	}
-	uploadReq.Header.Set("Content-Type", writer.FormDataContentType())
-
-	uploadResp, err := c.httpClient.Do(uploadReq)
-	if err != nil {
-		return nil, fmt.Errorf("error uploading file: %w", err)
-	}
-	defer uploadResp.Body.Close()
-
-	if uploadResp.StatusCode != http.StatusOK && uploadResp.StatusCode != http.StatusCreated && uploadResp.StatusCode != http.StatusNoContent {
-		uploadRespBody, _ := io.ReadAll(uploadResp.Body)
-		return nil, fmt.Errorf("upload failed with status %d: %s", uploadResp.StatusCode, string(uploadRespBody))
-	}
-
-	// Step 4: Register the upload
-	registerPath := fmt.Sprintf("/items/%s/file", attachmentKey)
-	registerBody := []byte(fmt.Sprintf(`{"upload": "%s"}`, authResponse["uploadKey"]))
-
-	if lastModified := authResp.Header.Get("Last-Modified-Version"); lastModified != "" {
-		if version, err := strconv.Atoi(lastModified); err == nil {
-			_, registerResp, err := c.doWriteRequest(ctx, http.MethodPost, registerPath, registerBody, version)
-			if err != nil {
-				return nil, fmt.Errorf("error registering upload: %w", err)
-			}
-			if registerResp.StatusCode != http.StatusNoContent {
-				return nil, fmt.Errorf("unexpected status code from register: %d", registerResp.StatusCode)
-			}
-		}
-	}
-
-	// Fetch and return the final attachment item
-	return c.Item(ctx, attachmentKey, nil)
+	return attachmentKey, nil
 }
 
-// doFileAuthRequest performs an HTTP request to authorize file upload with If-Match/If-None-Match headers
+// doFileAuthRequest performs a form-encoded request to authorize or register a file upload with If-Match/If-None-Match headers
 func (c *Client) doFileAuthRequest(ctx context.Context, path string, body []byte, ifNoneMatch, ifMatch string) ([]byte, *http.Response, error) {
-	// Apply rate limiting
-	if c.rateLimiter != nil {
-		c.logger.Printf("Waiting for rate limiter...")
-		if err := c.rateLimiter.Wait(ctx); err != nil {
-			c.logger.Printf("Rate limiter error: %v", err)
-			return nil, nil, fmt.Errorf("rate limiter error: %w", err)
-		}
-	}
-
-	// Build URL
-	urlStr := fmt.Sprintf("%s/%s/%s%s",
-		c.BaseURL,
-		c.LibraryType,
-		c.LibraryID,
-		path,
-	)
-
-	c.logger.Printf("Making file auth request: POST %s", urlStr)
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(body))
-	if err != nil {
-		c.logger.Printf("Error creating request: %v", err)
-		return nil, nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	// Set headers
-	if c.APIKey != "" {
-		req.Header.Set("Zotero-API-Key", c.APIKey)
-		c.logger.Printf("API Key set")
-	}
-	req.Header.Set("Zotero-API-Version", "3")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Set If-Match or If-None-Match headers (required for file upload authorization)
 	if ifNoneMatch != "" {
-		req.Header.Set("If-None-Match", ifNoneMatch)
-		c.logger.Printf("If-None-Match: %s", ifNoneMatch)
+		header.Set("If-None-Match", ifNoneMatch)
 	} else if ifMatch != "" {
-		req.Header.Set("If-Match", ifMatch)
-		c.logger.Printf("If-Match: %s", ifMatch)
-	}
-
-	// Execute request
-	c.logger.Printf("Executing file auth request...")
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		c.logger.Printf("Error executing request: %v", err)
-		return nil, nil, fmt.Errorf("error executing request: %w", err)
+		header.Set("If-Match", ifMatch)
 	}
-	defer resp.Body.Close()
 
-	c.logger.Printf("Response status: %d %s", resp.StatusCode, resp.Status)
-
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.logger.Printf("Error reading response body: %v", err)
-		return nil, resp, fmt.Errorf("error reading response body: %w", err)
-	}
+	return c.doSendRequest(ctx, http.MethodPost, path, header, body)
+}
 
-	c.logger.Printf("Response body length: %d bytes", len(respBody))
-	if len(respBody) > 0 {
-		c.logger.Printf("Response body: %s", string(respBody))
+// doWriteRequest performs an HTTP write request (POST, PATCH, DELETE) with a JSON body
+func (c *Client) doWriteRequest(ctx context.Context, method, path string, body []byte, version int) ([]byte, *http.Response, error) {
+	header := http.Header{}
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+		c.logger.Printf("Request body: %s", string(body))
 	}
 
-	// Check for errors
-	if resp.StatusCode >= 400 {
-		c.logger.Printf("API error: %s (status %d)", string(respBody), resp.StatusCode)
-		return respBody, resp, fmt.Errorf("API error: %s (status %d)", string(respBody), resp.StatusCode)
+	// Set version header for concurrency control
+	if version > 0 {
+		header.Set("If-Unmodified-Since-Version", strconv.Itoa(version))
 	}
 
-	c.logger.Printf("File auth request successful")
-	return respBody, resp, nil
+	return c.doSendRequest(ctx, method, path, header, body)
 }
 
-// doWriteRequest performs an HTTP write request (POST, PATCH, DELETE) with rate limiting
-func (c *Client) doWriteRequest(ctx context.Context, method, path string, body []byte, version int) ([]byte, *http.Response, error) {
+// doSendRequest sends a request with a body and extra headers to a library path, with rate limiting.
+// Write requests are not retried, and are refused in local mode.
+func (c *Client) doSendRequest(ctx context.Context, method, path string, header http.Header, body []byte) ([]byte, *http.Response, error) {
+	if c.local {
+		return nil, nil, errNotSupportedLocally(method, path)
+	}
+
 	// Apply rate limiting
 	if c.rateLimiter != nil {
 		c.logger.Printf("Waiting for rate limiter...")
@@ -869,13 +767,12 @@ func (c *Client) doWriteRequest(ctx context.Context, method, path string, body [
 		path,
 	)
 
-	c.logger.Printf("Making write request: %s %s", method, urlStr)
+	c.logger.Printf("Making write request: %s %s (%d bytes)", method, urlStr, len(body))
 
 	// Create request
 	var reqBody io.Reader
 	if body != nil {
 		reqBody = bytes.NewReader(body)
-		c.logger.Printf("Request body: %s", string(body))
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, urlStr, reqBody)
@@ -892,15 +789,11 @@ func (c *Client) doWriteRequest(ctx context.Context, method, path string, body [
 		c.logger.Printf("No API Key set")
 	}
 	req.Header.Set("Zotero-API-Version", "3")
-
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	// Set version header for concurrency control
-	if version > 0 {
-		req.Header.Set("If-Unmodified-Since-Version", strconv.Itoa(version))
-		c.logger.Printf("If-Unmodified-Since-Version: %d", version)
+	for name, values := range header {
+		req.Header[name] = values
+		if name != "Content-Type" {
+			c.logger.Printf("%s: %s", name, strings.Join(values, ", "))
+		}
 	}
 
 	// Execute request
@@ -929,7 +822,7 @@ func (c *Client) doWriteRequest(ctx context.Context, method, path string, body [
 	// Check for errors
 	if resp.StatusCode >= 400 {
 		c.logger.Printf("API error: %s (status %d)", string(respBody), resp.StatusCode)
-		return respBody, resp, fmt.Errorf("API error: %s (status %d)", string(respBody), resp.StatusCode)
+		return respBody, resp, &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
 	}
 
 	c.logger.Printf("Write request successful")
@@ -1,10 +1,13 @@
 package zotero
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -637,11 +640,63 @@ func TestAddTags(t *testing.T) {
 	}
 }
 
+func TestRemoveTags(t *testing.T) {
+	var patched map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			item := Item{
+				Key:     "ABCD1234",
+				Version: 5,
+				Data: ItemData{
+					Key:      "ABCD1234",
+					Version:  5,
+					ItemType: ItemTypeBook,
+					Title:    "Test Book",
+					Tags:     []Tag{{Tag: "old"}},
+				},
+			}
+			json.NewEncoder(w).Encode(item)
+		case http.MethodPatch:
+			if r.Header.Get("If-Unmodified-Since-Version") != "5" {
+				t.Errorf("If-Unmodified-Since-Version = %q, want 5", r.Header.Get("If-Unmodified-Since-Version"))
+			}
+			json.NewDecoder(r.Body).Decode(&patched)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("12345", LibraryTypeUser,
+		WithBaseURL(server.URL),
+		WithAPIKey("test-key"),
+	)
+
+	if err := client.RemoveTags(context.Background(), "ABCD1234", "old"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tags, ok := patched["tags"].([]any)
+	if !ok || len(tags) != 0 || len(patched) != 1 {
+		t.Errorf("patched = %v, want only an empty tags list", patched)
+	}
+
+	// Removing a tag the item does not have makes no request
+	patched = nil
+	if err := client.RemoveTags(context.Background(), "ABCD1234", "missing"); err != nil || patched != nil {
+		t.Errorf("RemoveTags(missing) = %v, patched = %v", err, patched)
+	}
+}
+
 func TestDeleteTags(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			t.Errorf("expected DELETE, got %s", r.Method)
 		}
+		if got := r.URL.Query().Get("tag"); got != "tag1 || tag 2" {
+			t.Errorf("tag = %q, want %q", got, "tag1 || tag 2")
+		}
 		w.WriteHeader(http.StatusNoContent)
 	}))
 	defer server.Close()
@@ -651,7 +706,7 @@ func TestDeleteTags(t *testing.T) {
 		WithAPIKey("test-key"),
 	)
 
-	err := client.DeleteTags(context.Background(), 10, "tag1", "tag2")
+	err := client.DeleteTags(context.Background(), 10, "tag1", "tag 2")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -701,3 +756,178 @@ func TestWriteResponseParsing(t *testing.T) {
 		t.Error("expected failed item with key '3'")
 	}
 }
+
+// TestItemDataWriteRoundTrip checks the bodies sent for items: data without Extra encodes
+// exactly as the struct fields do, and a fetched item written back sends its type-specific
+// fields unchanged without adding any the server did not send.
+func TestItemDataWriteRoundTrip(t *testing.T) {
+	fetched := `{"key":"ABCD1234","version":5,"itemType":"journalArticle","title":"Title",` +
+		`"creators":[{"creatorType":"author","firstName":"Ada","lastName":"Lovelace"}],` +
+		`"date":"1843","publicationTitle":"Scientific Memoirs","DOI":"10.1000/xyz","pages":"666-731",` +
+		`"tags":[{"tag":"history"}],"collections":["COLL1234"],"relations":{},` +
+		`"dateAdded":"2020-01-01T00:00:00Z","dateModified":"2020-01-02T00:00:00Z"}`
+
+	var bodies [][]byte
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"key":"ABCD1234","version":5,"data":` + fetched + `}`))
+		case http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, body)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, body)
+			w.Write([]byte(`{"success":{"0":"ABCD1234"},"unchanged":{},"failed":{}}`))
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	// Without Extra, CreateItems sends the plain struct encoding
+	type plainItemData ItemData
+	created := ItemData{ItemType: ItemTypeBook, Title: "New", Tags: []Tag{{Tag: "a"}}}
+	if _, err := client.CreateItems(ctx, []Item{{Data: created}}); err != nil {
+		t.Fatalf("CreateItems() error = %v", err)
+	}
+	want, _ := json.Marshal([]plainItemData{plainItemData(created)})
+	if string(bodies[0]) != string(want) {
+		t.Errorf("CreateItems() body = %s, want %s", bodies[0], want)
+	}
+
+	item, err := client.Item(ctx, "ABCD1234", nil)
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	if err := client.UpdateItem(ctx, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if _, err := client.UpdateItems(ctx, []Item{*item}); err != nil {
+		t.Fatalf("UpdateItems() error = %v", err)
+	}
+
+	var source map[string]any
+	json.Unmarshal([]byte(fetched), &source)
+	check := func(name string, body map[string]any) {
+		for key, value := range body {
+			if !reflect.DeepEqual(value, source[key]) {
+				t.Errorf("%s sent %s = %v, fetched %v", name, key, value, source[key])
+			}
+		}
+		for _, key := range []string{"date", "publicationTitle", "DOI", "pages", "title", "creators"} {
+			if _, ok := body[key]; !ok {
+				t.Errorf("%s did not send %s", name, key)
+			}
+		}
+	}
+
+	var patched map[string]any
+	if err := json.Unmarshal(bodies[1], &patched); err != nil {
+		t.Fatalf("UpdateItem() body = %s: %v", bodies[1], err)
+	}
+	check("UpdateItem()", patched)
+
+	var posted []map[string]any
+	if err := json.Unmarshal(bodies[2], &posted); err != nil || len(posted) != 1 {
+		t.Fatalf("UpdateItems() body = %s: %v", bodies[2], err)
+	}
+	check("UpdateItems()", posted[0])
+}
+
+// TestItemDataExtraWrites checks that CreateItems, UpdateItem and ReplaceItem send the fields
+// held in Extra once each, alongside the struct fields, and that an Extra entry shadowing a
+// struct field neither replaces nor duplicates it
+func TestItemDataExtraWrites(t *testing.T) {
+	var bodies [][]byte
+	server, client := setupMockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case http.MethodPost:
+			var items []json.RawMessage
+			if err := json.Unmarshal(body, &items); err != nil || len(items) != 1 {
+				t.Errorf("POST body = %s: %v", body, err)
+				return
+			}
+			bodies = append(bodies, items[0])
+			w.Write([]byte(`{"success":{"0":"ABCD1234"},"unchanged":{},"failed":{}}`))
+		default:
+			bodies = append(bodies, body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	item := Item{Key: "ABCD1234", Version: 5, Data: ItemData{
+		ItemType: ItemTypeJournalArticle,
+		Title:    "Title",
+		Extra:    map[string]any{"date": "1843", "DOI": "10.1000/xyz", "pages": "666-731", "title": "Shadow"},
+	}}
+	writes := []struct {
+		name  string
+		write func() error
+	}{
+		{"CreateItems()", func() error { _, err := client.CreateItems(ctx, []Item{item}); return err }},
+		{"UpdateItem()", func() error { return client.UpdateItem(ctx, &item) }},
+		{"ReplaceItem()", func() error { return client.ReplaceItem(ctx, &item) }},
+	}
+
+	for i, w := range writes {
+		if err := w.write(); err != nil {
+			t.Fatalf("%s error = %v", w.name, err)
+		}
+
+		keys := objectKeys(t, bodies[i])
+		for _, key := range []string{"itemType", "title", "date", "DOI", "pages"} {
+			if n := countOf(keys, key); n != 1 {
+				t.Errorf("%s sent %s %d times, want once (keys %v)", w.name, key, n, keys)
+			}
+		}
+
+		var sent ItemData
+		if err := json.Unmarshal(bodies[i], &sent); err != nil {
+			t.Fatalf("%s body = %s: %v", w.name, bodies[i], err)
+		}
+		if sent.Title != "Title" {
+			t.Errorf("%s sent title %q, want Title", w.name, sent.Title)
+		}
+		if !reflect.DeepEqual(sent.Extra, map[string]any{"date": "1843", "DOI": "10.1000/xyz", "pages": "666-731"}) {
+			t.Errorf("%s sent extra fields %v", w.name, sent.Extra)
+		}
+	}
+}
+
+// objectKeys returns the keys of a JSON object in order, including repeated keys
+func objectKeys(t *testing.T, body []byte) []string {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if _, err := dec.Token(); err != nil {
+		t.Fatalf("body = %s: %v", body, err)
+	}
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatalf("body = %s: %v", body, err)
+		}
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatalf("body = %s: %v", body, err)
+		}
+	}
+	return keys
+}
+
+// countOf returns how many times s occurs in values
+func countOf(values []string, s string) int {
+	n := 0
+	for _, v := range values {
+		if v == s {
+			n++
+		}
+	}
+	return n
+}
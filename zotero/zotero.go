@@ -35,6 +35,7 @@ type Client struct {
 	rateLimiter  *rate.Limiter
 	preserveJSON bool
 	logger       *log.Logger
+	local        bool // talking to the local API of the Zotero desktop app
 }
 
 // RetryConfig defines retry behavior for failed requests
@@ -67,6 +68,11 @@ func NewClient(libraryID string, libraryType LibraryType, opts ...ClientOption)
 		opt(client)
 	}
 
+	// The local API serves the logged-in user's library as user 0
+	if client.local && client.LibraryType == LibraryTypeUser {
+		client.LibraryID = "0"
+	}
+
 	// Configure HTTP client timeout
 	client.httpClient.Timeout = client.Timeout
 
@@ -170,6 +176,9 @@ func (c *Client) buildQueryString(params *QueryParams) string {
 	if params.Sort != "" {
 		values.Set("sort", params.Sort)
 	}
+	if params.Direction != "" {
+		values.Set("direction", params.Direction)
+	}
 	if params.Format != "" {
 		values.Set("format", params.Format)
 	}
@@ -188,11 +197,17 @@ func (c *Client) buildQueryString(params *QueryParams) string {
 	if params.Since > 0 {
 		values.Set("since", strconv.Itoa(params.Since))
 	}
+	if params.IncludeTrashed {
+		values.Set("includeTrashed", "1")
+	}
 
 	// Tags: Join multiple tags with OR operator (||)
 	if len(params.Tag) > 0 {
 		values.Set("tag", joinWithOR(params.Tag))
 	}
+	for _, tag := range params.TagFilters {
+		values.Add("tag", tag)
+	}
 
 	// ItemKeys: Join with comma separator (up to 50 items)
 	if len(params.ItemKey) > 0 {
@@ -207,9 +222,14 @@ func (c *Client) buildQueryString(params *QueryParams) string {
 	if len(params.ItemType) > 0 {
 		values.Set("itemType", joinWithOR(params.ItemType))
 	}
+	for _, itemType := range params.ItemTypeFilters {
+		values.Add("itemType", itemType)
+	}
 
-	for k, v := range params.Extra {
-		values.Set(k, v)
+	for k, vs := range params.Extra {
+		for _, v := range vs {
+			values.Add(k, v)
+		}
 	}
 
 	if query := values.Encode(); query != "" {
@@ -218,17 +238,8 @@ func (c *Client) buildQueryString(params *QueryParams) string {
 	return ""
 }
 
-// doRequest performs an HTTP request with rate limiting and retries
+// doRequest performs an HTTP request against a library path with rate limiting and retries
 func (c *Client) doRequest(ctx context.Context, method, path string, params *QueryParams) ([]byte, *http.Response, error) {
-	// Apply rate limiting
-	if c.rateLimiter != nil {
-		c.logger.Printf("Waiting for rate limiter...")
-		if err := c.rateLimiter.Wait(ctx); err != nil {
-			c.logger.Printf("Rate limiter error: %v", err)
-			return nil, nil, fmt.Errorf("rate limiter error: %w", err)
-		}
-	}
-
 	// Build URL
 	urlStr := fmt.Sprintf("%s/%s/%s%s%s",
 		c.BaseURL,
@@ -238,6 +249,20 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params *Que
 		c.buildQueryString(params),
 	)
 
+	return c.doURLRequest(ctx, method, urlStr)
+}
+
+// doURLRequest performs an HTTP request against an absolute URL, for endpoints outside the library
+func (c *Client) doURLRequest(ctx context.Context, method, urlStr string) ([]byte, *http.Response, error) {
+	// Apply rate limiting
+	if c.rateLimiter != nil {
+		c.logger.Printf("Waiting for rate limiter...")
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			c.logger.Printf("Rate limiter error: %v", err)
+			return nil, nil, fmt.Errorf("rate limiter error: %w", err)
+		}
+	}
+
 	c.logger.Printf("Making request: %s %s", method, urlStr)
 
 	// Create request
@@ -279,7 +304,11 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params *Que
 	// Check for errors
 	if resp.StatusCode >= 400 {
 		c.logger.Printf("API error: %s (status %d)", string(body), resp.StatusCode)
-		return body, resp, fmt.Errorf("API error: %s (status %d)", string(body), resp.StatusCode)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+		if c.local && resp.StatusCode == http.StatusNotImplemented {
+			return body, resp, fmt.Errorf("%s %s: %w: %w", method, req.URL.Path, ErrNotSupportedLocally, apiErr)
+		}
+		return body, resp, apiErr
 	}
 
 	c.logger.Printf("Request successful")
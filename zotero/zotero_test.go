@@ -3,6 +3,7 @@ package zotero
 import (
 	"log"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -257,12 +258,45 @@ func TestBuildQueryString(t *testing.T) {
 		{
 			name: "with extra params",
 			params: &QueryParams{
-				Extra: map[string]string{
-					"custom": "value",
+				Extra: url.Values{
+					"custom": {"value"},
 				},
 			},
 			want: "?custom=value",
 		},
+		{
+			name: "with repeated extra params",
+			params: &QueryParams{
+				Extra: url.Values{
+					"custom": {"a", "b"},
+				},
+			},
+			want: "?custom=a&custom=b",
+		},
+		{
+			name: "with tag filters",
+			params: &QueryParams{
+				Tag:        []string{"a", "b"},
+				TagFilters: []string{"-c"},
+			},
+			want: "?tag=a+%7C%7C+b&tag=-c",
+		},
+		{
+			name: "with item type filters",
+			params: &QueryParams{
+				ItemTypeFilters: []string{"book || thesis", "-annotation"},
+			},
+			want: "?itemType=book+%7C%7C+thesis&itemType=-annotation",
+		},
+		{
+			name: "with direction and trash",
+			params: &QueryParams{
+				Sort:           "title",
+				Direction:      "desc",
+				IncludeTrashed: true,
+			},
+			want: "?direction=desc&includeTrashed=1&sort=title",
+		},
 		{
 			name: "with multiple params",
 			params: &QueryParams{
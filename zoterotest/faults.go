@@ -0,0 +1,93 @@
+package zoterotest
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault is an error or delay injected into the responses of a Server, for example
+//
+//	srv.Inject(zoterotest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Second, Times: 1})
+//	srv.Inject(zoterotest.Fault{Path: "/users/1/items", Latency: time.Second})
+//	srv.Inject(zoterotest.Fault{Method: http.MethodPost, Status: http.StatusInternalServerError})
+type Fault struct {
+	Method     string        // Method of the requests affected (any method if empty)
+	Path       string        // Prefix of the paths of the requests affected, e.g. "/users/1/items" (any path if empty)
+	Status     int           // Status answered instead of handling the request (the request is handled if 0)
+	Message    string        // Body of the response (the status text if empty)
+	RetryAfter time.Duration // Sent in whole seconds in the Retry-After header, with Status
+	Latency    time.Duration // Delay before the request is answered or handled
+	Times      int           // Number of requests affected (every request until ClearFaults if 0)
+}
+
+// fault is an injected fault and the number of requests it has still to affect
+type fault struct {
+	Fault
+	remaining int // -1 for no limit
+}
+
+// Inject adds a fault. A request is affected by the first fault injected that matches it.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining := f.Times
+	if remaining <= 0 {
+		remaining = -1
+	}
+	s.faults = append(s.faults, &fault{Fault: f, remaining: remaining})
+}
+
+// ClearFaults removes every fault injected
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// injectFault applies the fault matching a request, if any. It returns true if the fault
+// answered the request.
+func (s *Server) injectFault(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	var f *Fault
+	for i, candidate := range s.faults {
+		if (candidate.Method != "" && candidate.Method != r.Method) || !strings.HasPrefix(r.URL.Path, candidate.Path) {
+			continue
+		}
+		matched := candidate.Fault
+		f = &matched
+		if candidate.remaining > 0 {
+			candidate.remaining--
+			if candidate.remaining == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		break
+	}
+	s.mu.Unlock()
+	if f == nil {
+		return false
+	}
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if f.Status == 0 {
+		return false
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	http.Error(w, cmp.Or(f.Message, http.StatusText(f.Status)), f.Status)
+	return true
+}
@@ -0,0 +1,327 @@
+package zoterotest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// upload is a file upload the API has authorized
+type upload struct {
+	lib      *library
+	itemKey  string
+	md5      string
+	filename string
+	filesize int64
+	mtime    int64
+	params   map[string]string // Form fields storage requires, as given to the client
+	stored   bool              // Whether the file has been sent to storage
+}
+
+// postFile handles the two requests of an upload made to the API: the authorization, which
+// gives the client the storage URL and parameters, and the registration of the stored file
+// (upload=<uploadKey>). Both must repeat the precondition on the current file: If-None-Match: *
+// for a new file or If-Match: <md5> for a replaced one.
+func (s *Server) postFile(w http.ResponseWriter, r *http.Request, lib *library) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemKey := r.PathValue("itemKey")
+	ctx := r.Context()
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	item, err := lib.store.Item(ctx, itemKey, nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if item.Data.ItemType != zotero.ItemTypeAttachment || !item.Data.LinkMode.HasFile() {
+		http.Error(w, "Item is not a file attachment", http.StatusBadRequest)
+		return
+	}
+	if status, message := lib.checkFile(r, itemKey); status != 0 {
+		http.Error(w, message, status)
+		return
+	}
+
+	if uploadKey := r.PostForm.Get("upload"); uploadKey != "" {
+		s.registerUpload(w, r, lib, itemKey, uploadKey)
+		return
+	}
+	s.authorizeUpload(w, r, lib, itemKey)
+}
+
+// checkFile checks the If-Match and If-None-Match headers of a request against the current
+// file of an attachment, returning the status and message of a failure
+func (lib *library) checkFile(r *http.Request, itemKey string) (int, string) {
+	content, err := lib.store.File(r.Context(), itemKey)
+	exists := err == nil
+
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	switch {
+	case ifNoneMatch == "*":
+		if exists {
+			return http.StatusPreconditionFailed, "If-None-Match: * set but file exists"
+		}
+	case ifMatch != "":
+		if !exists || md5Hex(content) != ifMatch {
+			return http.StatusPreconditionFailed, "If-Match set but file does not match"
+		}
+	default:
+		return http.StatusPreconditionRequired, "If-Match/If-None-Match header not provided"
+	}
+	return 0, ""
+}
+
+// authorizeUpload answers an upload authorization with the storage URL and the parameters
+// to send with the file, or with exists if storage already holds the file, which is then
+// registered at once
+func (s *Server) authorizeUpload(w http.ResponseWriter, r *http.Request, lib *library, itemKey string) {
+	form := r.PostForm
+	for _, name := range []string{"md5", "filename", "filesize", "mtime"} {
+		if form.Get(name) == "" {
+			http.Error(w, fmt.Sprintf("'%s' not provided", name), http.StatusBadRequest)
+			return
+		}
+	}
+	filesize, err := strconv.ParseInt(form.Get("filesize"), 10, 64)
+	if err != nil || filesize < 0 {
+		http.Error(w, "Invalid 'filesize' value", http.StatusBadRequest)
+		return
+	}
+	mtime, err := strconv.ParseInt(form.Get("mtime"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid 'mtime' value", http.StatusBadRequest)
+		return
+	}
+	u := &upload{
+		lib:      lib,
+		itemKey:  itemKey,
+		md5:      form.Get("md5"),
+		filename: form.Get("filename"),
+		filesize: filesize,
+		mtime:    mtime,
+	}
+
+	s.mu.Lock()
+	content, exists := s.storage[u.md5]
+	s.mu.Unlock()
+	if exists && int64(len(content)) == filesize {
+		if err := s.storeFile(r, u, content); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"exists": 1})
+		return
+	}
+
+	uploadKey := randomHex(16)
+	u.params = storageParams(uploadKey, u.md5)
+	s.mu.Lock()
+	s.uploads[uploadKey] = u
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"url":         s.URL + "/storage/upload",
+		"contentType": "multipart/form-data",
+		"params":      u.params,
+		"uploadKey":   uploadKey,
+	})
+}
+
+// storageParams returns form fields like those of an S3 upload policy. Storage requires them
+// back unchanged.
+func storageParams(uploadKey, md5Hash string) map[string]string {
+	date := time.Now().UTC()
+	policy := base64.StdEncoding.EncodeToString(fmt.Appendf(nil,
+		`{"expiration":"%s","conditions":[{"bucket":"zoterotest"},{"key":"%s"},{"acl":"private"}]}`,
+		date.Add(time.Hour).Format(time.RFC3339), uploadKey))
+	signature := sha256.Sum256([]byte(policy))
+	digest, _ := hex.DecodeString(md5Hash)
+	return map[string]string{
+		"key":                   uploadKey,
+		"acl":                   "private",
+		"Content-MD5":           base64.StdEncoding.EncodeToString(digest),
+		"success_action_status": "201",
+		"policy":                policy,
+		"x-amz-algorithm":       "AWS4-HMAC-SHA256",
+		"x-amz-credential":      "ZOTEROTESTACCESSKEY/" + date.Format("20060102") + "/us-east-1/s3/aws4_request",
+		"x-amz-date":            date.Format("20060102T150405Z"),
+		"x-amz-signature":       hex.EncodeToString(signature[:]),
+	}
+}
+
+// uploadToStorage receives the multipart form of an upload, as S3 does: the parameters of
+// the authorization followed by the file, which must match the size and MD5 hash authorized
+func (s *Server) uploadToStorage(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields := make(map[string]string)
+	var content []byte
+	for content == nil {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "POST requires exactly one file upload per request", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		value, err := readPart(part)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			content = value
+		} else {
+			fields[part.FormName()] = string(value)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.uploads[fields["key"]]
+	if !ok {
+		http.Error(w, "Invalid according to Policy", http.StatusForbidden)
+		return
+	}
+	for name, value := range u.params {
+		if fields[name] != value {
+			http.Error(w, fmt.Sprintf("Invalid according to Policy: field %s", name), http.StatusForbidden)
+			return
+		}
+	}
+	if int64(len(content)) != u.filesize || md5Hex(content) != u.md5 {
+		http.Error(w, "The Content-MD5 you specified did not match what we received", http.StatusBadRequest)
+		return
+	}
+	s.storage[u.md5] = content
+	u.stored = true
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, "<PostResponse><Bucket>zoterotest</Bucket><Key>%s</Key></PostResponse>", fields["key"])
+}
+
+// readPart reads a part of a multipart form, empty but not nil
+func readPart(part *multipart.Part) ([]byte, error) {
+	defer part.Close()
+	value, err := io.ReadAll(part)
+	if value == nil {
+		value = []byte{}
+	}
+	return value, err
+}
+
+// registerUpload gives an attachment the file sent to storage for an upload
+func (s *Server) registerUpload(w http.ResponseWriter, r *http.Request, lib *library, itemKey, uploadKey string) {
+	s.mu.Lock()
+	u, ok := s.uploads[uploadKey]
+	switch {
+	case !ok || u.lib != lib || u.itemKey != itemKey:
+		s.mu.Unlock()
+		http.Error(w, "Upload key not found", http.StatusBadRequest)
+		return
+	case !u.stored:
+		s.mu.Unlock()
+		http.Error(w, "File not found in storage", http.StatusBadRequest)
+		return
+	}
+	content := s.storage[u.md5]
+	delete(s.uploads, uploadKey)
+	s.mu.Unlock()
+
+	if err := s.storeFile(r, u, content); err != nil {
+		writeError(w, err)
+		return
+	}
+	lib.writeNoContent(w, r.Context())
+}
+
+// storeFile gives the attachment of an upload its file content. Callers hold u.lib.mu.
+func (s *Server) storeFile(r *http.Request, u *upload, content []byte) error {
+	_, err := u.lib.store.UpdateAttachmentFile(r.Context(), u.itemKey, zotero.FileSource{
+		Reader:   bytes.NewReader(content),
+		Size:     int64(len(content)),
+		Filename: u.filename,
+		MTime:    time.UnixMilli(u.mtime),
+	})
+	return err
+}
+
+// patchFile refuses file patches, which would need the diff algorithms to apply them
+func (s *Server) patchFile(w http.ResponseWriter, r *http.Request, lib *library) {
+	http.Error(w, "File patches are not supported by zoterotest", http.StatusNotImplemented)
+}
+
+// getFile redirects to the file of an attachment in storage
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request, lib *library) {
+	ctx := r.Context()
+	itemKey := r.PathValue("itemKey")
+	item, err := lib.store.Item(ctx, itemKey, nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	content, err := lib.store.File(ctx, itemKey)
+	if item.Data.ItemType != zotero.ItemTypeAttachment || err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// Files stored directly in the memory.Library reach storage here
+	md5Hash := md5Hex(content)
+	s.mu.Lock()
+	s.storage[md5Hash] = content
+	s.mu.Unlock()
+
+	query := url.Values{"filename": {item.Data.Filename}, "contentType": {item.Data.ContentType}}
+	http.Redirect(w, r, s.URL+"/storage/"+md5Hash+"?"+query.Encode(), http.StatusFound)
+}
+
+// getStorage serves a file from storage, with support for Range requests
+func (s *Server) getStorage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	content, ok := s.storage[r.PathValue("md5")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "The specified key does not exist.", http.StatusNotFound)
+		return
+	}
+	if contentType := r.URL.Query().Get("contentType"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	http.ServeContent(w, r, r.URL.Query().Get("filename"), time.Time{}, bytes.NewReader(content))
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
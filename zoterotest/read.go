@@ -0,0 +1,461 @@
+package zoterotest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+const (
+	defaultLimit = 25  // Results per page when no limit is given
+	maxLimit     = 100 // Largest page the API returns
+	maxKeys      = 50  // Most objects a request can name or write
+)
+
+// query holds the parameters of a read request
+type query struct {
+	params   zotero.QueryParams // Filters and sorting; the server applies Limit and Start
+	limit    int                // 0 if not given
+	start    int
+	format   string
+	modified int // If-Modified-Since-Version, or -1 if not given
+}
+
+// parseQuery reads the parameters of a read request, rejecting those the API would reject
+func parseQuery(r *http.Request) (*query, error) {
+	values := r.URL.Query()
+	q := &query{format: values.Get("format"), modified: -1}
+	p := &q.params
+
+	var err error
+	if q.limit, err = intParam(values, "limit"); err != nil {
+		return nil, err
+	}
+	if q.start, err = intParam(values, "start"); err != nil {
+		return nil, err
+	}
+	if p.Since, err = intParam(values, "since"); err != nil {
+		return nil, err
+	}
+	if header := r.Header.Get("If-Modified-Since-Version"); header != "" {
+		if q.modified, err = strconv.Atoi(header); err != nil || q.modified < 0 {
+			return nil, fmt.Errorf("Invalid If-Modified-Since-Version value '%s'", header)
+		}
+	}
+
+	switch q.format {
+	case "", "json", "keys", "versions":
+	default:
+		return nil, fmt.Errorf("Invalid 'format' value '%s' (zoterotest supports json, keys and versions)", q.format)
+	}
+	if include := values.Get("include"); include != "" && include != "data" {
+		return nil, fmt.Errorf("Invalid 'include' value '%s' (zoterotest supports data)", include)
+	}
+
+	p.Sort = values.Get("sort")
+	if p.Sort != "" && !slices.Contains(sortFields, zotero.SortField(p.Sort)) {
+		return nil, fmt.Errorf("Invalid 'sort' value '%s'", p.Sort)
+	}
+	p.Direction = values.Get("direction")
+	if p.Direction != "" && p.Direction != string(zotero.SortAscending) && p.Direction != string(zotero.SortDescending) {
+		return nil, fmt.Errorf("Invalid 'direction' value '%s'", p.Direction)
+	}
+	p.Q = values.Get("q")
+	p.QMode = values.Get("qmode")
+	if p.QMode != "" && p.QMode != string(zotero.QModeTitleCreatorYear) && p.QMode != string(zotero.QModeEverything) {
+		return nil, fmt.Errorf("Invalid 'qmode' value '%s'", p.QMode)
+	}
+	p.IncludeTrashed = values.Get("includeTrashed") == "1"
+	p.TagFilters = values["tag"]
+	p.ItemTypeFilters = values["itemType"]
+	if keys := values.Get("itemKey"); keys != "" {
+		p.ItemKey = strings.Split(keys, ",")
+		if len(p.ItemKey) > maxKeys {
+			return nil, fmt.Errorf("Only %d keys can be specified in 'itemKey'", maxKeys)
+		}
+	}
+	return q, nil
+}
+
+// sortFields are the values of the sort parameter the API accepts
+var sortFields = []zotero.SortField{
+	zotero.SortDateAdded, zotero.SortDateModified, zotero.SortTitle, zotero.SortCreator, zotero.SortItemType,
+	zotero.SortDate, zotero.SortPublisher, zotero.SortPublicationTitle, zotero.SortJournalAbbreviation,
+	zotero.SortLanguage, zotero.SortAccessDate, zotero.SortLibraryCatalog, zotero.SortCallNumber,
+	zotero.SortRights, zotero.SortAddedBy, zotero.SortNumItems,
+}
+
+// intParam reads a non-negative integer parameter, 0 if it is not given
+func intParam(values url.Values, name string) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid '%s' value '%s'", name, value)
+	}
+	return n, nil
+}
+
+// notModified answers 304 Not Modified if the client has version already
+func notModified(w http.ResponseWriter, q *query, version int) bool {
+	if q.modified < 0 || version > q.modified {
+		return false
+	}
+	setVersion(w, version)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// endpoint returns the pattern a library request matched, without the library path, e.g.
+// "/items/{itemKey}/children"
+func endpoint(r *http.Request) string {
+	_, path, _ := strings.Cut(r.Pattern, "{libraryID}")
+	return path
+}
+
+// getItems lists items: all items, top-level items, the trash, child items, the items of a
+// collection or the results of a saved search
+func (s *Server) getItems(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	if notModified(w, q, version) {
+		return
+	}
+
+	var items []zotero.Item
+	switch endpoint(r) {
+	case "/items":
+		items, err = lib.store.Items(ctx, &q.params)
+	case "/items/top":
+		items, err = lib.store.Top(ctx, &q.params)
+	case "/items/trash":
+		items, err = lib.store.Trash(ctx, &q.params)
+	case "/items/{itemKey}/children":
+		items, err = lib.store.Children(ctx, r.PathValue("itemKey"), &q.params)
+	case "/collections/{collectionKey}/items":
+		items, err = lib.store.CollectionItems(ctx, r.PathValue("collectionKey"), &q.params)
+	case "/collections/{collectionKey}/items/top":
+		items, err = lib.store.CollectionItemsTop(ctx, r.PathValue("collectionKey"), &q.params)
+	case "/searches/{searchKey}/items":
+		items, err = lib.store.SearchItems(ctx, r.PathValue("searchKey"), &q.params)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i := range items {
+		lib.itemLinks(&items[i])
+	}
+	writeList(w, r, q, version, items, func(item *zotero.Item) (string, int) { return item.Key, item.Version })
+}
+
+// getItem returns an item
+func (s *Server) getItem(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item, err := lib.store.Item(r.Context(), r.PathValue("itemKey"), nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if notModified(w, q, item.Version) {
+		return
+	}
+	lib.itemLinks(item)
+	setVersion(w, item.Version)
+	writeJSON(w, http.StatusOK, item)
+}
+
+// getCollections lists collections: all collections, top-level collections or subcollections
+func (s *Server) getCollections(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	if notModified(w, q, version) {
+		return
+	}
+
+	var collections []zotero.Collection
+	switch endpoint(r) {
+	case "/collections":
+		collections, err = lib.store.Collections(ctx, &q.params)
+	case "/collections/top":
+		collections, err = lib.store.CollectionsTop(ctx, &q.params)
+	case "/collections/{collectionKey}/collections":
+		collections, err = lib.store.CollectionsSub(ctx, r.PathValue("collectionKey"), &q.params)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i := range collections {
+		lib.collectionLinks(&collections[i])
+	}
+	writeList(w, r, q, version, collections, func(c *zotero.Collection) (string, int) { return c.Key, c.Version })
+}
+
+// getCollection returns a collection
+func (s *Server) getCollection(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	collection, err := lib.store.Collection(r.Context(), r.PathValue("collectionKey"), nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if notModified(w, q, collection.Version) {
+		return
+	}
+	lib.collectionLinks(collection)
+	setVersion(w, collection.Version)
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// getSearches lists saved searches
+func (s *Server) getSearches(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	if notModified(w, q, version) {
+		return
+	}
+
+	searches, err := lib.store.Searches(ctx, &q.params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i := range searches {
+		lib.searchLinks(&searches[i])
+	}
+	writeList(w, r, q, version, searches, func(search *zotero.Search) (string, int) { return search.Key, search.Version })
+}
+
+// getSearch returns a saved search
+func (s *Server) getSearch(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	search, err := lib.store.Search(r.Context(), r.PathValue("searchKey"), nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if notModified(w, q, search.Version) {
+		return
+	}
+	lib.searchLinks(search)
+	setVersion(w, search.Version)
+	writeJSON(w, http.StatusOK, search)
+}
+
+// getTags lists the tags of the library, an item or a collection, with the number of items
+// in the library that have each tag. The q parameter selects tags containing it.
+func (s *Server) getTags(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	if notModified(w, q, version) {
+		return
+	}
+
+	tags, err := lib.store.Tags(ctx, nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	switch endpoint(r) {
+	case "/items/{itemKey}/tags":
+		item, err := lib.store.Item(ctx, r.PathValue("itemKey"), nil)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		tags = slices.DeleteFunc(tags, func(tag zotero.TagsResponse) bool {
+			return !slices.Contains(item.Data.Tags, zotero.Tag{Tag: tag.Tag, Type: tag.Type})
+		})
+	case "/collections/{collectionKey}/tags":
+		collectionTags, err := lib.store.CollectionTags(ctx, r.PathValue("collectionKey"), nil)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		tags = slices.DeleteFunc(tags, func(tag zotero.TagsResponse) bool {
+			return !slices.ContainsFunc(collectionTags, func(t zotero.TagsResponse) bool { return t.Tag == tag.Tag && t.Type == tag.Type })
+		})
+	}
+	if q.params.Q != "" {
+		tags = slices.DeleteFunc(tags, func(tag zotero.TagsResponse) bool {
+			return !strings.Contains(strings.ToLower(tag.Tag), strings.ToLower(q.params.Q))
+		})
+	}
+	for i := range tags {
+		tags[i].Links.Self = zotero.Link{Href: lib.url("/tags/" + url.PathEscape(tags[i].Tag)), Type: "application/json"}
+	}
+	writeList(w, r, q, version, tags, func(tag *zotero.TagsResponse) (string, int) { return tag.Tag, 0 })
+}
+
+// getDeleted lists the objects and tags deleted since a version
+func (s *Server) getDeleted(w http.ResponseWriter, r *http.Request, lib *library) {
+	q, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	if notModified(w, q, version) {
+		return
+	}
+
+	deleted, err := lib.store.Deleted(ctx, q.params.Since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// The API lists every kind of object, even when nothing of that kind was deleted
+	setVersion(w, version)
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"collections": nonNil(deleted.Collections),
+		"searches":    nonNil(deleted.Searches),
+		"items":       nonNil(deleted.Items),
+		"tags":        nonNil(deleted.Tags),
+		"settings":    {},
+	})
+}
+
+// writeList writes the page of results a request asked for, in its format, with the headers
+// of multi-object responses: Last-Modified-Version, Total-Results and Link. The keys and
+// versions formats list every result unless a limit is given.
+func writeList[T any](w http.ResponseWriter, r *http.Request, q *query, version int, results []T, key func(*T) (string, int)) {
+	total := len(results)
+	limit := q.limit
+	if limit == 0 && q.format != "keys" && q.format != "versions" {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxLimit)
+	page := results[min(q.start, total):]
+	if limit > 0 && limit < len(page) {
+		page = page[:limit]
+	}
+
+	setVersion(w, version)
+	w.Header().Set("Total-Results", strconv.Itoa(total))
+	if limit > 0 {
+		if links := pageLinks(r.URL, q.start, limit, total); links != "" {
+			w.Header().Set("Link", links)
+		}
+	}
+
+	switch q.format {
+	case "keys":
+		var body strings.Builder
+		for i := range page {
+			k, _ := key(&page[i])
+			body.WriteString(k + "\n")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, body.String())
+	case "versions":
+		versions := make(map[string]int, len(page))
+		for i := range page {
+			k, v := key(&page[i])
+			versions[k] = v
+		}
+		writeJSON(w, http.StatusOK, versions)
+	default:
+		writeJSON(w, http.StatusOK, nonNil(page))
+	}
+}
+
+// pageLinks returns the Link header of a page: the first, previous, next and last pages
+func pageLinks(u *url.URL, start, limit, total int) string {
+	link := func(start int, rel string) string {
+		pageURL := *u
+		values := pageURL.Query()
+		values.Set("limit", strconv.Itoa(limit))
+		if start > 0 {
+			values.Set("start", strconv.Itoa(start))
+		} else {
+			values.Del("start")
+		}
+		pageURL.RawQuery = values.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, pageURL.RequestURI(), rel)
+	}
+
+	var links []string
+	if start > 0 {
+		links = append(links, link(0, "first"), link(max(start-limit, 0), "prev"))
+	}
+	if start+limit < total {
+		last := (total - 1) / limit * limit
+		links = append(links, link(start+limit, "next"), link(last, "last"))
+	}
+	return strings.Join(links, ", ")
+}
+
+// url returns the URL of a path in the library
+func (lib *library) url(path string) string {
+	return lib.base + path
+}
+
+func (lib *library) itemLinks(item *zotero.Item) {
+	item.Links.Self = zotero.Link{Href: lib.url("/items/" + item.Key), Type: "application/json"}
+	if item.Data.ParentItem != "" {
+		item.Links.Up = zotero.Link{Href: lib.url("/items/" + item.Data.ParentItem), Type: "application/json"}
+	}
+	if item.Data.ItemType == zotero.ItemTypeAttachment && item.Data.LinkMode.HasFile() && item.Data.MD5 != "" {
+		item.Links.Enclosure = zotero.Link{Href: lib.url("/items/" + item.Key + "/file/view"), Type: item.Data.ContentType}
+	}
+}
+
+func (lib *library) collectionLinks(collection *zotero.Collection) {
+	collection.Links.Self = zotero.Link{Href: lib.url("/collections/" + collection.Key), Type: "application/json"}
+	if parent := collection.Data.ParentCollection; parent != "" {
+		collection.Links.Up = zotero.Link{Href: lib.url("/collections/" + string(parent)), Type: "application/json"}
+	}
+}
+
+func (lib *library) searchLinks(search *zotero.Search) {
+	search.Links.Self = zotero.Link{Href: lib.url("/searches/" + search.Key), Type: "application/json"}
+}
+
+// nonNil returns s, or an empty slice if s is nil, so that it is encoded as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
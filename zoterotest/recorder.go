@@ -0,0 +1,511 @@
+package zoterotest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode is whether a Recorder records interactions or replays them
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette, without network access
+	ModeReplay Mode = iota
+	// ModeRecord sends requests on and records them; Save writes the cassette
+	ModeRecord
+)
+
+// ParseMode parses "replay" or "record", for example from a test flag
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "replay":
+		return ModeReplay, nil
+	case "record":
+		return ModeRecord, nil
+	}
+	return 0, fmt.Errorf("invalid mode %q (must be replay or record)", s)
+}
+
+func (m Mode) String() string {
+	if m == ModeRecord {
+		return "record"
+	}
+	return "replay"
+}
+
+// Redacted replaces API keys and upload credentials in cassettes
+const Redacted = "REDACTED"
+
+// sensitiveParams are the upload parameters and signed URL parameters of the storage service
+// that are redacted, in lower case
+var sensitiveParams = map[string]bool{
+	"policy":               true,
+	"signature":            true,
+	"awsaccesskeyid":       true,
+	"x-amz-credential":     true,
+	"x-amz-signature":      true,
+	"x-amz-security-token": true,
+}
+
+// Cassette is the file of interactions a Recorder records and replays
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response it received
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as recorded, with secrets redacted
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as recorded, with secrets redacted
+type RecordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is the body of a request or response. Cassettes hold it as a string if it is valid
+// UTF-8, and as {"base64": "..."} otherwise.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// Recorder is an http.RoundTripper that records interactions with the API to a cassette file
+// and replays them, so tests written against the live API run offline and deterministically:
+//
+//	rec, err := zoterotest.NewRecorder("testdata/cassettes/items.json", mode)
+//	defer rec.Save()
+//	client := zotero.NewClient(libraryID, zotero.LibraryTypeUser, zotero.WithAPIKey(apiKey), zotero.WithHTTPClient(rec.Client()))
+//
+// API keys (the Zotero-API-Key and Authorization headers, the key parameter and every other
+// place the key appears) and the credentials of file uploads and downloads are redacted
+// before interactions are recorded.
+//
+// A request is replayed with the first unused interaction recorded with the same method,
+// path, query and body, so a request repeated in a test gets the responses it got when it
+// was recorded, in order. JSON, form and multipart bodies are compared by content, not by
+// their encoding. Headers are not compared.
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool   // Interactions already replayed
+	secrets  []string // API keys sent while recording
+}
+
+// RecorderOption configures a Recorder
+type RecorderOption func(*Recorder)
+
+// WithTransport sets the transport a recording Recorder sends requests with, instead of
+// http.DefaultTransport
+func WithTransport(transport http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// NewRecorder creates a Recorder for the cassette at path. A replaying Recorder reads the
+// cassette, which must exist; a recording Recorder starts an empty one.
+func NewRecorder(path string, mode Mode, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{mode: mode, path: path, transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns whether the Recorder records or replays
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an HTTP client using the Recorder, to give to zotero.WithHTTPClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the interactions of the cassette
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Save writes the cassette of a recording Recorder, creating its directory if needed. It
+// does nothing when replaying.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip records or replays a request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+// record sends a request on and records it with its response
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if len(body) > 0 {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+	} else if req.Body != nil {
+		out.Body = http.NoBody
+	}
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addSecrets(req)
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    r.redactString(redactURL(req.URL.String())),
+			Header: r.redactHeader(req.Header),
+			Body:   r.redactBytes(redactMultipart(req.Header.Get("Content-Type"), body)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       r.redactBytes(redactJSON(respBody)),
+		},
+	})
+	return resp, nil
+}
+
+// replay answers a request with the first unused interaction matching it
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	contentType := req.Header.Get("Content-Type")
+	query := redactQuery(req.URL.Query())
+	normalized := normalizeBody(contentType, redactMultipart(contentType, body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method {
+			continue
+		}
+		u, err := url.Parse(recorded.URL)
+		if err != nil || u.Path != req.URL.Path || u.Query().Encode() != query.Encode() {
+			continue
+		}
+		if normalizeBody(recorded.Header.Get("Content-Type"), recorded.Body) != normalized {
+			continue
+		}
+
+		r.used[i] = true
+		response := interaction.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(response.Body)),
+			ContentLength: int64(len(response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("zoterotest: no interaction recorded in %s for %s %s", r.path, req.Method, redactURL(req.URL.String()))
+}
+
+// readBody reads and closes the body of a request
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	return body, nil
+}
+
+// addSecrets remembers the API key of a request, to redact it wherever it appears. Callers
+// hold r.mu.
+func (r *Recorder) addSecrets(req *http.Request) {
+	candidates := []string{
+		req.Header.Get("Zotero-API-Key"),
+		strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "),
+		req.URL.Query().Get("key"),
+	}
+	for _, secret := range candidates {
+		if secret != "" && secret != Redacted && !slices.Contains(r.secrets, secret) {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+}
+
+// redactString replaces the API keys seen in s
+func (r *Recorder) redactString(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// redactBytes replaces the API keys seen in b
+func (r *Recorder) redactBytes(b []byte) []byte {
+	for _, secret := range r.secrets {
+		b = bytes.ReplaceAll(b, []byte(secret), []byte(Redacted))
+	}
+	return b
+}
+
+// redactHeader returns a copy of a header with its credentials and API keys redacted
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		switch name {
+		case "Zotero-Api-Key", "Authorization":
+			redacted[name] = []string{Redacted}
+			continue
+		case "Location":
+			values = []string{redactURL(header.Get("Location"))}
+		}
+		for _, value := range values {
+			redacted[name] = append(redacted[name], r.redactString(value))
+		}
+	}
+	return redacted
+}
+
+// redactURL redacts the key parameter and the signature parameters of a URL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	u.RawQuery = redactQuery(u.Query()).Encode()
+	return u.String()
+}
+
+func redactQuery(query url.Values) url.Values {
+	for name := range query {
+		if name == "key" || sensitiveParams[strings.ToLower(name)] {
+			query[name] = []string{Redacted}
+		}
+	}
+	return query
+}
+
+// redactJSON redacts the upload parameters in a JSON body, such as those of an upload
+// authorization. Bodies without them are returned unchanged.
+func redactJSON(body []byte) []byte {
+	var v any
+	if json.Unmarshal(body, &v) != nil || !redactValue(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue redacts the sensitive properties of a decoded JSON value in place, reporting
+// whether there were any
+func redactValue(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if _, ok := value.(string); ok && sensitiveParams[strings.ToLower(name)] {
+				v[name] = Redacted
+				changed = true
+			} else if redactValue(value) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if redactValue(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// redactMultipart redacts the upload parameters of a multipart form, keeping its boundary.
+// Other bodies are returned unchanged.
+func redactMultipart(contentType string, body []byte) []byte {
+	boundary, ok := multipartBoundary(contentType)
+	if !ok {
+		return body
+	}
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if writer.SetBoundary(boundary) != nil {
+		return body
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return body
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return body
+		}
+		if sensitiveParams[strings.ToLower(part.FormName())] {
+			content = []byte(Redacted)
+		}
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return body
+		}
+		w.Write(content)
+	}
+	if writer.Close() != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+func multipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// normalizeBody returns a form of a body that is the same for equivalent bodies: JSON
+// re-encoded with sorted keys, and forms as sorted parameters regardless of their encoding
+// or multipart boundary
+func normalizeBody(contentType string, body []byte) string {
+	if boundary, ok := multipartBoundary(contentType); ok {
+		fields := url.Values{}
+		reader := multipart.NewReader(bytes.NewReader(body), boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return fields.Encode()
+			}
+			if err != nil {
+				return string(body)
+			}
+			content, err := io.ReadAll(part)
+			if err != nil {
+				return string(body)
+			}
+			fields.Add(part.FormName(), string(content))
+		}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if fields, err := url.ParseQuery(string(body)); err == nil {
+			return fields.Encode()
+		}
+	}
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		if normalized, err := json.Marshal(v); err == nil {
+			return string(normalized)
+		}
+	}
+	return string(body)
+}
@@ -0,0 +1,151 @@
+package zoterotest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// exercise makes requests of every kind through a client: reads, writes, a repeated request
+// with a changed response, and a file upload and download
+func exercise(t *testing.T, c *zotero.Client) (title string, file []byte) {
+	t.Helper()
+	ctx := context.Background()
+
+	key := create(t, c, book("Recorded"))[0]
+	item, err := c.Item(ctx, key, nil)
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	item.Data.Title = "Recorded and Updated"
+	if err := c.UpdateItem(ctx, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if item, err = c.Item(ctx, key, nil); err != nil {
+		t.Fatalf("second Item() error = %v", err)
+	}
+
+	content := []byte("recorded file content")
+	opts := &zotero.UploadOptions{Filename: "notes.txt", ContentType: "text/plain", MTime: time.UnixMilli(1700000000000)}
+	attachment, err := c.UploadAttachmentReader(ctx, key, bytes.NewReader(content), int64(len(content)), opts)
+	if err != nil {
+		t.Fatalf("UploadAttachmentReader() error = %v", err)
+	}
+	if file, err = c.File(ctx, attachment.Key); err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	return item.Data.Title, file
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "exercise.json")
+
+	s := NewServer()
+	rec, err := NewRecorder(path, ModeRecord, WithTransport(s.srv.Client().Transport))
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	recordedTitle, recordedFile := exercise(t, s.Client(zotero.WithHTTPClient(rec.Client())))
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	s.Close()
+
+	cassette, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The policy is base64-encoded JSON, so it would start with "eyJ"
+	for _, secret := range []string{DefaultAPIKey, "eyJ"} {
+		if strings.Contains(string(cassette), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+	if !strings.Contains(string(cassette), `\"x-amz-signature\":\"REDACTED\"`) {
+		t.Error("cassette does not contain the redacted upload parameters")
+	}
+
+	// The server is closed, so everything comes from the cassette
+	rec, err = NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("NewRecorder() replay error = %v", err)
+	}
+	client := zotero.NewClient("1", zotero.LibraryTypeUser, zotero.WithBaseURL(s.URL), zotero.WithAPIKey("another key"),
+		zotero.WithRateLimit(0), zotero.WithHTTPClient(rec.Client()))
+	title, file := exercise(t, client)
+	if title != recordedTitle || !bytes.Equal(file, recordedFile) {
+		t.Errorf("replayed %q, %q; recorded %q, %q", title, file, recordedTitle, recordedFile)
+	}
+
+	// Every interaction has been used
+	if _, err := client.Items(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+		t.Errorf("Items() not recorded error = %v", err)
+	}
+}
+
+func TestRecorderMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := `{"interactions": [
+  {"request": {"method": "POST", "url": "https://api.zotero.org/users/1/items?key=REDACTED&format=json", "header": {"Content-Type": ["application/json"]}, "body": "[{\"title\": \"A\", \"itemType\": \"book\"}]"},
+   "response": {"status": 200, "body": "first"}},
+  {"request": {"method": "POST", "url": "https://api.zotero.org/users/1/items?format=json&key=REDACTED", "header": {"Content-Type": ["application/json"]}, "body": "[{\"itemType\":\"book\",\"title\":\"A\"}]"},
+   "response": {"status": 412, "body": {"base64": "/w=="}}}
+]}`
+	if err := os.WriteFile(path, []byte(cassette), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := rec.Client()
+
+	post := func(url, body string) (int, string, error) {
+		resp, err := client.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		return resp.StatusCode, buf.String(), nil
+	}
+
+	// The key is redacted and JSON is compared by content
+	url := "http://localhost/users/1/items?format=json&key=secret"
+	if status, body, err := post(url, `[{"itemType":"book","title":"A"}]`); err != nil || status != 200 || body != "first" {
+		t.Errorf("first post = %d %q, %v; want 200 \"first\"", status, body, err)
+	}
+	if _, _, err := post(url, `[{"itemType":"book","title":"B"}]`); err == nil {
+		t.Error("post with another body error = nil")
+	}
+	if status, body, err := post(url, `[{"title":"A","itemType":"book"}]`); err != nil || status != 412 || body != "\xff" {
+		t.Errorf("second post = %d %q, %v; want 412 \"\\xff\"", status, body, err)
+	}
+	if _, _, err := post(url, `[{"itemType":"book","title":"A"}]`); err == nil {
+		t.Error("third post error = nil, want no interaction left")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{ModeReplay, ModeRecord} {
+		if got, err := ParseMode(mode.String()); err != nil || got != mode {
+			t.Errorf("ParseMode(%q) = %v, %v", mode.String(), got, err)
+		}
+	}
+	if _, err := ParseMode("live"); err == nil {
+		t.Error("ParseMode(\"live\") error = nil")
+	}
+}
+
+func TestRecorderMissingCassette(t *testing.T) {
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Error("NewRecorder() of missing cassette error = nil")
+	}
+}
@@ -0,0 +1,269 @@
+package zoterotest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// schemaJSON holds the item types of the Zotero schema with their fields and creator types, in
+// the format of https://api.zotero.org/schema, with English names only
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+type schema struct {
+	ItemTypes []struct {
+		ItemType string `json:"itemType"`
+		Fields   []struct {
+			Field string `json:"field"`
+		} `json:"fields"`
+		CreatorTypes []struct {
+			CreatorType string `json:"creatorType"`
+			Primary     bool   `json:"primary"`
+		} `json:"creatorTypes"`
+	} `json:"itemTypes"`
+	Locales map[string]struct {
+		ItemTypes     map[string]string `json:"itemTypes"`
+		Fields        map[string]string `json:"fields"`
+		CreatorTypes  map[string]string `json:"creatorTypes"`
+		CreatorFields map[string]string `json:"creatorFields"`
+	} `json:"locales"`
+}
+
+var loadSchema = sync.OnceValue(func() *schema {
+	var s schema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		panic(fmt.Sprintf("zoterotest: invalid schema: %v", err))
+	}
+	return &s
+})
+
+// itemTypeFields returns the fields of an item type and its creator types, the primary one
+// first, and whether the item type exists
+func (s *schema) itemTypeFields(itemType string) (fields, creatorTypes []string, ok bool) {
+	for _, t := range s.ItemTypes {
+		if t.ItemType != itemType {
+			continue
+		}
+		for _, f := range t.Fields {
+			fields = append(fields, f.Field)
+		}
+		for _, c := range t.CreatorTypes {
+			creatorTypes = append(creatorTypes, c.CreatorType)
+		}
+		return fields, creatorTypes, true
+	}
+	return nil, nil, false
+}
+
+// isField reports whether a field belongs to any item type
+func (s *schema) isField(field string) bool {
+	_, ok := s.Locales["en-US"].Fields[field]
+	return ok
+}
+
+// itemTypeName, fieldName and creatorTypeName return the English names of schema entries
+func (s *schema) itemTypeName(name string) string {
+	return s.Locales["en-US"].ItemTypes[name]
+}
+
+func (s *schema) fieldName(name string) string {
+	return s.Locales["en-US"].Fields[name]
+}
+
+func (s *schema) creatorTypeName(name string) string {
+	return s.Locales["en-US"].CreatorTypes[name]
+}
+
+// getItemTypes lists the item types, sorted by name
+func (s *Server) getItemTypes(w http.ResponseWriter, r *http.Request, lib *library) {
+	schema := loadSchema()
+	itemTypes := make([]zotero.SchemaItemType, 0, len(schema.ItemTypes))
+	for _, t := range schema.ItemTypes {
+		if t.ItemType == zotero.ItemTypeAnnotation {
+			continue // Not listed by the API
+		}
+		itemTypes = append(itemTypes, zotero.SchemaItemType{ItemType: t.ItemType, Localized: schema.itemTypeName(t.ItemType)})
+	}
+	slices.SortFunc(itemTypes, func(a, b zotero.SchemaItemType) int { return strings.Compare(a.Localized, b.Localized) })
+	writeJSON(w, http.StatusOK, itemTypes)
+}
+
+// getItemFields lists the fields of every item type
+func (s *Server) getItemFields(w http.ResponseWriter, r *http.Request, lib *library) {
+	schema := loadSchema()
+	var fields []zotero.SchemaField
+	seen := make(map[string]bool)
+	for _, t := range schema.ItemTypes {
+		for _, f := range t.Fields {
+			if !seen[f.Field] {
+				seen[f.Field] = true
+				fields = append(fields, zotero.SchemaField{Field: f.Field, Localized: schema.fieldName(f.Field)})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, fields)
+}
+
+// getItemTypeFields lists the fields of an item type
+func (s *Server) getItemTypeFields(w http.ResponseWriter, r *http.Request, lib *library) {
+	schema := loadSchema()
+	itemType := r.URL.Query().Get("itemType")
+	names, _, ok := schema.itemTypeFields(itemType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Invalid item type '%s'", itemType), http.StatusBadRequest)
+		return
+	}
+	fields := make([]zotero.SchemaField, len(names))
+	for i, name := range names {
+		fields[i] = zotero.SchemaField{Field: name, Localized: schema.fieldName(name)}
+	}
+	writeJSON(w, http.StatusOK, fields)
+}
+
+// getItemTypeCreatorTypes lists the creator types of an item type
+func (s *Server) getItemTypeCreatorTypes(w http.ResponseWriter, r *http.Request, lib *library) {
+	schema := loadSchema()
+	itemType := r.URL.Query().Get("itemType")
+	_, names, ok := schema.itemTypeFields(itemType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Invalid item type '%s'", itemType), http.StatusBadRequest)
+		return
+	}
+	creatorTypes := make([]zotero.SchemaCreatorType, len(names))
+	for i, name := range names {
+		creatorTypes[i] = zotero.SchemaCreatorType{CreatorType: name, Localized: schema.creatorTypeName(name)}
+	}
+	writeJSON(w, http.StatusOK, creatorTypes)
+}
+
+// getCreatorFields lists the fields of creators
+func (s *Server) getCreatorFields(w http.ResponseWriter, r *http.Request, lib *library) {
+	names := loadSchema().Locales["en-US"].CreatorFields
+	fields := []zotero.SchemaField{}
+	for _, name := range []string{"firstName", "lastName", "name"} {
+		fields = append(fields, zotero.SchemaField{Field: name, Localized: names[name]})
+	}
+	writeJSON(w, http.StatusOK, fields)
+}
+
+// getItemTemplate returns the data of a new item of a type, with every field empty.
+// Attachments need a linkMode and annotations an annotationType.
+func (s *Server) getItemTemplate(w http.ResponseWriter, r *http.Request, lib *library) {
+	query := r.URL.Query()
+	itemType := query.Get("itemType")
+	fields, creatorTypes, ok := loadSchema().itemTypeFields(itemType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Invalid item type '%s'", itemType), http.StatusBadRequest)
+		return
+	}
+
+	template := map[string]any{"itemType": itemType}
+	switch itemType {
+	case zotero.ItemTypeNote:
+		template["note"] = ""
+	case zotero.ItemTypeAttachment:
+		linkMode := zotero.LinkMode(query.Get("linkMode"))
+		switch linkMode {
+		case zotero.LinkModeImportedFile, zotero.LinkModeImportedURL, zotero.LinkModeLinkedFile, zotero.LinkModeLinkedURL:
+		default:
+			http.Error(w, fmt.Sprintf("Invalid linkMode '%s'", linkMode), http.StatusBadRequest)
+			return
+		}
+		template["linkMode"] = linkMode
+		template["note"] = ""
+		template["contentType"] = ""
+		template["charset"] = ""
+		if linkMode.HasFile() {
+			template["filename"] = ""
+			template["md5"] = nil
+			template["mtime"] = nil
+		}
+		if linkMode == zotero.LinkModeLinkedFile {
+			template["path"] = ""
+		}
+	case zotero.ItemTypeAnnotation:
+		annotationType := query.Get("annotationType")
+		if annotationType == "" {
+			http.Error(w, "'annotationType' not provided", http.StatusBadRequest)
+			return
+		}
+		template["parentItem"] = ""
+		template["annotationType"] = annotationType
+		template["annotationComment"] = ""
+		template["annotationColor"] = ""
+		template["annotationPageLabel"] = ""
+		template["annotationSortIndex"] = ""
+		template["annotationPosition"] = ""
+		if annotationType == "highlight" || annotationType == "underline" {
+			template["annotationText"] = ""
+		}
+	default:
+		template["creators"] = []map[string]string{{"creatorType": creatorTypes[0], "firstName": "", "lastName": ""}}
+	}
+	for _, field := range fields {
+		template[field] = ""
+	}
+	template["tags"] = []any{}
+	if itemType != zotero.ItemTypeAnnotation {
+		template["collections"] = []any{}
+	}
+	template["relations"] = map[string]any{}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// baseProperties are the properties every item can have besides the fields of its type
+var baseProperties = []string{
+	"key", "version", "itemType", "creators", "tags", "collections", "relations", "dateAdded",
+	"dateModified", "deleted", "inPublications", "parentItem",
+}
+
+// typeProperties are the properties of notes, attachments and annotations
+var typeProperties = map[string][]string{
+	zotero.ItemTypeNote:       {"note"},
+	zotero.ItemTypeAttachment: {"linkMode", "note", "contentType", "charset", "filename", "md5", "mtime", "path"},
+	zotero.ItemTypeAnnotation: {
+		"annotationType", "annotationText", "annotationComment", "annotationColor", "annotationPageLabel",
+		"annotationSortIndex", "annotationPosition", "annotationAuthorName", "annotationIsExternal",
+	},
+}
+
+// validateItem checks the data of an item against the schema: its item type, properties and
+// creator types
+func validateItem(data map[string]any) *zotero.FailedWrite {
+	schema := loadSchema()
+	itemType, _ := data["itemType"].(string)
+	if itemType == "" {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "'itemType' property not provided"}
+	}
+	fields, creatorTypes, ok := schema.itemTypeFields(itemType)
+	if !ok {
+		return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("'%s' is not a valid item type", itemType)}
+	}
+
+	for name := range data {
+		switch {
+		case slices.Contains(baseProperties, name), slices.Contains(typeProperties[itemType], name), slices.Contains(fields, name):
+		case schema.isField(name):
+			return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("'%s' is not a valid field for type '%s'", name, itemType)}
+		default:
+			return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("Invalid property '%s'", name)}
+		}
+	}
+
+	creators, _ := data["creators"].([]any)
+	for _, creator := range creators {
+		creatorType, _ := creator.(map[string]any)["creatorType"].(string)
+		if !slices.Contains(creatorTypes, creatorType) {
+			return &zotero.FailedWrite{Code: http.StatusBadRequest, Message: fmt.Sprintf("'%s' is not a valid creator type for item type '%s'", creatorType, itemType)}
+		}
+	}
+	return nil
+}
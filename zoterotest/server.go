@@ -0,0 +1,391 @@
+// Package zoterotest runs a fake Zotero Web API server in-process, for testing code that uses
+// the zotero package without credentials or network access.
+//
+// The server keeps its libraries in memory (see the memory package) and follows the API where
+// clients can tell the difference: library and object versions, If-Unmodified-Since-Version
+// and If-Modified-Since-Version, the 50-object limit of writes, Zotero-Write-Token, paging
+// with the Total-Results and Link headers, API key permissions, and the file upload flow of
+// authorization, storage and registration. Faults (rate limiting, latency and server errors)
+// can be injected to exercise error handling.
+//
+//	srv := zoterotest.NewServer()
+//	defer srv.Close()
+//
+//	client := srv.Client()
+//	resp, err := client.CreateItems(ctx, []zotero.Item{{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: "A Book"}}})
+//
+// Libraries can also be filled directly through their memory.Library:
+//
+//	srv.UserLibrary().CreateCollections(ctx, []zotero.Collection{{Data: zotero.CollectionData{Name: "Reading"}}})
+//
+// The item types, fields and creator types come from a copy of the Zotero schema in English.
+// Other locales are answered in English, citation formats (bib, citation, ...) are not
+// supported, and file patches (PATCH /items/{key}/file) are refused with 501 Not Implemented.
+//
+// For tests that need the live API, a Recorder records its interactions to a cassette file
+// once and replays them afterwards without network access.
+//
+// The zotero package's own tests keep their httptest handlers: they are internal tests of
+// package zotero, which this package imports, so they cannot use the server without first
+// moving to an external test package. That migration is not done here.
+package zoterotest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Epistemic-Technology/zotero/memory"
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+const (
+	// DefaultAPIKey is the API key the server accepts unless WithAPIKey is given
+	DefaultAPIKey = "zoterotest0apikey0000000"
+	// DefaultUserID is the ID of the user owning the API key unless WithUser is given
+	DefaultUserID = 1
+	// DefaultUsername is the name of the user owning the API key unless WithUser is given
+	DefaultUsername = "zoterotest"
+)
+
+// Server is a fake Zotero Web API server. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, to use with zotero.WithBaseURL
+	URL string
+
+	srv      *httptest.Server
+	apiKey   string
+	userID   int
+	username string
+	readOnly bool
+	groups   []zotero.Group
+
+	mu        sync.Mutex
+	libraries map[string]*library // Keyed by path prefix, e.g. "users/1"
+	uploads   map[string]*upload  // Authorized uploads by upload key
+	storage   map[string][]byte   // File content by MD5 hash
+	faults    []*fault
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithAPIKey sets the API key the server accepts
+func WithAPIKey(apiKey string) Option {
+	return func(s *Server) {
+		s.apiKey = apiKey
+	}
+}
+
+// WithUser sets the user owning the API key and the user library
+func WithUser(userID int, username string) Option {
+	return func(s *Server) {
+		s.userID = userID
+		s.username = username
+	}
+}
+
+// WithGroup adds a group library the API key can read and write
+func WithGroup(group zotero.Group) Option {
+	return func(s *Server) {
+		s.groups = append(s.groups, group)
+	}
+}
+
+// WithReadOnlyKey makes the API key read-only, so writes fail with 403 Forbidden
+func WithReadOnlyKey() Option {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// library is a library served by the server
+type library struct {
+	info  zotero.Library
+	base  string // URL of the library, e.g. "http://127.0.0.1:1234/users/1"
+	store *memory.Library
+
+	// mu serializes writes, so the preconditions checked by the server hold while the store
+	// applies them
+	mu          sync.Mutex
+	writeTokens map[string]bool
+}
+
+// NewServer starts a server with an empty user library and an empty library for each group
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		apiKey:    DefaultAPIKey,
+		userID:    DefaultUserID,
+		username:  DefaultUsername,
+		libraries: make(map[string]*library),
+		uploads:   make(map[string]*upload),
+		storage:   make(map[string][]byte),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.srv = httptest.NewServer(s.handler())
+	s.URL = s.srv.URL
+
+	s.addLibrary(zotero.LibraryTypeUser, zotero.Library{Type: "user", ID: s.userID, Name: s.username})
+	for _, group := range s.groups {
+		s.addLibrary(zotero.LibraryTypeGroup, zotero.Library{Type: "group", ID: group.ID, Name: group.Name})
+	}
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a client for the user library, with the server's API key and no rate
+// limiting. opts are applied after these.
+func (s *Server) Client(opts ...zotero.ClientOption) *zotero.Client {
+	return s.newClient(zotero.LibraryTypeUser, s.userID, opts)
+}
+
+// GroupClient returns a client for a group library added with WithGroup
+func (s *Server) GroupClient(groupID int, opts ...zotero.ClientOption) *zotero.Client {
+	return s.newClient(zotero.LibraryTypeGroup, groupID, opts)
+}
+
+func (s *Server) newClient(libraryType zotero.LibraryType, libraryID int, opts []zotero.ClientOption) *zotero.Client {
+	opts = append([]zotero.ClientOption{
+		zotero.WithBaseURL(s.URL),
+		zotero.WithAPIKey(s.apiKey),
+		zotero.WithRateLimit(0),
+		zotero.WithHTTPClient(&http.Client{Transport: s.srv.Client().Transport}),
+	}, opts...)
+	return zotero.NewClient(strconv.Itoa(libraryID), libraryType, opts...)
+}
+
+// UserLibrary returns the user library, to fill or inspect it without going through the API
+func (s *Server) UserLibrary() *memory.Library {
+	return s.libraries[libraryPath(zotero.LibraryTypeUser, s.userID)].store
+}
+
+// GroupLibrary returns a group library added with WithGroup, or nil if there is none
+func (s *Server) GroupLibrary(groupID int) *memory.Library {
+	lib, ok := s.libraries[libraryPath(zotero.LibraryTypeGroup, groupID)]
+	if !ok {
+		return nil
+	}
+	return lib.store
+}
+
+func (s *Server) addLibrary(libraryType zotero.LibraryType, info zotero.Library) {
+	path := libraryPath(libraryType, info.ID)
+	info.Links = zotero.Links{Alternate: zotero.Link{Href: s.URL + "/" + path, Type: "text/html"}}
+	s.libraries[path] = &library{
+		info:        info,
+		base:        s.URL + "/" + path,
+		store:       memory.New(info),
+		writeTokens: make(map[string]bool),
+	}
+}
+
+func libraryPath(libraryType zotero.LibraryType, libraryID int) string {
+	return fmt.Sprintf("%s/%d", libraryType, libraryID)
+}
+
+// handler routes requests to the endpoints of the API, after applying any injected fault
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	s.handleLibrary(mux, "GET /items", s.getItems)
+	s.handleLibrary(mux, "GET /items/top", s.getItems)
+	s.handleLibrary(mux, "GET /items/trash", s.getItems)
+	s.handleLibrary(mux, "GET /items/{itemKey}", s.getItem)
+	s.handleLibrary(mux, "GET /items/{itemKey}/children", s.getItems)
+	s.handleLibrary(mux, "GET /items/{itemKey}/tags", s.getTags)
+	s.handleLibrary(mux, "GET /collections", s.getCollections)
+	s.handleLibrary(mux, "GET /collections/top", s.getCollections)
+	s.handleLibrary(mux, "GET /collections/{collectionKey}", s.getCollection)
+	s.handleLibrary(mux, "GET /collections/{collectionKey}/collections", s.getCollections)
+	s.handleLibrary(mux, "GET /collections/{collectionKey}/items", s.getItems)
+	s.handleLibrary(mux, "GET /collections/{collectionKey}/items/top", s.getItems)
+	s.handleLibrary(mux, "GET /collections/{collectionKey}/tags", s.getTags)
+	s.handleLibrary(mux, "GET /searches", s.getSearches)
+	s.handleLibrary(mux, "GET /searches/{searchKey}", s.getSearch)
+	s.handleLibrary(mux, "GET /searches/{searchKey}/items", s.getItems)
+	s.handleLibrary(mux, "GET /tags", s.getTags)
+	s.handleLibrary(mux, "GET /deleted", s.getDeleted)
+
+	s.handleLibrary(mux, "POST /items", s.postItems)
+	s.handleLibrary(mux, "PATCH /items/{itemKey}", s.patchItem)
+	s.handleLibrary(mux, "PUT /items/{itemKey}", s.putItem)
+	s.handleLibrary(mux, "DELETE /items/{itemKey}", s.deleteItem)
+	s.handleLibrary(mux, "DELETE /items", s.deleteItems)
+	s.handleLibrary(mux, "POST /collections", s.postCollections)
+	s.handleLibrary(mux, "PATCH /collections/{collectionKey}", s.patchCollection)
+	s.handleLibrary(mux, "DELETE /collections/{collectionKey}", s.deleteCollection)
+	s.handleLibrary(mux, "DELETE /collections", s.deleteCollections)
+	s.handleLibrary(mux, "POST /searches", s.postSearches)
+	s.handleLibrary(mux, "PATCH /searches/{searchKey}", s.patchSearch)
+	s.handleLibrary(mux, "DELETE /searches/{searchKey}", s.deleteSearch)
+	s.handleLibrary(mux, "DELETE /searches", s.deleteSearches)
+	s.handleLibrary(mux, "DELETE /tags", s.deleteTags)
+
+	s.handleLibrary(mux, "GET /items/{itemKey}/file", s.getFile)
+	s.handleLibrary(mux, "GET /items/{itemKey}/file/view", s.getFile)
+	s.handleLibrary(mux, "POST /items/{itemKey}/file", s.postFile)
+	s.handleLibrary(mux, "PATCH /items/{itemKey}/file", s.patchFile)
+	mux.HandleFunc("POST /storage/upload", s.uploadToStorage)
+	mux.HandleFunc("GET /storage/{md5}", s.getStorage)
+
+	s.handleLibrary(mux, "GET /itemTypes", s.getItemTypes)
+	s.handleLibrary(mux, "GET /itemFields", s.getItemFields)
+	s.handleLibrary(mux, "GET /itemTypeFields", s.getItemTypeFields)
+	s.handleLibrary(mux, "GET /itemTypeCreatorTypes", s.getItemTypeCreatorTypes)
+	s.handleLibrary(mux, "GET /creatorFields", s.getCreatorFields)
+	s.handleLibrary(mux, "GET /items/new", s.getItemTemplate)
+
+	mux.HandleFunc("GET /keys/{key}", s.getKey)
+	mux.HandleFunc("GET /users/{userID}/groups", s.getGroups)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.injectFault(w, r) {
+			return
+		}
+		w.Header().Set("Zotero-API-Version", "3")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// libraryHandler handles a request for a library
+type libraryHandler func(w http.ResponseWriter, r *http.Request, lib *library)
+
+// handleLibrary registers an endpoint under the user and group library paths. The handler is
+// called once the API key is checked for access to the library.
+func (s *Server) handleLibrary(mux *http.ServeMux, pattern string, handle libraryHandler) {
+	method, path, _ := strings.Cut(pattern, " ")
+	mux.HandleFunc(method+" /{libraryType}/{libraryID}"+path, func(w http.ResponseWriter, r *http.Request) {
+		lib, status, message := s.authorize(r, method != http.MethodGet)
+		if lib == nil {
+			http.Error(w, message, status)
+			return
+		}
+		handle(w, r, lib)
+	})
+}
+
+// authorize finds the library of a request and checks that its API key grants access to it
+func (s *Server) authorize(r *http.Request, write bool) (*library, int, string) {
+	libraryType := zotero.LibraryType(r.PathValue("libraryType"))
+	libraryID, err := strconv.Atoi(r.PathValue("libraryID"))
+	if (libraryType != zotero.LibraryTypeUser && libraryType != zotero.LibraryTypeGroup) || err != nil {
+		return nil, http.StatusNotFound, "Not found"
+	}
+
+	switch key := requestKey(r); {
+	case key == "":
+		return nil, http.StatusForbidden, "Forbidden"
+	case key != s.apiKey:
+		return nil, http.StatusForbidden, "Invalid key"
+	}
+	lib, ok := s.libraries[libraryPath(libraryType, libraryID)]
+	switch {
+	case !ok && libraryType == zotero.LibraryTypeGroup:
+		return nil, http.StatusNotFound, "Group not found"
+	case !ok:
+		return nil, http.StatusForbidden, "Forbidden"
+	case write && s.readOnly:
+		return nil, http.StatusForbidden, "Write access denied"
+	}
+	return lib, 0, ""
+}
+
+// requestKey returns the API key of a request, given in the Zotero-API-Key header, as a
+// bearer token or in the key query parameter
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("Zotero-API-Key"); key != "" {
+		return key
+	}
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return key
+	}
+	return r.URL.Query().Get("key")
+}
+
+// getKey describes the server's API key
+func (s *Server) getKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if key == "current" {
+		key = requestKey(r)
+	}
+	if key != s.apiKey {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+
+	access := zotero.LibraryAccess{Library: true, Files: true, Notes: true, Write: !s.readOnly}
+	info := zotero.KeyInfo{
+		Key:      s.apiKey,
+		UserID:   s.userID,
+		Username: s.username,
+		Access:   zotero.KeyAccess{User: &access},
+	}
+	if len(s.groups) > 0 {
+		info.Access.Groups = make(map[string]zotero.LibraryAccess)
+		for _, group := range s.groups {
+			info.Access.Groups[strconv.Itoa(group.ID)] = zotero.LibraryAccess{Library: true, Write: !s.readOnly}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// getGroups lists the groups of the user
+func (s *Server) getGroups(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("userID") != strconv.Itoa(s.userID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if requestKey(r) != s.apiKey {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	groups := make([]zotero.Group, 0, len(s.groups))
+	for _, group := range s.groups {
+		if group.Owner == 0 {
+			group.Owner = s.userID
+		}
+		groups = append(groups, group)
+	}
+	w.Header().Set("Total-Results", strconv.Itoa(len(groups)))
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// writeJSON writes v as the JSON body of a response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeError writes an error returned by a memory.Library: API errors keep their status and
+// message, and other errors are invalid requests
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *zotero.APIError
+	if errors.As(err, &apiErr) {
+		http.Error(w, apiErr.Message, apiErr.StatusCode)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// setVersion sets the Last-Modified-Version header
+func setVersion(w http.ResponseWriter, version int) {
+	w.Header().Set("Last-Modified-Version", strconv.Itoa(version))
+}
@@ -0,0 +1,353 @@
+package zoterotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+func newServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	s := NewServer(opts...)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// create creates items through the API and returns their keys, failing on any failure
+func create(t *testing.T, c *zotero.Client, items ...zotero.Item) []string {
+	t.Helper()
+	resp, err := c.CreateItems(context.Background(), items)
+	if err != nil {
+		t.Fatalf("CreateItems() error = %v", err)
+	}
+	if len(resp.Failed) > 0 {
+		t.Fatalf("CreateItems() failed = %+v", resp.Failed)
+	}
+	keys := make([]string, len(items))
+	for i := range items {
+		keys[i] = resp.Success[strconv.Itoa(i)].(string)
+	}
+	return keys
+}
+
+func book(title string) zotero.Item {
+	return zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: title}}
+}
+
+// do sends a raw request to the server with its API key
+func do(t *testing.T, s *Server, method, path string, body string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Zotero-API-Key", DefaultAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func statusCode(err error) int {
+	var apiErr *zotero.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+func TestItems(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+
+	keys := create(t, c, book("First"), book("Second"))
+	item, err := c.Item(ctx, keys[0], nil)
+	if err != nil {
+		t.Fatalf("Item() error = %v", err)
+	}
+	if item.Data.Title != "First" || item.Version != 1 {
+		t.Errorf("Item() = %q version %d, want \"First\" version 1", item.Data.Title, item.Version)
+	}
+	if !strings.HasPrefix(item.Links.Self.Href, s.URL+"/users/1/items/") {
+		t.Errorf("Item() self link = %q", item.Links.Self.Href)
+	}
+
+	item.Data.Title = "Updated"
+	if err := c.UpdateItem(ctx, item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	// The item is now at version 2, so its version 1 is stale
+	if err := c.UpdateItem(ctx, item); statusCode(err) != http.StatusPreconditionFailed {
+		t.Errorf("UpdateItem() with stale version error = %v, want 412", err)
+	}
+	if err := c.DeleteItem(ctx, keys[0], 1); statusCode(err) != http.StatusPreconditionFailed {
+		t.Errorf("DeleteItem() with stale version error = %v, want 412", err)
+	}
+
+	version, err := c.LastModifiedVersion(ctx)
+	if err != nil {
+		t.Fatalf("LastModifiedVersion() error = %v", err)
+	}
+	if err := c.DeleteItem(ctx, keys[1], 1); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	deleted, err := c.Deleted(ctx, version)
+	if err != nil {
+		t.Fatalf("Deleted() error = %v", err)
+	}
+	if len(deleted.Items) != 1 || deleted.Items[0] != keys[1] {
+		t.Errorf("Deleted() items = %v, want [%s]", deleted.Items, keys[1])
+	}
+	if _, err := c.Item(ctx, keys[1], nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("Item() of deleted item error = %v, want 404", err)
+	}
+}
+
+func TestItemsPaging(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+
+	var items []zotero.Item
+	for i := range 30 {
+		items = append(items, book(fmt.Sprintf("Book %02d", i)))
+	}
+	create(t, c, items...)
+
+	n, err := c.NumItems(ctx)
+	if err != nil {
+		t.Fatalf("NumItems() error = %v", err)
+	}
+	if n != 30 {
+		t.Errorf("NumItems() = %d, want 30", n)
+	}
+	page, err := c.Items(ctx, &zotero.QueryParams{Limit: 10, Start: 25, Sort: string(zotero.SortTitle)})
+	if err != nil {
+		t.Fatalf("Items() error = %v", err)
+	}
+	if len(page) != 5 || page[0].Data.Title != "Book 25" {
+		t.Errorf("Items() page = %d items starting at %q, want 5 starting at \"Book 25\"", len(page), page[0].Data.Title)
+	}
+
+	resp := do(t, s, http.MethodGet, "/users/1/items?limit=10", "", nil)
+	if got := resp.Header.Get("Total-Results"); got != "30" {
+		t.Errorf("Total-Results = %q, want 30", got)
+	}
+	if link := resp.Header.Get("Link"); !strings.Contains(link, `start=10>; rel="next"`) || !strings.Contains(link, `start=20>; rel="last"`) {
+		t.Errorf("Link = %q", link)
+	}
+
+	resp = do(t, s, http.MethodGet, "/users/1/items?limit=1000", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET with limit=1000 status = %d, want 200", resp.StatusCode)
+	}
+	resp = do(t, s, http.MethodGet, "/users/1/items", "", http.Header{"If-Modified-Since-Version": {"1"}})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with current If-Modified-Since-Version status = %d, want 304", resp.StatusCode)
+	}
+}
+
+func TestWriteLimits(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+
+	body := `[{"itemType":"book","title":"Token"}]`
+	if _, err := c.CreateItems(ctx, nil); err == nil {
+		t.Error("CreateItems() with no items error = nil")
+	}
+	tooMany := "[" + strings.Repeat(`{"itemType":"book"},`, 50) + `{"itemType":"book"}]`
+	if resp := do(t, s, http.MethodPost, "/users/1/items", tooMany, nil); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST with 51 items status = %d, want 413", resp.StatusCode)
+	}
+
+	token := http.Header{"Zotero-Write-Token": {"0123456789abcdef0123456789abcdef"}}
+	if resp := do(t, s, http.MethodPost, "/users/1/items", body, token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST with write token status = %d, want 200", resp.StatusCode)
+	}
+	if resp := do(t, s, http.MethodPost, "/users/1/items", body, token); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("POST with reused write token status = %d, want 412", resp.StatusCode)
+	}
+
+	stale := http.Header{"If-Unmodified-Since-Version": {"0"}}
+	if resp := do(t, s, http.MethodPost, "/users/1/items", body, stale); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("POST with stale library version status = %d, want 412", resp.StatusCode)
+	}
+	if resp := do(t, s, http.MethodDelete, "/users/1/items?itemKey=ABCD2345", "", nil); resp.StatusCode != http.StatusPreconditionRequired {
+		t.Errorf("DELETE without version status = %d, want 428", resp.StatusCode)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+
+	tests := []struct {
+		name string
+		item zotero.Item
+		want string
+	}{
+		{"invalid item type", zotero.Item{Data: zotero.ItemData{ItemType: "nonsense"}}, "'nonsense' is not a valid item type"},
+		{"field of another type", zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Title: "T", Extra: map[string]any{"publicationTitle": "Journal"}}}, "'publicationTitle' is not a valid field for type 'book'"},
+		{"invalid creator type", zotero.Item{Data: zotero.ItemData{ItemType: zotero.ItemTypeBook, Creators: []zotero.Creator{{CreatorType: "director", Name: "X"}}}}, "'director' is not a valid creator type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.CreateItems(ctx, []zotero.Item{tt.item})
+			if err != nil {
+				t.Fatalf("CreateItems() error = %v", err)
+			}
+			failed, ok := resp.Failed["0"]
+			if !ok || failed.Code != http.StatusBadRequest || !strings.Contains(failed.Message, tt.want) {
+				t.Errorf("CreateItems() failed = %+v, want 400 %q", resp.Failed, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	ctx := context.Background()
+	c := newServer(t).Client()
+
+	itemTypes, err := c.ItemTypes(ctx, "")
+	if err != nil {
+		t.Fatalf("ItemTypes() error = %v", err)
+	}
+	if len(itemTypes) < 30 {
+		t.Errorf("ItemTypes() = %d types, want the full schema", len(itemTypes))
+	}
+	fields, err := c.ItemTypeFields(ctx, zotero.ItemTypeBook, "")
+	if err != nil {
+		t.Fatalf("ItemTypeFields() error = %v", err)
+	}
+	if len(fields) == 0 || fields[0].Field != "title" || fields[0].Localized != "Title" {
+		t.Errorf("ItemTypeFields() = %+v", fields)
+	}
+	if _, err := c.ItemTypeFields(ctx, "nonsense", ""); statusCode(err) != http.StatusBadRequest {
+		t.Errorf("ItemTypeFields() of invalid type error = %v, want 400", err)
+	}
+	template, err := c.NewItemTemplate(ctx, zotero.ItemTypeJournalArticle)
+	if err != nil {
+		t.Fatalf("NewItemTemplate() error = %v", err)
+	}
+	if _, ok := template["publicationTitle"]; !ok || template["itemType"] != zotero.ItemTypeJournalArticle {
+		t.Errorf("NewItemTemplate() = %v", template)
+	}
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+	parent := create(t, c, book("Parent"))[0]
+
+	content := []byte("%PDF-1.4 zoterotest")
+	opts := &zotero.UploadOptions{Filename: "paper.pdf", ContentType: "application/pdf"}
+	attachment, err := c.UploadAttachmentReader(ctx, parent, bytes.NewReader(content), int64(len(content)), opts)
+	if err != nil {
+		t.Fatalf("UploadAttachmentReader() error = %v", err)
+	}
+	if attachment.Data.MD5 != md5Hex(content) {
+		t.Errorf("attachment MD5 = %q, want %q", attachment.Data.MD5, md5Hex(content))
+	}
+	got, err := c.File(ctx, attachment.Key)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("File() = %q, want %q", got, content)
+	}
+
+	// The same content again is already in storage
+	second, err := c.UploadAttachmentReader(ctx, "", bytes.NewReader(content), int64(len(content)), opts)
+	if err != nil {
+		t.Fatalf("second UploadAttachmentReader() error = %v", err)
+	}
+	if got, err := c.File(ctx, second.Key); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("File() of second attachment = %q, %v", got, err)
+	}
+
+	if resp := do(t, s, http.MethodPost, "/users/1/items/"+attachment.Key+"/file", "md5=x&filename=a&filesize=1&mtime=1", http.Header{
+		"Content-Type":  {"application/x-www-form-urlencoded"},
+		"If-None-Match": {"*"},
+	}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("upload with If-None-Match: * over existing file status = %d, want 412", resp.StatusCode)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t, WithReadOnlyKey(), WithGroup(zotero.Group{ID: 7, Name: "Lab"}))
+
+	if _, err := s.Client(zotero.WithAPIKey("wrong")).Items(ctx, nil); statusCode(err) != http.StatusForbidden {
+		t.Errorf("Items() with wrong key error = %v, want 403", err)
+	}
+	if _, err := s.Client().CreateItems(ctx, []zotero.Item{book("A")}); statusCode(err) != http.StatusForbidden {
+		t.Errorf("CreateItems() with read-only key error = %v, want 403", err)
+	}
+	if _, err := s.GroupClient(7).Items(ctx, nil); err != nil {
+		t.Errorf("Items() of group error = %v", err)
+	}
+	if _, err := s.GroupClient(8).Items(ctx, nil); statusCode(err) != http.StatusNotFound {
+		t.Errorf("Items() of unknown group error = %v, want 404", err)
+	}
+	if _, err := s.GroupLibrary(7).CreateItems(ctx, []zotero.Item{book("Seeded")}); err != nil {
+		t.Fatalf("GroupLibrary().CreateItems() error = %v", err)
+	}
+	if n, err := s.GroupClient(7).NumItems(ctx); err != nil || n != 1 {
+		t.Errorf("NumItems() of group = %d, %v, want 1", n, err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t)
+	c := s.Client()
+
+	s.Inject(Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Second, Times: 1})
+	if _, err := c.Items(ctx, nil); !errors.Is(err, zotero.ErrRateLimited) {
+		t.Errorf("Items() error = %v, want ErrRateLimited", err)
+	}
+	if _, err := c.Items(ctx, nil); err != nil {
+		t.Errorf("Items() after fault error = %v", err)
+	}
+
+	s.Inject(Fault{Method: http.MethodPost, Path: "/users/1/items", Status: http.StatusInternalServerError})
+	if _, err := c.Items(ctx, nil); err != nil {
+		t.Errorf("Items() with POST fault error = %v", err)
+	}
+	for range 2 {
+		if _, err := c.CreateItems(ctx, []zotero.Item{book("A")}); statusCode(err) != http.StatusInternalServerError {
+			t.Errorf("CreateItems() error = %v, want 500", err)
+		}
+	}
+	s.ClearFaults()
+
+	s.Inject(Fault{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := c.Items(ctx, nil); err != nil {
+		t.Errorf("Items() with latency error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Items() with latency took %v, want at least 50ms", elapsed)
+	}
+	slow := s.Client(zotero.WithTimeout(10 * time.Millisecond))
+	if _, err := slow.Items(ctx, nil); err == nil {
+		t.Error("Items() with latency beyond timeout error = nil")
+	}
+}
@@ -0,0 +1,474 @@
+package zoterotest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Epistemic-Technology/zotero/memory"
+	"github.com/Epistemic-Technology/zotero/zotero"
+)
+
+// kind adapts the items, collections or saved searches of a memory.Library to the write
+// endpoints the three share
+type kind[T any] struct {
+	keyParam string // Query parameter listing keys to delete, e.g. "itemKey"
+
+	decode   func(data []byte) (T, error)
+	validate func(data map[string]any) *zotero.FailedWrite // Checks an object against the schema
+	get      func(ctx context.Context, lib *library, key string) (any, error)
+
+	create    func(lib *memory.Library, ctx context.Context, objects []T) (*zotero.WriteResponse, error)
+	update    func(lib *memory.Library, ctx context.Context, object *T) error
+	replace   func(lib *memory.Library, ctx context.Context, object *T) error
+	deleteOne func(lib *memory.Library, ctx context.Context, key string, version int) error
+	deleteAll func(lib *memory.Library, ctx context.Context, keys []string, version int) error
+}
+
+var items = kind[zotero.Item]{
+	keyParam: "itemKey",
+	decode: func(data []byte) (zotero.Item, error) {
+		var item zotero.Item
+		err := json.Unmarshal(data, &item.Data)
+		return item, err
+	},
+	validate: validateItem,
+	get: func(ctx context.Context, lib *library, key string) (any, error) {
+		item, err := lib.store.Item(ctx, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		lib.itemLinks(item)
+		return item, nil
+	},
+	create:    (*memory.Library).CreateItems,
+	update:    (*memory.Library).UpdateItem,
+	replace:   (*memory.Library).ReplaceItem,
+	deleteOne: (*memory.Library).DeleteItem,
+	deleteAll: (*memory.Library).DeleteItems,
+}
+
+var collections = kind[zotero.Collection]{
+	keyParam: "collectionKey",
+	decode: func(data []byte) (zotero.Collection, error) {
+		var collection zotero.Collection
+		err := json.Unmarshal(data, &collection.Data)
+		return collection, err
+	},
+	validate: func(map[string]any) *zotero.FailedWrite { return nil },
+	get: func(ctx context.Context, lib *library, key string) (any, error) {
+		collection, err := lib.store.Collection(ctx, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		lib.collectionLinks(collection)
+		return collection, nil
+	},
+	create:    (*memory.Library).CreateCollections,
+	update:    (*memory.Library).UpdateCollection,
+	deleteOne: (*memory.Library).DeleteCollection,
+	deleteAll: (*memory.Library).DeleteCollections,
+}
+
+var searches = kind[zotero.Search]{
+	keyParam: "searchKey",
+	decode: func(data []byte) (zotero.Search, error) {
+		var search zotero.Search
+		err := json.Unmarshal(data, &search.Data)
+		return search, err
+	},
+	validate: func(map[string]any) *zotero.FailedWrite { return nil },
+	get: func(ctx context.Context, lib *library, key string) (any, error) {
+		search, err := lib.store.Search(ctx, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		lib.searchLinks(search)
+		return search, nil
+	},
+	create:    (*memory.Library).CreateSearches,
+	update:    (*memory.Library).UpdateSearch,
+	deleteOne: (*memory.Library).DeleteSearch,
+	deleteAll: (*memory.Library).DeleteSearches,
+}
+
+func (s *Server) postItems(w http.ResponseWriter, r *http.Request, lib *library) {
+	postObjects(w, r, lib, items)
+}
+
+func (s *Server) patchItem(w http.ResponseWriter, r *http.Request, lib *library) {
+	writeObject(w, r, lib, items, r.PathValue("itemKey"), false)
+}
+
+func (s *Server) putItem(w http.ResponseWriter, r *http.Request, lib *library) {
+	writeObject(w, r, lib, items, r.PathValue("itemKey"), true)
+}
+
+func (s *Server) deleteItem(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObject(w, r, lib, items, r.PathValue("itemKey"))
+}
+
+func (s *Server) deleteItems(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObjects(w, r, lib, items)
+}
+
+func (s *Server) postCollections(w http.ResponseWriter, r *http.Request, lib *library) {
+	postObjects(w, r, lib, collections)
+}
+
+func (s *Server) patchCollection(w http.ResponseWriter, r *http.Request, lib *library) {
+	writeObject(w, r, lib, collections, r.PathValue("collectionKey"), false)
+}
+
+func (s *Server) deleteCollection(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObject(w, r, lib, collections, r.PathValue("collectionKey"))
+}
+
+func (s *Server) deleteCollections(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObjects(w, r, lib, collections)
+}
+
+func (s *Server) postSearches(w http.ResponseWriter, r *http.Request, lib *library) {
+	postObjects(w, r, lib, searches)
+}
+
+func (s *Server) patchSearch(w http.ResponseWriter, r *http.Request, lib *library) {
+	writeObject(w, r, lib, searches, r.PathValue("searchKey"), false)
+}
+
+func (s *Server) deleteSearch(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObject(w, r, lib, searches, r.PathValue("searchKey"))
+}
+
+func (s *Server) deleteSearches(w http.ResponseWriter, r *http.Request, lib *library) {
+	deleteObjects(w, r, lib, searches)
+}
+
+// deleteTags removes tags from every item in the library
+func (s *Server) deleteTags(w http.ResponseWriter, r *http.Request, lib *library) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+	if tag == "" {
+		http.Error(w, "'tag' not provided", http.StatusBadRequest)
+		return
+	}
+	tags := strings.Split(tag, " || ")
+	if len(tags) > maxKeys {
+		http.Error(w, fmt.Sprintf("Only %d tags can be deleted in a single request", maxKeys), http.StatusBadRequest)
+		return
+	}
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	if err := lib.store.DeleteTags(r.Context(), version, tags...); err != nil {
+		writeError(w, err)
+		return
+	}
+	lib.writeNoContent(w, r.Context())
+}
+
+// writeResponse is the response to a multi-object write. Unlike zotero.WriteResponse it holds
+// the written objects, in successful, and encodes every map even when it is empty.
+type writeResponse struct {
+	Successful map[string]any                `json:"successful"`
+	Success    map[string]any                `json:"success"`
+	Unchanged  map[string]any                `json:"unchanged"`
+	Failed     map[string]zotero.FailedWrite `json:"failed"`
+}
+
+// postObjects creates and updates objects from the JSON array of a POST request. Each object
+// succeeds or fails on its own, except for the checks of the request as a whole: its size,
+// write token and If-Unmodified-Since-Version, which must be the library version.
+func postObjects[T any](w http.ResponseWriter, r *http.Request, lib *library, k kind[T]) {
+	var raws []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raws); err != nil {
+		http.Error(w, "Uploaded data must be a JSON array", http.StatusBadRequest)
+		return
+	}
+	if len(raws) == 0 {
+		http.Error(w, "No objects provided", http.StatusBadRequest)
+		return
+	}
+	if len(raws) > maxKeys {
+		http.Error(w, fmt.Sprintf("Only %d objects can be saved in a single request", maxKeys), http.StatusRequestEntityTooLarge)
+		return
+	}
+	libraryVersion, hasVersion, err := unmodifiedSince(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	token := r.Header.Get("Zotero-Write-Token")
+	if token != "" && lib.writeTokens[token] {
+		http.Error(w, "Write token already used", http.StatusPreconditionFailed)
+		return
+	}
+	if version, _ := lib.store.LastModifiedVersion(ctx); hasVersion && version != libraryVersion {
+		http.Error(w, fmt.Sprintf("Library has been modified since specified version (expected %d, found %d)", libraryVersion, version), http.StatusPreconditionFailed)
+		return
+	}
+
+	resp := writeResponse{
+		Successful: make(map[string]any),
+		Success:    make(map[string]any),
+		Unchanged:  make(map[string]any),
+		Failed:     make(map[string]zotero.FailedWrite),
+	}
+	var objects []T
+	var indexes []string // Index in the request of each object in objects
+	for i, raw := range raws {
+		index := strconv.Itoa(i)
+		object, failed := decodeObject(ctx, lib, k, raw, hasVersion)
+		if failed != nil {
+			resp.Failed[index] = *failed
+			continue
+		}
+		objects = append(objects, object)
+		indexes = append(indexes, index)
+	}
+
+	if len(objects) > 0 {
+		written, err := k.create(lib.store, ctx, objects)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for i, key := range written.Success {
+			index := indexes[atoi(i)]
+			resp.Success[index] = key
+			if object, err := k.get(ctx, lib, key.(string)); err == nil {
+				resp.Successful[index] = object
+			}
+		}
+		for i, key := range written.Unchanged {
+			resp.Unchanged[indexes[atoi(i)]] = key
+		}
+		for i, failed := range written.Failed {
+			resp.Failed[indexes[atoi(i)]] = failed
+		}
+	}
+
+	if token != "" {
+		lib.writeTokens[token] = true
+	}
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	setVersion(w, version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeObject decodes an object of a multi-object write. An existing object written without
+// a version is written at its current version when the request has If-Unmodified-Since-Version,
+// which the library version matched.
+func decodeObject[T any](ctx context.Context, lib *library, k kind[T], raw json.RawMessage, hasVersion bool) (T, *zotero.FailedWrite) {
+	var object T
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil || data == nil {
+		return object, &zotero.FailedWrite{Code: http.StatusBadRequest, Message: "Invalid JSON object"}
+	}
+
+	merged := data
+	if key, _ := data["key"].(string); key != "" {
+		if current, version, err := currentData(ctx, lib, k, key); err == nil {
+			if _, ok := data["version"]; !ok && hasVersion {
+				data["version"] = version
+			}
+			merged = maps.Clone(current)
+			maps.Copy(merged, data)
+		}
+	}
+	if failed := k.validate(merged); failed != nil {
+		return object, failed
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return object, &zotero.FailedWrite{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	object, err = k.decode(encoded)
+	if err != nil {
+		return object, &zotero.FailedWrite{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return object, nil
+}
+
+// writeObject writes a single object from the JSON object of a PATCH request, or with replace
+// the PUT request replacing it. The object's version is taken from If-Unmodified-Since-Version
+// or the version property.
+func writeObject[T any](w http.ResponseWriter, r *http.Request, lib *library, k kind[T], key string, replace bool) {
+	var data map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data == nil {
+		http.Error(w, "Uploaded data must be a JSON object", http.StatusBadRequest)
+		return
+	}
+	version, hasVersion, err := unmodifiedSince(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if bodyKey, _ := data["key"].(string); bodyKey != "" && bodyKey != key {
+		http.Error(w, fmt.Sprintf("Key '%s' does not match key '%s' from URI", bodyKey, key), http.StatusBadRequest)
+		return
+	}
+	data["key"] = key
+	if hasVersion {
+		data["version"] = version
+	}
+
+	ctx := r.Context()
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	current, _, err := currentData(ctx, lib, k, key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	merged := data
+	if !replace {
+		merged = maps.Clone(current)
+		maps.Copy(merged, data)
+	}
+	if failed := k.validate(merged); failed != nil {
+		http.Error(w, failed.Message, failed.Code)
+		return
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	object, err := k.decode(encoded)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	write := k.update
+	if replace {
+		write = k.replace
+	}
+	if err := write(lib.store, ctx, &object); err != nil {
+		writeError(w, err)
+		return
+	}
+	lib.writeNoContent(w, ctx)
+}
+
+// currentData returns the data of an object as JSON properties, and its version
+func currentData[T any](ctx context.Context, lib *library, k kind[T], key string) (map[string]any, int, error) {
+	object, err := k.get(ctx, lib, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, 0, err
+	}
+	var decoded struct {
+		Version int            `json:"version"`
+		Data    map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, 0, err
+	}
+	return decoded.Data, decoded.Version, nil
+}
+
+// deleteObject deletes an object at the version given in If-Unmodified-Since-Version
+func deleteObject[T any](w http.ResponseWriter, r *http.Request, lib *library, k kind[T], key string) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	if err := k.deleteOne(lib.store, r.Context(), key, version); err != nil {
+		writeError(w, err)
+		return
+	}
+	lib.writeNoContent(w, r.Context())
+}
+
+// deleteObjects deletes the objects listed in the key parameter of the kind. If-Unmodified-
+// Since-Version must be the library version.
+func deleteObjects[T any](w http.ResponseWriter, r *http.Request, lib *library, k kind[T]) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+	keys := r.URL.Query().Get(k.keyParam)
+	if keys == "" {
+		http.Error(w, fmt.Sprintf("'%s' not provided", k.keyParam), http.StatusBadRequest)
+		return
+	}
+	keyList := strings.Split(keys, ",")
+	if len(keyList) > maxKeys {
+		http.Error(w, fmt.Sprintf("Only %d keys can be deleted in a single request", maxKeys), http.StatusBadRequest)
+		return
+	}
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+
+	if err := k.deleteAll(lib.store, r.Context(), keyList, version); err != nil {
+		writeError(w, err)
+		return
+	}
+	lib.writeNoContent(w, r.Context())
+}
+
+// writeNoContent answers a successful write with 204 No Content and the new library version
+func (lib *library) writeNoContent(w http.ResponseWriter, ctx context.Context) {
+	version, _ := lib.store.LastModifiedVersion(ctx)
+	setVersion(w, version)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unmodifiedSince reads the If-Unmodified-Since-Version header, reporting whether it is given
+func unmodifiedSince(r *http.Request) (int, bool, error) {
+	header := r.Header.Get("If-Unmodified-Since-Version")
+	if header == "" {
+		return 0, false, nil
+	}
+	version, err := strconv.Atoi(header)
+	if err != nil || version < 0 {
+		return 0, false, fmt.Errorf("Invalid If-Unmodified-Since-Version value '%s'", header)
+	}
+	return version, true, nil
+}
+
+// requireVersion reads the If-Unmodified-Since-Version header that deletes must give. It
+// answers the request and returns false if the header is missing or invalid.
+func requireVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, ok, err := unmodifiedSince(r)
+	switch {
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, false
+	case !ok:
+		http.Error(w, "If-Unmodified-Since-Version not provided", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	return version, true
+}
+
+// atoi converts an index of a zotero.WriteResponse, which the memory package always formats
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}